@@ -55,7 +55,7 @@ BuildWinArm64() {
   export CC=$(pwd)/wrapper/zcc-arm64
   export CXX=$(pwd)/wrapper/zcxx-arm64
   export CGO_ENABLED=1
-  go build -o "$1" -ldflags="$ldflags" -tags=jsoniter .
+  go build -o "$1" -ldflags="$ldflags" -tags=jsoniter,nosqlite .
 }
 
 BuildDev() {
@@ -79,9 +79,9 @@ BuildDev() {
     export GOARCH=${os_arch##*-}
     export CC=${cgo_cc}
     export CGO_ENABLED=1
-    go build -o ./dist/$appName-$os_arch -ldflags="$muslflags" -tags=jsoniter .
+    go build -o ./dist/$appName-$os_arch -ldflags="$muslflags" -tags=jsoniter,nosqlite .
   done
-  xgo -targets=windows/amd64,darwin/amd64,darwin/arm64 -out "$appName" -ldflags="$ldflags" -tags=jsoniter .
+  xgo -targets=windows/amd64,darwin/amd64,darwin/arm64 -out "$appName" -ldflags="$ldflags" -tags=jsoniter,nosqlite .
   mv "$appName"-* dist
   cd dist
   # cp ./"$appName"-windows-amd64.exe ./"$appName"-windows-amd64-upx.exe
@@ -91,7 +91,7 @@ BuildDev() {
 }
 
 BuildDocker() {
-  go build -o ./bin/"$appName" -ldflags="$ldflags" -tags=jsoniter .
+  go build -o ./bin/"$appName" -ldflags="$ldflags" -tags=jsoniter,nosqlite .
 }
 
 PrepareBuildDockerMusl() {
@@ -127,7 +127,7 @@ BuildDockerMultiplatform() {
     export GOARCH=$arch
     export CC=${cgo_cc}
     echo "building for $os_arch"
-    go build -o build/$os/$arch/"$appName" -ldflags="$docker_lflags" -tags=jsoniter .
+    go build -o build/$os/$arch/"$appName" -ldflags="$docker_lflags" -tags=jsoniter,nosqlite .
   done
 
   DOCKER_ARM_ARCHES=(linux-arm/v6 linux-arm/v7)
@@ -141,7 +141,7 @@ BuildDockerMultiplatform() {
     export GOARM=${GO_ARM[$i]}
     export CC=${cgo_cc}
     echo "building for $docker_arch"
-    go build -o build/${docker_arch%%-*}/${docker_arch##*-}/"$appName" -ldflags="$docker_lflags" -tags=jsoniter .
+    go build -o build/${docker_arch%%-*}/${docker_arch##*-}/"$appName" -ldflags="$docker_lflags" -tags=jsoniter,nosqlite .
   done
 }
 
@@ -149,7 +149,7 @@ BuildRelease() {
   rm -rf .git/
   mkdir -p "build"
   BuildWinArm64 ./build/"$appName"-windows-arm64.exe
-  xgo -out "$appName" -ldflags="$ldflags" -tags=jsoniter .
+  xgo -out "$appName" -ldflags="$ldflags" -tags=jsoniter,nosqlite .
   # why? Because some target platforms seem to have issues with upx compression
   # upx -9 ./"$appName"-linux-amd64
   # cp ./"$appName"-windows-amd64.exe ./"$appName"-windows-amd64-upx.exe
@@ -179,7 +179,7 @@ BuildReleaseLinuxMusl() {
     export GOARCH=${os_arch##*-}
     export CC=${cgo_cc}
     export CGO_ENABLED=1
-    go build -o ./build/$appName-$os_arch -ldflags="$muslflags" -tags=jsoniter .
+    go build -o ./build/$appName-$os_arch -ldflags="$muslflags" -tags=jsoniter,nosqlite .
   done
 }
 
@@ -208,7 +208,7 @@ BuildReleaseLinuxMuslArm() {
     export CC=${cgo_cc}
     export CGO_ENABLED=1
     export GOARM=${arm}
-    go build -o ./build/$appName-$os_arch -ldflags="$muslflags" -tags=jsoniter .
+    go build -o ./build/$appName-$os_arch -ldflags="$muslflags" -tags=jsoniter,nosqlite .
   done
 }
 
@@ -228,7 +228,7 @@ BuildReleaseAndroid() {
     export GOARCH=${os_arch##*-}
     export CC=${cgo_cc}
     export CGO_ENABLED=1
-    go build -o ./build/$appName-android-$os_arch -ldflags="$ldflags" -tags=jsoniter .
+    go build -o ./build/$appName-android-$os_arch -ldflags="$ldflags" -tags=jsoniter,nosqlite .
     android-ndk-r26b/toolchains/llvm/prebuilt/linux-x86_64/bin/llvm-strip ./build/$appName-android-$os_arch
   done
 }
@@ -270,7 +270,7 @@ BuildReleaseFreeBSD() {
     export CC=${cgo_cc}
     export CGO_ENABLED=1
     export CGO_LDFLAGS="-fuse-ld=lld"
-    go build -o ./build/$appName-freebsd-$os_arch -ldflags="$ldflags" -tags=jsoniter .
+    go build -o ./build/$appName-freebsd-$os_arch -ldflags="$ldflags" -tags=jsoniter,nosqlite .
   done
 }
 
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/backup"
+	"github.com/OpenListTeam/OpenList/v4/internal/bootstrap"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupSections []string
+	backupPassword string
+	backupStrategy string
+)
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Export or import the storages, users, metas, settings, sharings and ssh keys",
+}
+
+var exportBackupCmd = &cobra.Command{
+	Use:     "export [file]",
+	Short:   "Export the instance into a json archive, printed to stdout if no file is given",
+	Example: `openlist backup export backup.json --sections storages,users,tasks --password 123456`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bootstrap.Init()
+		defer bootstrap.Release()
+		archive, err := backup.Export(backup.ExportOptions{
+			Sections: backupSections,
+			Password: backupPassword,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to export: %+v", err)
+		}
+		data, err := utils.Json.MarshalIndent(archive, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal archive: %+v", err)
+		}
+		if len(args) == 0 {
+			fmt.Println(string(data))
+			return nil
+		}
+		if err = os.WriteFile(args[0], data, 0o600); err != nil {
+			return fmt.Errorf("failed to write archive: %+v", err)
+		}
+		utils.Log.Infof("exported the backup to [%s] from CLI", args[0])
+		fmt.Printf("Exported the backup to [%s]\n", args[0])
+		return nil
+	},
+}
+
+var importBackupCmd = &cobra.Command{
+	Use:     "import [file]",
+	Short:   "Import a json archive, the imported storages are mounted by the next start",
+	Example: `openlist backup import backup.json --sections storages,metas --strategy rename --password 123456`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return fmt.Errorf("archive file is required")
+		}
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %+v", err)
+		}
+		var archive backup.Archive
+		if err = utils.Json.Unmarshal(data, &archive); err != nil {
+			return fmt.Errorf("failed to parse archive: %+v", err)
+		}
+		bootstrap.Init()
+		defer bootstrap.Release()
+		report, err := backup.Import(context.Background(), &archive, backup.ImportOptions{
+			Sections: backupSections,
+			Strategy: backupStrategy,
+			Password: backupPassword,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to import: %+v", err)
+		}
+		utils.Log.Infof("imported the backup [%s] from CLI", args[0])
+		sections := make([]string, 0, len(report.Sections))
+		for section := range report.Sections {
+			sections = append(sections, section)
+		}
+		sort.Strings(sections)
+		for _, section := range sections {
+			r := report.Sections[section]
+			fmt.Printf("%s: created %d, overwritten %d, renamed %d, skipped %d, failed %d\n",
+				section, r.Created, r.Overwritten, r.Renamed, r.Skipped, r.Failed)
+		}
+		for _, w := range report.Warnings {
+			fmt.Println("warning:", w)
+		}
+		fmt.Println("Restart the server if it is running to apply the backup")
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(backupCmd)
+	backupCmd.AddCommand(exportBackupCmd)
+	backupCmd.AddCommand(importBackupCmd)
+	backupCmd.PersistentFlags().StringSliceVar(&backupSections, "sections", nil,
+		"sections of storages,users,metas,settings,sharings,ssh_keys,tasks, defaults to all but tasks for export and all for import")
+	backupCmd.PersistentFlags().StringVar(&backupPassword, "password", "", "password to encrypt or decrypt the secrets")
+	importBackupCmd.Flags().StringVar(&backupStrategy, "strategy", backup.StrategySkip, "skip, overwrite or rename the existing items")
+}
@@ -6,9 +6,9 @@ import (
 	"sync"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	streamPkg "github.com/OpenListTeam/OpenList/v4/internal/stream"
-	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
 	"github.com/pkg/errors"
@@ -169,20 +169,10 @@ func (d *Pan115) Put(ctx context.Context, dstDir model.Obj, stream model.FileStr
 	//	return err
 	//}
 
-	const PreHashSize int64 = 128 * utils.KB
-	hashSize := PreHashSize
-	if stream.GetSize() < PreHashSize {
-		hashSize = stream.GetSize()
-	}
-	reader, err := stream.RangeRead(http_range.Range{Start: 0, Length: hashSize})
-	if err != nil {
-		return nil, err
-	}
-	preHash, err := utils.HashReader(utils.SHA1, reader)
+	preHash, err := getPreHash(stream)
 	if err != nil {
 		return nil, err
 	}
-	preHash = strings.ToUpper(preHash)
 	fullHash := stream.GetHash().GetHash(utils.SHA1)
 	if len(fullHash) != utils.SHA1.Width {
 		_, fullHash, err = streamPkg.CacheFullAndHash(stream, &up, utils.SHA1)
@@ -228,6 +218,39 @@ func (d *Pan115) Put(ctx context.Context, dstDir model.Obj, stream model.FileStr
 	return file, nil
 }
 
+func (d *Pan115) PutRapid(ctx context.Context, dstDir model.Obj, stream model.FileStreamer) (model.Obj, error) {
+	fullHash := stream.GetHash().GetHash(utils.SHA1)
+	if len(fullHash) != utils.SHA1.Width {
+		return nil, errs.NotImplement
+	}
+	if err := d.WaitLimit(ctx); err != nil {
+		return nil, err
+	}
+	if ok, err := d.client.UploadAvailable(); err != nil {
+		return nil, err
+	} else if !ok || stream.GetSize() > d.client.UploadMetaInfo.SizeLimit {
+		return nil, errs.NotImplement
+	}
+	preHash, err := getPreHash(stream)
+	if err != nil {
+		return nil, err
+	}
+	fastInfo, err := d.rapidUpload(stream.GetSize(), stream.GetName(), dstDir.GetID(), preHash, strings.ToUpper(fullHash), stream)
+	if err != nil {
+		return nil, err
+	}
+	if matched, err := fastInfo.Ok(); err != nil {
+		return nil, err
+	} else if !matched {
+		return nil, errs.NotImplement
+	}
+	f, err := d.getNewFileByPickCode(fastInfo.PickCode)
+	if err != nil {
+		return nil, nil
+	}
+	return f, nil
+}
+
 func (d *Pan115) OfflineList(ctx context.Context) ([]*driver115.OfflineTask, error) {
 	resp, err := d.client.ListOfflineTask(0)
 	if err != nil {
@@ -258,3 +281,4 @@ func (d *Pan115) GetDetails(ctx context.Context) (*model.StorageDetails, error)
 }
 
 var _ driver.Driver = (*Pan115)(nil)
+var _ driver.PutRapid = (*Pan115)(nil)
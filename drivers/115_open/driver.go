@@ -12,6 +12,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
 	"github.com/OpenListTeam/OpenList/v4/drivers/base"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
@@ -233,6 +234,30 @@ func (d *Open115) Put(ctx context.Context, dstDir model.Obj, file model.FileStre
 			return err
 		}
 	}
+	resp, err := d.rapidUpload(ctx, dstDir, file, sha1)
+	if err != nil {
+		return err
+	}
+	if resp.Status == 2 {
+		up(100)
+		return nil
+	}
+	// 3. get upload token
+	tokenResp, err := d.client.UploadGetToken(ctx)
+	if err != nil {
+		return err
+	}
+	// 4. upload
+	err = d.multpartUpload(ctx, file, up, tokenResp, resp)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// rapidUpload inits the upload by the sha1, the file is created at once if
+// the server has the content, which is told by the status 2.
+func (d *Open115) rapidUpload(ctx context.Context, dstDir model.Obj, file model.FileStreamer, sha1 string) (*sdk.UploadInitResp, error) {
 	const PreHashSize int64 = 128 * utils.KB
 	hashSize := PreHashSize
 	if file.GetSize() < PreHashSize {
@@ -240,11 +265,11 @@ func (d *Open115) Put(ctx context.Context, dstDir model.Obj, file model.FileStre
 	}
 	reader, err := file.RangeRead(http_range.Range{Start: 0, Length: hashSize})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	sha1128k, err := utils.HashReader(utils.SHA1, reader)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// 1. Init
 	resp, err := d.client.UploadInit(ctx, &sdk.UploadInitReq{
@@ -255,30 +280,26 @@ func (d *Open115) Put(ctx context.Context, dstDir model.Obj, file model.FileStre
 		PreID:    strings.ToUpper(sha1128k),
 	})
 	if err != nil {
-		return err
-	}
-	if resp.Status == 2 {
-		up(100)
-		return nil
+		return nil, err
 	}
 	// 2. two way verify
 	if utils.SliceContains([]int{6, 7, 8}, resp.Status) {
 		signCheck := strings.Split(resp.SignCheck, "-") //"sign_check": "2392148-2392298" 取2392148-2392298之间的内容(包含2392148、2392298)的sha1
 		start, err := strconv.ParseInt(signCheck[0], 10, 64)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		end, err := strconv.ParseInt(signCheck[1], 10, 64)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		reader, err = file.RangeRead(http_range.Range{Start: start, Length: end - start + 1})
 		if err != nil {
-			return err
+			return nil, err
 		}
 		signVal, err := utils.HashReader(utils.SHA1, reader)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		resp, err = d.client.UploadInit(ctx, &sdk.UploadInitReq{
 			FileName: file.GetName(),
@@ -290,24 +311,28 @@ func (d *Open115) Put(ctx context.Context, dstDir model.Obj, file model.FileStre
 			SignVal:  strings.ToUpper(signVal),
 		})
 		if err != nil {
-			return err
-		}
-		if resp.Status == 2 {
-			up(100)
-			return nil
+			return nil, err
 		}
 	}
-	// 3. get upload token
-	tokenResp, err := d.client.UploadGetToken(ctx)
-	if err != nil {
-		return err
+	return resp, nil
+}
+
+func (d *Open115) PutRapid(ctx context.Context, dstDir model.Obj, file model.FileStreamer) (model.Obj, error) {
+	sha1 := file.GetHash().GetHash(utils.SHA1)
+	if len(sha1) != utils.SHA1.Width {
+		return nil, errs.NotImplement
 	}
-	// 4. upload
-	err = d.multpartUpload(ctx, file, up, tokenResp, resp)
+	if err := d.WaitLimit(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := d.rapidUpload(ctx, dstDir, file, sha1)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	if resp.Status != 2 {
+		return nil, errs.NotImplement
+	}
+	return nil, nil
 }
 
 func (d *Open115) OfflineDownload(ctx context.Context, uris []string, dstDir model.Obj) ([]string, error) {
@@ -374,3 +399,4 @@ func (d *Open115) GetDetails(ctx context.Context) (*model.StorageDetails, error)
 //}
 
 var _ driver.Driver = (*Open115)(nil)
+var _ driver.PutRapid = (*Open115)(nil)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
@@ -19,6 +20,8 @@ type Open123 struct {
 	Addition
 	UID uint64
 	tm  *tokenManager
+	// the upload sessions created by PutRapid which missed, reused by Put
+	pendingCreates sync.Map
 }
 
 func (d *Open123) Config() driver.Config {
@@ -205,9 +208,13 @@ func (d *Open123) Put(ctx context.Context, dstDir model.Obj, file model.FileStre
 			return nil, err
 		}
 	}
-	createResp, err := d.create(parentFileId, file.GetName(), etag, file.GetSize(), 2, false)
-	if err != nil {
-		return nil, err
+	// the session created by PutRapid is reused, 123 has no way to cancel it
+	createResp, ok := d.takePendingCreate(parentFileId, file.GetName(), etag, file.GetSize())
+	if !ok {
+		createResp, err = d.create(parentFileId, file.GetName(), etag, file.GetSize(), 2, false)
+		if err != nil {
+			return nil, err
+		}
 	}
 	// 是否秒传
 	if createResp.Data.Reuse {
@@ -249,6 +256,43 @@ func (d *Open123) Put(ctx context.Context, dstDir model.Obj, file model.FileStre
 	return nil, fmt.Errorf("upload complete timeout")
 }
 
+func (d *Open123) PutRapid(ctx context.Context, dstDir model.Obj, file model.FileStreamer) (model.Obj, error) {
+	parentFileId, err := strconv.ParseInt(dstDir.GetID(), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse parentFileID error: %v", err)
+	}
+	if sha1Hash := file.GetHash().GetHash(utils.SHA1); len(sha1Hash) == utils.SHA1.Width {
+		resp, err := d.sha1Reuse(parentFileId, file.GetName(), sha1Hash, file.GetSize(), 2)
+		if err == nil && resp.Data.Reuse {
+			return File{
+				FileName: file.GetName(),
+				Size:     file.GetSize(),
+				FileId:   resp.Data.FileID,
+				Type:     2,
+				SHA1:     sha1Hash,
+			}, nil
+		}
+	}
+	if etag := file.GetHash().GetHash(utils.MD5); len(etag) == utils.MD5.Width {
+		createResp, err := d.create(parentFileId, file.GetName(), etag, file.GetSize(), 2, false)
+		if err != nil {
+			return nil, err
+		}
+		if createResp.Data.Reuse && createResp.Data.FileID != 0 {
+			return File{
+				FileName: file.GetName(),
+				Size:     file.GetSize(),
+				FileId:   createResp.Data.FileID,
+				Type:     2,
+				Etag:     etag,
+			}, nil
+		}
+		// the file is uploaded by Put next, in the session created
+		d.putPendingCreate(parentFileId, file.GetName(), etag, file.GetSize(), createResp)
+	}
+	return nil, errs.NotImplement
+}
+
 func (d *Open123) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
 	userInfo, err := d.getUserInfo(ctx)
 	if err != nil {
@@ -273,4 +317,5 @@ func (d *Open123) OfflineDownloadProcess(ctx context.Context, taskID int) (float
 var (
 	_ driver.Driver    = (*Open123)(nil)
 	_ driver.PutResult = (*Open123)(nil)
+	_ driver.PutRapid  = (*Open123)(nil)
 )
@@ -41,6 +41,38 @@ func (d *Open123) create(parentFileID int64, filename string, etag string, size
 	return &resp, nil
 }
 
+// pendingCreateTTL is how long a session created by PutRapid waits for Put.
+const pendingCreateTTL = 10 * time.Minute
+
+type pendingCreate struct {
+	resp    *UploadCreateResp
+	created time.Time
+}
+
+func pendingCreateKey(parentFileID int64, filename, etag string, size int64) string {
+	return fmt.Sprintf("%d/%s/%s/%d", parentFileID, filename, strings.ToLower(etag), size)
+}
+
+// putPendingCreate keeps the session of a file for Put, the stale ones are dropped.
+func (d *Open123) putPendingCreate(parentFileID int64, filename, etag string, size int64, resp *UploadCreateResp) {
+	now := time.Now()
+	d.pendingCreates.Range(func(key, value any) bool {
+		if now.Sub(value.(pendingCreate).created) > pendingCreateTTL {
+			d.pendingCreates.Delete(key)
+		}
+		return true
+	})
+	d.pendingCreates.Store(pendingCreateKey(parentFileID, filename, etag, size), pendingCreate{resp: resp, created: now})
+}
+
+func (d *Open123) takePendingCreate(parentFileID int64, filename, etag string, size int64) (*UploadCreateResp, bool) {
+	value, ok := d.pendingCreates.LoadAndDelete(pendingCreateKey(parentFileID, filename, etag, size))
+	if !ok || time.Since(value.(pendingCreate).created) > pendingCreateTTL {
+		return nil, false
+	}
+	return value.(pendingCreate).resp, true
+}
+
 // 上传分片 V2
 func (d *Open123) Upload(ctx context.Context, file model.FileStreamer, createResp *UploadCreateResp, up driver.UpdateProgress) error {
 	uploadDomain := createResp.Data.Servers[0]
@@ -17,6 +17,7 @@ import (
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/aliyundrive"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/aliyundrive_open"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/aliyundrive_share"
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/archive"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/autoindex"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/azure_blob"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/baidu_netdisk"
@@ -33,8 +34,10 @@ import (
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/dropbox"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/febbox"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/ftp"
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/git_repo"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/github"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/github_releases"
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/google_cloud_storage"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/google_drive"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/google_photo"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/halalcloud"
@@ -56,6 +59,7 @@ import (
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/onedrive_sharelink"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/openlist"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/openlist_share"
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/openstack_swift"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/pikpak"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/pikpak_share"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/proton_drive"
@@ -75,6 +79,7 @@ import (
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/thunderx"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/url_tree"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/uss"
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/vfs_cache"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/virtual"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/webdav"
 	_ "github.com/OpenListTeam/OpenList/v4/drivers/weiyun"
@@ -0,0 +1,171 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/archive/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/go-cache"
+)
+
+type Archive struct {
+	model.Storage
+	Addition
+
+	mu       sync.Mutex
+	meta     model.ArchiveMeta
+	metaTime time.Time
+	metaG    singleflight.Group[model.ArchiveMeta]
+
+	// the files extracted for the ranged reads, by the paths in the archive
+	tempDir  string
+	files    cache.ICache[string]
+	extractG singleflight.Group[string]
+}
+
+func (d *Archive) Config() driver.Config {
+	return config
+}
+
+func (d *Archive) GetAddition() driver.Additional {
+	return &d.Addition
+}
+
+func (d *Archive) Init(ctx context.Context) error {
+	d.ArchivePath = utils.FixAndCleanPath(d.ArchivePath)
+	if utils.PathEqual(d.ArchivePath, "/") {
+		return errors.New("the archive path is required")
+	}
+	// the storage of the archive may not be loaded yet, so only the path is checked
+	if utils.IsSubPath(d.MountPath, d.ArchivePath) {
+		return errors.New("the archive can't be in the storage itself")
+	}
+	if !isArchive(d.ArchivePath) {
+		return errors.New("the archive path is not a supported archive")
+	}
+	d.mu.Lock()
+	d.meta = nil
+	d.mu.Unlock()
+	tempDir, err := os.MkdirTemp(conf.Conf.TempDir, "archive-*")
+	if err != nil {
+		return err
+	}
+	d.tempDir = tempDir
+	d.files = cache.NewMemCache(cache.WithShards[string](8), cache.WithExpiredCallback(func(_ string, name string) error {
+		// the readers opened keep reading the removed file
+		return os.Remove(name)
+	}))
+	return nil
+}
+
+func (d *Archive) Drop(ctx context.Context) error {
+	if d.files != nil {
+		d.files.Clear()
+	}
+	if d.tempDir != "" {
+		_ = os.RemoveAll(d.tempDir)
+		d.tempDir = ""
+	}
+	return nil
+}
+
+func (d *Archive) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	path := dir.GetPath()
+	refresh := args.Refresh && utils.PathEqual(path, d.GetRootPath())
+	meta, err := d.getMeta(ctx, refresh)
+	if err != nil {
+		return nil, err
+	}
+	objs, ok, err := childrenOf(meta.GetTree(), path)
+	if !ok {
+		objs, err = d.listArchive(ctx, path, args.Refresh)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return utils.SliceConvert(objs, func(src model.Obj) (model.Obj, error) {
+		return toObj(src, path), nil
+	})
+}
+
+func (d *Archive) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	if file.IsDir() {
+		return nil, errs.NotFile
+	}
+	storage, actualPath, err := op.GetStorageAndActualPath(d.ArchivePath)
+	if err != nil {
+		return nil, err
+	}
+	innerArgs := model.ArchiveInnerArgs{
+		ArchiveArgs: model.ArchiveArgs{Password: d.Password, LinkArgs: args},
+		InnerPath:   file.GetPath(),
+	}
+	if _, ok := storage.(driver.ArchiveReader); ok {
+		link, _, err := op.DriverExtract(ctx, storage, actualPath, innerArgs)
+		if err == nil {
+			return link, nil
+		}
+		if !errors.Is(err, errs.NotImplement) && !errors.Is(err, errs.DriverExtractNotSupported) {
+			return nil, err
+		}
+	}
+	// the internal tools can only extract from the start of the file, so the
+	// file is extracted to the temp dir once for the reads from the middle
+	size := file.GetSize()
+	return &model.Link{
+		RangeReader: stream.RangeReaderFunc(func(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+			length := httpRange.Length
+			if length < 0 || httpRange.Start+length > size {
+				length = size - httpRange.Start
+			}
+			if _, ok := d.files.Get(innerArgs.InnerPath); !ok && httpRange.Start == 0 {
+				rc, _, err := op.InternalExtract(ctx, storage, actualPath, innerArgs)
+				if err != nil {
+					return nil, err
+				}
+				return utils.NewLimitReadCloser(rc, rc.Close, length), nil
+			}
+			name, err := d.extracted(ctx, storage, actualPath, innerArgs, size)
+			if err != nil {
+				return nil, err
+			}
+			f, err := os.Open(name)
+			if err != nil {
+				return nil, err
+			}
+			return utils.NewLimitReadCloser(io.NewSectionReader(f, httpRange.Start, length), f.Close, length), nil
+		}),
+	}, nil
+}
+
+var _ driver.Driver = (*Archive)(nil)
+
+// isArchive tells whether any of the extensions of the name has a tool, as
+// op.GetArchiveToolAndStream finds the tool.
+func isArchive(path string) bool {
+	ext := strings.ToLower(path[strings.LastIndex(path, "/")+1:])
+	for {
+		var found bool
+		_, ext, found = strings.Cut(ext, ".")
+		if !found {
+			return false
+		}
+		if _, _, err := tool.GetArchiveTool("." + ext); err == nil {
+			return true
+		}
+	}
+}
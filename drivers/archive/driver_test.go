@@ -0,0 +1,156 @@
+package archive
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	_ "github.com/OpenListTeam/OpenList/v4/internal/archive"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const content = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func writeZip(t *testing.T, path string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, data := range map[string]string{
+		"a.txt":     content,
+		"dir/b.txt": "b",
+	} {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = io.WriteString(fw, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setup(t *testing.T) *Archive {
+	conf.Conf.TempDir = t.TempDir()
+	root := t.TempDir()
+	writeZip(t, filepath.Join(root, "t.zip"))
+	ctx := context.Background()
+	localID, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/local",
+		Addition:  `{"root_folder_path":"` + root + `"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Archive",
+		MountPath: "/archive",
+		Addition:  `{"root_folder_path":"/","archive_path":"/local/t.zip"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = op.DeleteStorageById(ctx, id)
+		_ = op.DeleteStorageById(ctx, localID)
+	})
+	storage, err := op.GetStorageByMountPath("/archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage.(*Archive)
+}
+
+func list(t *testing.T, d *Archive, path string) []model.Obj {
+	objs, err := d.List(context.Background(), &model.Object{Path: path, IsFolder: true}, model.ListArgs{})
+	if err != nil {
+		t.Fatalf("failed to list %s: %+v", path, err)
+	}
+	return objs
+}
+
+func TestListAndLink(t *testing.T) {
+	d := setup(t)
+	var names []string
+	for _, o := range list(t, d, "/") {
+		names = append(names, o.GetName())
+	}
+	sort.Strings(names)
+	if !slices.Equal(names, []string{"a.txt", "dir"}) {
+		t.Fatalf("unexpected root: %v", names)
+	}
+	if objs := list(t, d, "/dir"); len(objs) != 1 || objs[0].GetName() != "b.txt" || objs[0].GetPath() != "/dir/b.txt" {
+		t.Fatalf("unexpected /dir: %+v", objs)
+	}
+
+	file := &model.Object{Path: "/a.txt", Name: "a.txt", Size: int64(len(content))}
+	link, err := d.Link(context.Background(), file, model.LinkArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := func(r http_range.Range) string {
+		rc, err := link.RangeReader.RangeRead(context.Background(), r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if got := read(http_range.Range{Start: 0, Length: 5}); got != content[:5] {
+		t.Errorf("expect %q, got %q", content[:5], got)
+	}
+	if _, ok := d.files.Get("/a.txt"); ok {
+		t.Error("the reads from the start should not extract to the temp dir")
+	}
+	if got := read(http_range.Range{Start: 10, Length: 6}); got != content[10:16] {
+		t.Errorf("expect %q, got %q", content[10:16], got)
+	}
+	name, ok := d.files.Get("/a.txt")
+	if !ok {
+		t.Fatal("expect the file extracted for the ranged reads")
+	}
+	if got := read(http_range.Range{Start: 30, Length: -1}); got != content[30:] {
+		t.Errorf("expect %q, got %q", content[30:], got)
+	}
+	if again, _ := d.files.Get("/a.txt"); again != name {
+		t.Errorf("expect the extracted file reused, got %s and %s", name, again)
+	}
+
+	if err = d.Drop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("expect the extracted file removed on drop, got %v", err)
+	}
+}
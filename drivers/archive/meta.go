@@ -0,0 +1,33 @@
+package archive
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+type Addition struct {
+	// the dir inside the archive to be the root
+	driver.RootPath
+	ArchivePath string `json:"archive_path" required:"true" help:"the path of the archive in openlist, e.g. /local/files.zip"`
+	Password    string `json:"password" help:"the password of the encrypted archive"`
+	MetaCache   int    `json:"meta_cache" type:"number" default:"60" help:"the minutes the meta of the archive is kept, refresh the root to read it again"`
+}
+
+var config = driver.Config{
+	Name:        "Archive",
+	LocalSort:   true,
+	OnlyProxy:   true,
+	NoUpload:    true,
+	DefaultRoot: "/",
+	CheckStatus: true,
+}
+
+func init() {
+	op.RegisterDriver(func() driver.Driver {
+		return &Archive{
+			Addition: Addition{
+				MetaCache: 60,
+			},
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"os"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+)
+
+// the extracted files are kept for the ranged reads after the last use for
+const extractedTTL = 10 * time.Minute
+
+// getMeta returns the meta of the archive, which is kept for MetaCache
+// minutes, since reading it again needs to go through the whole archive for
+// the most of the formats. The reading is shared by the concurrent listings
+// instead of holding the lock.
+func (d *Archive) getMeta(ctx context.Context, refresh bool) (model.ArchiveMeta, error) {
+	d.mu.Lock()
+	if !refresh && d.meta != nil && time.Since(d.metaTime) < time.Duration(d.MetaCache)*time.Minute {
+		meta := d.meta
+		d.mu.Unlock()
+		return meta, nil
+	}
+	d.mu.Unlock()
+	key := "meta"
+	if refresh {
+		key = "refresh"
+	}
+	// the reading is not cancelled with the first caller
+	ctx = context.WithoutCancel(ctx)
+	meta, err, _ := d.metaG.Do(key, func() (model.ArchiveMeta, error) {
+		storage, actualPath, err := op.GetStorageAndActualPath(d.ArchivePath)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed get storage of [%s]", d.ArchivePath)
+		}
+		meta, err := op.GetArchiveMeta(ctx, storage, actualPath, model.ArchiveMetaArgs{
+			ArchiveArgs: model.ArchiveArgs{Password: d.Password},
+			Refresh:     refresh,
+		})
+		if err != nil {
+			return nil, err
+		}
+		d.mu.Lock()
+		d.meta = meta.ArchiveMeta
+		d.metaTime = time.Now()
+		d.mu.Unlock()
+		return meta.ArchiveMeta, nil
+	})
+	return meta, err
+}
+
+// extracted returns the name of the temp file which the file in the archive
+// is extracted to, so that the ranged reads can seek in it instead of
+// extracting from the start of the archive again.
+func (d *Archive) extracted(ctx context.Context, storage driver.Driver, actualPath string, args model.ArchiveInnerArgs, size int64) (string, error) {
+	if name, ok := d.files.Get(args.InnerPath); ok {
+		d.files.Expire(args.InnerPath, extractedTTL)
+		return name, nil
+	}
+	ctx = context.WithoutCancel(ctx)
+	name, err, _ := d.extractG.Do(args.InnerPath, func() (string, error) {
+		rc, _, err := op.InternalExtract(ctx, storage, actualPath, args)
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+		f, err := os.CreateTemp(d.tempDir, "file-*")
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		n, err := utils.CopyWithBuffer(f, rc)
+		if err == nil && n != size {
+			err = fmt.Errorf("extracted %d bytes of %s, expect %d", n, args.InnerPath, size)
+		}
+		if err != nil {
+			_ = os.Remove(f.Name())
+			return "", err
+		}
+		d.files.Set(args.InnerPath, f.Name(), cache.WithEx[string](extractedTTL))
+		return f.Name(), nil
+	})
+	return name, err
+}
+
+// listArchive lists the dir by the storage of the archive, for the archives
+// of which the meta gives no tree.
+func (d *Archive) listArchive(ctx context.Context, path string, refresh bool) ([]model.Obj, error) {
+	storage, actualPath, err := op.GetStorageAndActualPath(d.ArchivePath)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed get storage of [%s]", d.ArchivePath)
+	}
+	return op.ListArchive(ctx, storage, actualPath, model.ArchiveListArgs{
+		ArchiveInnerArgs: model.ArchiveInnerArgs{
+			ArchiveArgs: model.ArchiveArgs{Password: d.Password},
+			InnerPath:   path,
+		},
+		Refresh: refresh,
+	})
+}
+
+// childrenOf finds the children of the dir in the tree, ok is false if the
+// tree doesn't reach the dir, since some tools give only the top level.
+func childrenOf(tree []model.ObjTree, path string) (objs []model.Obj, ok bool, err error) {
+	if tree == nil {
+		return nil, false, nil
+	}
+	for _, name := range strings.Split(strings.Trim(path, "/"), "/") {
+		if name == "" {
+			continue
+		}
+		var next model.ObjTree
+		for _, c := range tree {
+			if c.GetName() == name {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			return nil, true, errs.ObjectNotFound
+		}
+		if !next.IsDir() {
+			return nil, true, errs.NotFolder
+		}
+		if tree = next.GetChildren(); tree == nil {
+			return nil, false, nil
+		}
+	}
+	objs = make([]model.Obj, 0, len(tree))
+	for _, c := range tree {
+		objs = append(objs, c)
+	}
+	return objs, true, nil
+}
+
+// toObj drops the children of the tree nodes, and gives the paths in the
+// archive which the files are extracted by.
+func toObj(src model.Obj, dir string) model.Obj {
+	return &model.Object{
+		Path:     stdpath.Join(dir, src.GetName()),
+		Name:     src.GetName(),
+		Size:     src.GetSize(),
+		Modified: src.ModTime(),
+		Ctime:    src.CreateTime(),
+		IsFolder: src.IsDir(),
+		HashInfo: src.GetHash(),
+	}
+}
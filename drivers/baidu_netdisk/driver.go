@@ -163,8 +163,8 @@ func (d *BaiduNetdisk) Remove(ctx context.Context, obj model.Obj) error {
 
 func (d *BaiduNetdisk) PutRapid(ctx context.Context, dstDir model.Obj, stream model.FileStreamer) (model.Obj, error) {
 	contentMd5 := stream.GetHash().GetHash(utils.MD5)
-	if len(contentMd5) < utils.MD5.Width {
-		return nil, errors.New("invalid hash")
+	if len(contentMd5) < utils.MD5.Width || stream.GetSize() < 1 {
+		return nil, errs.NotImplement
 	}
 
 	streamSize := stream.GetSize()
@@ -498,3 +498,4 @@ func (d *BaiduNetdisk) GetDetails(ctx context.Context) (*model.StorageDetails, e
 }
 
 var _ driver.Driver = (*BaiduNetdisk)(nil)
+var _ driver.PutRapid = (*BaiduNetdisk)(nil)
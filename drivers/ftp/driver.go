@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	stdpath "path"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
@@ -13,6 +14,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/jlaffaye/ftp"
+	log "github.com/sirupsen/logrus"
 )
 
 type FTP struct {
@@ -167,11 +169,30 @@ func (d *FTP) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer, u
 	if err := d.login(); err != nil {
 		return err
 	}
-	path := stdpath.Join(dstDir.GetPath(), s.GetName())
-	return d.conn.Stor(encode(path, d.Encoding), driver.NewLimitedUploadStream(ctx, &driver.ReaderUpdatingProgress{
+	path := encode(stdpath.Join(dstDir.GetPath(), s.GetName()), d.Encoding)
+	err := d.conn.Stor(path, driver.NewLimitedUploadStream(ctx, &driver.ReaderUpdatingProgress{
 		Reader:         s,
 		UpdateProgress: up,
 	}))
+	if err != nil {
+		return err
+	}
+	if !s.ModTime().IsZero() && d.conn.IsSetTimeSupported() {
+		if err := d.conn.SetTime(path, s.ModTime()); err != nil {
+			log.Warnf("[ftp] failed to change time of %s: %s", path, err)
+		}
+	}
+	return nil
+}
+
+func (d *FTP) SetModTime(ctx context.Context, obj model.Obj, modified, created time.Time) error {
+	if err := d.login(); err != nil {
+		return err
+	}
+	if !d.conn.IsSetTimeSupported() {
+		return errs.NotSupport
+	}
+	return d.conn.SetTime(encode(obj.GetPath(), d.Encoding), modified)
 }
 
 var _ driver.Driver = (*FTP)(nil)
@@ -0,0 +1,198 @@
+package git_repo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+type GitRepo struct {
+	model.Storage
+	Addition
+	repo *git.Repository
+	// the repository is cloned from the url if it's not empty
+	cloneDir string
+
+	// go-git is not safe for the concurrent lookups of the objects
+	mu       sync.Mutex
+	refs     map[string]plumbing.Hash
+	refsTime time.Time
+	// the entries of the trees by the tree hashes, which never change
+	trees cache.ICache[[]treeEntry]
+}
+
+func (d *GitRepo) Config() driver.Config {
+	return config
+}
+
+func (d *GitRepo) GetAddition() driver.Additional {
+	return &d.Addition
+}
+
+func (d *GitRepo) Init(ctx context.Context) error {
+	var err error
+	switch {
+	case d.RepoPath != "":
+		d.repo, err = git.PlainOpenWithOptions(d.RepoPath, &git.PlainOpenOptions{DetectDotGit: true})
+	case d.URL != "":
+		d.cloneDir = d.clonePath()
+		d.repo, err = d.openClone(ctx)
+	default:
+		return errors.New("the repo path or the url is required")
+	}
+	if err != nil {
+		return err
+	}
+	if d.CommitsLimit <= 0 {
+		d.CommitsLimit = 100
+	}
+	d.trees = cache.NewMemCache(cache.WithShards[[]treeEntry](8))
+	d.refs = nil
+	return d.loadRefs()
+}
+
+func (d *GitRepo) clonePath() string {
+	return filepath.Join(flags.DataDir, "git_repo", strconv.Itoa(int(d.ID)))
+}
+
+// openClone opens the bare clone of the url, which is cloned if not yet, and
+// fetches all the branches and tags.
+func (d *GitRepo) openClone(ctx context.Context) (*git.Repository, error) {
+	repo, err := git.PlainOpen(d.cloneDir)
+	if err == nil && !clonedFrom(repo, d.URL) {
+		// the url is changed, the clone of the old one is dropped
+		closeRepo(repo)
+		if err = os.RemoveAll(d.cloneDir); err != nil {
+			return nil, err
+		}
+		err = git.ErrRepositoryNotExists
+	}
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		if err = os.MkdirAll(d.cloneDir, 0o777); err != nil {
+			return nil, err
+		}
+		repo, err = git.PlainCloneContext(ctx, d.cloneDir, true, &git.CloneOptions{
+			URL:  d.URL,
+			Auth: d.auth(),
+		})
+		if err != nil {
+			// cloned again on the next init
+			_ = os.RemoveAll(d.cloneDir)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.repo = repo
+	return repo, d.fetch(ctx)
+}
+
+func clonedFrom(repo *git.Repository, url string) bool {
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return false
+	}
+	urls := remote.Config().URLs
+	return len(urls) > 0 && urls[0] == url
+}
+
+func closeRepo(repo *git.Repository) {
+	if c, ok := repo.Storer.(io.Closer); ok {
+		_ = c.Close()
+	}
+}
+
+func (d *GitRepo) auth() transport.AuthMethod {
+	if d.Username == "" && d.Password == "" {
+		return nil
+	}
+	return &githttp.BasicAuth{Username: d.Username, Password: d.Password}
+}
+
+func (d *GitRepo) fetch(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	err := d.repo.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: fetchRefSpecs,
+		Auth:     d.auth(),
+		Force:    true,
+		Prune:    true,
+	})
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil
+	}
+	return err
+}
+
+func (d *GitRepo) Drop(ctx context.Context) error {
+	if d.repo != nil {
+		closeRepo(d.repo)
+		d.repo = nil
+	}
+	return nil
+}
+
+// Purge removes the clone of the storage deleted.
+func (d *GitRepo) Purge(ctx context.Context) error {
+	if d.RepoPath != "" || d.URL == "" {
+		return nil
+	}
+	return os.RemoveAll(d.clonePath())
+}
+
+func (d *GitRepo) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	path := dir.GetPath()
+	if args.Refresh && utils.PathEqual(path, "/") {
+		if d.cloneDir != "" {
+			if err := d.fetch(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+	d.mu.Lock()
+	stale := args.Refresh || time.Since(d.refsTime) > refsTTL
+	d.mu.Unlock()
+	if stale {
+		if err := d.loadRefs(); err != nil {
+			return nil, err
+		}
+	}
+	return d.list(path)
+}
+
+func (d *GitRepo) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	if file.IsDir() {
+		return nil, errs.NotFile
+	}
+	d.mu.Lock()
+	blob, err := d.repo.BlobObject(plumbing.NewHash(file.GetID()))
+	d.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return &model.Link{
+		RangeReader: stream.RangeReaderFunc(func(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+			return d.rangeRead(blob, httpRange)
+		}),
+	}, nil
+}
+
+var _ driver.Driver = (*GitRepo)(nil)
@@ -0,0 +1,193 @@
+package git_repo
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func initRepo(t *testing.T) string {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"README.md":   "hello git repo",
+		"src/main.go": "package main\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err = os.MkdirAll(filepath.Dir(path), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(path, []byte(content), 0o666); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = wt.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash, err := wt.Commit("init", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range []plumbing.ReferenceName{
+		plumbing.NewBranchReferenceName("feature/x"),
+		plumbing.NewTagReferenceName("v1"),
+	} {
+		if err = repo.Storer.SetReference(plumbing.NewHashReference(ref, hash)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func newDriver(t *testing.T, showCommits bool) *GitRepo {
+	d := &GitRepo{Addition: Addition{RepoPath: initRepo(t), ShowCommits: showCommits}}
+	if err := d.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = d.Drop(context.Background()) })
+	return d
+}
+
+func listNames(t *testing.T, d *GitRepo, path string) []string {
+	objs, err := d.List(context.Background(), &model.Object{Path: path, IsFolder: true}, model.ListArgs{})
+	if err != nil {
+		t.Fatalf("failed to list %s: %+v", path, err)
+	}
+	var names []string
+	for _, o := range objs {
+		names = append(names, o.GetName())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestList(t *testing.T) {
+	d := newDriver(t, true)
+	cases := map[string][]string{
+		"/":           {"commits", "feature", "master", "v1"},
+		"/feature":    {"x"},
+		"/feature/x":  {"README.md", "src"},
+		"/master/src": {"main.go"},
+		"/v1":         {"README.md", "src"},
+	}
+	for path, expect := range cases {
+		if names := listNames(t, d, path); !slices.Equal(names, expect) {
+			t.Errorf("list %s: expect %v, got %v", path, expect, names)
+		}
+	}
+	commits := listNames(t, d, "/commits")
+	if len(commits) != 1 {
+		t.Fatalf("expect 1 commit, got %v", commits)
+	}
+	if names := listNames(t, d, "/commits/"+commits[0][:7]); !slices.Equal(names, []string{"README.md", "src"}) {
+		t.Errorf("list the commit by the short sha: got %v", names)
+	}
+	if _, err := d.List(context.Background(), &model.Object{Path: "/master/none", IsFolder: true}, model.ListArgs{}); err == nil {
+		t.Error("expect an error listing a missing dir")
+	}
+}
+
+func TestLink(t *testing.T) {
+	d := newDriver(t, false)
+	objs, err := d.List(context.Background(), &model.Object{Path: "/master", IsFolder: true}, model.ListArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var readme model.Obj
+	for _, o := range objs {
+		if o.GetName() == "README.md" {
+			readme = o
+		}
+	}
+	if readme == nil || readme.GetSize() != int64(len("hello git repo")) {
+		t.Fatalf("unexpected README.md: %+v", readme)
+	}
+	link, err := d.Link(context.Background(), readme, model.LinkArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[http_range.Range]string{
+		{Start: 0, Length: -1}:  "hello git repo",
+		{Start: 6, Length: 3}:   "git",
+		{Start: 10, Length: 10}: "repo",
+	}
+	for r, expect := range cases {
+		rc, err := link.RangeReader.RangeRead(context.Background(), r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expect {
+			t.Errorf("range %+v: expect %q, got %q", r, expect, data)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	oldDataDir := flags.DataDir
+	flags.DataDir = t.TempDir()
+	t.Cleanup(func() { flags.DataDir = oldDataDir })
+	src, other := initRepo(t), initRepo(t)
+	repo, err := git.PlainOpen(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("other"), head.Hash())); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &GitRepo{Storage: model.Storage{ID: 1}, Addition: Addition{URL: src}}
+	if err = d.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if names := listNames(t, d, "/"); !slices.Equal(names, []string{"feature", "master", "v1"}) {
+		t.Fatalf("unexpected refs %v", names)
+	}
+	// the url edited, the clone is made again
+	_ = d.Drop(context.Background())
+	d.URL = other
+	if err = d.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if names := listNames(t, d, "/"); !slices.Equal(names, []string{"feature", "master", "other", "v1"}) {
+		t.Errorf("expect the refs of the new url, got %v", names)
+	}
+
+	_ = d.Drop(context.Background())
+	if err = d.Purge(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(d.cloneDir); !os.IsNotExist(err) {
+		t.Errorf("expect the clone removed, got %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package git_repo
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+type Addition struct {
+	driver.RootPath
+	RepoPath     string `json:"repo_path" help:"the local path of a bare or non-bare repository"`
+	URL          string `json:"url" help:"the url to clone the repository from into the data dir, if the repo path is empty"`
+	Username     string `json:"username"`
+	Password     string `json:"password" help:"the password or the token of the http(s) url"`
+	ShowCommits  bool   `json:"show_commits" default:"false" help:"list the recent commits in /commits, any commit can be browsed by /commits/<sha>"`
+	CommitsLimit int    `json:"commits_limit" type:"number" default:"100"`
+}
+
+var config = driver.Config{
+	Name:        "GitRepo",
+	LocalSort:   true,
+	OnlyProxy:   true,
+	NoUpload:    true,
+	DefaultRoot: "/",
+	NoLinkURL:   true,
+	CheckStatus: true,
+}
+
+func init() {
+	op.RegisterDriver(func() driver.Driver {
+		return &GitRepo{
+			Addition: Addition{
+				CommitsLimit: 100,
+			},
+		}
+	})
+}
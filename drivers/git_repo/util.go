@@ -0,0 +1,266 @@
+package git_repo
+
+import (
+	"errors"
+	"io"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const (
+	// the commits are browsed by /commits/<sha>, which hides a ref of the name
+	commitsDir = "commits"
+	// the refs are read again after, or on refresh
+	refsTTL = time.Minute
+	// the entries of a tree are kept in the cache for
+	treeTTL = 30 * time.Minute
+)
+
+var fetchRefSpecs = []gitconfig.RefSpec{
+	"+refs/heads/*:refs/heads/*",
+	"+refs/tags/*:refs/tags/*",
+}
+
+type treeEntry struct {
+	Name  string
+	Hash  plumbing.Hash
+	Size  int64
+	IsDir bool
+}
+
+// loadRefs reads the branches and the tags, a branch hides the tag of the
+// same name.
+func (d *GitRepo) loadRefs() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	refs := make(map[string]plumbing.Hash)
+	iter, err := d.repo.References()
+	if err != nil {
+		return err
+	}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name()
+		if !name.IsBranch() && !name.IsTag() {
+			return nil
+		}
+		short := name.Short()
+		if _, ok := refs[short]; ok && name.IsTag() {
+			return nil
+		}
+		// the annotated tags are resolved to the commits
+		hash, err := d.repo.ResolveRevision(plumbing.Revision(name.String()))
+		if err != nil {
+			// a tag of a tree or a blob
+			return nil
+		}
+		refs[short] = *hash
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	d.refs = refs
+	d.refsTime = time.Now()
+	return nil
+}
+
+func (d *GitRepo) list(path string) ([]model.Obj, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	if segs[0] == "" {
+		segs = nil
+	}
+	if d.ShowCommits && len(segs) > 0 && segs[0] == commitsDir {
+		if len(segs) == 1 {
+			return d.listCommits(path)
+		}
+		hash, err := d.repo.ResolveRevision(plumbing.Revision(segs[1]))
+		if err != nil {
+			return nil, errs.ObjectNotFound
+		}
+		return d.listTree(*hash, strings.Join(segs[2:], "/"), path)
+	}
+	// the longest ref matched, the refs may contain slashes
+	for i := len(segs); i > 0; i-- {
+		if hash, ok := d.refs[strings.Join(segs[:i], "/")]; ok {
+			return d.listTree(hash, strings.Join(segs[i:], "/"), path)
+		}
+	}
+	return d.listRefs(strings.Join(segs, "/"), path)
+}
+
+// listRefs lists the refs under the prefix as dirs, the refs containing
+// slashes are split into the nested dirs.
+func (d *GitRepo) listRefs(prefix, path string) ([]model.Obj, error) {
+	if prefix != "" {
+		prefix += "/"
+	}
+	var res []model.Obj
+	seen := make(map[string]struct{})
+	if prefix == "" && d.ShowCommits {
+		seen[commitsDir] = struct{}{}
+		res = append(res, &model.Object{
+			Path:     stdpath.Join(path, commitsDir),
+			Name:     commitsDir,
+			IsFolder: true,
+		})
+	}
+	for name, hash := range d.refs {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		first, _, nested := strings.Cut(rest, "/")
+		if _, ok := seen[first]; ok {
+			continue
+		}
+		seen[first] = struct{}{}
+		obj := &model.Object{
+			Path:     stdpath.Join(path, first),
+			Name:     first,
+			IsFolder: true,
+		}
+		if !nested {
+			if commit, err := d.repo.CommitObject(hash); err == nil {
+				obj.Modified = commit.Committer.When
+				obj.Ctime = commit.Author.When
+			}
+		}
+		res = append(res, obj)
+	}
+	if prefix != "" && len(res) == 0 {
+		return nil, errs.ObjectNotFound
+	}
+	return res, nil
+}
+
+func (d *GitRepo) listCommits(path string) ([]model.Obj, error) {
+	head, err := d.repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	iter, err := d.repo.Log(&git.LogOptions{From: head.Hash(), Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	var res []model.Obj
+	for len(res) < d.CommitsLimit {
+		commit, err := iter.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := commit.Hash.String()
+		res = append(res, &model.Object{
+			Path:     stdpath.Join(path, name),
+			Name:     name,
+			Modified: commit.Committer.When,
+			Ctime:    commit.Author.When,
+			IsFolder: true,
+		})
+	}
+	return res, nil
+}
+
+// listTree lists the dir of the commit, all the entries have the time of the
+// commit, since finding the last commit of each entry is too expensive.
+func (d *GitRepo) listTree(hash plumbing.Hash, dir, path string) ([]model.Obj, error) {
+	commit, err := d.repo.CommitObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		tree, err = tree.Tree(dir)
+		if errors.Is(err, object.ErrDirectoryNotFound) {
+			return nil, errs.ObjectNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	entries, err := d.treeEntries(tree)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]model.Obj, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, &model.Object{
+			ID:       e.Hash.String(),
+			Path:     stdpath.Join(path, e.Name),
+			Name:     e.Name,
+			Size:     e.Size,
+			Modified: commit.Committer.When,
+			Ctime:    commit.Author.When,
+			IsFolder: e.IsDir,
+		})
+	}
+	return res, nil
+}
+
+func (d *GitRepo) treeEntries(tree *object.Tree) ([]treeEntry, error) {
+	if entries, ok := d.trees.Get(tree.Hash.String()); ok {
+		return entries, nil
+	}
+	entries := make([]treeEntry, 0, len(tree.Entries))
+	for _, e := range tree.Entries {
+		entry := treeEntry{Name: e.Name, Hash: e.Hash}
+		switch e.Mode {
+		case filemode.Dir:
+			entry.IsDir = true
+		case filemode.Submodule:
+			// the commit of a submodule is not in the repository
+			continue
+		default:
+			blob, err := d.repo.BlobObject(e.Hash)
+			if err != nil {
+				return nil, err
+			}
+			entry.Size = blob.Size
+		}
+		entries = append(entries, entry)
+	}
+	d.trees.Set(tree.Hash.String(), entries, cache.WithEx[[]treeEntry](treeTTL))
+	return entries, nil
+}
+
+// rangeRead opens the blob under the lock, since the object cache of the
+// storage is used by the opening, and the reader reads by its own file then.
+func (d *GitRepo) rangeRead(blob *object.Blob, httpRange http_range.Range) (io.ReadCloser, error) {
+	d.mu.Lock()
+	r, err := blob.Reader()
+	d.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if httpRange.Start > 0 {
+		if _, err = utils.CopyWithBufferN(io.Discard, r, httpRange.Start); err != nil {
+			_ = r.Close()
+			return nil, err
+		}
+	}
+	length := httpRange.Length
+	if length < 0 || httpRange.Start+length > blob.Size {
+		length = blob.Size - httpRange.Start
+	}
+	return utils.NewLimitReadCloser(r, r.Close, length), nil
+}
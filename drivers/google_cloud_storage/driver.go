@@ -0,0 +1,195 @@
+package google_cloud_storage
+
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	stdpath "path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/drivers/base"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/go-resty/resty/v2"
+	"golang.org/x/oauth2"
+)
+
+// Google Cloud Storage based on the JSON APIs
+// Link: https://cloud.google.com/storage/docs/json_api
+type GCS struct {
+	model.Storage
+	Addition
+	// nil if the bucket is accessed anonymously
+	tokenSource oauth2.TokenSource
+	email       string
+	key         *rsa.PrivateKey
+}
+
+func (d *GCS) Config() driver.Config {
+	return config
+}
+
+func (d *GCS) GetAddition() driver.Additional {
+	return &d.Addition
+}
+
+func (d *GCS) Init(ctx context.Context) error {
+	d.Endpoint = strings.TrimSuffix(d.Endpoint, "/")
+	if d.Endpoint == "" {
+		d.Endpoint = defaultEndpoint
+	}
+	if d.ChunkSize <= 0 {
+		d.ChunkSize = 8
+	}
+	d.SignURLExpire = min(max(d.SignURLExpire, 1), maxSignURLExpire)
+	d.tokenSource, d.email, d.key = nil, "", nil
+	if d.ServiceAccount != "" {
+		if err := d.initServiceAccount(); err != nil {
+			return err
+		}
+	}
+	// the bucket may be not readable by the service account which can only
+	// access the objects, so the objects are listed to check
+	_, err := d.request(ctx, http.MethodGet, d.bucketURL()+"/o", func(req *resty.Request) {
+		req.SetQueryParams(map[string]string{
+			"prefix":     getKey(d.GetRootPath(), true),
+			"maxResults": "1",
+		})
+	}, nil)
+	return err
+}
+
+func (d *GCS) Drop(ctx context.Context) error {
+	return nil
+}
+
+func (d *GCS) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	prefix := getKey(dir.GetPath(), true)
+	var res []model.Obj
+	err := d.listObjects(ctx, prefix, "/", func(resp *ListResp) {
+		for _, p := range resp.Prefixes {
+			name := strings.TrimSuffix(strings.TrimPrefix(p, prefix), "/")
+			if name == "" {
+				continue
+			}
+			res = append(res, &model.Object{
+				Path:     stdpath.Join(dir.GetPath(), name),
+				Name:     name,
+				IsFolder: true,
+			})
+		}
+		for _, o := range resp.Items {
+			// the marker of the dir itself
+			if o.Name == prefix {
+				continue
+			}
+			res = append(res, o.toObj(dir.GetPath(), strings.TrimPrefix(o.Name, prefix)))
+		}
+	})
+	return res, err
+}
+
+func (d *GCS) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	key := getKey(file.GetPath(), false)
+	if !common.ShouldProxy(d, file.GetName()) {
+		if d.key == nil {
+			// only the objects of the public buckets can be downloaded so
+			return &model.Link{URL: d.Endpoint + "/" + d.Bucket + "/" + encodeKey(key)}, nil
+		}
+		u, err := d.signURL(key, time.Hour*time.Duration(d.SignURLExpire))
+		if err != nil {
+			return nil, err
+		}
+		return &model.Link{URL: u}, nil
+	}
+	link := &model.Link{URL: d.objectURL(key) + "?alt=media"}
+	if d.tokenSource != nil {
+		token, err := d.tokenSource.Token()
+		if err != nil {
+			return nil, err
+		}
+		link.Header = http.Header{"Authorization": []string{"Bearer " + token.AccessToken}}
+		expiration := time.Until(token.Expiry) - time.Minute
+		link.Expiration = &expiration
+	}
+	return link, nil
+}
+
+func (d *GCS) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	key := getKey(stdpath.Join(parentDir.GetPath(), dirName), true)
+	_, err := d.request(ctx, http.MethodPost, d.uploadURL(), func(req *resty.Request) {
+		req.SetQueryParams(map[string]string{
+			"uploadType": "media",
+			"name":       key,
+		}).SetHeader("Content-Type", "application/octet-stream").SetBody([]byte{})
+	}, nil)
+	return err
+}
+
+func (d *GCS) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return d.move(ctx, srcObj, stdpath.Join(dstDir.GetPath(), srcObj.GetName()))
+}
+
+func (d *GCS) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	return d.move(ctx, srcObj, stdpath.Join(stdpath.Dir(srcObj.GetPath()), newName))
+}
+
+func (d *GCS) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	dst := stdpath.Join(dstDir.GetPath(), srcObj.GetName())
+	if !srcObj.IsDir() {
+		return d.rewrite(ctx, getKey(srcObj.GetPath(), false), getKey(dst, false))
+	}
+	return d.walkDir(ctx, srcObj.GetPath(), dst, d.rewrite)
+}
+
+func (d *GCS) Remove(ctx context.Context, obj model.Obj) error {
+	if !obj.IsDir() {
+		return d.deleteObject(ctx, getKey(obj.GetPath(), false))
+	}
+	var keys []string
+	err := d.listObjects(ctx, getKey(obj.GetPath(), true), "", func(resp *ListResp) {
+		for _, o := range resp.Items {
+			keys = append(keys, o.Name)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err = d.deleteObject(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *GCS) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer, up driver.UpdateProgress) error {
+	key := getKey(stdpath.Join(dstDir.GetPath(), s.GetName()), false)
+	data := base.Json{"contentType": s.GetMimetype()}
+	if !s.ModTime().IsZero() {
+		data["metadata"] = base.Json{mtimeKey: s.ModTime().UTC().Format(time.RFC3339Nano)}
+	}
+	res, err := d.request(ctx, http.MethodPost, d.uploadURL(), func(req *resty.Request) {
+		req.SetQueryParams(map[string]string{
+			"uploadType": "resumable",
+			"name":       key,
+		}).SetHeaders(map[string]string{
+			"X-Upload-Content-Type":   s.GetMimetype(),
+			"X-Upload-Content-Length": strconv.FormatInt(s.GetSize(), 10),
+		}).SetBody(data)
+	}, nil)
+	if err != nil {
+		return err
+	}
+	session := res.Header().Get("Location")
+	if session == "" {
+		return fmt.Errorf("failed to create the upload session of %s", key)
+	}
+	return d.chunkUpload(ctx, s, session, up)
+}
+
+var _ driver.Driver = (*GCS)(nil)
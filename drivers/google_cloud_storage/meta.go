@@ -0,0 +1,28 @@
+package google_cloud_storage
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+type Addition struct {
+	driver.RootPath
+	Bucket         string `json:"bucket" required:"true"`
+	ServiceAccount string `json:"service_account" type:"text" help:"the json key of the service account, the bucket is accessed anonymously if empty"`
+	Endpoint       string `json:"endpoint" default:"https://storage.googleapis.com" help:"change it for an emulator"`
+	SignURLExpire  int    `json:"sign_url_expire" type:"number" default:"4" help:"the expiration of the signed urls, in hours, no more than 168"`
+	ChunkSize      int64  `json:"chunk_size" type:"number" default:"8" help:"the chunk size of the resumable uploads, in MB"`
+}
+
+var config = driver.Config{
+	Name:        "Google Cloud Storage",
+	LocalSort:   true,
+	DefaultRoot: "/",
+	CheckStatus: true,
+}
+
+func init() {
+	op.RegisterDriver(func() driver.Driver {
+		return &GCS{}
+	})
+}
@@ -0,0 +1,64 @@
+package google_cloud_storage
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	stdpath "path"
+	"strconv"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+type Object struct {
+	Name        string            `json:"name"`
+	Size        string            `json:"size"`
+	ContentType string            `json:"contentType"`
+	Md5Hash     string            `json:"md5Hash"`
+	Updated     time.Time         `json:"updated"`
+	TimeCreated time.Time         `json:"timeCreated"`
+	Metadata    map[string]string `json:"metadata"`
+}
+
+func (o *Object) toObj(dir, name string) model.Obj {
+	size, _ := strconv.ParseInt(o.Size, 10, 64)
+	obj := &model.Object{
+		Path:     stdpath.Join(dir, name),
+		Name:     name,
+		Size:     size,
+		Modified: o.Updated,
+		Ctime:    o.TimeCreated,
+	}
+	// the time of the file uploaded is kept in the metadata as rclone does
+	if mtime, err := time.Parse(time.RFC3339Nano, o.Metadata[mtimeKey]); err == nil {
+		obj.Modified = mtime
+	}
+	if sum, err := base64.StdEncoding.DecodeString(o.Md5Hash); err == nil && len(sum) > 0 {
+		obj.HashInfo = utils.NewHashInfo(utils.MD5, hex.EncodeToString(sum))
+	}
+	return obj
+}
+
+type ListResp struct {
+	Items         []Object `json:"items"`
+	Prefixes      []string `json:"prefixes"`
+	NextPageToken string   `json:"nextPageToken"`
+}
+
+type RewriteResp struct {
+	Done         bool   `json:"done"`
+	RewriteToken string `json:"rewriteToken"`
+}
+
+type ErrorResp struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+}
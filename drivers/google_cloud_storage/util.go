@@ -0,0 +1,351 @@
+package google_cloud_storage
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	stdpath "path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/drivers/base"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/avast/retry-go"
+	"github.com/go-resty/resty/v2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	defaultEndpoint = "https://storage.googleapis.com"
+	scope           = "https://www.googleapis.com/auth/devstorage.read_write"
+	// the max expiration of the V4 signed urls, in hours
+	maxSignURLExpire = 7 * 24
+	mtimeKey         = "mtime"
+)
+
+// getKey converts the path to the object name, a dir ends with a slash.
+func getKey(path string, dir bool) string {
+	key := strings.TrimPrefix(path, "/")
+	if dir && key != "" {
+		key += "/"
+	}
+	return key
+}
+
+// encodeKey escapes the object name for the XML APIs, all the characters but
+// the unreserved ones and the slashes are escaped.
+func encodeKey(key string) string {
+	var sb strings.Builder
+	for _, c := range []byte(key) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~', c == '/':
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+func (d *GCS) bucketURL() string {
+	return d.Endpoint + "/storage/v1/b/" + url.PathEscape(d.Bucket)
+}
+
+func (d *GCS) objectURL(key string) string {
+	return d.bucketURL() + "/o/" + url.PathEscape(key)
+}
+
+func (d *GCS) uploadURL() string {
+	return d.Endpoint + "/upload/storage/v1/b/" + url.PathEscape(d.Bucket) + "/o"
+}
+
+func (d *GCS) initServiceAccount() error {
+	conf, err := google.JWTConfigFromJSON([]byte(d.ServiceAccount), scope)
+	if err != nil {
+		return fmt.Errorf("failed to parse the service account: %w", err)
+	}
+	// the tokens are refreshed after the init, so the context of it is not used
+	d.tokenSource = conf.TokenSource(context.Background())
+	var sa serviceAccount
+	if err = utils.Json.UnmarshalFromString(d.ServiceAccount, &sa); err != nil {
+		return err
+	}
+	block, _ := pem.Decode([]byte(sa.PrivateKey))
+	if block == nil {
+		return errors.New("invalid private key of the service account")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse the private key: %w", err)
+		}
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return errors.New("the private key of the service account is not a RSA key")
+	}
+	d.email, d.key = sa.ClientEmail, rsaKey
+	return nil
+}
+
+func (d *GCS) request(ctx context.Context, method, url string, callback base.ReqCallback, resp any) (*resty.Response, error) {
+	req := base.RestyClient.R().SetContext(ctx)
+	if d.tokenSource != nil {
+		token, err := d.tokenSource.Token()
+		if err != nil {
+			return nil, err
+		}
+		req.SetAuthToken(token.AccessToken)
+	}
+	if callback != nil {
+		callback(req)
+	}
+	if resp != nil {
+		req.SetResult(resp)
+	}
+	var e ErrorResp
+	req.SetError(&e)
+	res, err := req.Execute(method, url)
+	if err != nil {
+		return nil, err
+	}
+	if res.IsError() {
+		if res.StatusCode() == http.StatusNotFound {
+			return nil, errs.ObjectNotFound
+		}
+		if e.Error.Message != "" {
+			return nil, fmt.Errorf("%d: %s", res.StatusCode(), e.Error.Message)
+		}
+		return nil, fmt.Errorf("%s: %s", res.Status(), res.String())
+	}
+	return res, nil
+}
+
+// listObjects lists all the objects under the prefix page by page, the
+// objects are listed recursively if the delimiter is empty.
+func (d *GCS) listObjects(ctx context.Context, prefix, delimiter string, fn func(resp *ListResp)) error {
+	pageToken := ""
+	for {
+		var resp ListResp
+		_, err := d.request(ctx, http.MethodGet, d.bucketURL()+"/o", func(req *resty.Request) {
+			req.SetQueryParam("prefix", prefix)
+			if delimiter != "" {
+				req.SetQueryParam("delimiter", delimiter)
+			}
+			if pageToken != "" {
+				req.SetQueryParam("pageToken", pageToken)
+			}
+		}, &resp)
+		if err != nil {
+			return err
+		}
+		fn(&resp)
+		if resp.NextPageToken == "" {
+			return nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// signURL generates the V4 signed url to download the object.
+// Link: https://cloud.google.com/storage/docs/access-control/signing-urls-manually
+func (d *GCS) signURL(key string, expire time.Duration) (string, error) {
+	u, err := url.Parse(d.Endpoint)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	date := now.Format("20060102")
+	credentialScope := date + "/auto/storage/goog4_request"
+	query := map[string]string{
+		"X-Goog-Algorithm":     "GOOG4-RSA-SHA256",
+		"X-Goog-Credential":    d.email + "/" + credentialScope,
+		"X-Goog-Date":          now.Format("20060102T150405Z"),
+		"X-Goog-Expires":       strconv.FormatInt(int64(expire.Seconds()), 10),
+		"X-Goog-SignedHeaders": "host",
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	params := make([]string, 0, len(keys))
+	for _, k := range keys {
+		params = append(params, encodeKey(k)+"="+strings.ReplaceAll(encodeKey(query[k]), "/", "%2F"))
+	}
+	canonicalQuery := strings.Join(params, "&")
+	canonicalPath := "/" + encodeKey(d.Bucket) + "/" + encodeKey(key)
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		canonicalPath,
+		canonicalQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"GOOG4-RSA-SHA256",
+		query["X-Goog-Date"],
+		credentialScope,
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+	hash = sha256.Sum256([]byte(stringToSign))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, d.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s://%s%s?%s&X-Goog-Signature=%s",
+		u.Scheme, u.Host, canonicalPath, canonicalQuery, hex.EncodeToString(signature)), nil
+}
+
+// chunkUpload uploads the file to the session of the resumable upload chunk
+// by chunk, the server responds 308 until the last chunk.
+func (d *GCS) chunkUpload(ctx context.Context, file model.FileStreamer, session string, up driver.UpdateProgress) error {
+	size := file.GetSize()
+	if size == 0 {
+		return d.putChunk(ctx, session, nil, 0, "bytes */0")
+	}
+	defaultChunkSize := d.ChunkSize * utils.MB
+	ss, err := stream.NewStreamSectionReader(file, int(defaultChunkSize), &up)
+	if err != nil {
+		return err
+	}
+	var offset int64 = 0
+	for offset < size {
+		if utils.IsCanceled(ctx) {
+			return ctx.Err()
+		}
+		chunkSize := min(size-offset, defaultChunkSize)
+		reader, err := ss.GetSectionReader(offset, chunkSize)
+		if err != nil {
+			return err
+		}
+		contentRange := fmt.Sprintf("bytes %d-%d/%d", offset, offset+chunkSize-1, size)
+		err = retry.Do(func() error {
+			reader.Seek(0, io.SeekStart)
+			return d.putChunk(ctx, session, driver.NewLimitedUploadStream(ctx, reader), chunkSize, contentRange)
+		},
+			retry.Context(ctx),
+			retry.Attempts(3),
+			retry.DelayType(retry.BackOffDelay),
+			retry.Delay(time.Second))
+		ss.FreeSectionReader(reader)
+		if err != nil {
+			return err
+		}
+		offset += chunkSize
+		up(float64(offset) / float64(size) * 100)
+	}
+	return nil
+}
+
+func (d *GCS) putChunk(ctx context.Context, session string, body io.Reader, size int64, contentRange string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, session, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Range", contentRange)
+	res, err := base.HttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusPermanentRedirect || res.StatusCode < 300 {
+		return nil
+	}
+	var e ErrorResp
+	data, _ := io.ReadAll(res.Body)
+	if utils.Json.Unmarshal(data, &e) == nil && e.Error.Message != "" {
+		return fmt.Errorf("%d: %s", res.StatusCode, e.Error.Message)
+	}
+	return fmt.Errorf("%s: %s", res.Status, string(data))
+}
+
+// rewrite copies the object by the server, the large objects may take several
+// calls to be copied.
+func (d *GCS) rewrite(ctx context.Context, src, dst string) error {
+	rewriteToken := ""
+	for {
+		var resp RewriteResp
+		_, err := d.request(ctx, http.MethodPost, d.objectURL(src)+"/rewriteTo/b/"+url.PathEscape(d.Bucket)+"/o/"+url.PathEscape(dst), func(req *resty.Request) {
+			if rewriteToken != "" {
+				req.SetQueryParam("rewriteToken", rewriteToken)
+			}
+			req.SetHeader("Content-Type", "application/json").SetBody(base.Json{})
+		}, &resp)
+		if err != nil {
+			return err
+		}
+		if resp.Done {
+			return nil
+		}
+		rewriteToken = resp.RewriteToken
+	}
+}
+
+func (d *GCS) deleteObject(ctx context.Context, key string) error {
+	_, err := d.request(ctx, http.MethodDelete, d.objectURL(key), nil, nil)
+	if errors.Is(err, errs.ObjectNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (d *GCS) moveObject(ctx context.Context, src, dst string) error {
+	if err := d.rewrite(ctx, src, dst); err != nil {
+		return err
+	}
+	return d.deleteObject(ctx, src)
+}
+
+func (d *GCS) move(ctx context.Context, srcObj model.Obj, dst string) error {
+	if !srcObj.IsDir() {
+		return d.moveObject(ctx, getKey(srcObj.GetPath(), false), getKey(dst, false))
+	}
+	return d.walkDir(ctx, srcObj.GetPath(), dst, d.moveObject)
+}
+
+// walkDir applies fn on all the objects under the dir, including the marker
+// of the dir.
+func (d *GCS) walkDir(ctx context.Context, src, dst string, fn func(ctx context.Context, src, dst string) error) error {
+	srcPrefix, dstPrefix := getKey(src, true), getKey(dst, true)
+	var names []string
+	err := d.listObjects(ctx, srcPrefix, "", func(resp *ListResp) {
+		for _, o := range resp.Items {
+			names = append(names, o.Name)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		// an empty dir without the marker
+		return d.MakeDir(ctx, &model.Object{Path: stdpath.Dir(dst)}, stdpath.Base(dst))
+	}
+	for _, name := range names {
+		if err = fn(ctx, name, dstPrefix+strings.TrimPrefix(name, srcPrefix)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/drivers/base"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
@@ -130,6 +131,9 @@ func (d *GoogleDrive) Put(ctx context.Context, dstDir model.Obj, stream model.Fi
 		}
 		url = "https://www.googleapis.com/upload/drive/v3/files?uploadType=resumable&supportsAllDrives=true"
 	}
+	if !stream.ModTime().IsZero() {
+		data["modifiedTime"] = stream.ModTime().UTC().Format(time.RFC3339Nano)
+	}
 	req := base.NoRedirectClient.R().
 		SetHeaders(map[string]string{
 			"Authorization":           "Bearer " + d.AccessToken,
@@ -167,6 +171,17 @@ func (d *GoogleDrive) Put(ctx context.Context, dstDir model.Obj, stream model.Fi
 	return err
 }
 
+func (d *GoogleDrive) SetModTime(ctx context.Context, obj model.Obj, modified, created time.Time) error {
+	data := base.Json{
+		"modifiedTime": modified.UTC().Format(time.RFC3339Nano),
+	}
+	url := "https://www.googleapis.com/drive/v3/files/" + obj.GetID() + "?supportsAllDrives=true"
+	_, err := d.request(url, http.MethodPatch, func(req *resty.Request) {
+		req.SetBody(data).SetContext(ctx)
+	}, nil)
+	return err
+}
+
 func (d *GoogleDrive) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
 	if d.DisableDiskUsage {
 		return nil, errs.NotImplement
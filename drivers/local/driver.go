@@ -143,6 +143,9 @@ func (d *Local) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([
 	}
 	var files []model.Obj
 	for _, f := range rawFiles {
+		if isPart(f.Name()) {
+			continue
+		}
 		if d.ShowHidden || !isHidden(f, fullPath) {
 			files = append(files, d.FileInfoToObj(ctx, f, args.ReqPath, fullPath))
 		}
@@ -417,7 +420,7 @@ func (d *Local) Put(ctx context.Context, dstDir model.Obj, stream model.FileStre
 			_ = os.Remove(fullPath)
 		}
 	}()
-	err = utils.CopyWithCtx(ctx, out, stream, stream.GetSize(), up)
+	err = utils.CopyWithCtx(ctx, out, driver.NewTaskLimitedStream(ctx, stream), stream.GetSize(), up)
 	if err != nil {
 		return err
 	}
@@ -433,6 +436,11 @@ func (d *Local) Put(ctx context.Context, dstDir model.Obj, stream model.FileStre
 	return nil
 }
 
+func (d *Local) SetModTime(ctx context.Context, obj model.Obj, modified, created time.Time) error {
+	// the created time can't be changed on most of the systems
+	return os.Chtimes(obj.GetPath(), modified, modified)
+}
+
 func (d *Local) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
 	du, err := getDiskUsage(d.RootFolderPath)
 	if err != nil {
@@ -0,0 +1,122 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	partSuffix = ".openlist_part"
+	// a part file not continued for this long is given up
+	partRetention = 7 * 24 * time.Hour
+)
+
+var partVersion = regexp.MustCompile(`^\d+--?\d+$`)
+
+func isPart(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.HasSuffix(name, partSuffix)
+}
+
+// cleanParts removes the part files of the name left by a changed source,
+// and the part files of the dir which are not continued for partRetention.
+func cleanParts(dir, name, keep string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	prefix := "." + name + "."
+	for _, e := range entries {
+		if e.IsDir() || !isPart(e.Name()) || e.Name() == keep {
+			continue
+		}
+		version, ok := strings.CutPrefix(strings.TrimSuffix(e.Name(), partSuffix), prefix)
+		stale := ok && partVersion.MatchString(version)
+		if !stale {
+			info, err := e.Info()
+			stale = err == nil && time.Since(info.ModTime()) > partRetention
+		}
+		if stale {
+			if err := os.Remove(filepath.Join(dir, e.Name())); err == nil {
+				log.Infof("[local] removed stale part file %s", filepath.Join(dir, e.Name()))
+			}
+		}
+	}
+}
+
+// PutResumable writes the file into a hidden part file first, which is
+// continued by the next attempt and renamed once it is complete.
+func (d *Local) PutResumable(ctx context.Context, dstDir model.Obj, file model.FileStreamer, up driver.UpdateProgress) (err error) {
+	size := file.GetSize()
+	if size <= 0 {
+		return d.Put(ctx, dstDir, file, up)
+	}
+	fullPath := filepath.Join(dstDir.GetPath(), file.GetName())
+	// named by the size and the modified time, so that only the part of the
+	// same file is continued
+	partName := fmt.Sprintf(".%s.%d-%d%s", file.GetName(), size, file.ModTime().Unix(), partSuffix)
+	partPath := filepath.Join(dstDir.GetPath(), partName)
+	cleanParts(dstDir.GetPath(), file.GetName(), partName)
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil && info.Size() <= size {
+		offset = info.Size()
+	}
+	out, err := os.OpenFile(partPath, os.O_WRONLY|os.O_CREATE, 0o666)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = out.Close()
+		if errors.Is(err, context.Canceled) {
+			_ = os.Remove(partPath)
+		}
+	}()
+	if err = out.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err = out.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	var r io.Reader = file
+	if offset > 0 {
+		log.Infof("[local] resume putting %s from %d", fullPath, offset)
+		if r, err = file.RangeRead(http_range.Range{Start: offset, Length: size - offset}); err != nil {
+			return err
+		}
+		if c, ok := r.(io.Closer); ok {
+			defer c.Close()
+		}
+	}
+	err = utils.CopyWithCtx(ctx, out, driver.NewTaskLimitedStream(ctx, r), size-offset, model.UpdateProgressWithRange(up, float64(offset)/float64(size)*100, 100))
+	if err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(partPath, fullPath); err != nil {
+		return err
+	}
+	if err := os.Chtimes(fullPath, file.ModTime(), file.ModTime()); err != nil {
+		log.Errorf("[local] failed to change time of %s: %s", fullPath, err)
+	}
+	if d.directoryMap.Has(dstDir.GetPath()) {
+		d.directoryMap.UpdateDirSize(dstDir.GetPath())
+		d.directoryMap.UpdateDirParents(dstDir.GetPath())
+	}
+	return nil
+}
+
+var _ driver.ResumablePut = (*Local)(nil)
@@ -0,0 +1,38 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCleanParts(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{
+		".a.txt.10-100.openlist_part",   // kept
+		".a.txt.20-200.openlist_part",   // changed source
+		".a.txt.b.10-100.openlist_part", // another file
+		".c.txt.10-100.openlist_part",   // expired
+		"a.txt",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-partRetention - time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, names[3]), old, old); err != nil {
+		t.Fatal(err)
+	}
+	cleanParts(dir, "a.txt", names[0])
+	for i, name := range names {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if removed := os.IsNotExist(err); removed != (i == 1 || i == 3) {
+			t.Errorf("%s removed: %v", name, removed)
+		}
+	}
+	if !isPart(names[0]) || isPart("a.txt") {
+		t.Error("isPart mismatched")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"path"
 	"sync"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/drivers/base"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
@@ -222,6 +223,18 @@ func (d *Onedrive) Put(ctx context.Context, dstDir model.Obj, stream model.FileS
 	return err
 }
 
+func (d *Onedrive) SetModTime(ctx context.Context, obj model.Obj, modified, created time.Time) error {
+	info := map[string]any{"lastModifiedDateTime": modified.UTC()}
+	if !created.IsZero() {
+		info["createdDateTime"] = created.UTC()
+	}
+	url := d.GetMetaUrl(false, obj.GetPath())
+	_, err := d.Request(url, http.MethodPatch, func(req *resty.Request) {
+		req.SetBody(map[string]any{"fileSystemInfo": info}).SetContext(ctx)
+	}, nil)
+	return err
+}
+
 func (d *Onedrive) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
 	if d.DisableDiskUsage {
 		return nil, errs.NotImplement
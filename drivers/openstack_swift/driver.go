@@ -0,0 +1,241 @@
+package openstack_swift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	stdpath "path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/ncw/swift/v2"
+)
+
+// OpenStack Swift based on the object storage APIs
+// Link: https://docs.openstack.org/api-ref/object-store/
+type Swift struct {
+	model.Storage
+	Addition
+	conn *swift.Connection
+}
+
+func (d *Swift) Config() driver.Config {
+	return config
+}
+
+func (d *Swift) GetAddition() driver.Additional {
+	return &d.Addition
+}
+
+func (d *Swift) Init(ctx context.Context) error {
+	if d.ChunkSize <= 0 || d.ChunkSize > maxChunkSize {
+		d.ChunkSize = maxChunkSize
+	}
+	d.conn = &swift.Connection{
+		AuthUrl:                     d.AuthURL,
+		AuthVersion:                 d.AuthVersion,
+		UserName:                    d.Username,
+		ApiKey:                      d.Password,
+		Domain:                      d.UserDomain,
+		Tenant:                      d.Project,
+		TenantId:                    d.ProjectID,
+		TenantDomain:                d.ProjectDomain,
+		ApplicationCredentialId:     d.ApplicationCredentialID,
+		ApplicationCredentialSecret: d.ApplicationCredentialSecret,
+		Region:                      d.Region,
+		EndpointType:                swift.EndpointType(d.EndpointType),
+		UserAgent:                   "OpenList",
+	}
+	if err := d.conn.Authenticate(ctx); err != nil {
+		return fmt.Errorf("failed to authenticate: %w", err)
+	}
+	_, _, err := d.conn.Container(ctx, d.Container)
+	if errors.Is(err, swift.ContainerNotFound) {
+		err = d.conn.ContainerCreate(ctx, d.Container, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get container: %w", err)
+	}
+	return nil
+}
+
+func (d *Swift) Drop(ctx context.Context) error {
+	if d.conn != nil {
+		d.conn.UnAuthenticate()
+	}
+	return nil
+}
+
+func (d *Swift) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	prefix := getKey(dir.GetPath(), true)
+	objects, err := d.conn.ObjectsAll(ctx, d.Container, &swift.ObjectsOpts{
+		Prefix:    prefix,
+		Delimiter: '/',
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]model.Obj, 0, len(objects))
+	dirs := make(map[string]struct{})
+	for _, o := range objects {
+		name := strings.TrimSuffix(strings.TrimPrefix(o.Name, prefix), "/")
+		if name == "" {
+			// the marker of the dir itself
+			continue
+		}
+		if o.PseudoDirectory || isDirMarker(o) {
+			if _, ok := dirs[name]; ok {
+				continue
+			}
+			dirs[name] = struct{}{}
+			res = append(res, &model.Object{
+				Path:     stdpath.Join(dir.GetPath(), name),
+				Name:     name,
+				Modified: o.LastModified,
+				IsFolder: true,
+			})
+			continue
+		}
+		obj, err := d.toObj(ctx, o, dir.GetPath(), name)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, obj)
+	}
+	return res, nil
+}
+
+func (d *Swift) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	key := getKey(file.GetPath(), false)
+	if !d.conn.Authenticated() {
+		if err := d.conn.Authenticate(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if !common.ShouldProxy(d, file.GetName()) {
+		if d.TempURLKey == "" {
+			// only the objects of the public containers can be downloaded so
+			return &model.Link{URL: d.conn.StorageUrl + "/" + d.Container + "/" + utils.EncodePath(key, true)}, nil
+		}
+		expires := time.Now().Add(time.Hour * time.Duration(d.SignURLExpire))
+		return &model.Link{
+			URL: d.conn.ObjectTempUrl(d.Container, key, d.TempURLKey, http.MethodGet, expires),
+		}, nil
+	}
+	// the objects are read by the connection, which authenticates again if
+	// the token expires
+	return &model.Link{
+		RangeReader: stream.RangeReaderFunc(func(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+			headers := swift.Headers{}
+			if r := http_range.ApplyRangeToHttpHeader(httpRange, nil).Get("Range"); r != "" {
+				headers["Range"] = r
+			}
+			f, _, err := d.conn.ObjectOpen(ctx, d.Container, key, false, headers)
+			if err != nil {
+				return nil, err
+			}
+			return f, nil
+		}),
+	}, nil
+}
+
+func (d *Swift) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	key := getKey(stdpath.Join(parentDir.GetPath(), dirName), true)
+	return d.conn.ObjectPutBytes(ctx, d.Container, key, nil, dirContentType)
+}
+
+func (d *Swift) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	return d.move(ctx, srcObj, stdpath.Join(dstDir.GetPath(), srcObj.GetName()))
+}
+
+func (d *Swift) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	return d.move(ctx, srcObj, stdpath.Join(stdpath.Dir(srcObj.GetPath()), newName))
+}
+
+func (d *Swift) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	dst := stdpath.Join(dstDir.GetPath(), srcObj.GetName())
+	if !srcObj.IsDir() {
+		return d.copyObject(ctx, getKey(srcObj.GetPath(), false), getKey(dst, false))
+	}
+	return d.walkDir(ctx, srcObj.GetPath(), dst, d.copyObject)
+}
+
+func (d *Swift) Remove(ctx context.Context, obj model.Obj) error {
+	if !obj.IsDir() {
+		return d.removeObject(ctx, getKey(obj.GetPath(), false))
+	}
+	objects, err := d.conn.ObjectNamesAll(ctx, d.Container, &swift.ObjectsOpts{
+		Prefix: getKey(obj.GetPath(), true),
+	})
+	if err != nil {
+		return err
+	}
+	// the marker of a dir created by other tools has no slash
+	objects = append(objects, getKey(obj.GetPath(), false))
+	for _, name := range objects {
+		if err = d.removeObject(ctx, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Swift) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer, up driver.UpdateProgress) error {
+	key := getKey(stdpath.Join(dstDir.GetPath(), s.GetName()), false)
+	headers := swift.Headers{}
+	if !s.ModTime().IsZero() {
+		headers["X-Object-Meta-Mtime"] = swift.TimeToFloatString(s.ModTime())
+	}
+	reader := driver.NewLimitedUploadStream(ctx, &driver.ReaderUpdatingProgress{
+		Reader:         s,
+		UpdateProgress: up,
+	})
+	return d.replaceObject(ctx, key, func() error {
+		if s.GetSize() > int64(d.ChunkSize)*utils.MB {
+			return d.putLarge(ctx, key, reader, s.GetSize(), s.GetMimetype(), headers)
+		}
+		_, err := d.conn.ObjectPut(ctx, d.Container, key, reader, false, s.GetHash().GetHash(utils.MD5), s.GetMimetype(), headers)
+		return err
+	})
+}
+
+func (d *Swift) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
+	container, headers, err := d.conn.Container(ctx, d.Container)
+	if err != nil {
+		return nil, err
+	}
+	if quota, ok := parseQuota(headers["X-Container-Meta-Quota-Bytes"]); ok {
+		return &model.StorageDetails{
+			DiskUsage: model.DiskUsage{TotalSpace: quota, UsedSpace: container.Bytes},
+		}, nil
+	}
+	account, headers, err := d.conn.Account(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if quota, ok := parseQuota(headers["X-Account-Meta-Quota-Bytes"]); ok {
+		return &model.StorageDetails{
+			DiskUsage: model.DiskUsage{TotalSpace: quota, UsedSpace: account.BytesUsed},
+		}, nil
+	}
+	// the space is unlimited without the quotas
+	return nil, errs.NotImplement
+}
+
+func parseQuota(s string) (int64, bool) {
+	quota, err := strconv.ParseInt(s, 10, 64)
+	return quota, err == nil && quota > 0
+}
+
+var _ driver.Driver = (*Swift)(nil)
+var _ driver.WithDetails = (*Swift)(nil)
@@ -0,0 +1,149 @@
+package openstack_swift
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/ncw/swift/v2"
+	"github.com/ncw/swift/v2/swifttest"
+)
+
+func newDriver(t *testing.T) *Swift {
+	srv, err := swifttest.NewSwiftServer("localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+	d := &Swift{Addition: Addition{
+		AuthURL:   srv.AuthURL,
+		Username:  swifttest.TEST_ACCOUNT,
+		Password:  swifttest.TEST_ACCOUNT,
+		Container: "c",
+		ChunkSize: 1,
+	}}
+	if err = d.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func testData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+func (d *Swift) read(t *testing.T, key string) []byte {
+	var buf bytes.Buffer
+	if _, err := d.conn.ObjectGet(context.Background(), d.Container, key, &buf, false, nil); err != nil {
+		t.Fatalf("failed to read %s: %v", key, err)
+	}
+	return buf.Bytes()
+}
+
+func (d *Swift) segmentNames(t *testing.T) []string {
+	names, err := d.conn.ObjectNamesAll(context.Background(), d.Container+segmentsSuffix, nil)
+	if err != nil && !errors.Is(err, swift.ContainerNotFound) {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestPutLarge(t *testing.T) {
+	d := newDriver(t)
+	ctx := context.Background()
+	data := testData(int(utils.MB*5/2) + 1)
+	if err := d.putLarge(ctx, "dir/large.bin", bytes.NewReader(data), int64(len(data)), "application/octet-stream", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.read(t, "dir/large.bin"); !bytes.Equal(got, data) {
+		t.Fatalf("the content of the large object is different, %d bytes read", len(got))
+	}
+	if names := d.segmentNames(t); len(names) != 3 {
+		t.Errorf("expect 3 segments, got %v", names)
+	}
+}
+
+func TestReplaceLargeObject(t *testing.T) {
+	d := newDriver(t)
+	ctx := context.Background()
+	data := testData(int(utils.MB * 3 / 2))
+	if err := d.putLarge(ctx, "large.bin", bytes.NewReader(data), int64(len(data)), "", nil); err != nil {
+		t.Fatal(err)
+	}
+	segments := d.segmentNames(t)
+
+	// a failed upload keeps the former object
+	err := d.replaceObject(ctx, "large.bin", func() error {
+		return errors.New("upload failed")
+	})
+	if err == nil {
+		t.Fatal("expect the error of the upload")
+	}
+	if got := d.read(t, "large.bin"); !bytes.Equal(got, data) {
+		t.Fatal("the former object should be kept after a failed upload")
+	}
+	if names := d.segmentNames(t); len(names) != len(segments) {
+		t.Fatalf("the former segments should be kept, got %v", names)
+	}
+
+	// the segments of the former object are removed after the upload, the
+	// emulator keeps the manifest of the former object on the put of a small
+	// one, so it is replaced by another large object
+	data2 := testData(int(utils.MB*5/2) + 5)
+	err = d.replaceObject(ctx, "large.bin", func() error {
+		return d.putLarge(ctx, "large.bin", bytes.NewReader(data2), int64(len(data2)), "", nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.read(t, "large.bin"); !bytes.Equal(got, data2) {
+		t.Fatalf("the content of the new object is different, %d bytes read", len(got))
+	}
+	names := d.segmentNames(t)
+	if len(names) != 3 {
+		t.Fatalf("expect the 3 segments of the new object, got %v", names)
+	}
+	for _, name := range names {
+		if slices.Contains(segments, name) {
+			t.Errorf("the former segment %s should be removed", name)
+		}
+	}
+}
+
+func TestCopyObject(t *testing.T) {
+	d := newDriver(t)
+	ctx := context.Background()
+	data := testData(int(utils.MB*3/2) + 3)
+	if err := d.putLarge(ctx, "src.bin", bytes.NewReader(data), int64(len(data)), "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.conn.ObjectPut(ctx, d.Container, "small.txt", bytes.NewReader([]byte("hello")), false, "", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.copyObject(ctx, "src.bin", "dst.bin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.copyObject(ctx, "small.txt", "small2.txt"); err != nil {
+		t.Fatal(err)
+	}
+	// the copy has its own segments, which are not removed with the source
+	if err := d.removeObject(ctx, "src.bin"); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.read(t, "dst.bin"); !bytes.Equal(got, data) {
+		t.Fatalf("the content of the copy is different, %d bytes read", len(got))
+	}
+	if got := d.read(t, "small2.txt"); string(got) != "hello" {
+		t.Fatalf("unexpected content of the copy: %q", got)
+	}
+	if names := d.segmentNames(t); len(names) != 2 {
+		t.Errorf("expect the 2 segments of the copy, got %v", names)
+	}
+}
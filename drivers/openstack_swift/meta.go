@@ -0,0 +1,39 @@
+package openstack_swift
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+type Addition struct {
+	driver.RootPath
+	AuthURL                     string `json:"auth_url" required:"true" help:"e.g. https://keystone.example.com/v3"`
+	AuthVersion                 int    `json:"auth_version" type:"select" options:"0,1,2,3" default:"0" help:"0 to detect by the auth url"`
+	Username                    string `json:"username"`
+	Password                    string `json:"password" help:"the password, or the api key of the v1 auth"`
+	UserDomain                  string `json:"user_domain" help:"the domain of the user, v3 only"`
+	Project                     string `json:"project" help:"the name of the project (tenant)"`
+	ProjectID                   string `json:"project_id"`
+	ProjectDomain               string `json:"project_domain" help:"the domain of the project if it differs from the user domain, v3 only"`
+	ApplicationCredentialID     string `json:"application_credential_id" help:"used instead of the username and the password, v3 only"`
+	ApplicationCredentialSecret string `json:"application_credential_secret"`
+	Region                      string `json:"region"`
+	EndpointType                string `json:"endpoint_type" type:"select" options:"public,internal,admin" default:"public"`
+	Container                   string `json:"container" required:"true"`
+	TempURLKey                  string `json:"temp_url_key" help:"the key of the account or the container to sign the temp urls, otherwise the files can be downloaded without web proxy only from the public containers"`
+	SignURLExpire               int    `json:"sign_url_expire" type:"number" default:"4" help:"the expiration of the temp urls, in hours"`
+	ChunkSize                   int    `json:"chunk_size" type:"number" default:"1024" help:"the files larger than it are uploaded as the large objects of the segments of the size, in MB, no more than 5120"`
+}
+
+var config = driver.Config{
+	Name:        "OpenStack Swift",
+	LocalSort:   true,
+	DefaultRoot: "/",
+	CheckStatus: true,
+}
+
+func init() {
+	op.RegisterDriver(func() driver.Driver {
+		return &Swift{}
+	})
+}
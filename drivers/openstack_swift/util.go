@@ -0,0 +1,214 @@
+package openstack_swift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/ncw/swift/v2"
+)
+
+const (
+	// the max size of an object, the larger ones are split into the segments
+	maxChunkSize   = 5 * 1024
+	segmentsSuffix = "_segments"
+	dirContentType = "application/directory"
+)
+
+// getKey converts the path to the object name, a dir ends with a slash.
+func getKey(path string, dir bool) string {
+	key := strings.TrimPrefix(path, "/")
+	if dir && key != "" {
+		key += "/"
+	}
+	return key
+}
+
+// isDirMarker tells whether the object is the marker of a dir created by other
+// tools, which has no slash at the end.
+func isDirMarker(o swift.Object) bool {
+	return o.ContentType == dirContentType && o.Bytes == 0
+}
+
+func (d *Swift) toObj(ctx context.Context, o swift.Object, dir, name string) (model.Obj, error) {
+	obj := &model.Object{
+		Path:     stdpath.Join(dir, name),
+		Name:     name,
+		Size:     o.Bytes,
+		Modified: o.LastModified,
+	}
+	switch {
+	case o.ObjectType == swift.StaticLargeObjectType:
+		// the hash of the manifest is not the md5 of the content
+	case o.Bytes == 0:
+		// the dynamic large objects are listed as empty ones
+		info, _, err := d.conn.Object(ctx, d.Container, o.Name)
+		if err != nil && !errors.Is(err, swift.ObjectNotFound) {
+			return nil, err
+		}
+		if err == nil && info.ObjectType != swift.RegularObjectType {
+			obj.Size = info.Bytes
+			break
+		}
+		fallthrough
+	default:
+		obj.HashInfo = utils.NewHashInfo(utils.MD5, o.Hash)
+	}
+	return obj, nil
+}
+
+// putLarge uploads the segments one by one as rclone does, and then the
+// manifest of the dynamic large object, which all the clusters support.
+func (d *Swift) putLarge(ctx context.Context, key string, r io.Reader, size int64, contentType string, headers swift.Headers) error {
+	segmentContainer := d.Container + segmentsSuffix
+	if err := d.conn.ContainerCreate(ctx, segmentContainer, nil); err != nil {
+		return err
+	}
+	chunkSize := int64(d.ChunkSize) * utils.MB
+	segmentPrefix := fmt.Sprintf("%s/%d/%d/%d", key, time.Now().UnixNano(), size, chunkSize)
+	for i, left := 1, size; left > 0; i++ {
+		n := min(left, chunkSize)
+		segment := fmt.Sprintf("%s/%08d", segmentPrefix, i)
+		_, err := d.conn.ObjectPut(ctx, segmentContainer, segment, io.LimitReader(r, n), false, "", "application/octet-stream", nil)
+		if err != nil {
+			return err
+		}
+		left -= n
+	}
+	manifest := swift.Headers{}
+	for k, v := range headers {
+		manifest[k] = v
+	}
+	manifest["X-Object-Manifest"] = segmentContainer + "/" + segmentPrefix + "/"
+	_, err := d.conn.ObjectPut(ctx, d.Container, key, strings.NewReader(""), false, "", contentType, manifest)
+	return err
+}
+
+// copyObject copies by the server, but the large objects are copied through
+// the connection, since the COPY of a manifest is limited to 5GB.
+func (d *Swift) copyObject(ctx context.Context, src, dst string) error {
+	info, headers, err := d.conn.Object(ctx, d.Container, src)
+	if err != nil {
+		return err
+	}
+	return d.replaceObject(ctx, dst, func() error {
+		if !headers.IsLargeObject() {
+			_, err := d.conn.ObjectCopy(ctx, d.Container, src, d.Container, dst, nil)
+			return err
+		}
+		f, _, err := d.conn.ObjectOpen(ctx, d.Container, src, false, nil)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return d.putLarge(ctx, dst, f, info.Bytes, info.ContentType, headers.ObjectMetadata().ObjectHeaders())
+	})
+}
+
+// moveObject moves the manifest only for the large objects, the segments are
+// kept where they are.
+func (d *Swift) moveObject(ctx context.Context, src, dst string) error {
+	_, headers, err := d.conn.Object(ctx, d.Container, src)
+	if err != nil {
+		return err
+	}
+	return d.replaceObject(ctx, dst, func() error {
+		switch {
+		case headers.IsLargeObjectSLO():
+			return d.conn.StaticLargeObjectMove(ctx, d.Container, src, d.Container, dst)
+		case headers.IsLargeObjectDLO():
+			return d.conn.DynamicLargeObjectMove(ctx, d.Container, src, d.Container, dst)
+		}
+		return d.conn.ObjectMove(ctx, d.Container, src, d.Container, dst)
+	})
+}
+
+// replaceObject writes the object by put, and then removes the segments of
+// the large object replaced, so that the former object is kept if the put
+// fails. The segments the new object still refers to are kept.
+func (d *Swift) replaceObject(ctx context.Context, key string, put func() error) error {
+	container, segments, err := d.segments(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err = put(); err != nil {
+		return err
+	}
+	if len(segments) == 0 {
+		return nil
+	}
+	newContainer, newSegments, err := d.segments(ctx, key)
+	if err != nil {
+		return err
+	}
+	kept := make(map[string]struct{}, len(newSegments))
+	for _, s := range newSegments {
+		kept[newContainer+"/"+s.Name] = struct{}{}
+	}
+	for _, s := range segments {
+		if _, ok := kept[container+"/"+s.Name]; ok {
+			continue
+		}
+		if err = d.conn.ObjectDelete(ctx, container, s.Name); err != nil && !errors.Is(err, swift.ObjectNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// segments returns the segments of the object if it is a large object.
+func (d *Swift) segments(ctx context.Context, key string) (string, []swift.Object, error) {
+	_, headers, err := d.conn.Object(ctx, d.Container, key)
+	if errors.Is(err, swift.ObjectNotFound) {
+		return "", nil, nil
+	}
+	if err != nil || !headers.IsLargeObject() {
+		return "", nil, err
+	}
+	return d.conn.LargeObjectGetSegments(ctx, d.Container, key)
+}
+
+func (d *Swift) removeObject(ctx context.Context, key string) error {
+	err := d.conn.LargeObjectDelete(ctx, d.Container, key)
+	if errors.Is(err, swift.ObjectNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (d *Swift) move(ctx context.Context, srcObj model.Obj, dst string) error {
+	if !srcObj.IsDir() {
+		return d.moveObject(ctx, getKey(srcObj.GetPath(), false), getKey(dst, false))
+	}
+	if err := d.walkDir(ctx, srcObj.GetPath(), dst, d.moveObject); err != nil {
+		return err
+	}
+	// the marker of a dir created by other tools
+	return d.removeObject(ctx, getKey(srcObj.GetPath(), false))
+}
+
+// walkDir applies fn on all the objects under the dir, including the marker
+// of the dir.
+func (d *Swift) walkDir(ctx context.Context, src, dst string, fn func(ctx context.Context, src, dst string) error) error {
+	srcPrefix, dstPrefix := getKey(src, true), getKey(dst, true)
+	names, err := d.conn.ObjectNamesAll(ctx, d.Container, &swift.ObjectsOpts{Prefix: srcPrefix})
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		// an empty dir without the marker
+		return d.conn.ObjectPutBytes(ctx, d.Container, dstPrefix, nil, dirContentType)
+	}
+	for _, name := range names {
+		if err = fn(ctx, name, dstPrefix+strings.TrimPrefix(name, srcPrefix)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -210,10 +211,58 @@ func (d *S3) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer, up
 		}),
 		ContentType: &contentType,
 	}
+	if !s.ModTime().IsZero() {
+		input.Metadata = setMetaModTime(nil, s.ModTime())
+	}
 	_, err := uploader.UploadWithContext(ctx, input)
 	return err
 }
 
+// Get reads the modified time kept in the metadata, the dirs are found by
+// listing.
+func (d *S3) Get(ctx context.Context, path string) (model.Obj, error) {
+	path = stdpath.Join(d.GetRootPath(), path)
+	key := getKey(path, false)
+	head, err := d.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: &d.Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, errs.NotSupport
+		}
+		return nil, err
+	}
+	obj := &model.Object{
+		Path:     path,
+		Name:     stdpath.Base(path),
+		Size:     aws.Int64Value(head.ContentLength),
+		Modified: aws.TimeValue(head.LastModified),
+	}
+	if t, ok := metaModTime(head.Metadata); ok {
+		obj.Modified = t
+	}
+	return obj, nil
+}
+
+// SetModTime records the time in the metadata as rclone does, by copying the
+// object onto itself, since the LastModified of S3 can not be changed.
+func (d *S3) SetModTime(ctx context.Context, obj model.Obj, modified, created time.Time) error {
+	if obj.IsDir() {
+		return errs.NotSupport
+	}
+	key := getKey(obj.GetPath(), false)
+	head, err := d.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: &d.Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return err
+	}
+	metadata := setMetaModTime(head.Metadata, modified)
+	return d.copyObject(ctx, key, key, aws.Int64Value(head.ContentLength), head.ContentType, metadata)
+}
+
 func (d *S3) GetDirectUploadTools() []string {
 	if !d.EnableDirectUpload {
 		return nil
@@ -23,6 +23,7 @@ type Addition struct {
 	AddFilenameToDisposition bool   `json:"add_filename_to_disposition" help:"Add filename to Content-Disposition header."`
 	EnableDirectUpload       bool   `json:"enable_direct_upload" default:"false"`
 	DirectUploadHost         string `json:"direct_upload_host" required:"false"`
+	ReadModTime              bool   `json:"read_mod_time" help:"Read the modified time kept in the metadata of the files listed, which takes a request for each file."`
 }
 
 func init() {
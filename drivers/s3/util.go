@@ -3,19 +3,24 @@ package s3
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/ncw/swift/v2"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -133,7 +138,7 @@ func (d *S3) listV1(dirPath string, args model.ListArgs) ([]model.Obj, error) {
 				Path:     path.Join(dirPath, name),
 				Name:     name,
 				Size:     *object.Size,
-				Modified: *object.LastModified,
+				Modified: d.modTime(*object.Key, *object.LastModified),
 			}
 			files = append(files, &file)
 		}
@@ -188,7 +193,7 @@ func (d *S3) listV2(dirPath string, args model.ListArgs) ([]model.Obj, error) {
 				Path:     path.Join(dirPath, name),
 				Name:     name,
 				Size:     *object.Size,
-				Modified: *object.LastModified,
+				Modified: d.modTime(*object.Key, *object.LastModified),
 			}
 			files = append(files, &file)
 		}
@@ -277,3 +282,124 @@ func (d *S3) removeFile(src string) error {
 	_, err := d.client.DeleteObject(input)
 	return err
 }
+
+const (
+	// the metadata keeping the modified time, as rclone does
+	metaMtime = "mtime"
+	// the largest object copied by CopyObject, the larger ones are copied by
+	// parts
+	maxCopySize  = 5 * 1024 * 1024 * 1024
+	copyPartSize = 512 * 1024 * 1024
+)
+
+func isNotFound(err error) bool {
+	var e awserr.RequestFailure
+	return errors.As(err, &e) && e.StatusCode() == http.StatusNotFound
+}
+
+// metaModTime reads the modified time in the metadata, whose keys are
+// canonicalized by the sdk.
+func metaModTime(metadata map[string]*string) (time.Time, bool) {
+	for k, v := range metadata {
+		if strings.EqualFold(k, metaMtime) && v != nil {
+			t, err := swift.FloatStringToTime(*v)
+			return t, err == nil
+		}
+	}
+	return time.Time{}, false
+}
+
+func setMetaModTime(metadata map[string]*string, t time.Time) map[string]*string {
+	res := make(map[string]*string, len(metadata)+1)
+	for k, v := range metadata {
+		if !strings.EqualFold(k, metaMtime) {
+			res[k] = v
+		}
+	}
+	res[metaMtime] = aws.String(swift.TimeToFloatString(t))
+	return res
+}
+
+// modTime returns the modified time kept in the metadata if ReadModTime is
+// set, which takes a request for each file listed.
+func (d *S3) modTime(key string, lastModified time.Time) time.Time {
+	if !d.ReadModTime {
+		return lastModified
+	}
+	head, err := d.client.HeadObject(&s3.HeadObjectInput{
+		Bucket: &d.Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		log.Warnf("failed read the metadata of %s: %v", key, err)
+		return lastModified
+	}
+	if t, ok := metaModTime(head.Metadata); ok {
+		return t
+	}
+	return lastModified
+}
+
+// copyObject copies the object with the metadata replaced, by parts if it's
+// too large for CopyObject.
+func (d *S3) copyObject(ctx context.Context, srcKey, dstKey string, size int64, contentType *string, metadata map[string]*string) error {
+	source := aws.String(strings.ReplaceAll(url.PathEscape(d.Bucket+"/"+srcKey), "+", "%2B"))
+	if size <= maxCopySize {
+		_, err := d.client.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
+			Bucket:            &d.Bucket,
+			CopySource:        source,
+			Key:               &dstKey,
+			ContentType:       contentType,
+			Metadata:          metadata,
+			MetadataDirective: aws.String(s3.MetadataDirectiveReplace),
+		})
+		return err
+	}
+	upload, err := d.client.CreateMultipartUploadWithContext(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      &d.Bucket,
+		Key:         &dstKey,
+		ContentType: contentType,
+		Metadata:    metadata,
+	})
+	if err != nil {
+		return err
+	}
+	partSize := max(int64(copyPartSize), (size+s3manager.MaxUploadParts-1)/s3manager.MaxUploadParts)
+	var parts []*s3.CompletedPart
+	for n, start := int64(1), int64(0); start < size; n, start = n+1, start+partSize {
+		res, err := d.client.UploadPartCopyWithContext(ctx, &s3.UploadPartCopyInput{
+			Bucket:          &d.Bucket,
+			Key:             &dstKey,
+			UploadId:        upload.UploadId,
+			PartNumber:      aws.Int64(n),
+			CopySource:      source,
+			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", start, min(start+partSize, size)-1)),
+		})
+		if err != nil {
+			d.abortUpload(dstKey, upload.UploadId)
+			return err
+		}
+		parts = append(parts, &s3.CompletedPart{ETag: res.CopyPartResult.ETag, PartNumber: aws.Int64(n)})
+	}
+	_, err = d.client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          &d.Bucket,
+		Key:             &dstKey,
+		UploadId:        upload.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		d.abortUpload(dstKey, upload.UploadId)
+	}
+	return err
+}
+
+func (d *S3) abortUpload(key string, uploadID *string) {
+	_, err := d.client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   &d.Bucket,
+		Key:      &key,
+		UploadId: uploadID,
+	})
+	if err != nil {
+		log.Warnf("failed abort the upload of %s: %v", key, err)
+	}
+}
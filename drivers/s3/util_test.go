@@ -0,0 +1,92 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestMetaModTime(t *testing.T) {
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 600, time.UTC)
+	// the sdk canonicalizes the keys read back
+	metadata := setMetaModTime(map[string]*string{"Mtime": aws.String("1"), "Other": aws.String("x")}, modified)
+	if len(metadata) != 2 || aws.StringValue(metadata["Other"]) != "x" {
+		t.Fatalf("unexpected metadata: %v", aws.StringValueMap(metadata))
+	}
+	got, ok := metaModTime(map[string]*string{"Mtime": metadata[metaMtime]})
+	if !ok || !got.Equal(modified) {
+		t.Errorf("expect %v, got %v, %v", modified, got, ok)
+	}
+	if _, ok = metaModTime(map[string]*string{"Mtime": aws.String("bad")}); ok {
+		t.Error("expect the bad time ignored")
+	}
+}
+
+func TestCopyObjectByParts(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		ranges   []string
+		metadata string
+		parts    int
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		q := r.URL.Query()
+		switch {
+		case r.Method == http.MethodPost && q.Has("uploads"):
+			metadata = r.Header.Get("X-Amz-Meta-Mtime")
+			fmt.Fprint(w, `<InitiateMultipartUploadResult><Bucket>b</Bucket><Key>k</Key><UploadId>u1</UploadId></InitiateMultipartUploadResult>`)
+		case r.Method == http.MethodPut && q.Get("uploadId") == "u1":
+			ranges = append(ranges, r.Header.Get("X-Amz-Copy-Source-Range"))
+			fmt.Fprintf(w, `<CopyPartResult><ETag>"e%s"</ETag></CopyPartResult>`, q.Get("partNumber"))
+		case r.Method == http.MethodPost && q.Get("uploadId") == "u1":
+			var body struct {
+				Parts []struct{ ETag string } `xml:"Part"`
+			}
+			_ = xml.NewDecoder(r.Body).Decode(&body)
+			parts = len(body.Parts)
+			fmt.Fprint(w, `<CompleteMultipartUploadResult><ETag>"e"</ETag></CompleteMultipartUploadResult>`)
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+	sess, err := session.NewSession(&aws.Config{
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String(ts.URL),
+		S3ForcePathStyle: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &S3{Addition: Addition{Bucket: "b"}, client: s3.New(sess)}
+	size := int64(maxCopySize + copyPartSize + 1)
+	modified := time.Unix(1577934245, 0)
+	err = d.copyObject(context.Background(), "k", "k", size, aws.String("text/plain"), setMetaModTime(nil, modified))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 12 || ranges[0] != fmt.Sprintf("bytes=0-%d", copyPartSize-1) ||
+		ranges[11] != fmt.Sprintf("bytes=%d-%d", 11*copyPartSize, size-1) {
+		t.Errorf("unexpected ranges: %v", ranges)
+	}
+	if parts != len(ranges) {
+		t.Errorf("expect %d parts completed, got %d", len(ranges), parts)
+	}
+	if t2, ok := metaModTime(map[string]*string{metaMtime: &metadata}); !ok || !t2.Equal(modified) {
+		t.Errorf("unexpected metadata: %q", metadata)
+	}
+}
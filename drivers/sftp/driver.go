@@ -5,6 +5,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
@@ -123,6 +124,13 @@ func (d *SFTP) Put(ctx context.Context, dstDir model.Obj, stream model.FileStrea
 	return err
 }
 
+func (d *SFTP) SetModTime(ctx context.Context, obj model.Obj, modified, created time.Time) error {
+	if err := d.clientReconnectOnConnectionError(); err != nil {
+		return err
+	}
+	return d.client.Chtimes(obj.GetPath(), modified, modified)
+}
+
 func (d *SFTP) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
 	stat, err := d.client.StatVFS(d.RootFolderPath)
 	if err != nil {
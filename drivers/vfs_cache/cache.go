@@ -0,0 +1,171 @@
+package vfs_cache
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// chunk is a cached part of a remote file, saved as a file in the cache dir.
+type chunk struct {
+	name    string
+	path    string // the remote path, for the invalidation
+	size    int64
+	expires time.Time
+}
+
+// chunkCache indexes the chunks in memory, the cache dir is emptied on init
+// since the chunks left by the last run are unknown. The dir is a sub dir
+// owned by the storage, nothing else in the configured dir is touched.
+type chunkCache struct {
+	dir     string
+	maxSize int64
+	ttl     time.Duration
+
+	mu     sync.Mutex
+	lru    *list.List // the most recently used first
+	chunks map[string]*list.Element
+	size   int64
+	// the last version of the files seen, by the remote paths
+	versions map[string]string
+}
+
+func newChunkCache(dir string, maxSize int64, ttl time.Duration) (*chunkCache, error) {
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return nil, err
+	}
+	return &chunkCache{
+		dir:      dir,
+		maxSize:  maxSize,
+		ttl:      ttl,
+		lru:      list.New(),
+		chunks:   make(map[string]*list.Element),
+		versions: make(map[string]string),
+	}, nil
+}
+
+// fileVersion tells the version of the file by its size, time and hashes.
+func fileVersion(size int64, modified time.Time, hash string) string {
+	return fmt.Sprintf("%d\x00%d\x00%s", size, modified.UnixNano(), hash)
+}
+
+// chunkName names the chunk by the version of the file, so that a file
+// changed out of the driver is never served from the stale chunks.
+func chunkName(path, version string, index int64) string {
+	sum := sha1.Sum([]byte(path + "\x00" + version))
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), index)
+}
+
+func (c *chunkCache) file(name string) string {
+	return filepath.Join(c.dir, name)
+}
+
+// get returns the file of the chunk if it is cached.
+func (c *chunkCache) get(name string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.chunks[name]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(e.Value.(*chunk).expires) {
+		c.remove(e)
+		return "", false
+	}
+	c.lru.MoveToFront(e)
+	return c.file(name), true
+}
+
+// add indexes the chunk written to its file, and removes the expired and
+// the least recently used chunks beyond the max size.
+func (c *chunkCache) add(name, path string, size int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.chunks[name]; ok {
+		c.remove(e)
+	}
+	c.chunks[name] = c.lru.PushFront(&chunk{
+		name:    name,
+		path:    path,
+		size:    size,
+		expires: time.Now().Add(c.ttl),
+	})
+	c.size += size
+	now := time.Now()
+	for e := c.lru.Back(); e != nil; {
+		prev := e.Prev()
+		if now.After(e.Value.(*chunk).expires) {
+			c.remove(e)
+		}
+		e = prev
+	}
+	for c.size > c.maxSize && c.lru.Len() > 1 {
+		c.remove(c.lru.Back())
+	}
+}
+
+// checkVersion removes the chunks of the former version of the file, which
+// is changed out of the driver.
+func (c *chunkCache) checkVersion(path, version string) {
+	c.mu.Lock()
+	last, ok := c.versions[path]
+	c.mu.Unlock()
+	if ok && last != version {
+		c.invalidate(path)
+	}
+	c.mu.Lock()
+	c.versions[path] = version
+	c.mu.Unlock()
+}
+
+// invalidate removes the chunks of the path and the files under it.
+func (c *chunkCache) invalidate(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	under := func(p string) bool {
+		return p == path || strings.HasPrefix(p, strings.TrimSuffix(path, "/")+"/")
+	}
+	for e := c.lru.Front(); e != nil; {
+		next := e.Next()
+		if under(e.Value.(*chunk).path) {
+			c.remove(e)
+		}
+		e = next
+	}
+	for p := range c.versions {
+		if under(p) {
+			delete(c.versions, p)
+		}
+	}
+}
+
+func (c *chunkCache) remove(e *list.Element) {
+	ch := c.lru.Remove(e).(*chunk)
+	delete(c.chunks, ch.name)
+	c.size -= ch.size
+	// the readers having the file opened are not affected
+	if err := os.Remove(c.file(ch.name)); err != nil && !os.IsNotExist(err) {
+		log.Warnf("failed remove cached chunk %s: %v", ch.name, err)
+	}
+}
+
+func (c *chunkCache) clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Init()
+	c.chunks = make(map[string]*list.Element)
+	c.versions = make(map[string]string)
+	c.size = 0
+	return os.RemoveAll(c.dir)
+}
@@ -0,0 +1,95 @@
+package vfs_cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+func addChunk(t *testing.T, c *chunkCache, name, path string, size int64) {
+	if err := os.WriteFile(c.file(name), make([]byte, size), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	c.add(name, path, size)
+}
+
+func TestCacheDirOwned(t *testing.T) {
+	dir := t.TempDir()
+	other := filepath.Join(dir, "other.txt")
+	if err := os.WriteFile(other, []byte("keep"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	d := &VfsCache{
+		Storage:  model.Storage{ID: 7},
+		Addition: Addition{CacheDir: dir, ChunkSize: 1, MaxSize: 1, TTL: 1},
+	}
+	for i := 0; i < 2; i++ {
+		if err := d.Init(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d.cache.dir != filepath.Join(dir, "7") {
+		t.Errorf("unexpected cache dir: %s", d.cache.dir)
+	}
+	addChunk(t, d.cache, "a-0", "/a", 1)
+	if err := d.Drop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("the file in the configured dir should be kept: %v", err)
+	}
+	if _, err := os.Stat(d.cache.dir); !os.IsNotExist(err) {
+		t.Errorf("the cache dir should be removed: %v", err)
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	c, err := newChunkCache(t.TempDir(), 1024, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1 := fileVersion(1, time.Unix(1, 0), "")
+	v2 := fileVersion(1, time.Unix(2, 0), "")
+	c.checkVersion("/a", v1)
+	name := chunkName("/a", v1, 0)
+	addChunk(t, c, name, "/a", 1)
+	addChunk(t, c, chunkName("/b", v1, 0), "/b", 1)
+	c.checkVersion("/a", v1)
+	if _, ok := c.get(name); !ok {
+		t.Fatal("the chunk of the same version should be kept")
+	}
+	c.checkVersion("/a", v2)
+	if _, ok := c.get(name); ok {
+		t.Error("the chunk of the former version should be removed")
+	}
+	if _, err = os.Stat(c.file(name)); !os.IsNotExist(err) {
+		t.Errorf("the file of the former version should be removed: %v", err)
+	}
+	if _, ok := c.get(chunkName("/b", v1, 0)); !ok {
+		t.Error("the chunk of the other file should be kept")
+	}
+}
+
+func TestEvict(t *testing.T) {
+	c, err := newChunkCache(t.TempDir(), 2, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addChunk(t, c, "a", "/a", 1)
+	addChunk(t, c, "b", "/b", 1)
+	// a is used after b, so b is the least recently used
+	c.get("a")
+	addChunk(t, c, "c", "/c", 1)
+	if _, ok := c.get("b"); ok {
+		t.Error("the least recently used chunk should be removed")
+	}
+	for _, name := range []string{"a", "c"} {
+		if _, ok := c.get(name); !ok {
+			t.Errorf("chunk %s should be kept", name)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package vfs_cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	stdpath "path"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+type VfsCache struct {
+	model.Storage
+	Addition
+	cache  *chunkCache
+	fetchG singleflight.Group[string]
+
+	// the prefetches running, by the versions of the files
+	prefetching sync.Map
+	prefetchSem chan struct{}
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+// the files prefetched at the same time at most, the others are not prefetched
+const maxPrefetches = 2
+
+func (d *VfsCache) Config() driver.Config {
+	return config
+}
+
+func (d *VfsCache) GetAddition() driver.Additional {
+	return &d.Addition
+}
+
+func (d *VfsCache) Init(ctx context.Context) error {
+	if d.ChunkSize <= 0 || d.MaxSize <= 0 || d.TTL <= 0 {
+		return errors.New("chunk size, max size and ttl must be positive")
+	}
+	d.RemotePath = utils.FixAndCleanPath(d.RemotePath)
+	dir := d.CacheDir
+	if dir == "" {
+		dir = filepath.Join(flags.DataDir, "vfs_cache")
+	}
+	// the configured dir may be an existing one, only the sub dir is emptied
+	dir = filepath.Join(dir, strconv.Itoa(int(d.ID)))
+	if err := d.Drop(ctx); err != nil {
+		return err
+	}
+	cache, err := newChunkCache(dir, d.MaxSize*utils.MB, time.Duration(d.TTL)*time.Minute)
+	if err != nil {
+		return err
+	}
+	d.cache = cache
+	d.prefetchSem = make(chan struct{}, maxPrefetches)
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	return nil
+}
+
+func (d *VfsCache) Drop(ctx context.Context) error {
+	if d.cancel != nil {
+		d.cancel()
+	}
+	if d.cache != nil {
+		return d.cache.clear()
+	}
+	return nil
+}
+
+func (Addition) GetRootPath() string {
+	return "/"
+}
+
+func (d *VfsCache) remote(path string) (driver.Driver, string, error) {
+	return op.GetStorageAndActualPath(stdpath.Join(d.RemotePath, path))
+}
+
+func (d *VfsCache) Get(ctx context.Context, path string) (model.Obj, error) {
+	remoteStorage, remoteActualPath, err := d.remote(path)
+	if err != nil {
+		return nil, err
+	}
+	remoteObj, err := op.Get(ctx, remoteStorage, remoteActualPath)
+	if err != nil {
+		return nil, err
+	}
+	return wrapObj(path, remoteObj), nil
+}
+
+func (d *VfsCache) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+	remoteStorage, remoteActualPath, err := d.remote(dir.GetPath())
+	if err != nil {
+		return nil, err
+	}
+	remoteObjs, err := op.List(ctx, remoteStorage, remoteActualPath, model.ListArgs{
+		ReqPath: args.ReqPath,
+		Refresh: args.Refresh,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return utils.SliceConvert(remoteObjs, func(obj model.Obj) (model.Obj, error) {
+		return wrapObj(stdpath.Join(dir.GetPath(), obj.GetName()), obj), nil
+	})
+}
+
+func wrapObj(path string, obj model.Obj) model.Obj {
+	return &model.Object{
+		Path:     path,
+		Name:     obj.GetName(),
+		Size:     obj.GetSize(),
+		Modified: obj.ModTime(),
+		Ctime:    obj.CreateTime(),
+		IsFolder: obj.IsDir(),
+		HashInfo: obj.GetHash(),
+	}
+}
+
+func (d *VfsCache) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+	// the file may be changed on the remote storage out of the driver, so the
+	// version is taken from the remote storage rather than the listing
+	remoteStorage, remoteActualPath, err := d.remote(file.GetPath())
+	if err != nil {
+		return nil, err
+	}
+	remoteObj, err := op.Get(ctx, remoteStorage, remoteActualPath)
+	if err != nil {
+		return nil, err
+	}
+	f := &cachedFile{
+		d:       d,
+		path:    file.GetPath(),
+		size:    remoteObj.GetSize(),
+		version: fileVersion(remoteObj.GetSize(), remoteObj.ModTime(), remoteObj.GetHash().String()),
+	}
+	d.cache.checkVersion(f.path, f.version)
+	if d.Prefetch && f.size <= d.PrefetchMaxSize*utils.MB {
+		d.prefetch(f)
+	}
+	return &model.Link{
+		RangeReader: stream.RangeReaderFunc(func(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+			return f.rangeRead(ctx, httpRange)
+		}),
+	}, nil
+}
+
+// prefetch starts the prefetch of the file in the background, unless it is
+// being prefetched or too many files are.
+func (d *VfsCache) prefetch(f *cachedFile) {
+	key := chunkName(f.path, f.version, -1)
+	if _, running := d.prefetching.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+	select {
+	case d.prefetchSem <- struct{}{}:
+	default:
+		d.prefetching.Delete(key)
+		return
+	}
+	go func() {
+		defer func() {
+			<-d.prefetchSem
+			d.prefetching.Delete(key)
+		}()
+		f.prefetch(d.ctx)
+	}()
+}
+
+func (d *VfsCache) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) error {
+	remoteStorage, remoteActualPath, err := d.remote(parentDir.GetPath())
+	if err != nil {
+		return err
+	}
+	return op.MakeDir(ctx, remoteStorage, stdpath.Join(remoteActualPath, dirName))
+}
+
+func (d *VfsCache) Move(ctx context.Context, srcObj, dstDir model.Obj) error {
+	remoteStorage, remoteActualPath, err := d.remote(srcObj.GetPath())
+	if err != nil {
+		return err
+	}
+	dstStorage, dstActualPath, err := d.remote(dstDir.GetPath())
+	if err != nil {
+		return err
+	}
+	if remoteStorage != dstStorage {
+		return errs.NotImplement
+	}
+	defer d.cache.invalidate(srcObj.GetPath())
+	return op.Move(ctx, remoteStorage, remoteActualPath, dstActualPath)
+}
+
+func (d *VfsCache) Rename(ctx context.Context, srcObj model.Obj, newName string) error {
+	remoteStorage, remoteActualPath, err := d.remote(srcObj.GetPath())
+	if err != nil {
+		return err
+	}
+	defer d.cache.invalidate(srcObj.GetPath())
+	return op.Rename(ctx, remoteStorage, remoteActualPath, newName)
+}
+
+func (d *VfsCache) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	remoteStorage, remoteActualPath, err := d.remote(srcObj.GetPath())
+	if err != nil {
+		return err
+	}
+	dstStorage, dstActualPath, err := d.remote(dstDir.GetPath())
+	if err != nil {
+		return err
+	}
+	if remoteStorage != dstStorage {
+		return errs.NotImplement
+	}
+	defer d.cache.invalidate(stdpath.Join(dstDir.GetPath(), srcObj.GetName()))
+	return op.Copy(ctx, remoteStorage, remoteActualPath, dstActualPath)
+}
+
+func (d *VfsCache) Remove(ctx context.Context, obj model.Obj) error {
+	remoteStorage, remoteActualPath, err := d.remote(obj.GetPath())
+	if err != nil {
+		return err
+	}
+	defer d.cache.invalidate(obj.GetPath())
+	return op.Remove(ctx, remoteStorage, remoteActualPath)
+}
+
+func (d *VfsCache) Put(ctx context.Context, dstDir model.Obj, file model.FileStreamer, up driver.UpdateProgress) error {
+	remoteStorage, remoteActualPath, err := d.remote(dstDir.GetPath())
+	if err != nil {
+		return err
+	}
+	defer d.cache.invalidate(stdpath.Join(dstDir.GetPath(), file.GetName()))
+	return op.Put(ctx, remoteStorage, remoteActualPath, &stream.FileStream{
+		Obj:      file,
+		Mimetype: file.GetMimetype(),
+		Reader:   file,
+	}, up)
+}
+
+func (d *VfsCache) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
+	remoteStorage, _, err := d.remote("/")
+	if err != nil {
+		return nil, errs.NotImplement
+	}
+	return op.GetStorageDetails(ctx, remoteStorage)
+}
+
+var _ driver.Driver = (*VfsCache)(nil)
@@ -0,0 +1,39 @@
+package vfs_cache
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+type Addition struct {
+	RemotePath      string `json:"remote_path" required:"true" help:"the path of the storage to cache"`
+	CacheDir        string `json:"cache_dir" help:"the local dir keeping the cached chunks in the sub dir named by the storage id, vfs_cache in the data dir if empty"`
+	ChunkSize       int64  `json:"chunk_size" required:"true" type:"number" default:"4" help:"MB, the files are cached by chunks of the size"`
+	MaxSize         int64  `json:"max_size" required:"true" type:"number" default:"1024" help:"MB, the least recently used chunks are removed beyond the size"`
+	TTL             int    `json:"ttl" required:"true" type:"number" default:"1440" help:"minutes, the chunks are removed after the time"`
+	Prefetch        bool   `json:"prefetch" default:"false" help:"cache the whole file in the background on first access, 2 files at most at the same time"`
+	PrefetchMaxSize int64  `json:"prefetch_max_size" type:"number" default:"256" help:"MB, the larger files are not prefetched"`
+}
+
+var config = driver.Config{
+	Name:        "VfsCache",
+	LocalSort:   true,
+	OnlyProxy:   true,
+	NoCache:     true,
+	DefaultRoot: "/",
+	NoLinkURL:   true,
+	CheckStatus: true,
+}
+
+func init() {
+	op.RegisterDriver(func() driver.Driver {
+		return &VfsCache{
+			Addition: Addition{
+				ChunkSize:       4,
+				MaxSize:         1024,
+				TTL:             1440,
+				PrefetchMaxSize: 256,
+			},
+		}
+	})
+}
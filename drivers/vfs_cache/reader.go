@@ -0,0 +1,193 @@
+package vfs_cache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// cachedFile reads a version of the remote file by the cached chunks.
+type cachedFile struct {
+	d       *VfsCache
+	path    string
+	size    int64
+	version string
+}
+
+func (f *cachedFile) chunkSize() int64 {
+	return f.d.ChunkSize * utils.MB
+}
+
+func (f *cachedFile) chunks() int64 {
+	return (f.size + f.chunkSize() - 1) / f.chunkSize()
+}
+
+// chunk returns the file of the chunk, which is fetched from the remote
+// storage if not cached. The concurrent fetches of a chunk are merged, and
+// the merged fetch is not cancelled with the caller which starts it, the
+// others may still be waiting for it.
+func (f *cachedFile) chunk(ctx context.Context, index int64) (string, error) {
+	name := chunkName(f.path, f.version, index)
+	if file, ok := f.d.cache.get(name); ok {
+		return file, nil
+	}
+	fetchCtx := context.WithoutCancel(ctx)
+	ch := f.d.fetchG.DoChan(name, func() (string, error) {
+		if file, ok := f.d.cache.get(name); ok {
+			return file, nil
+		}
+		return f.fetch(fetchCtx, name, index)
+	})
+	select {
+	case res := <-ch:
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (f *cachedFile) fetch(ctx context.Context, name string, index int64) (string, error) {
+	remoteStorage, remoteActualPath, err := f.d.remote(f.path)
+	if err != nil {
+		return "", err
+	}
+	link, _, err := op.Link(ctx, remoteStorage, remoteActualPath, model.LinkArgs{})
+	if err != nil {
+		return "", err
+	}
+	defer link.Close()
+	rr, err := stream.GetRangeReaderFromLink(f.size, link)
+	if err != nil {
+		return "", err
+	}
+	start := index * f.chunkSize()
+	length := min(f.chunkSize(), f.size-start)
+	rc, err := rr.RangeRead(ctx, http_range.Range{Start: start, Length: length})
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	// written aside and renamed, so that a broken chunk is never indexed
+	file := f.d.cache.file(name)
+	tmp, err := os.CreateTemp(f.d.cache.dir, name+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	n, err := utils.CopyWithBuffer(tmp, io.LimitReader(rc, length))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n != length {
+		err = fmt.Errorf("chunk %d of %s is incomplete: %d of %d bytes", index, f.path, n, length)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	f.d.cache.add(name, f.path, length)
+	return file, nil
+}
+
+func (f *cachedFile) rangeRead(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+	if httpRange.Length < 0 || httpRange.Start+httpRange.Length > f.size {
+		httpRange.Length = f.size - httpRange.Start
+	}
+	return &chunkReader{
+		ctx: ctx,
+		f:   f,
+		off: httpRange.Start,
+		end: httpRange.Start + httpRange.Length,
+	}, nil
+}
+
+// prefetch caches all chunks of the file, until the storage is dropped.
+func (f *cachedFile) prefetch(ctx context.Context) {
+	for i := int64(0); i < f.chunks(); i++ {
+		if _, err := f.chunk(ctx, i); err != nil {
+			log.Warnf("failed prefetch %s: %v", f.path, err)
+			return
+		}
+	}
+}
+
+// chunkReader reads the range chunk by chunk, a chunk is fetched only when
+// the reading reaches it.
+type chunkReader struct {
+	ctx  context.Context
+	f    *cachedFile
+	off  int64
+	end  int64
+	cur  io.Reader
+	file *os.File
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.off >= r.end {
+			return 0, io.EOF
+		}
+		if r.cur == nil {
+			if err := r.open(); err != nil {
+				return 0, err
+			}
+		}
+		n, err := r.cur.Read(p)
+		r.off += int64(n)
+		if err == io.EOF {
+			r.closeChunk()
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) open() error {
+	index := r.off / r.f.chunkSize()
+	var file *os.File
+	// the chunk may be removed by the others before opened, then fetched again
+	for retry := 0; file == nil; retry++ {
+		name, err := r.f.chunk(r.ctx, index)
+		if err != nil {
+			return err
+		}
+		file, err = os.Open(name)
+		if err != nil && (!os.IsNotExist(err) || retry > 0) {
+			return err
+		}
+	}
+	start := index * r.f.chunkSize()
+	if _, err := file.Seek(r.off-start, io.SeekStart); err != nil {
+		_ = file.Close()
+		return err
+	}
+	chunkEnd := min(start+r.f.chunkSize(), r.end)
+	r.file = file
+	r.cur = io.LimitReader(file, chunkEnd-r.off)
+	return nil
+}
+
+func (r *chunkReader) closeChunk() {
+	if r.file != nil {
+		_ = r.file.Close()
+	}
+	r.file, r.cur = nil, nil
+}
+
+func (r *chunkReader) Close() error {
+	r.closeChunk()
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"strconv"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/drivers/base"
@@ -116,6 +117,10 @@ func (d *WebDav) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer
 	callback := func(r *http.Request) {
 		r.Header.Set("Content-Type", s.GetMimetype())
 		r.ContentLength = s.GetSize()
+		if !s.ModTime().IsZero() {
+			// honored by ownCloud, Nextcloud and OpenList
+			r.Header.Set("X-OC-Mtime", strconv.FormatInt(s.ModTime().Unix(), 10))
+		}
 	}
 	reader := driver.NewLimitedUploadStream(ctx, &driver.ReaderUpdatingProgress{
 		Reader:         s,
@@ -125,4 +130,8 @@ func (d *WebDav) Put(ctx context.Context, dstDir model.Obj, s model.FileStreamer
 	return err
 }
 
+func (d *WebDav) SetModTime(ctx context.Context, obj model.Obj, modified, created time.Time) error {
+	return d.client.SetModTime(obj.GetPath(), modified)
+}
+
 var _ driver.Driver = (*WebDav)(nil)
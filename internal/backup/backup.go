@@ -0,0 +1,198 @@
+package backup
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// Version is the version of the archive format, an archive of a newer
+// version is refused to import.
+const Version = 1
+
+// the sections of an archive
+const (
+	Storages = "storages"
+	Users    = "users"
+	Metas    = "metas"
+	Settings = "settings"
+	Sharings = "sharings"
+	SSHKeys  = "ssh_keys"
+	Tasks    = "tasks"
+)
+
+// DefaultSections are exported and imported if no section is given, the
+// persisted tasks are only exported on request.
+var DefaultSections = []string{Storages, Users, Metas, Settings, Sharings, SSHKeys}
+
+var allSections = []string{Storages, Users, Metas, Settings, Sharings, SSHKeys, Tasks}
+
+type Archive struct {
+	Version    int         `json:"version"`
+	Created    time.Time   `json:"created"`
+	Encryption *Encryption `json:"encryption,omitempty"`
+
+	Storages []model.Storage     `json:"storages,omitempty"`
+	Users    []User              `json:"users,omitempty"`
+	Metas    []model.Meta        `json:"metas,omitempty"`
+	Settings []model.SettingItem `json:"settings,omitempty"`
+	Sharings []Sharing           `json:"sharings,omitempty"`
+	SSHKeys  []SSHKey            `json:"ssh_keys,omitempty"`
+	Tasks    []model.TaskItem    `json:"tasks,omitempty"`
+}
+
+// User is a user with the credentials hidden from the json of model.User
+type User struct {
+	model.User
+	PwdHash   string `json:"pwd_hash"`
+	PwdTS     int64  `json:"pwd_ts"`
+	Salt      string `json:"salt"`
+	OtpSecret string `json:"otp_secret"`
+	Authn     string `json:"authn"`
+}
+
+// Sharing refers to its creator by the username, since the ids of the users
+// are changed by the import.
+type Sharing struct {
+	model.SharingDB
+	FilesRaw string `json:"files_raw"`
+	Creator  string `json:"creator"`
+}
+
+type SSHKey struct {
+	model.SSHPublicKey
+	KeyStr   string `json:"key_str"`
+	Username string `json:"username"`
+}
+
+type ExportOptions struct {
+	Sections []string `json:"sections"`
+	// the secrets are encrypted by the password if it is given
+	Password string `json:"password"`
+}
+
+func checkSections(sections []string, def []string) ([]string, error) {
+	if len(sections) == 0 {
+		return def, nil
+	}
+	for _, s := range sections {
+		if !utils.SliceContains(allSections, s) {
+			return nil, errors.Errorf("unknown section: %s", s)
+		}
+	}
+	return sections, nil
+}
+
+// all gets all the items by the paged getter
+func all[T any](get func(pageIndex, pageSize int) ([]T, int64, error)) ([]T, error) {
+	const pageSize = 500
+	var res []T
+	for page := 1; ; page++ {
+		items, total, err := get(page, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, items...)
+		if len(items) < pageSize || int64(len(res)) >= total {
+			return res, nil
+		}
+	}
+}
+
+// Export dumps the sections into an archive.
+func Export(opts ExportOptions) (*Archive, error) {
+	sections, err := checkSections(opts.Sections, DefaultSections)
+	if err != nil {
+		return nil, err
+	}
+	a := &Archive{Version: Version, Created: time.Now()}
+	var c *secretCipher
+	if opts.Password != "" {
+		if a.Encryption, c, err = newEncryption(opts.Password); err != nil {
+			return nil, err
+		}
+	}
+	users, err := all(db.GetUsers)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get users")
+	}
+	usernames := make(map[uint]string, len(users))
+	for _, u := range users {
+		usernames[u.ID] = u.Username
+	}
+	for _, section := range sections {
+		switch section {
+		case Storages:
+			if a.Storages, err = all(db.GetStorages); err != nil {
+				return nil, errors.WithMessage(err, "failed get storages")
+			}
+			for i := range a.Storages {
+				a.Storages[i].Addition = c.encrypt(a.Storages[i].Addition)
+			}
+		case Users:
+			for _, u := range users {
+				a.Users = append(a.Users, User{
+					User:      u,
+					PwdHash:   c.encrypt(u.PwdHash),
+					PwdTS:     u.PwdTS,
+					Salt:      c.encrypt(u.Salt),
+					OtpSecret: c.encrypt(u.OtpSecret),
+					Authn:     c.encrypt(u.Authn),
+				})
+			}
+		case Metas:
+			if a.Metas, err = all(db.GetMetas); err != nil {
+				return nil, errors.WithMessage(err, "failed get metas")
+			}
+			for i := range a.Metas {
+				a.Metas[i].Password = c.encrypt(a.Metas[i].Password)
+			}
+		case Settings:
+			if a.Settings, err = db.GetSettingItems(); err != nil {
+				return nil, errors.WithMessage(err, "failed get settings")
+			}
+			for i := range a.Settings {
+				if a.Settings[i].Flag == model.PRIVATE {
+					a.Settings[i].Value = c.encrypt(a.Settings[i].Value)
+				}
+			}
+		case Sharings:
+			sharings, err := all(db.GetSharings)
+			if err != nil {
+				return nil, errors.WithMessage(err, "failed get sharings")
+			}
+			for _, s := range sharings {
+				s.Pwd = c.encrypt(s.Pwd)
+				a.Sharings = append(a.Sharings, Sharing{
+					SharingDB: s,
+					FilesRaw:  s.FilesRaw,
+					Creator:   usernames[s.CreatorId],
+				})
+			}
+		case SSHKeys:
+			keys, err := all(db.GetSSHPublicKeys)
+			if err != nil {
+				return nil, errors.WithMessage(err, "failed get ssh keys")
+			}
+			for _, k := range keys {
+				a.SSHKeys = append(a.SSHKeys, SSHKey{
+					SSHPublicKey: k,
+					KeyStr:       k.KeyStr,
+					Username:     usernames[k.UserId],
+				})
+			}
+		case Tasks:
+			if a.Tasks, err = db.GetTaskItems(); err != nil {
+				return nil, errors.WithMessage(err, "failed get tasks")
+			}
+			// the tasks keep the credentials of their sources and creators
+			for i := range a.Tasks {
+				a.Tasks[i].PersistData = c.encrypt(a.Tasks[i].PersistData)
+			}
+		}
+	}
+	return a, nil
+}
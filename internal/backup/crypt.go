@@ -0,0 +1,110 @@
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	encryptedPrefix = "enc:"
+	// encrypted to check the password before the secrets are decrypted
+	encryptionCheck = "openlist"
+)
+
+// Encryption describes how the secrets in the archive are encrypted: the
+// storage additions, the credentials of the users, the passwords of the
+// metas and sharings, the private settings, and the persisted tasks.
+type Encryption struct {
+	Salt  string `json:"salt"`
+	Check string `json:"check"`
+}
+
+// secretCipher encrypts the secrets by AES-GCM with the key derived from the
+// password, a nil secretCipher keeps the secrets as they are.
+type secretCipher struct {
+	aead cipher.AEAD
+}
+
+func newSecretCipher(password string, salt []byte) (*secretCipher, error) {
+	key, err := scrypt.Key([]byte(password), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &secretCipher{aead: aead}, nil
+}
+
+func newEncryption(password string) (*Encryption, *secretCipher, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	c, err := newSecretCipher(password, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Encryption{
+		Salt:  base64.StdEncoding.EncodeToString(salt),
+		Check: c.encrypt(encryptionCheck),
+	}, c, nil
+}
+
+// cipher returns the cipher to decrypt the secrets of the archive.
+func (e *Encryption) cipher(password string) (*secretCipher, error) {
+	if password == "" {
+		return nil, errors.New("the archive is encrypted, password is required")
+	}
+	salt, err := base64.StdEncoding.DecodeString(e.Salt)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid encryption salt")
+	}
+	c, err := newSecretCipher(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	if check, err := c.decrypt(e.Check); err != nil || check != encryptionCheck {
+		return nil, errors.New("wrong password of the archive")
+	}
+	return c, nil
+}
+
+func (c *secretCipher) encrypt(s string) string {
+	if c == nil || s == "" {
+		return s
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	_, _ = rand.Read(nonce)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(c.aead.Seal(nonce, nonce, []byte(s), nil))
+}
+
+func (c *secretCipher) decrypt(s string) (string, error) {
+	if c == nil || !strings.HasPrefix(s, encryptedPrefix) {
+		return s, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, encryptedPrefix))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	size := c.aead.NonceSize()
+	if len(data) < size {
+		return "", errors.New("invalid encrypted secret")
+	}
+	plain, err := c.aead.Open(nil, data[:size], data[size:], nil)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,28 @@
+package backup
+
+import "testing"
+
+func TestEncryption(t *testing.T) {
+	e, c, err := newEncryption("pwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := c.encrypt(`{"refresh_token":"abc"}`)
+	if secret == `{"refresh_token":"abc"}` || c.encrypt("") != "" {
+		t.Fatalf("unexpected encrypted secret: %s", secret)
+	}
+	if _, err = e.cipher("wrong"); err == nil {
+		t.Fatal("expect the wrong password refused")
+	}
+	c, err = e.cipher("pwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := c.decrypt(secret); err != nil || plain != `{"refresh_token":"abc"}` {
+		t.Errorf("unexpected decrypted secret: %s, %v", plain, err)
+	}
+	// the secrets of an archive not encrypted are kept
+	if plain, _ := (*secretCipher)(nil).decrypt("abc"); plain != "abc" {
+		t.Errorf("unexpected secret: %s", plain)
+	}
+}
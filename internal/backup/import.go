@@ -0,0 +1,496 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// the strategies for the items existing in the instance, an item is
+// identified by the mount path of the storage, the username of the user,
+// the path of the meta, the key of the setting or the task, the id of the
+// sharing, and the user and title of the ssh key. Renaming is not possible
+// for the metas, settings and tasks, and the admin and guest users, they
+// are skipped instead.
+const (
+	StrategySkip      = "skip"
+	StrategyOverwrite = "overwrite"
+	StrategyRename    = "rename"
+)
+
+type ImportOptions struct {
+	Sections []string `json:"sections"`
+	Strategy string   `json:"strategy"`
+	Password string   `json:"password"`
+	// Mount is set by the running server, which inits the imported storages
+	// at once and refuses the tasks, since they would be overwritten by its
+	// task managers. Otherwise the storages are only saved and mounted by
+	// the next start of the server.
+	Mount bool `json:"-"`
+}
+
+type SectionReport struct {
+	Created     int `json:"created"`
+	Overwritten int `json:"overwritten"`
+	Renamed     int `json:"renamed"`
+	Skipped     int `json:"skipped"`
+	Failed      int `json:"failed"`
+}
+
+type Report struct {
+	Sections map[string]*SectionReport `json:"sections"`
+	Warnings []string                  `json:"warnings"`
+}
+
+type importer struct {
+	ctx    context.Context
+	opts   ImportOptions
+	c      *secretCipher
+	report *Report
+	// the usernames in the archive to the ones they are imported as
+	usernames map[string]string
+}
+
+// Import restores the sections of the archive, an item failed to import is
+// reported as a warning instead of stopping the import.
+func Import(ctx context.Context, a *Archive, opts ImportOptions) (*Report, error) {
+	if a.Version > Version || a.Version <= 0 {
+		return nil, errors.Errorf("unsupported archive version: %d", a.Version)
+	}
+	sections, err := checkSections(opts.Sections, allSections)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Mount {
+		if utils.SliceContains(opts.Sections, Tasks) {
+			return nil, errors.New("the tasks can only be imported by the CLI while the server is stopped")
+		}
+		sections = utils.SliceFilter(sections, func(s string) bool { return s != Tasks })
+	}
+	switch opts.Strategy {
+	case "":
+		opts.Strategy = StrategySkip
+	case StrategySkip, StrategyOverwrite, StrategyRename:
+	default:
+		return nil, errors.Errorf("unknown strategy: %s", opts.Strategy)
+	}
+	im := &importer{
+		ctx:       ctx,
+		opts:      opts,
+		report:    &Report{Sections: make(map[string]*SectionReport), Warnings: []string{}},
+		usernames: make(map[string]string),
+	}
+	if a.Encryption != nil {
+		if im.c, err = a.Encryption.cipher(opts.Password); err != nil {
+			return nil, err
+		}
+	}
+	// the users first, which are referred by the sharings and ssh keys
+	steps := []struct {
+		section string
+		run     func(*Archive, *SectionReport)
+	}{
+		{Users, im.users},
+		{Storages, im.storages},
+		{Metas, im.metas},
+		{Settings, im.settings},
+		{Sharings, im.sharings},
+		{SSHKeys, im.sshKeys},
+		{Tasks, im.tasks},
+	}
+	for _, step := range steps {
+		if utils.SliceContains(sections, step.section) {
+			r := &SectionReport{}
+			im.report.Sections[step.section] = r
+			step.run(a, r)
+		}
+	}
+	return im.report, nil
+}
+
+func (im *importer) warn(r *SectionReport, format string, args ...any) {
+	r.Failed++
+	im.report.Warnings = append(im.report.Warnings, fmt.Sprintf(format, args...))
+}
+
+// decrypt decrypts the secrets in place
+func (im *importer) decrypt(secrets ...*string) error {
+	for _, s := range secrets {
+		plain, err := im.c.decrypt(*s)
+		if err != nil {
+			return errors.WithMessage(err, "failed decrypt secret")
+		}
+		*s = plain
+	}
+	return nil
+}
+
+// uniqueName returns the name with the smallest suffix not taken.
+func uniqueName(name string, taken func(string) bool) string {
+	for i := 1; ; i++ {
+		if n := fmt.Sprintf("%s_%d", name, i); !taken(n) {
+			return n
+		}
+	}
+}
+
+func (im *importer) users(a *Archive, r *SectionReport) {
+	for _, bu := range a.Users {
+		u := bu.User
+		u.PwdHash, u.PwdTS, u.Salt, u.OtpSecret, u.Authn = bu.PwdHash, bu.PwdTS, bu.Salt, bu.OtpSecret, bu.Authn
+		if err := im.decrypt(&u.PwdHash, &u.Salt, &u.OtpSecret, &u.Authn); err != nil {
+			im.warn(r, "user [%s]: %v", u.Username, err)
+			continue
+		}
+		name := u.Username
+		var existing *model.User
+		if u.IsAdmin() || u.IsGuest() {
+			// there is only one admin and guest, they are matched by the role
+			existing, _ = db.GetUserByRole(u.Role)
+		} else {
+			existing, _ = db.GetUserByName(u.Username)
+		}
+		if existing != nil {
+			switch {
+			case im.opts.Strategy == StrategyOverwrite:
+				u.ID = existing.ID
+				if err := op.UpdateUser(&u); err != nil {
+					im.warn(r, "user [%s]: %v", name, err)
+					continue
+				}
+				r.Overwritten++
+			case im.opts.Strategy == StrategyRename && !u.IsAdmin() && !u.IsGuest():
+				u.ID = 0
+				u.Username = uniqueName(u.Username, func(n string) bool {
+					_, err := db.GetUserByName(n)
+					return err == nil
+				})
+				if err := op.CreateUser(&u); err != nil {
+					im.warn(r, "user [%s]: %v", name, err)
+					continue
+				}
+				r.Renamed++
+			default:
+				u.Username = existing.Username
+				r.Skipped++
+			}
+			im.usernames[name] = u.Username
+			continue
+		}
+		if u.IsAdmin() || u.IsGuest() {
+			im.warn(r, "user [%s]: no user of the role to import as", name)
+			continue
+		}
+		u.ID = 0
+		if err := op.CreateUser(&u); err != nil {
+			im.warn(r, "user [%s]: %v", name, err)
+			continue
+		}
+		im.usernames[name] = u.Username
+		r.Created++
+	}
+}
+
+// user returns the user the username in the archive refers to.
+func (im *importer) user(username string) (*model.User, error) {
+	if n, ok := im.usernames[username]; ok {
+		username = n
+	}
+	u, err := db.GetUserByName(username)
+	if err != nil {
+		return nil, errors.Errorf("user [%s] not found", username)
+	}
+	return u, nil
+}
+
+func (im *importer) userID(username string) (uint, error) {
+	u, err := im.user(username)
+	if err != nil {
+		return 0, err
+	}
+	return u.ID, nil
+}
+
+func (im *importer) storages(a *Archive, r *SectionReport) {
+	for _, s := range a.Storages {
+		mountPath := s.MountPath
+		if _, err := op.GetDriver(s.Driver); err != nil {
+			im.warn(r, "storage [%s]: %v", mountPath, err)
+			continue
+		}
+		if err := im.decrypt(&s.Addition); err != nil {
+			im.warn(r, "storage [%s]: %v", mountPath, err)
+			continue
+		}
+		existing, _ := db.GetStorageByMountPath(s.MountPath)
+		if existing == nil {
+			if im.createStorage(s, r) {
+				r.Created++
+			}
+			continue
+		}
+		switch im.opts.Strategy {
+		case StrategyOverwrite:
+			s.ID = existing.ID
+			if err := im.overwriteStorage(existing, s); err != nil {
+				im.warn(r, "storage [%s]: %v", mountPath, err)
+				continue
+			}
+			r.Overwritten++
+		case StrategyRename:
+			s.MountPath = uniqueName(utils.FixAndCleanPath(s.MountPath), func(n string) bool {
+				_, err := db.GetStorageByMountPath(n)
+				return err == nil
+			})
+			if im.createStorage(s, r) {
+				r.Renamed++
+			}
+		default:
+			r.Skipped++
+		}
+	}
+}
+
+func (im *importer) overwriteStorage(existing *model.Storage, s model.Storage) error {
+	if im.opts.Mount && !existing.Disabled && op.HasStorage(existing.MountPath) {
+		if !s.Disabled {
+			return op.UpdateStorage(im.ctx, s)
+		}
+		if err := op.DisableStorage(im.ctx, existing.ID); err != nil {
+			return err
+		}
+	}
+	if err := db.UpdateStorage(&s); err != nil {
+		return err
+	}
+	if im.opts.Mount && !s.Disabled {
+		return op.LoadStorage(im.ctx, s)
+	}
+	return nil
+}
+
+func (im *importer) createStorage(s model.Storage, r *SectionReport) bool {
+	s.ID = 0
+	if !im.opts.Mount || s.Disabled {
+		s.MountPath = utils.FixAndCleanPath(s.MountPath)
+		if err := db.CreateStorage(&s); err != nil {
+			im.warn(r, "storage [%s]: %v", s.MountPath, err)
+			return false
+		}
+		return true
+	}
+	id, err := op.CreateStorage(im.ctx, s)
+	if err != nil {
+		if id == 0 {
+			im.warn(r, "storage [%s]: %v", s.MountPath, err)
+			return false
+		}
+		// created but failed to init, which is left to the admin
+		im.report.Warnings = append(im.report.Warnings, fmt.Sprintf("storage [%s]: %v", s.MountPath, err))
+	}
+	return true
+}
+
+func (im *importer) metas(a *Archive, r *SectionReport) {
+	for _, m := range a.Metas {
+		if err := im.decrypt(&m.Password); err != nil {
+			im.warn(r, "meta [%s]: %v", m.Path, err)
+			continue
+		}
+		existing, _ := db.GetMetaByPath(m.Path)
+		var err error
+		switch {
+		case existing == nil:
+			m.ID = 0
+			if err = op.CreateMeta(&m); err == nil {
+				r.Created++
+			}
+		case im.opts.Strategy == StrategyOverwrite:
+			m.ID = existing.ID
+			if err = op.UpdateMeta(&m); err == nil {
+				r.Overwritten++
+			}
+		default:
+			r.Skipped++
+		}
+		if err != nil {
+			im.warn(r, "meta [%s]: %v", m.Path, err)
+		}
+	}
+}
+
+// settings only replaces the values, the types and options of the settings
+// are defined by the running version, and the unknown ones are skipped.
+func (im *importer) settings(a *Archive, r *SectionReport) {
+	var items []model.SettingItem
+	for _, item := range a.Settings {
+		existing, err := db.GetSettingItemByKey(item.Key)
+		if err != nil || existing.Flag == model.READONLY || im.opts.Strategy != StrategyOverwrite {
+			r.Skipped++
+			continue
+		}
+		if err := im.decrypt(&item.Value); err != nil {
+			im.warn(r, "setting [%s]: %v", item.Key, err)
+			continue
+		}
+		existing.Value = item.Value
+		items = append(items, *existing)
+	}
+	if len(items) == 0 {
+		return
+	}
+	if err := op.SaveSettingItems(items); err != nil {
+		im.warn(r, "settings: %v", err)
+		return
+	}
+	r.Overwritten += len(items)
+}
+
+func (im *importer) sharings(a *Archive, r *SectionReport) {
+	for _, bs := range a.Sharings {
+		s := bs.SharingDB
+		s.FilesRaw = bs.FilesRaw
+		if err := im.decrypt(&s.Pwd); err != nil {
+			im.warn(r, "sharing [%s]: %v", s.ID, err)
+			continue
+		}
+		id, err := im.userID(bs.Creator)
+		if err != nil {
+			im.warn(r, "sharing [%s]: %v", s.ID, err)
+			continue
+		}
+		s.CreatorId = id
+		existing, _ := db.GetSharingById(s.ID)
+		switch {
+		case existing == nil:
+			_, err = db.CreateSharing(&s)
+			if err == nil {
+				r.Created++
+			}
+		case im.opts.Strategy == StrategyOverwrite:
+			if err = op.UpdateSharing(&model.Sharing{SharingDB: &s}, true); err == nil {
+				r.Overwritten++
+			}
+		case im.opts.Strategy == StrategyRename:
+			// a new id is generated
+			s.ID = ""
+			if _, err = db.CreateSharing(&s); err == nil {
+				r.Renamed++
+			}
+		default:
+			r.Skipped++
+		}
+		if err != nil {
+			im.warn(r, "sharing [%s]: %v", bs.ID, err)
+		}
+	}
+}
+
+func (im *importer) sshKeys(a *Archive, r *SectionReport) {
+	for _, bk := range a.SSHKeys {
+		k := bk.SSHPublicKey
+		k.KeyStr = bk.KeyStr
+		id, err := im.userID(bk.Username)
+		if err != nil {
+			im.warn(r, "ssh key [%s]: %v", k.Title, err)
+			continue
+		}
+		k.UserId = id
+		existing, _ := db.GetSSHPublicKeyByUserTitle(k.UserId, k.Title)
+		switch {
+		case existing == nil:
+			k.ID = 0
+			if err = db.CreateSSHPublicKey(&k); err == nil {
+				r.Created++
+			}
+		case im.opts.Strategy == StrategyOverwrite:
+			k.ID = existing.ID
+			if err = db.UpdateSSHPublicKey(&k); err == nil {
+				r.Overwritten++
+			}
+		case im.opts.Strategy == StrategyRename:
+			k.ID = 0
+			k.Title = uniqueName(k.Title, func(n string) bool {
+				_, err := db.GetSSHPublicKeyByUserTitle(k.UserId, n)
+				return err == nil
+			})
+			if err = db.CreateSSHPublicKey(&k); err == nil {
+				r.Renamed++
+			}
+		default:
+			r.Skipped++
+		}
+		if err != nil {
+			im.warn(r, "ssh key [%s]: %v", bk.Title, err)
+		}
+	}
+}
+
+// tasks restores the persisted tasks, which are loaded by the next start of
+// the server.
+func (im *importer) tasks(a *Archive, r *SectionReport) {
+	for _, t := range a.Tasks {
+		err := im.decrypt(&t.PersistData)
+		if err == nil {
+			t.PersistData, err = im.taskCreators(t.Key, t.PersistData, r)
+		}
+		if err != nil {
+			im.warn(r, "task [%s]: %v", t.Key, err)
+			continue
+		}
+		existing, _ := db.GetTaskDataByType(t.Key)
+		switch {
+		case existing == nil:
+			if err = db.CreateTaskData(&t); err == nil {
+				r.Created++
+			}
+		case im.opts.Strategy == StrategyOverwrite:
+			if err = db.UpdateTaskData(&t); err == nil {
+				r.Overwritten++
+			}
+		default:
+			r.Skipped++
+		}
+		if err != nil {
+			im.warn(r, "task [%s]: %v", t.Key, err)
+		}
+	}
+}
+
+// taskCreators replaces the creators of the persisted tasks by the users they
+// are imported as, since the ids of the users are changed by the import. The
+// tasks whose creators are not found are dropped.
+func (im *importer) taskCreators(key, data string, r *SectionReport) (string, error) {
+	if data == "" {
+		return data, nil
+	}
+	var tasks []map[string]json.RawMessage
+	if err := utils.Json.UnmarshalFromString(data, &tasks); err != nil {
+		return "", errors.WithMessage(err, "invalid task data")
+	}
+	res := make([]map[string]json.RawMessage, 0, len(tasks))
+	for _, t := range tasks {
+		if raw, ok := t["Creator"]; ok && string(raw) != "null" {
+			var creator model.User
+			if err := utils.Json.Unmarshal(raw, &creator); err != nil {
+				return "", errors.WithMessage(err, "invalid task creator")
+			}
+			u, err := im.user(creator.Username)
+			if err != nil {
+				im.warn(r, "task [%s]: %v, the task is dropped", key, err)
+				continue
+			}
+			if t["Creator"], err = utils.Json.Marshal(u); err != nil {
+				return "", errors.WithStack(err)
+			}
+		}
+		res = append(res, t)
+	}
+	return utils.Json.MarshalToString(res)
+}
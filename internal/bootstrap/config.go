@@ -173,13 +173,34 @@ func initURL() {
 	conf.URL = u
 }
 
-func CleanTempDir() {
-	files, err := os.ReadDir(conf.Conf.TempDir)
+// CleanTempDir removes the files left in the temp dir by the last run,
+// except for the given ones which are still referenced by recovered tasks.
+func CleanTempDir(keep ...string) {
+	cleanTempDir(conf.Conf.TempDir, keep)
+}
+
+func cleanTempDir(dir string, keep []string) {
+	files, err := os.ReadDir(dir)
 	if err != nil {
 		log.Errorln("列出临时文件失败: ", err)
 	}
 	for _, file := range files {
-		if err := os.RemoveAll(filepath.Join(conf.Conf.TempDir, file.Name())); err != nil {
+		name := filepath.Join(dir, file.Name())
+		kept, inside := false, false
+		for _, k := range keep {
+			if rel, err := filepath.Rel(name, k); err == nil {
+				kept = kept || rel == "."
+				inside = inside || (rel != "." && filepath.IsLocal(rel))
+			}
+		}
+		if kept {
+			continue
+		}
+		if inside && file.IsDir() {
+			cleanTempDir(name, keep)
+			continue
+		}
+		if err := os.RemoveAll(name); err != nil {
 			log.Errorln("删除临时文件失败: ", err)
 		}
 	}
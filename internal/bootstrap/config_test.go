@@ -0,0 +1,42 @@
+package bootstrap
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCleanTempDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "tasks/1/x", "tasks/1/y", "tasks/2/z", "stages/s"} {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, nil, 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cleanTempDir(dir, []string{
+		filepath.Join(dir, "a"),
+		filepath.Join(dir, "tasks", "1", "x"),
+		filepath.Join(dir, "stages"),
+		filepath.Join(dir, "missing"),
+		filepath.Join(filepath.Dir(dir), "outside"),
+	})
+	var left []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			left = append(left, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"a", "stages/s", "tasks/1/x"}; !slices.Equal(left, expect) {
+		t.Errorf("expect %v left, got %v", expect, left)
+	}
+}
@@ -94,6 +94,8 @@ func InitialSettings() []model.SettingItem {
 		token = random.Token()
 	}
 	siteVersion := fmt.Sprintf("%s - Frontend: %s", conf.Version, conf.WebVersion)
+	// the help of the time windows of all the task queues
+	const timeWindowHelp = `daily periods in which the tasks are started, e.g. "01:00-07:00,22:00-23:30", empty means any time`
 	initialSettingItems := []model.SettingItem{
 		// site settings
 		{Key: conf.VERSION, Value: siteVersion, Type: conf.TypeString, Group: model.SITE, Flag: model.READONLY},
@@ -160,6 +162,7 @@ func InitialSettings() []model.SettingItem {
 		{Key: conf.HandleHookAfterWriting, Value: "false", Type: conf.TypeBool, Group: model.GLOBAL, Flag: model.PRIVATE},
 		{Key: conf.HandleHookRateLimit, Value: "0", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE},
 		{Key: conf.IgnoreSystemFiles, Value: "false", Type: conf.TypeBool, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `When enabled, ignores common system files during upload (.DS_Store, desktop.ini, Thumbs.db, and files starting with ._)`},
+		{Key: conf.StorageHealthInterval, Value: "5", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `the minutes between the health checks of the storages, the failed ones are reloaded automatically, 0 means disabled`},
 
 		// single settings
 		{Key: conf.Token, Value: token, Type: conf.TypeString, Group: model.SINGLE, Flag: model.PRIVATE},
@@ -218,10 +221,24 @@ func InitialSettings() []model.SettingItem {
 		{Key: conf.TaskCopyThreadsNum, Value: strconv.Itoa(conf.Conf.Tasks.Copy.Workers), Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
 		{Key: conf.TaskDecompressDownloadThreadsNum, Value: strconv.Itoa(conf.Conf.Tasks.Decompress.Workers), Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
 		{Key: conf.TaskDecompressUploadThreadsNum, Value: strconv.Itoa(conf.Conf.Tasks.DecompressUpload.Workers), Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
+		{Key: conf.TaskPipelineThreadsNum, Value: strconv.Itoa(conf.Conf.Tasks.Pipeline.Workers), Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
+		{Key: conf.TaskOfflineDownloadTimeWindow, Value: "", Type: conf.TypeString, Group: model.TRAFFIC, Flag: model.PRIVATE, Help: timeWindowHelp},
+		{Key: conf.TaskOfflineDownloadTransferTimeWindow, Value: "", Type: conf.TypeString, Group: model.TRAFFIC, Flag: model.PRIVATE, Help: timeWindowHelp},
+		{Key: conf.TaskUploadTimeWindow, Value: "", Type: conf.TypeString, Group: model.TRAFFIC, Flag: model.PRIVATE, Help: timeWindowHelp},
+		{Key: conf.TaskCopyTimeWindow, Value: "", Type: conf.TypeString, Group: model.TRAFFIC, Flag: model.PRIVATE, Help: timeWindowHelp},
+		{Key: conf.TaskMoveTimeWindow, Value: "", Type: conf.TypeString, Group: model.TRAFFIC, Flag: model.PRIVATE, Help: timeWindowHelp},
+		{Key: conf.TaskDecompressDownloadTimeWindow, Value: "", Type: conf.TypeString, Group: model.TRAFFIC, Flag: model.PRIVATE, Help: timeWindowHelp},
+		{Key: conf.TaskDecompressUploadTimeWindow, Value: "", Type: conf.TypeString, Group: model.TRAFFIC, Flag: model.PRIVATE, Help: timeWindowHelp},
+		{Key: conf.TaskPipelineTimeWindow, Value: "", Type: conf.TypeString, Group: model.TRAFFIC, Flag: model.PRIVATE, Help: timeWindowHelp},
+		{Key: conf.TaskCopyVerify, Value: "true", Type: conf.TypeBool, Group: model.TRAFFIC, Flag: model.PRIVATE, Help: `verify the copied dirs when all the files are copied`},
+		{Key: conf.HashSizeLimit, Value: "100", Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE, Help: `the largest file in MB whose hash is computed by reading it if the storage gives none, used by the duplicate finder and the copies skipping the same files, 0 means never`},
+		{Key: conf.TaskHistoryRetentionDays, Value: "30", Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE, Help: `the days the finished tasks are kept in the history, 0 means forever`},
+		{Key: conf.TaskHistoryMaxRecords, Value: "100000", Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE, Help: `the most finished tasks kept in the history, 0 means no limit`},
 		{Key: conf.StreamMaxClientDownloadSpeed, Value: "-1", Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
 		{Key: conf.StreamMaxClientUploadSpeed, Value: "-1", Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
 		{Key: conf.StreamMaxServerDownloadSpeed, Value: "-1", Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
 		{Key: conf.StreamMaxServerUploadSpeed, Value: "-1", Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
+		{Key: conf.UploadStageMaxSize, Value: "0", Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE, Help: `max total size (MB) of unfinished resumable uploads staged in the temp dir, 0 means unlimited`},
 	}
 	additionalSettingItems := tool.Tools.Items()
 	// 固定顺序
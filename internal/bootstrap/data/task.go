@@ -25,6 +25,10 @@ func InitialTasks() []model.TaskItem {
 		{Key: "move", PersistData: "[]"},
 		{Key: "download", PersistData: "[]"},
 		{Key: "transfer", PersistData: "[]"},
+		{Key: "upload", PersistData: "[]"},
+		{Key: "decompress", PersistData: "[]"},
+		{Key: "decompress_upload", PersistData: "[]"},
+		{Key: "pipeline", PersistData: "[]"},
 	}
 	return initialTaskItems
 }
@@ -15,10 +15,13 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/rss"
+	"github.com/OpenListTeam/OpenList/v4/internal/task_history"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server"
 	"github.com/OpenListTeam/OpenList/v4/server/middlewares"
-	"github.com/OpenListTeam/sftpd-openlist"
+	"github.com/OpenListTeam/OpenList/v4/server/sftp"
 	ftpserver "github.com/fclairamb/ftpserverlib"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -59,7 +62,7 @@ var (
 	ftpServer    *ftpserver.FtpServer
 	ftpRunning   bool
 	sftpDriver   *server.SftpDriver
-	sftpServer   *sftpd.SftpServer
+	sftpServer   *sftp.Server
 	sftpRunning  bool
 )
 
@@ -92,6 +95,9 @@ func Start() {
 	InitOfflineDownloadTools()
 	LoadStorages()
 	InitTaskManager()
+	task_history.Start()
+	rss.Start()
+	op.StartHealthMonitor()
 	if !flags.Debug && !flags.Dev {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -255,7 +261,7 @@ func Start() {
 			fmt.Printf("启动 SFTP 服务器 @ %s\n", conf.Conf.SFTP.Listen)
 			utils.Log.Infof("启动 SFTP 服务器 @ %s", conf.Conf.SFTP.Listen)
 			go func() {
-				sftpServer = sftpd.NewSftpServer(sftpDriver)
+				sftpServer = sftp.NewServer(sftpDriver)
 				sftpRunning = true
 				err = sftpServer.RunServer()
 				sftpRunning = false
@@ -273,7 +279,12 @@ func Start() {
 
 func Shutdown(timeout time.Duration) {
 	utils.Log.Println("Shutdown server...")
-	fs.ArchiveContentUploadTaskManager.RemoveAll()
+	rss.Stop()
+	op.StopHealthMonitor()
+	task_history.Stop()
+	if !conf.Conf.Tasks.DecompressUpload.TaskPersistant {
+		fs.ArchiveContentUploadTaskManager.RemoveAll()
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 	var wg sync.WaitGroup
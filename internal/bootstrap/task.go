@@ -1,13 +1,20 @@
 package bootstrap
 
 import (
+	"math"
+
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
 	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/pipeline"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/internal/task_history"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/tache"
+	log "github.com/sirupsen/logrus"
 )
 
 func taskFilterNegative(num int) int64 {
@@ -17,36 +24,63 @@ func taskFilterNegative(num int) int64 {
 	return int64(num)
 }
 
+// initScheduler hands the queueing of m over to s, m pops every task at once
+// and s starts them by priority within the configured workers and time windows.
+func initScheduler[T tache.Task](m *tache.Manager[T], s *task.Scheduler, workersKey string, workers int, windowKey string) {
+	update := func() {
+		s.SetWorkers(int(taskFilterNegative(setting.GetInt(workersKey, workers))))
+		windows, err := task.ParseTimeWindows(setting.GetStr(windowKey))
+		if err != nil {
+			log.Errorf("invalid %s: %v", windowKey, err)
+		}
+		s.SetWindows(windows)
+	}
+	update()
+	op.RegisterSettingChangingCallback(update)
+	m.SetWorkersNumActive(math.MaxInt32)
+}
+
 func InitTaskManager() {
-	fs.UploadTaskManager = tache.NewManager[*fs.UploadTask](tache.WithWorks(setting.GetInt(conf.TaskUploadThreadsNum, conf.Conf.Tasks.Upload.Workers)), tache.WithMaxRetry(conf.Conf.Tasks.Upload.MaxRetry)) //upload will not support persist
-	op.RegisterSettingChangingCallback(func() {
-		fs.UploadTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskUploadThreadsNum, conf.Conf.Tasks.Upload.Workers)))
-	})
+	fs.UploadTaskManager.Manager = tache.NewManager[*fs.UploadTask](tache.WithWorks(setting.GetInt(conf.TaskUploadThreadsNum, conf.Conf.Tasks.Upload.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("upload", conf.Conf.Tasks.Upload.TaskPersistant), db.UpdateTaskDataFunc("upload", conf.Conf.Tasks.Upload.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Upload.MaxRetry))
+	initScheduler(fs.UploadTaskManager.Manager, fs.UploadScheduler, conf.TaskUploadThreadsNum, conf.Conf.Tasks.Upload.Workers, conf.TaskUploadTimeWindow)
 	fs.CopyTaskManager = tache.NewManager[*fs.FileTransferTask](tache.WithWorks(setting.GetInt(conf.TaskCopyThreadsNum, conf.Conf.Tasks.Copy.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("copy", conf.Conf.Tasks.Copy.TaskPersistant), db.UpdateTaskDataFunc("copy", conf.Conf.Tasks.Copy.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Copy.MaxRetry))
-	op.RegisterSettingChangingCallback(func() {
-		fs.CopyTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskCopyThreadsNum, conf.Conf.Tasks.Copy.Workers)))
-	})
+	initScheduler(fs.CopyTaskManager, fs.CopyScheduler, conf.TaskCopyThreadsNum, conf.Conf.Tasks.Copy.Workers, conf.TaskCopyTimeWindow)
 	fs.MoveTaskManager = tache.NewManager[*fs.FileTransferTask](tache.WithWorks(setting.GetInt(conf.TaskMoveThreadsNum, conf.Conf.Tasks.Move.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("move", conf.Conf.Tasks.Move.TaskPersistant), db.UpdateTaskDataFunc("move", conf.Conf.Tasks.Move.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Move.MaxRetry))
-	op.RegisterSettingChangingCallback(func() {
-		fs.MoveTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskMoveThreadsNum, conf.Conf.Tasks.Move.Workers)))
-	})
+	initScheduler(fs.MoveTaskManager, fs.MoveScheduler, conf.TaskMoveThreadsNum, conf.Conf.Tasks.Move.Workers, conf.TaskMoveTimeWindow)
 	tool.DownloadTaskManager = tache.NewManager[*tool.DownloadTask](tache.WithWorks(setting.GetInt(conf.TaskOfflineDownloadThreadsNum, conf.Conf.Tasks.Download.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("download", conf.Conf.Tasks.Download.TaskPersistant), db.UpdateTaskDataFunc("download", conf.Conf.Tasks.Download.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Download.MaxRetry))
-	op.RegisterSettingChangingCallback(func() {
-		tool.DownloadTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskOfflineDownloadThreadsNum, conf.Conf.Tasks.Download.Workers)))
-	})
+	initScheduler(tool.DownloadTaskManager, tool.DownloadScheduler, conf.TaskOfflineDownloadThreadsNum, conf.Conf.Tasks.Download.Workers, conf.TaskOfflineDownloadTimeWindow)
 	tool.TransferTaskManager = tache.NewManager[*tool.TransferTask](tache.WithWorks(setting.GetInt(conf.TaskOfflineDownloadTransferThreadsNum, conf.Conf.Tasks.Transfer.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("transfer", conf.Conf.Tasks.Transfer.TaskPersistant), db.UpdateTaskDataFunc("transfer", conf.Conf.Tasks.Transfer.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Transfer.MaxRetry))
-	op.RegisterSettingChangingCallback(func() {
-		tool.TransferTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskOfflineDownloadTransferThreadsNum, conf.Conf.Tasks.Transfer.Workers)))
-	})
+	initScheduler(tool.TransferTaskManager, tool.TransferScheduler, conf.TaskOfflineDownloadTransferThreadsNum, conf.Conf.Tasks.Transfer.Workers, conf.TaskOfflineDownloadTransferTimeWindow)
+	fs.ArchiveDownloadTaskManager = tache.NewManager[*fs.ArchiveDownloadTask](tache.WithWorks(setting.GetInt(conf.TaskDecompressDownloadThreadsNum, conf.Conf.Tasks.Decompress.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("decompress", conf.Conf.Tasks.Decompress.TaskPersistant), db.UpdateTaskDataFunc("decompress", conf.Conf.Tasks.Decompress.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Decompress.MaxRetry))
+	initScheduler(fs.ArchiveDownloadTaskManager, fs.ArchiveDownloadScheduler, conf.TaskDecompressDownloadThreadsNum, conf.Conf.Tasks.Decompress.Workers, conf.TaskDecompressDownloadTimeWindow)
+	fs.ArchiveContentUploadTaskManager.Manager = tache.NewManager[*fs.ArchiveContentUploadTask](tache.WithWorks(setting.GetInt(conf.TaskDecompressUploadThreadsNum, conf.Conf.Tasks.DecompressUpload.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("decompress_upload", conf.Conf.Tasks.DecompressUpload.TaskPersistant), db.UpdateTaskDataFunc("decompress_upload", conf.Conf.Tasks.DecompressUpload.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.DecompressUpload.MaxRetry))
+	initScheduler(fs.ArchiveContentUploadTaskManager.Manager, fs.ArchiveContentUploadScheduler, conf.TaskDecompressUploadThreadsNum, conf.Conf.Tasks.DecompressUpload.Workers, conf.TaskDecompressUploadTimeWindow)
+	pipeline.TaskManager = tache.NewManager[*pipeline.PipelineTask](tache.WithWorks(setting.GetInt(conf.TaskPipelineThreadsNum, conf.Conf.Tasks.Pipeline.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("pipeline", conf.Conf.Tasks.Pipeline.TaskPersistant), db.UpdateTaskDataFunc("pipeline", conf.Conf.Tasks.Pipeline.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Pipeline.MaxRetry))
+	initScheduler(pipeline.TaskManager, pipeline.Scheduler, conf.TaskPipelineThreadsNum, conf.Conf.Tasks.Pipeline.Workers, conf.TaskPipelineTimeWindow)
+	task_history.Watch("upload", fs.UploadTaskManager)
+	task_history.Watch("copy", fs.CopyTaskManager)
+	task_history.Watch("move", fs.MoveTaskManager)
+	task_history.Watch("offline_download", tool.DownloadTaskManager)
+	task_history.Watch("offline_download_transfer", tool.TransferTaskManager)
+	task_history.Watch("decompress", fs.ArchiveDownloadTaskManager)
+	task_history.Watch("decompress_upload", fs.ArchiveContentUploadTaskManager)
+	task_history.Watch("pipeline", pipeline.TaskManager)
+	fs.LoadUploadStages()
+	// clean the temp dir only after the tasks are recovered, so that their files are kept
 	if len(tool.TransferTaskManager.GetAll()) == 0 { //prevent offline downloaded files from being deleted
-		CleanTempDir()
+		keep := append(fs.UploadTaskManager.TmpFiles(), fs.ArchiveContentUploadTaskManager.TmpFiles()...)
+		keep = append(keep, fs.UploadStageFiles()...)
+		// these tools resume from the data downloaded
+		resumable := []string{"BitTorrent", "SimpleHttp", "Remote"}
+		for _, t := range tool.DownloadTaskManager.GetAll() {
+			if !utils.SliceContains(resumable, t.Toolname) || t.GetState() == tache.StateSucceeded {
+				continue
+			}
+			keep = append(keep, t.TempDir)
+			if t.Toolname == "BitTorrent" {
+				keep = append(keep, tool.StatePath(t.TempDir))
+			}
+		}
+		CleanTempDir(keep...)
 	}
-	fs.ArchiveDownloadTaskManager = tache.NewManager[*fs.ArchiveDownloadTask](tache.WithWorks(setting.GetInt(conf.TaskDecompressDownloadThreadsNum, conf.Conf.Tasks.Decompress.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("decompress", conf.Conf.Tasks.Decompress.TaskPersistant), db.UpdateTaskDataFunc("decompress", conf.Conf.Tasks.Decompress.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Decompress.MaxRetry))
-	op.RegisterSettingChangingCallback(func() {
-		fs.ArchiveDownloadTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskDecompressDownloadThreadsNum, conf.Conf.Tasks.Decompress.Workers)))
-	})
-	fs.ArchiveContentUploadTaskManager.Manager = tache.NewManager[*fs.ArchiveContentUploadTask](tache.WithWorks(setting.GetInt(conf.TaskDecompressUploadThreadsNum, conf.Conf.Tasks.DecompressUpload.Workers)), tache.WithMaxRetry(conf.Conf.Tasks.DecompressUpload.MaxRetry)) //decompress upload will not support persist
-	op.RegisterSettingChangingCallback(func() {
-		fs.ArchiveContentUploadTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskDecompressUploadThreadsNum, conf.Conf.Tasks.DecompressUpload.Workers)))
-	})
 }
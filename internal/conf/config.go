@@ -73,6 +73,7 @@ type TasksConfig struct {
 	Move               TaskConfig `json:"move" envPrefix:"MOVE_"`
 	Decompress         TaskConfig `json:"decompress" envPrefix:"DECOMPRESS_"`
 	DecompressUpload   TaskConfig `json:"decompress_upload" envPrefix:"DECOMPRESS_UPLOAD_"`
+	Pipeline           TaskConfig `json:"pipeline" envPrefix:"PIPELINE_"`
 	AllowRetryCanceled bool       `json:"allow_retry_canceled" env:"ALLOW_RETRY_CANCELED"`
 }
 
@@ -196,6 +197,7 @@ func DefaultConfig(dataDir string) *Config {
 			},
 			Upload: TaskConfig{
 				Workers: 5,
+				// TaskPersistant: true,
 			},
 			Copy: TaskConfig{
 				Workers:  5,
@@ -215,6 +217,12 @@ func DefaultConfig(dataDir string) *Config {
 			DecompressUpload: TaskConfig{
 				Workers:  5,
 				MaxRetry: 2,
+				// TaskPersistant: true,
+			},
+			Pipeline: TaskConfig{
+				Workers:  5,
+				MaxRetry: 2,
+				// TaskPersistant: true,
 			},
 			AllowRetryCanceled: false,
 		},
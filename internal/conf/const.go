@@ -59,6 +59,7 @@ const (
 	HandleHookAfterWriting  = "handle_hook_after_writing"
 	HandleHookRateLimit     = "handle_hook_rate_limit"
 	IgnoreSystemFiles       = "ignore_system_files"
+	StorageHealthInterval   = "storage_health_interval"
 
 	// index
 	SearchIndex     = "search_index"
@@ -74,6 +75,16 @@ const (
 	TransmissionUri      = "transmission_uri"
 	TransmissionSeedtime = "transmission_seedtime"
 
+	// built-in bittorrent
+	TorrentListenPort = "torrent_listen_port"
+	TorrentDHT        = "torrent_dht"
+	TorrentSeedRatio  = "torrent_seed_ratio"
+	TorrentSeedtime   = "torrent_seedtime"
+	TorrentFileFilter = "torrent_file_filter"
+
+	// simple http
+	SimpleHttpThreads = "simple_http_threads"
+
 	// 115
 	Pan115TempDir = "115_temp_dir"
 
@@ -157,10 +168,24 @@ const (
 	TaskMoveThreadsNum                    = "move_task_threads_num"
 	TaskDecompressDownloadThreadsNum      = "decompress_download_task_threads_num"
 	TaskDecompressUploadThreadsNum        = "decompress_upload_task_threads_num"
+	TaskPipelineThreadsNum                = "pipeline_task_threads_num"
+	TaskOfflineDownloadTimeWindow         = "offline_download_task_time_window"
+	TaskOfflineDownloadTransferTimeWindow = "offline_download_transfer_task_time_window"
+	TaskUploadTimeWindow                  = "upload_task_time_window"
+	TaskCopyTimeWindow                    = "copy_task_time_window"
+	TaskMoveTimeWindow                    = "move_task_time_window"
+	TaskDecompressDownloadTimeWindow      = "decompress_download_task_time_window"
+	TaskDecompressUploadTimeWindow        = "decompress_upload_task_time_window"
+	TaskPipelineTimeWindow                = "pipeline_task_time_window"
+	TaskHistoryRetentionDays              = "task_history_retention_days"
+	TaskHistoryMaxRecords                 = "task_history_max_records"
+	TaskCopyVerify                        = "copy_task_verify"
+	HashSizeLimit                         = "hash_size_limit"
 	StreamMaxClientDownloadSpeed          = "max_client_download_speed"
 	StreamMaxClientUploadSpeed            = "max_client_upload_speed"
 	StreamMaxServerDownloadSpeed          = "max_server_download_speed"
 	StreamMaxServerUploadSpeed            = "max_server_upload_speed"
+	UploadStageMaxSize                    = "upload_stage_max_size"
 )
 
 const (
@@ -191,4 +216,10 @@ const (
 	PathKey
 	SharingIDKey
 	SkipHookKey
+	// the upload may continue the one left by an earlier attempt
+	ResumeUploadKey
+	// the copy skips the files whose content exists in the dst dir
+	SkipSameHashKey
+	// the upload tries the rapid upload by the hashes of the stream first
+	RapidUploadKey
 )
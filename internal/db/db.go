@@ -12,7 +12,7 @@ var db *gorm.DB
 
 func Init(d *gorm.DB) {
 	db = d
-	err := AutoMigrate(new(model.Storage), new(model.User), new(model.Meta), new(model.SettingItem), new(model.SearchNode), new(model.TaskItem), new(model.SSHPublicKey), new(model.SharingDB))
+	err := AutoMigrate(new(model.Storage), new(model.User), new(model.Meta), new(model.SettingItem), new(model.SearchNode), new(model.TaskItem), new(model.SSHPublicKey), new(model.SharingDB), new(model.S3ObjectMeta), new(model.RssFeed), new(model.RssHistory), new(model.OfflineDownloadRule), new(model.TaskHistory), new(model.TransferCheckpoint))
 	if err != nil {
 		log.Fatalf("failed migrate database: %s", err.Error())
 	}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetOfflineDownloadRules(pageIndex, pageSize int) (rules []model.OfflineDownloadRule, count int64, err error) {
+	ruleDB := db.Model(&model.OfflineDownloadRule{})
+	if err := ruleDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get offline download rules count")
+	}
+	if err := ruleDB.Order(columnName("order")).Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&rules).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find offline download rules")
+	}
+	return rules, count, nil
+}
+
+func GetEnabledOfflineDownloadRules() (rules []model.OfflineDownloadRule, err error) {
+	if err := db.Where(map[string]any{"disabled": false}).Order(columnName("order")).Order(columnName("id")).Find(&rules).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find enabled offline download rules")
+	}
+	return rules, nil
+}
+
+func GetOfflineDownloadRuleById(id uint) (*model.OfflineDownloadRule, error) {
+	var r model.OfflineDownloadRule
+	if err := db.First(&r, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get offline download rule")
+	}
+	return &r, nil
+}
+
+func CreateOfflineDownloadRule(r *model.OfflineDownloadRule) error {
+	return errors.WithStack(db.Create(r).Error)
+}
+
+func UpdateOfflineDownloadRule(r *model.OfflineDownloadRule) error {
+	return errors.WithStack(db.Save(r).Error)
+}
+
+func DeleteOfflineDownloadRuleById(id uint) error {
+	return errors.WithStack(db.Delete(&model.OfflineDownloadRule{}, id).Error)
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetRssFeeds(pageIndex, pageSize int) (feeds []model.RssFeed, count int64, err error) {
+	feedDB := db.Model(&model.RssFeed{})
+	if err := feedDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get rss feeds count")
+	}
+	if err := feedDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&feeds).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find rss feeds")
+	}
+	return feeds, count, nil
+}
+
+func GetRssFeedsByUserId(userId uint, pageIndex, pageSize int) (feeds []model.RssFeed, count int64, err error) {
+	feedDB := db.Model(&model.RssFeed{})
+	query := model.RssFeed{UserId: userId}
+	if err := feedDB.Where(query).Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get user's rss feeds count")
+	}
+	if err := feedDB.Where(query).Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&feeds).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find user's rss feeds")
+	}
+	return feeds, count, nil
+}
+
+func GetEnabledRssFeeds() (feeds []model.RssFeed, err error) {
+	if err := db.Where(map[string]any{"disabled": false}).Find(&feeds).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find enabled rss feeds")
+	}
+	return feeds, nil
+}
+
+func GetRssFeedById(id uint) (*model.RssFeed, error) {
+	var f model.RssFeed
+	if err := db.First(&f, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get rss feed")
+	}
+	return &f, nil
+}
+
+func CreateRssFeed(f *model.RssFeed) error {
+	return errors.WithStack(db.Create(f).Error)
+}
+
+func UpdateRssFeed(f *model.RssFeed) error {
+	return errors.WithStack(db.Save(f).Error)
+}
+
+func DeleteRssFeedById(id uint) error {
+	if err := db.Where("feed_id = ?", id).Delete(&model.RssHistory{}).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(db.Delete(&model.RssFeed{}, id).Error)
+}
+
+func DeleteRssFeedsByUserId(userId uint) error {
+	if err := db.Where("user_id = ?", userId).Delete(&model.RssHistory{}).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(db.Where("user_id = ?", userId).Delete(&model.RssFeed{}).Error)
+}
+
+func GetRssHistories(feedId uint, pageIndex, pageSize int) (histories []model.RssHistory, count int64, err error) {
+	historyDB := db.Model(&model.RssHistory{})
+	query := model.RssHistory{FeedId: feedId}
+	if err := historyDB.Where(query).Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get rss histories count")
+	}
+	if err := historyDB.Where(query).Order(columnName("id") + " DESC").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&histories).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find rss histories")
+	}
+	return histories, count, nil
+}
+
+func ExistRssHistory(userId uint, itemKey string) (bool, error) {
+	var count int64
+	err := db.Model(&model.RssHistory{}).Where(model.RssHistory{UserId: userId, ItemKey: itemKey}).Count(&count).Error
+	return count > 0, errors.WithStack(err)
+}
+
+func CreateRssHistory(h *model.RssHistory) error {
+	return errors.WithStack(db.Create(h).Error)
+}
+
+func ClearRssHistories(feedId uint) error {
+	return errors.WithStack(db.Where("feed_id = ?", feedId).Delete(&model.RssHistory{}).Error)
+}
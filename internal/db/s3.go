@@ -0,0 +1,26 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetS3ObjectMetaByPath(path string) (*model.S3ObjectMeta, error) {
+	m := model.S3ObjectMeta{Path: path}
+	if err := db.Where(m).First(&m).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed select s3 object meta")
+	}
+	return &m, nil
+}
+
+func SaveS3ObjectMeta(m *model.S3ObjectMeta) error {
+	var old model.S3ObjectMeta
+	if err := db.Where(model.S3ObjectMeta{Path: m.Path}).First(&old).Error; err == nil {
+		m.ID = old.ID
+	}
+	return errors.WithStack(db.Save(m).Error)
+}
+
+func DeleteS3ObjectMetaByPath(path string) error {
+	return errors.WithStack(db.Where(model.S3ObjectMeta{Path: path}).Delete(&model.S3ObjectMeta{}).Error)
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func filterTaskHistories(historyDB *gorm.DB, f model.TaskHistoryFilter) *gorm.DB {
+	if f.Type != "" {
+		historyDB = historyDB.Where("type = ?", f.Type)
+	}
+	if f.Result != "" {
+		historyDB = historyDB.Where("result = ?", f.Result)
+	}
+	if f.Storage != "" {
+		historyDB = historyDB.Where("storage = ?", f.Storage)
+	}
+	if f.CreatorId != 0 {
+		historyDB = historyDB.Where("creator_id = ?", f.CreatorId)
+	}
+	if f.Keyword != "" {
+		keyword := "%" + f.Keyword + "%"
+		historyDB = historyDB.Where("name LIKE ? OR src_path LIKE ? OR dst_path LIKE ?", keyword, keyword, keyword)
+	}
+	if !f.Since.IsZero() {
+		historyDB = historyDB.Where("end_time >= ?", f.Since)
+	}
+	if !f.Until.IsZero() {
+		historyDB = historyDB.Where("end_time < ?", f.Until)
+	}
+	return historyDB
+}
+
+func GetTaskHistories(f model.TaskHistoryFilter, pageIndex, pageSize int) (histories []model.TaskHistory, count int64, err error) {
+	historyDB := filterTaskHistories(db.Model(&model.TaskHistory{}), f)
+	if err := historyDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get task histories count")
+	}
+	if err := historyDB.Order(columnName("id") + " DESC").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&histories).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find task histories")
+	}
+	return histories, count, nil
+}
+
+// SummarizeTaskHistories groups the task histories by the column, which is
+// one of creator and storage.
+func SummarizeTaskHistories(f model.TaskHistoryFilter, column string) (summaries []model.TaskHistorySummary, err error) {
+	succeeded := fmt.Sprintf("result = '%s'", model.TaskResultSucceeded)
+	err = filterTaskHistories(db.Model(&model.TaskHistory{}), f).
+		Select(fmt.Sprintf("%[1]s AS %[2]s, COUNT(*) AS %[3]s, "+
+			"SUM(CASE WHEN %[4]s THEN 1 ELSE 0 END) AS %[5]s, "+
+			"SUM(CASE WHEN %[4]s THEN total_bytes ELSE 0 END) AS %[6]s, "+
+			"SUM(CASE WHEN %[4]s THEN duration ELSE 0 END) AS %[7]s",
+			columnName(column), columnName("key"), columnName("count"), succeeded,
+			columnName("succeeded"), columnName("bytes"), columnName("duration"))).
+		Group(columnName(column)).
+		Order(columnName("bytes") + " DESC").
+		Scan(&summaries).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed summarize task histories")
+	}
+	return summaries, nil
+}
+
+func CreateTaskHistory(h *model.TaskHistory) error {
+	return errors.WithStack(db.Create(h).Error)
+}
+
+func DeleteTaskHistoriesBefore(t time.Time) error {
+	return errors.WithStack(db.Where("end_time < ?", t).Delete(&model.TaskHistory{}).Error)
+}
+
+// TrimTaskHistories keeps the latest max task histories.
+func TrimTaskHistories(max int) error {
+	var ids []uint
+	if err := db.Model(&model.TaskHistory{}).Order(columnName("id")+" DESC").Offset(max).Limit(1).Pluck("id", &ids).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return errors.WithStack(db.Where("id <= ?", ids[0]).Delete(&model.TaskHistory{}).Error)
+}
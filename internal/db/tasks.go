@@ -14,6 +14,14 @@ func GetTaskDataByType(type_s string) (*model.TaskItem, error) {
 	return &task, nil
 }
 
+func GetTaskItems() ([]model.TaskItem, error) {
+	var tasks []model.TaskItem
+	if err := db.Find(&tasks).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find tasks")
+	}
+	return tasks, nil
+}
+
 func UpdateTaskData(t *model.TaskItem) error {
 	return errors.WithStack(db.Model(&model.TaskItem{}).Where("key = ?", t.Key).Update("persist_data", t.PersistData).Error)
 }
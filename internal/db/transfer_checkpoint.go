@@ -0,0 +1,28 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetTransferCheckpoint(key, srcPath string) (*model.TransferCheckpoint, error) {
+	var c model.TransferCheckpoint
+	if err := db.Where("root_key = ? AND src_path = ?", key, srcPath).First(&c).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get transfer checkpoint")
+	}
+	return &c, nil
+}
+
+func CreateTransferCheckpoint(c *model.TransferCheckpoint) error {
+	return errors.WithStack(db.Create(c).Error)
+}
+
+func DeleteTransferCheckpoints(key string) error {
+	return errors.WithStack(db.Where("root_key = ?", key).Delete(&model.TransferCheckpoint{}).Error)
+}
+
+func DeleteTransferCheckpointsBefore(t time.Time) error {
+	return errors.WithStack(db.Where("created < ?", t).Delete(&model.TransferCheckpoint{}).Error)
+}
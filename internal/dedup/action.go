@@ -0,0 +1,244 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+	stdpath "path"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the bulk actions on the groups, all the files of a group but the one kept
+// are removed, and replaced by the links to it with ActionLink
+const (
+	ActionDelete = "delete"
+	ActionLink   = "link"
+)
+
+// the suffix of the link put in place before the duplicate is removed
+const linkTempSuffix = ".openlist_link"
+
+type GroupAction struct {
+	ID string `json:"id"`
+	// the path of the file kept, the oldest one if empty
+	Keep string `json:"keep"`
+}
+
+type ActionReq struct {
+	Action string        `json:"action"`
+	Groups []GroupAction `json:"groups"`
+	// the dir of an url_tree or alias storage the links are put in, the links
+	// replace the duplicates in their dirs if empty
+	LinkDir string `json:"link_dir"`
+}
+
+type ActionResult struct {
+	Removed int      `json:"removed"`
+	Linked  int      `json:"linked"`
+	Failed  []string `json:"failed"`
+}
+
+// Act applies the action on the groups found by the last run.
+func Act(ctx context.Context, req ActionReq) (*ActionResult, error) {
+	if req.Action != ActionDelete && req.Action != ActionLink {
+		return nil, errors.Errorf("unknown action: %s", req.Action)
+	}
+	if len(req.Groups) == 0 {
+		return nil, errors.New("no group to act on")
+	}
+	if Running() {
+		return nil, errors.New("duplicate finder is running, please try later")
+	}
+	if req.Action == ActionLink && req.LinkDir != "" {
+		req.LinkDir = utils.FixAndCleanPath(req.LinkDir)
+		if err := checkPutURL(req.LinkDir); err != nil {
+			return nil, err
+		}
+	}
+	found := make(map[string]Group)
+	for _, g := range GetGroups() {
+		found[g.ID] = g
+	}
+	res := &ActionResult{}
+	done := make(map[string]struct{})
+	v := newVerifier(ctx)
+	for _, ga := range req.Groups {
+		g, ok := found[ga.ID]
+		if !ok {
+			res.Failed = append(res.Failed, fmt.Sprintf("group %s: not found", ga.ID))
+			continue
+		}
+		keep := g.Files[0].Path
+		if ga.Keep != "" {
+			keep = utils.FixAndCleanPath(ga.Keep)
+		}
+		kept := false
+		for _, f := range g.Files {
+			kept = kept || f.Path == keep
+		}
+		if !kept {
+			res.Failed = append(res.Failed, fmt.Sprintf("group %s: %s is not in the group", ga.ID, keep))
+			continue
+		}
+		// the files may have changed since the scan
+		if err := v.check(g, keep); err != nil {
+			res.Failed = append(res.Failed, fmt.Sprintf("group %s: %v", ga.ID, err))
+			continue
+		}
+		for _, f := range g.Files {
+			if f.Path == keep {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				removeFiles(done)
+				return res, err
+			}
+			if err := v.check(g, f.Path); err != nil {
+				res.Failed = append(res.Failed, fmt.Sprintf("%s: %v", f.Path, err))
+				continue
+			}
+			removed, linked, err := act(ctx, req, f.Path, keep)
+			if err != nil {
+				res.Failed = append(res.Failed, fmt.Sprintf("%s: %v", f.Path, err))
+			}
+			if removed {
+				done[f.Path] = struct{}{}
+				res.Removed++
+			}
+			if linked {
+				res.Linked++
+			}
+		}
+	}
+	removeFiles(done)
+	return res, nil
+}
+
+func act(ctx context.Context, req ActionReq, path, keep string) (removed, linked bool, err error) {
+	if req.Action == ActionDelete {
+		err = fs.Remove(ctx, path)
+		return err == nil, false, err
+	}
+	url := fmt.Sprintf("%s/d%s?sign=%s",
+		common.GetApiUrl(ctx),
+		utils.EncodePath(keep, true),
+		sign.NotExpired(keep))
+	dir, name := stdpath.Dir(path), stdpath.Base(path)
+	if req.LinkDir != "" {
+		// the link is put before the duplicate is removed
+		if err = fs.PutURL(ctx, req.LinkDir, name, url); err != nil {
+			return false, false, errors.WithMessage(err, "failed put link")
+		}
+		err = fs.Remove(ctx, path)
+		return err == nil, true, err
+	}
+	if err = checkPutURL(dir); err != nil {
+		return false, false, err
+	}
+	// the link is put by a temp name before the duplicate is removed, and
+	// renamed to the name of the duplicate after
+	tempName := name + linkTempSuffix
+	if err = fs.PutURL(ctx, dir, tempName, url); err != nil {
+		return false, false, errors.WithMessage(err, "failed put link")
+	}
+	if err = fs.Remove(ctx, path); err != nil {
+		if err := fs.Remove(ctx, stdpath.Join(dir, tempName)); err != nil {
+			log.Errorf("failed remove the link [%s]: %+v", stdpath.Join(dir, tempName), err)
+		}
+		return false, false, err
+	}
+	if err = fs.Rename(ctx, stdpath.Join(dir, tempName), name); err != nil {
+		return true, true, errors.WithMessagef(err, "the link is left as [%s]", tempName)
+	}
+	return true, true, nil
+}
+
+// checkPutURL checks whether the links can be put in the dir.
+func checkPutURL(dir string) error {
+	storage, _, err := op.GetStorageAndActualPath(dir)
+	if err != nil {
+		return errors.WithMessagef(err, "failed get storage of [%s]", dir)
+	}
+	_, ok := storage.(driver.PutURL)
+	_, okResult := storage.(driver.PutURLResult)
+	if (!ok && !okResult) || storage.Config().NoUpload {
+		return errors.WithMessagef(errs.NotImplement, "links can't be put in [%s]", dir)
+	}
+	return nil
+}
+
+// verifier checks the files are still the same as the scan found, by the
+// listings refreshed once for each dir.
+type verifier struct {
+	ctx   context.Context
+	limit int64
+	dirs  map[string]map[string]model.Obj
+}
+
+func newVerifier(ctx context.Context) *verifier {
+	return &verifier{
+		ctx:   ctx,
+		limit: int64(setting.GetInt(conf.HashSizeLimit, 0)) << 20,
+		dirs:  make(map[string]map[string]model.Obj),
+	}
+}
+
+func (v *verifier) check(g Group, path string) error {
+	dir, name := stdpath.Dir(path), stdpath.Base(path)
+	objs, ok := v.dirs[dir]
+	if !ok {
+		list, err := fs.List(v.ctx, dir, &fs.ListArgs{Refresh: true, NoLog: true})
+		if err != nil && !errs.IsObjectNotFound(err) {
+			return errors.WithMessagef(err, "failed list [%s]", dir)
+		}
+		objs = make(map[string]model.Obj, len(list))
+		for _, obj := range list {
+			objs[obj.GetName()] = obj
+		}
+		v.dirs[dir] = objs
+	}
+	obj, ok := objs[name]
+	if !ok || obj.IsDir() {
+		return errors.New("the file no longer exists")
+	}
+	if obj.GetSize() != g.Size {
+		return errors.New("the size of the file has changed")
+	}
+	htName, _, _ := strings.Cut(g.ID, ":")
+	var ht *utils.HashType
+	for _, typ := range hashTypes {
+		if typ.Name == htName {
+			ht = typ
+		}
+	}
+	if ht == nil {
+		return errors.Errorf("unknown hash type: %s", htName)
+	}
+	storage, actualPath, err := op.GetStorageAndActualPath(path)
+	if err != nil {
+		return err
+	}
+	sum, err := op.GetObjHash(v.ctx, storage, actualPath, obj, ht, v.limit)
+	if err != nil {
+		return err
+	}
+	if sum == "" {
+		return errors.New("the hash of the file is unknown")
+	}
+	if !strings.EqualFold(sum, g.Hash) {
+		return errors.New("the content of the file has changed")
+	}
+	return nil
+}
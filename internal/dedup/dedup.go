@@ -0,0 +1,276 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the hash types tried in order to compare the files of the same size, the
+// md5 is computed for the files which the storages give no common hash for
+var hashTypes = []*utils.HashType{utils.MD5, utils.SHA1, utils.SHA256}
+
+type File struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// Group is the files with the same content, the oldest one is the first.
+type Group struct {
+	ID    string `json:"id"`
+	Hash  string `json:"hash"`
+	Size  int64  `json:"size"`
+	Files []File `json:"files"`
+}
+
+type Progress struct {
+	Running  bool      `json:"running"`
+	Paths    []string  `json:"paths"`
+	Scanned  uint64    `json:"scanned"`
+	Hashed   uint64    `json:"hashed"`
+	Unknown  uint64    `json:"unknown"`
+	Groups   int       `json:"groups"`
+	Wasted   int64     `json:"wasted"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Error    string    `json:"error"`
+}
+
+var (
+	mu       sync.Mutex
+	cancel   context.CancelFunc
+	progress Progress
+	groups   []Group
+)
+
+// Running tells whether the duplicate finder is running.
+func Running() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return progress.Running
+}
+
+// Start finds the duplicate files under the paths in the background, the
+// result of the last run is dropped.
+func Start(paths []string) error {
+	if len(paths) == 0 {
+		return errors.New("no path to find duplicates")
+	}
+	for i := range paths {
+		paths[i] = utils.FixAndCleanPath(paths[i])
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if progress.Running {
+		return errors.New("duplicate finder is running, please try later")
+	}
+	var ctx context.Context
+	ctx, cancel = context.WithCancel(context.Background())
+	progress = Progress{Running: true, Paths: paths, Started: time.Now()}
+	groups = nil
+	go func() {
+		res, err := find(ctx, paths)
+		mu.Lock()
+		defer mu.Unlock()
+		cancel()
+		progress.Running = false
+		progress.Finished = time.Now()
+		if err != nil {
+			log.Errorf("failed find duplicates: %+v", err)
+			progress.Error = err.Error()
+		}
+		groups = res
+		progress.Groups, progress.Wasted = summarize(res)
+	}()
+	return nil
+}
+
+// Stop cancels the duplicate finder, the groups found while hashing are kept.
+func Stop() {
+	mu.Lock()
+	defer mu.Unlock()
+	if progress.Running {
+		cancel()
+	}
+}
+
+func GetProgress() Progress {
+	mu.Lock()
+	defer mu.Unlock()
+	return progress
+}
+
+// GetGroups returns the groups of the last run, the most wasteful first.
+func GetGroups() []Group {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Group(nil), groups...)
+}
+
+func summarize(groups []Group) (int, int64) {
+	var wasted int64
+	for _, g := range groups {
+		wasted += g.Size * int64(len(g.Files)-1)
+	}
+	return len(groups), wasted
+}
+
+func count(f func(p *Progress)) {
+	mu.Lock()
+	f(&progress)
+	mu.Unlock()
+}
+
+type file struct {
+	File
+	obj model.Obj
+}
+
+func find(ctx context.Context, paths []string) ([]Group, error) {
+	// only the files of the same size need to be hashed
+	sizes := make(map[int64][]file)
+	seen := make(map[string]struct{})
+	for _, p := range paths {
+		root, err := fs.Get(ctx, p, &fs.GetArgs{NoLog: true})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed get [%s]", p)
+		}
+		err = fs.WalkFS(ctx, -1, p, root, func(reqPath string, obj model.Obj) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if obj.IsDir() {
+				return nil
+			}
+			count(func(pr *Progress) { pr.Scanned++ })
+			// the empty files are not worth it, and the paths may overlap
+			if _, ok := seen[reqPath]; ok || obj.GetSize() == 0 {
+				return nil
+			}
+			seen[reqPath] = struct{}{}
+			sizes[obj.GetSize()] = append(sizes[obj.GetSize()], file{
+				File: File{Path: reqPath, Size: obj.GetSize(), Modified: obj.ModTime()},
+				obj:  obj,
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	limit := int64(setting.GetInt(conf.HashSizeLimit, 0)) << 20
+	var res []Group
+	for size, files := range sizes {
+		if len(files) < 2 {
+			continue
+		}
+		ht := commonHashType(files)
+		sums := make(map[string][]File)
+		for _, f := range files {
+			if err := ctx.Err(); err != nil {
+				return res, err
+			}
+			sum := hashOf(ctx, f, ht, limit)
+			if sum == "" {
+				count(func(pr *Progress) { pr.Unknown++ })
+				continue
+			}
+			count(func(pr *Progress) { pr.Hashed++ })
+			sums[sum] = append(sums[sum], f.File)
+		}
+		for sum, same := range sums {
+			if len(same) < 2 {
+				continue
+			}
+			sort.Slice(same, func(i, j int) bool {
+				if !same[i].Modified.Equal(same[j].Modified) {
+					return same[i].Modified.Before(same[j].Modified)
+				}
+				return same[i].Path < same[j].Path
+			})
+			res = append(res, Group{
+				ID:    fmt.Sprintf("%s:%s", ht.Name, sum),
+				Hash:  sum,
+				Size:  size,
+				Files: same,
+			})
+		}
+	}
+	sortGroups(res)
+	return res, nil
+}
+
+func sortGroups(groups []Group) {
+	sort.Slice(groups, func(i, j int) bool {
+		wi := groups[i].Size * int64(len(groups[i].Files)-1)
+		wj := groups[j].Size * int64(len(groups[j].Files)-1)
+		if wi != wj {
+			return wi > wj
+		}
+		return groups[i].ID < groups[j].ID
+	})
+}
+
+// commonHashType is the first hash type all the files have.
+func commonHashType(files []file) *utils.HashType {
+	for _, ht := range hashTypes {
+		all := true
+		for _, f := range files {
+			if f.obj.GetHash().GetHash(ht) == "" {
+				all = false
+				break
+			}
+		}
+		if all {
+			return ht
+		}
+	}
+	return utils.MD5
+}
+
+func hashOf(ctx context.Context, f file, ht *utils.HashType, limit int64) string {
+	storage, actualPath, err := op.GetStorageAndActualPath(f.Path)
+	if err != nil {
+		return ""
+	}
+	sum, err := op.GetObjHash(ctx, storage, actualPath, f.obj, ht, limit)
+	if err != nil {
+		log.Warnf("failed get hash of [%s]: %+v", f.Path, err)
+		return ""
+	}
+	return sum
+}
+
+// removeFiles drops the files from the groups, and the groups left with only
+// one file.
+func removeFiles(paths map[string]struct{}) {
+	mu.Lock()
+	defer mu.Unlock()
+	res := groups[:0]
+	for _, g := range groups {
+		files := make([]File, 0, len(g.Files))
+		for _, f := range g.Files {
+			if _, ok := paths[f.Path]; !ok {
+				files = append(files, f)
+			}
+		}
+		g.Files = files
+		if len(g.Files) > 1 {
+			res = append(res, g)
+		}
+	}
+	groups = res
+	progress.Groups, progress.Wasted = summarize(groups)
+}
@@ -0,0 +1,202 @@
+package dedup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/drivers/url_tree"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	// the local storage gives no hash, they are computed by reading the files
+	if err = op.SaveSettingItem(&model.SettingItem{Key: conf.HashSizeLimit, Value: "1", Type: conf.TypeNumber}); err != nil {
+		panic(err)
+	}
+}
+
+func createStorage(t *testing.T, storage model.Storage) {
+	ctx := context.Background()
+	id, err := op.CreateStorage(ctx, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = op.DeleteStorageById(ctx, id) })
+}
+
+// setup mounts a local storage with the files, and finds the duplicates.
+func setup(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	createStorage(t, model.Storage{
+		Driver:    "Local",
+		MountPath: "/local",
+		Addition:  `{"root_folder_path":"` + root + `"}`,
+	})
+	scan(t, "/local")
+	return root
+}
+
+func scan(t *testing.T, paths ...string) {
+	res, err := find(context.Background(), paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	groups = res
+	mu.Unlock()
+}
+
+func exists(root, name string) bool {
+	_, err := os.Stat(filepath.Join(root, name))
+	return err == nil
+}
+
+func TestFind(t *testing.T) {
+	setup(t, map[string]string{"a.txt": "same", "b.txt": "same", "c.txt": "diff", "d.txt": "other"})
+	g := GetGroups()
+	if len(g) != 1 || len(g[0].Files) != 2 || g[0].Size != 4 {
+		t.Fatalf("unexpected groups: %+v", g)
+	}
+	paths := []string{g[0].Files[0].Path, g[0].Files[1].Path}
+	slices.Sort(paths)
+	if !slices.Equal(paths, []string{"/local/a.txt", "/local/b.txt"}) {
+		t.Errorf("unexpected files: %v", paths)
+	}
+}
+
+func TestActDelete(t *testing.T) {
+	root := setup(t, map[string]string{"a.txt": "same", "b.txt": "same", "c.txt": "same"})
+	g := GetGroups()[0]
+	res, err := Act(context.Background(), ActionReq{
+		Action: ActionDelete,
+		Groups: []GroupAction{{ID: g.ID, Keep: "/local/b.txt"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Removed != 2 || len(res.Failed) != 0 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if exists(root, "a.txt") || !exists(root, "b.txt") || exists(root, "c.txt") {
+		t.Error("only the kept file should be left")
+	}
+	if len(GetGroups()) != 0 {
+		t.Errorf("the group should be dropped, got %+v", GetGroups())
+	}
+}
+
+func TestActSkipsChanged(t *testing.T) {
+	root := setup(t, map[string]string{"a.txt": "same", "b.txt": "same", "c.txt": "same"})
+	g := GetGroups()[0]
+	// changed after the scan, with the size kept
+	if err := os.WriteFile(filepath.Join(root, "b.txt"), []byte("diff"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(root, "c.txt")); err != nil {
+		t.Fatal(err)
+	}
+	res, err := Act(context.Background(), ActionReq{
+		Action: ActionDelete,
+		Groups: []GroupAction{{ID: g.ID, Keep: "/local/a.txt"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Removed != 0 || len(res.Failed) != 2 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if !exists(root, "a.txt") || !exists(root, "b.txt") {
+		t.Error("the changed file and the kept one should be left")
+	}
+
+	// the kept file changed
+	if err = os.WriteFile(filepath.Join(root, "b.txt"), []byte("same"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	scan(t, "/local")
+	g = GetGroups()[0]
+	if err = os.WriteFile(filepath.Join(root, "a.txt"), []byte("diff"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	res, err = Act(context.Background(), ActionReq{
+		Action: ActionDelete,
+		Groups: []GroupAction{{ID: g.ID, Keep: "/local/a.txt"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Removed != 0 || len(res.Failed) != 1 || !exists(root, "b.txt") {
+		t.Errorf("nothing should be removed if the kept file changed: %+v", res)
+	}
+}
+
+func TestActLinkInPlace(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("same"))
+	}))
+	defer srv.Close()
+	structure := "a.txt:4:1:" + srv.URL + "/a\nb.txt:4:2:" + srv.URL + "/b"
+	addition, err := utils.Json.MarshalToString(map[string]any{"url_structure": structure, "writable": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	createStorage(t, model.Storage{Driver: "UrlTree", MountPath: "/urls", Addition: addition})
+	scan(t, "/urls")
+	g := GetGroups()
+	if len(g) != 1 {
+		t.Fatalf("unexpected groups: %+v", g)
+	}
+	res, err := Act(context.Background(), ActionReq{
+		Action: ActionLink,
+		Groups: []GroupAction{{ID: g[0].ID, Keep: "/urls/a.txt"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Removed != 1 || res.Linked != 1 || len(res.Failed) != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	storage, err := op.GetStorageByMountPath("/urls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs, err := op.List(context.Background(), storage, "/", model.ListArgs{Refresh: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, obj := range objs {
+		names = append(names, obj.GetName())
+	}
+	if len(names) != 2 || names[0] != "a.txt" || names[1] != "b.txt" {
+		t.Fatalf("unexpected files: %v", names)
+	}
+	structure = storage.(*url_tree.Urls).UrlStructure
+	if !strings.Contains(structure, "/d/urls/a.txt?sign=") || strings.Contains(structure, linkTempSuffix) {
+		t.Errorf("b.txt should link to a.txt: %s", structure)
+	}
+}
@@ -2,6 +2,7 @@ package driver
 
 import (
 	"context"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 )
@@ -103,6 +104,40 @@ type Put interface {
 	Put(ctx context.Context, dstDir model.Obj, file model.FileStreamer, up UpdateProgress) error
 }
 
+// ResumablePut is implemented by the drivers which can continue an upload
+// interrupted by an earlier attempt, e.g. by the part uploaded already.
+type ResumablePut interface {
+	// PutResumable puts the file like Put, the part uploaded by an earlier
+	// attempt of the same file is kept and only the rest is read by RangeRead.
+	PutResumable(ctx context.Context, dstDir model.Obj, file model.FileStreamer, up UpdateProgress) error
+}
+
+// PutRapid is implemented by the drivers whose servers create a file by the
+// hashes of its content if they have the content already, which is known as
+// rapid upload.
+type PutRapid interface {
+	// PutRapid creates the file by the hashes given by file.GetHash(), only
+	// the small parts asked by the server are read by RangeRead.
+	// return errs.NotImplement if the hashes needed are missing or the server
+	// doesn't have the content, then the file is uploaded by Put
+	PutRapid(ctx context.Context, dstDir model.Obj, file model.FileStreamer) (model.Obj, error)
+}
+
+// SetModTime is implemented by the drivers which can change the times of an
+// obj. op.Put also sets the ModTime of the stream with it after Put, unless the
+// obj put already has it.
+type Purge interface {
+	// Purge removes the data kept locally for the storage, e.g. a cache in
+	// the data dir, it's called after the storage is deleted
+	Purge(ctx context.Context) error
+}
+
+type SetModTime interface {
+	// SetModTime sets the modified time of the obj, and the created time if
+	// it isn't zero and the storage supports
+	SetModTime(ctx context.Context, obj model.Obj, modified, created time.Time) error
+}
+
 type PutURL interface {
 	// PutURL directly put a URL into the storage
 	// Applicable to index-based drivers like URL-Tree or drivers that support uploading files as URLs
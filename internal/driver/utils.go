@@ -39,7 +39,7 @@ type RateLimitFile = stream.RateLimitFile
 func NewLimitedUploadStream(ctx context.Context, r io.Reader) *RateLimitReader {
 	return &RateLimitReader{
 		Reader:  r,
-		Limiter: stream.ServerUploadLimit,
+		Limiter: stream.UploadLimiter(ctx),
 		Ctx:     ctx,
 	}
 }
@@ -47,13 +47,19 @@ func NewLimitedUploadStream(ctx context.Context, r io.Reader) *RateLimitReader {
 func NewLimitedUploadFile(ctx context.Context, f model.File) *RateLimitFile {
 	return &RateLimitFile{
 		File:    f,
-		Limiter: stream.ServerUploadLimit,
+		Limiter: stream.UploadLimiter(ctx),
 		Ctx:     ctx,
 	}
 }
 
+// NewTaskLimitedStream only applies the limits of the task uploading r,
+// for storages which are not slowed down by `stream.ServerUploadLimit`.
+func NewTaskLimitedStream(ctx context.Context, r io.Reader) io.Reader {
+	return stream.TaskLimitReader(ctx, r)
+}
+
 func ServerUploadLimitWaitN(ctx context.Context, n int) error {
-	return stream.ServerUploadLimit.WaitN(ctx, n)
+	return stream.UploadLimiter(ctx).WaitN(ctx, n)
 }
 
 type ReaderWithCtx = stream.ReaderWithCtx
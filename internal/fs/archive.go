@@ -30,6 +30,12 @@ import (
 type ArchiveDownloadTask struct {
 	TaskData
 	model.ArchiveDecompressArgs
+	// the paths the upload tasks write to
+	Outputs []string `json:"outputs,omitempty"`
+}
+
+func (t *ArchiveDownloadTask) GetOutputs() []string {
+	return t.Outputs
 }
 
 func (t *ArchiveDownloadTask) GetName() string {
@@ -38,6 +44,11 @@ func (t *ArchiveDownloadTask) GetName() string {
 }
 
 func (t *ArchiveDownloadTask) Run() error {
+	release, err := ArchiveDownloadScheduler.Acquire(&t.TaskExtension)
+	if err != nil {
+		return err
+	}
+	defer release()
 	if t.SrcStorage == nil {
 		if srcStorage, _, err := op.GetStorageAndActualPath(t.SrcStorageMp); err == nil {
 			t.SrcStorage = srcStorage
@@ -111,10 +122,24 @@ func (t *ArchiveDownloadTask) RunWithoutPushUploadTask() (*ArchiveContentUploadT
 		return nil, err
 	}
 	baseName := strings.TrimSuffix(srcObj.GetName(), stdpath.Ext(srcObj.GetName()))
+	dstDirPath := stdpath.Join(t.DstStorageMp, t.DstActualPath)
+	if t.PutIntoNewDir {
+		t.Outputs = []string{stdpath.Join(dstDirPath, baseName)}
+	} else {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		t.Outputs = make([]string, 0, len(entries))
+		for _, entry := range entries {
+			t.Outputs = append(t.Outputs, stdpath.Join(dstDirPath, entry.Name()))
+		}
+	}
 	uploadTask := &ArchiveContentUploadTask{
 		TaskExtension: task.TaskExtension{
-			Creator: t.Creator,
-			ApiUrl:  t.ApiUrl,
+			Creator:  t.Creator,
+			ApiUrl:   t.ApiUrl,
+			Priority: t.Priority,
 		},
 		ObjName:       baseName,
 		InPlace:       !t.PutIntoNewDir,
@@ -122,12 +147,15 @@ func (t *ArchiveDownloadTask) RunWithoutPushUploadTask() (*ArchiveContentUploadT
 		DstActualPath: t.DstActualPath,
 		dstStorage:    t.DstStorage,
 		DstStorageMp:  t.DstStorageMp,
-		overwrite:     t.Overwrite,
+		Overwrite:     t.Overwrite,
 	}
 	return uploadTask, nil
 }
 
-var ArchiveDownloadTaskManager *tache.Manager[*ArchiveDownloadTask]
+var (
+	ArchiveDownloadTaskManager *tache.Manager[*ArchiveDownloadTask]
+	ArchiveDownloadScheduler   = task.NewScheduler("decompress")
+)
 
 type ArchiveContentUploadTask struct {
 	task.TaskExtension
@@ -140,18 +168,38 @@ type ArchiveContentUploadTask struct {
 	DstStorageMp  string
 	finalized     bool
 	groupID       string
-	overwrite     bool
+	Overwrite     bool
 }
 
 func (t *ArchiveContentUploadTask) GetName() string {
 	return fmt.Sprintf("upload %s to [%s](%s)", t.ObjName, t.DstStorageMp, t.DstActualPath)
 }
 
+func (t *ArchiveContentUploadTask) GetSrcPath() string {
+	return ""
+}
+
+func (t *ArchiveContentUploadTask) GetDstPath() string {
+	return stdpath.Join(t.DstStorageMp, t.DstActualPath, t.ObjName)
+}
+
 func (t *ArchiveContentUploadTask) GetStatus() string {
 	return t.status
 }
 
 func (t *ArchiveContentUploadTask) Run() error {
+	release, err := ArchiveContentUploadScheduler.Acquire(&t.TaskExtension)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if t.dstStorage == nil {
+		if dstStorage, _, err := op.GetStorageAndActualPath(t.DstStorageMp); err == nil {
+			t.dstStorage = dstStorage
+		} else {
+			return err
+		}
+	}
 	t.ClearEndTime()
 	t.SetStartTime(time.Now())
 	defer func() { t.SetEndTime(time.Now()) }()
@@ -180,6 +228,11 @@ func (t *ArchiveContentUploadTask) SetRetry(retry int, maxRetry int) {
 	}
 }
 
+// Recoverable reports whether the decompressed files are still in the temp dir.
+func (t *ArchiveContentUploadTask) Recoverable() bool {
+	return isFinished(t.GetState()) || utils.Exists(t.FilePath)
+}
+
 func (t *ArchiveContentUploadTask) RunWithNextTaskCallback(f func(nextTask *ArchiveContentUploadTask) error) error {
 	info, err := os.Stat(t.FilePath)
 	if err != nil {
@@ -216,8 +269,9 @@ func (t *ArchiveContentUploadTask) RunWithNextTaskCallback(f func(nextTask *Arch
 			}
 			err = f(&ArchiveContentUploadTask{
 				TaskExtension: task.TaskExtension{
-					Creator: t.Creator,
-					ApiUrl:  t.ApiUrl,
+					Creator:  t.Creator,
+					ApiUrl:   t.ApiUrl,
+					Priority: t.Priority,
 				},
 				ObjName:       entry.Name(),
 				InPlace:       false,
@@ -226,7 +280,7 @@ func (t *ArchiveContentUploadTask) RunWithNextTaskCallback(f func(nextTask *Arch
 				dstStorage:    t.dstStorage,
 				DstStorageMp:  t.DstStorageMp,
 				groupID:       t.groupID,
-				overwrite:     t.overwrite,
+				Overwrite:     t.Overwrite,
 			})
 			if err != nil {
 				es = stderrors.Join(es, err)
@@ -236,7 +290,7 @@ func (t *ArchiveContentUploadTask) RunWithNextTaskCallback(f func(nextTask *Arch
 			return es
 		}
 	} else {
-		if !t.overwrite {
+		if !t.Overwrite {
 			dstPath := stdpath.Join(t.DstActualPath, t.ObjName)
 			if res, _ := op.Get(t.Ctx(), t.dstStorage, dstPath); res != nil {
 				return errs.ObjectAlreadyExists
@@ -343,10 +397,24 @@ func (m *archiveContentUploadTaskManagerType) RemoveByCondition(condition func(t
 	}
 }
 
-var ArchiveContentUploadTaskManager = &archiveContentUploadTaskManagerType{
-	Manager: nil,
+// TmpFiles returns the decompressed files of the tasks which must survive a restart.
+func (m *archiveContentUploadTaskManagerType) TmpFiles() []string {
+	var res []string
+	for _, t := range m.GetAll() {
+		if !t.finalized && !isFinished(t.GetState()) {
+			res = append(res, t.FilePath)
+		}
+	}
+	return res
 }
 
+var (
+	ArchiveContentUploadTaskManager = &archiveContentUploadTaskManagerType{
+		Manager: nil,
+	}
+	ArchiveContentUploadScheduler = task.NewScheduler("decompress_upload")
+)
+
 func archiveMeta(ctx context.Context, path string, args model.ArchiveMetaArgs) (*model.ArchiveMetaProvider, error) {
 	storage, actualPath, err := op.GetStorageAndActualPath(path)
 	if err != nil {
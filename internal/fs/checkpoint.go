@@ -0,0 +1,289 @@
+package fs
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	stdpath "path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	gocache "github.com/OpenListTeam/go-cache"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// the checkpoints of the copies not finished are removed after
+	checkpointRetention = 7 * 24 * time.Hour
+	// the mismatches listed in the error of a verify task
+	maxMismatchesShown = 10
+	// the dst dirs are listed again after, for the files skipped by hashes
+	dstIndexExpiration = 10 * time.Minute
+	// the longest src path kept in a checkpoint, as the column is limited
+	maxCheckpointPath = 512
+)
+
+// cleanCheckpoints removes the checkpoints of the copies not finished in the
+// retention.
+func cleanCheckpoints() {
+	if err := db.DeleteTransferCheckpointsBefore(time.Now().Add(-checkpointRetention)); err != nil {
+		log.Errorf("failed clean transfer checkpoints: %+v", err)
+	}
+}
+
+// checkpointKey is decided by the src and the dst, so that the copy added
+// again after the task is lost also skips the files copied.
+func checkpointKey(t *FileTransferTask) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s\n%s\n%s\n%s", t.SrcStorageMp, t.SrcActualPath, t.DstStorageMp, t.DstActualPath)))
+	return hex.EncodeToString(sum[:])
+}
+
+func (t *FileTransferTask) saveCheckpoint(srcObj model.Obj) {
+	if t.Checkpoint == "" || len(t.SrcActualPath) > maxCheckpointPath {
+		return
+	}
+	err := db.CreateTransferCheckpoint(&model.TransferCheckpoint{
+		RootKey:  t.Checkpoint,
+		SrcPath:  t.SrcActualPath,
+		Size:     srcObj.GetSize(),
+		Modified: srcObj.ModTime(),
+		Created:  time.Now(),
+	})
+	if err != nil {
+		log.Errorf("failed save transfer checkpoint: %+v", err)
+	}
+}
+
+// identical tells whether dstObj is a copy of srcObj, by the checkpoint
+// saved when it was copied, or by the modified time or a hash. The src of a
+// move is removed after, so only the same hash is trusted for it.
+func (t *FileTransferTask) identical(srcObj, dstObj model.Obj) bool {
+	if dstObj.IsDir() || srcObj.GetSize() != dstObj.GetSize() {
+		return false
+	}
+	if t.TaskType == move {
+		return t.sameHash(srcObj, dstObj)
+	}
+	if t.Checkpoint != "" {
+		if c, err := db.GetTransferCheckpoint(t.Checkpoint, t.SrcActualPath); err == nil &&
+			c.Size == srcObj.GetSize() && c.Modified.Unix() == srcObj.ModTime().Unix() {
+			return true
+		}
+	}
+	if !srcObj.ModTime().IsZero() && srcObj.ModTime().Unix() == dstObj.ModTime().Unix() {
+		return true
+	}
+	dstHash := dstObj.GetHash()
+	for ht, sum := range srcObj.GetHash().All() {
+		if sum != "" && strings.EqualFold(sum, dstHash.GetHash(ht)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameHash compares the hashes of the src and the dst obj of the same name,
+// which are computed if the storages give none.
+func (t *FileTransferTask) sameHash(srcObj, dstObj model.Obj) bool {
+	limit := int64(setting.GetInt(conf.HashSizeLimit, 0)) << 20
+	ht := commonHashType(srcObj, dstObj)
+	srcSum, err := op.GetObjHash(t.Ctx(), t.SrcStorage, t.SrcActualPath, srcObj, ht, limit)
+	if err != nil || srcSum == "" {
+		return false
+	}
+	dstPath := stdpath.Join(t.DstActualPath, srcObj.GetName())
+	dstSum, err := op.GetObjHash(t.Ctx(), t.DstStorage, dstPath, dstObj, ht, limit)
+	if err != nil {
+		log.Warnf("failed get hash of [%s]: %+v", dstPath, err)
+	}
+	return dstSum != "" && strings.EqualFold(srcSum, dstSum)
+}
+
+// commonHashType prefers a hash both the objs give, or MD5 to compute.
+func commonHashType(srcObj, dstObj model.Obj) *utils.HashType {
+	dstHash := dstObj.GetHash()
+	for typ, sum := range srcObj.GetHash().All() {
+		if sum != "" && dstHash.GetHash(typ) != "" {
+			return typ
+		}
+	}
+	return utils.MD5
+}
+
+// dstIndex keeps the files of a dst dir by size and the hashes computed, so
+// that the dir is listed and each file is hashed once for all the files
+// copied into it.
+type dstIndex struct {
+	bySize map[int64][]model.Obj
+	mu     sync.Mutex
+	sums   map[string]string
+}
+
+var (
+	dstIndexes = gocache.NewMemCache(gocache.WithShards[*dstIndex](16))
+	dstIndexG  singleflight.Group[*dstIndex]
+)
+
+func (t *FileTransferTask) dstIndex() (*dstIndex, error) {
+	key := stdpath.Join(t.DstStorageMp, t.DstActualPath)
+	if idx, ok := dstIndexes.Get(key); ok {
+		return idx, nil
+	}
+	idx, err, _ := dstIndexG.Do(key, func() (*dstIndex, error) {
+		dstObjs, err := op.List(t.Ctx(), t.DstStorage, t.DstActualPath, model.ListArgs{})
+		if err != nil {
+			return nil, err
+		}
+		idx := &dstIndex{bySize: make(map[int64][]model.Obj), sums: make(map[string]string)}
+		for _, obj := range dstObjs {
+			if !obj.IsDir() {
+				idx.bySize[obj.GetSize()] = append(idx.bySize[obj.GetSize()], obj)
+			}
+		}
+		dstIndexes.Set(key, idx, gocache.WithEx[*dstIndex](dstIndexExpiration))
+		return idx, nil
+	})
+	return idx, err
+}
+
+// sum returns the hash of the dst obj, which is computed once.
+func (idx *dstIndex) sum(t *FileTransferTask, obj model.Obj, ht *utils.HashType, limit int64) string {
+	key := ht.Name + "/" + obj.GetName()
+	idx.mu.Lock()
+	sum, ok := idx.sums[key]
+	idx.mu.Unlock()
+	if ok {
+		return sum
+	}
+	dstPath := stdpath.Join(t.DstActualPath, obj.GetName())
+	sum, err := op.GetObjHash(t.Ctx(), t.DstStorage, dstPath, obj, ht, limit)
+	if err != nil {
+		log.Warnf("failed get hash of [%s]: %+v", dstPath, err)
+	}
+	idx.mu.Lock()
+	idx.sums[key] = sum
+	idx.mu.Unlock()
+	return sum
+}
+
+// sameHashExists finds the file in the dst dir with the same content as
+// srcObj by the hashes, which are computed if the storages give none.
+func (t *FileTransferTask) sameHashExists(srcObj model.Obj) (string, bool) {
+	idx, err := t.dstIndex()
+	if err != nil {
+		return "", false
+	}
+	limit := int64(setting.GetInt(conf.HashSizeLimit, 0)) << 20
+	srcSums := make(map[*utils.HashType]string)
+	for _, dstObj := range idx.bySize[srcObj.GetSize()] {
+		ht := commonHashType(srcObj, dstObj)
+		srcSum, ok := srcSums[ht]
+		if !ok {
+			srcSum, err = op.GetObjHash(t.Ctx(), t.SrcStorage, t.SrcActualPath, srcObj, ht, limit)
+			if err != nil {
+				log.Warnf("failed get hash of [%s]: %+v", t.SrcActualPath, err)
+			}
+			srcSums[ht] = srcSum
+		}
+		if srcSum == "" {
+			continue
+		}
+		if dstSum := idx.sum(t, dstObj, ht, limit); dstSum != "" && strings.EqualFold(srcSum, dstSum) {
+			return dstObj.GetName(), true
+		}
+	}
+	return "", false
+}
+
+func (t *FileTransferTask) newVerifyTask() *FileTransferTask {
+	return &FileTransferTask{
+		TaskType: verify,
+		TaskData: TaskData{
+			TaskExtension: task.TaskExtension{
+				Creator:  t.Creator,
+				ApiUrl:   t.ApiUrl,
+				Priority: t.Priority,
+			},
+			SrcStorage:    t.SrcStorage,
+			DstStorage:    t.DstStorage,
+			SrcActualPath: t.SrcActualPath,
+			DstActualPath: t.DstActualPath,
+			SrcStorageMp:  t.SrcStorageMp,
+			DstStorageMp:  t.DstStorageMp,
+		},
+		Checkpoint: t.Checkpoint,
+	}
+}
+
+// verify compares the dir copied with the src, the checkpoints are removed
+// if all the files are copied.
+func (t *FileTransferTask) verify() error {
+	var mismatches []string
+	dstPath := stdpath.Join(t.DstActualPath, stdpath.Base(t.SrcActualPath))
+	files, err := t.verifyDir(t.SrcActualPath, dstPath, &mismatches)
+	if err != nil {
+		return err
+	}
+	if len(mismatches) > 0 {
+		shown := mismatches[:min(len(mismatches), maxMismatchesShown)]
+		return errors.Errorf("%d of %d files mismatched: %s", len(mismatches), files, strings.Join(shown, "; "))
+	}
+	if err = db.DeleteTransferCheckpoints(t.Checkpoint); err != nil {
+		log.Errorf("failed delete transfer checkpoints: %+v", err)
+	}
+	t.Status = fmt.Sprintf("verified %d files", files)
+	return nil
+}
+
+func (t *FileTransferTask) verifyDir(srcPath, dstPath string, mismatches *[]string) (int, error) {
+	t.Status = "verifying " + srcPath
+	srcObjs, err := op.List(t.Ctx(), t.SrcStorage, srcPath, model.ListArgs{})
+	if err != nil {
+		return 0, errors.WithMessagef(err, "failed list src [%s] objs", srcPath)
+	}
+	dstObjs, err := op.List(t.Ctx(), t.DstStorage, dstPath, model.ListArgs{Refresh: true})
+	if err != nil {
+		return 0, errors.WithMessagef(err, "failed list dst [%s] objs", dstPath)
+	}
+	dstMap := make(map[string]model.Obj, len(dstObjs))
+	for _, obj := range dstObjs {
+		dstMap[obj.GetName()] = obj
+	}
+	files := 0
+	for _, srcObj := range srcObjs {
+		if err := t.Ctx().Err(); err != nil {
+			return files, err
+		}
+		name := srcObj.GetName()
+		dstObj, ok := dstMap[name]
+		switch {
+		case !ok:
+			*mismatches = append(*mismatches, "missing "+stdpath.Join(dstPath, name))
+		case srcObj.IsDir() != dstObj.IsDir():
+			*mismatches = append(*mismatches, "type differs "+stdpath.Join(dstPath, name))
+		case srcObj.IsDir():
+			n, err := t.verifyDir(stdpath.Join(srcPath, name), stdpath.Join(dstPath, name), mismatches)
+			if err != nil {
+				return files, err
+			}
+			files += n
+			continue
+		case srcObj.GetSize() != dstObj.GetSize():
+			*mismatches = append(*mismatches, fmt.Sprintf("size differs %s (%d != %d)", stdpath.Join(dstPath, name), srcObj.GetSize(), dstObj.GetSize()))
+		}
+		if !srcObj.IsDir() {
+			files++
+		}
+	}
+	return files, nil
+}
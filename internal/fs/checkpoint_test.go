@@ -0,0 +1,206 @@
+package fs
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	// the local storage gives no hash, they are computed by reading the files
+	if err = op.SaveSettingItem(&model.SettingItem{Key: conf.HashSizeLimit, Value: "1", Type: conf.TypeNumber}); err != nil {
+		panic(err)
+	}
+}
+
+// localStorage mounts a local storage with the files, and returns it with its root.
+func localStorage(t *testing.T, mountPath string, files map[string]string) (driver.Driver, string) {
+	root := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+	id, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: mountPath,
+		Addition:  `{"root_folder_path":"` + root + `"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = op.DeleteStorageById(ctx, id) })
+	storage, err := op.GetStorageByMountPath(mountPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage, root
+}
+
+func TestSameHashExists(t *testing.T) {
+	src, _ := localStorage(t, "/src", map[string]string{"x.bin": "content", "y.bin": "other!!", "z.bin": "nomatch"})
+	dst, dstRoot := localStorage(t, "/dst", map[string]string{"a.bin": "content", "b.bin": "other!!", "c.bin": "content!"})
+	check := func(name, expect string) {
+		t.Helper()
+		tsk := &FileTransferTask{TaskData: TaskData{
+			SrcStorage:    src,
+			DstStorage:    dst,
+			SrcActualPath: "/" + name,
+			DstActualPath: "/",
+			SrcStorageMp:  "/src",
+			DstStorageMp:  "/dst",
+		}}
+		tsk.SetCtx(context.Background())
+		srcObj, err := op.Get(context.Background(), src, tsk.SrcActualPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := tsk.sameHashExists(srcObj)
+		if got != expect || ok != (expect != "") {
+			t.Errorf("%s: expect %q, got %q, %v", name, expect, got, ok)
+		}
+	}
+	check("x.bin", "a.bin")
+	check("y.bin", "b.bin")
+	check("z.bin", "")
+
+	idx, ok := dstIndexes.Get("/dst")
+	if !ok {
+		t.Fatal("the dst dir should be indexed")
+	}
+	// the file of another size is never hashed
+	if len(idx.bySize[7]) != 2 || len(idx.sums) != 2 {
+		t.Errorf("unexpected index: %+v", idx)
+	}
+	// the dst dir is listed once
+	if err := os.WriteFile(filepath.Join(dstRoot, "d.bin"), []byte("nomatch"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	check("z.bin", "")
+	dstIndexes.Del("/dst")
+}
+
+func TestIdentical(t *testing.T) {
+	src, srcRoot := localStorage(t, "/src_identical", map[string]string{"same.bin": "content", "other.bin": "content"})
+	dst, dstRoot := localStorage(t, "/dst_identical", map[string]string{"same.bin": "content", "other.bin": "CONTENT"})
+	// all the files have the same modified time
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, root := range []string{srcRoot, dstRoot} {
+		for _, name := range []string{"same.bin", "other.bin"} {
+			if err := os.Chtimes(filepath.Join(root, name), modified, modified); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	check := func(typ taskType, name string, expect bool) {
+		t.Helper()
+		tsk := &FileTransferTask{TaskType: typ, TaskData: TaskData{
+			SrcStorage:    src,
+			DstStorage:    dst,
+			SrcActualPath: "/" + name,
+			DstActualPath: "/",
+		}}
+		tsk.SetCtx(context.Background())
+		srcObj, err := op.Get(context.Background(), src, tsk.SrcActualPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dstObj, err := op.Get(context.Background(), dst, tsk.SrcActualPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tsk.identical(srcObj, dstObj); got != expect {
+			t.Errorf("%s %s: expect %v, got %v", typ, name, expect, got)
+		}
+	}
+	check(copy, "same.bin", true)
+	check(copy, "other.bin", true)
+	// the src of a move is removed, so the content must be the same
+	check(move, "same.bin", true)
+	check(move, "other.bin", false)
+}
+
+func TestCheckpoint(t *testing.T) {
+	src, srcRoot := localStorage(t, "/src_checkpoint", map[string]string{"a.bin": "content"})
+	dst, _ := localStorage(t, "/dst_checkpoint", map[string]string{"a.bin": "CONTENT"})
+	if err := os.Mkdir(filepath.Join(srcRoot, "dir"), 0o777); err != nil {
+		t.Fatal(err)
+	}
+	root := &FileTransferTask{TaskType: copy, Root: true, TaskData: TaskData{
+		SrcStorage:    src,
+		DstStorage:    dst,
+		SrcActualPath: "/dir",
+		DstActualPath: "/",
+		SrcStorageMp:  "/src_checkpoint",
+		DstStorageMp:  "/dst_checkpoint",
+	}}
+	root.SetCtx(context.Background())
+	if err := root.RunWithNextTaskCallback(func(*FileTransferTask) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if root.Checkpoint != checkpointKey(root) {
+		t.Fatalf("unexpected checkpoint: %q", root.Checkpoint)
+	}
+	// the root is kept for the task recovered, whose checkpoint is set
+	data, err := json.Marshal(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recovered FileTransferTask
+	if err = json.Unmarshal(data, &recovered); err != nil {
+		t.Fatal(err)
+	}
+	if !recovered.Root || recovered.Checkpoint != root.Checkpoint {
+		t.Errorf("unexpected task recovered: %s", data)
+	}
+
+	tsk := &FileTransferTask{TaskType: copy, Checkpoint: root.Checkpoint, TaskData: TaskData{
+		SrcStorage:    src,
+		DstStorage:    dst,
+		SrcActualPath: "/a.bin",
+		DstActualPath: "/",
+	}}
+	tsk.SetCtx(context.Background())
+	// the modified times differ, and the sizes are the same
+	if err = os.Chtimes(filepath.Join(srcRoot, "a.bin"), time.Now(), time.Unix(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	srcObj, err := op.Get(context.Background(), src, "/a.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstObj, err := op.Get(context.Background(), dst, "/a.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tsk.identical(srcObj, dstObj) {
+		t.Fatal("expect the file not copied yet")
+	}
+	tsk.saveCheckpoint(srcObj)
+	defer db.DeleteTransferCheckpoints(root.Checkpoint)
+	if !tsk.identical(srcObj, dstObj) {
+		t.Error("expect the file copied skipped by the checkpoint")
+	}
+	if !db.GetDb().Migrator().HasIndex(&model.TransferCheckpoint{}, "idx_transfer_checkpoint_src") {
+		t.Error("expect the checkpoints indexed by the key and the src path")
+	}
+}
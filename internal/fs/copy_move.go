@@ -10,6 +10,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
 	"github.com/OpenListTeam/OpenList/v4/internal/task"
 	"github.com/OpenListTeam/OpenList/v4/internal/task_group"
@@ -29,6 +30,8 @@ func (t taskType) String() string {
 		return "move"
 	case merge:
 		return "merge"
+	case verify:
+		return "verify"
 	default:
 		return "unknown"
 	}
@@ -38,12 +41,20 @@ const (
 	copy taskType = iota
 	move
 	merge
+	// verifies the dir copied after all its files are copied
+	verify
 )
 
 type FileTransferTask struct {
 	TaskData
 	TaskType taskType
-	groupID  string
+	// the key of the files copied of the dir copied, shared by its subtasks
+	Checkpoint string `json:"checkpoint,omitempty"`
+	// the task added by the user, not a subtask of a dir
+	Root bool `json:"root,omitempty"`
+	// skips the files whose content exists in the dst dir by another name
+	SkipSameHash bool `json:"skip_same_hash,omitempty"`
+	groupID      string
 }
 
 func (t *FileTransferTask) GetName() string {
@@ -51,6 +62,15 @@ func (t *FileTransferTask) GetName() string {
 }
 
 func (t *FileTransferTask) Run() error {
+	scheduler := CopyScheduler
+	if t.TaskType == move {
+		scheduler = MoveScheduler
+	}
+	release, err := scheduler.Acquire(&t.TaskExtension)
+	if err != nil {
+		return err
+	}
+	defer release()
 	if t.SrcStorage == nil {
 		if srcStorage, _, err := op.GetStorageAndActualPath(t.SrcStorageMp); err == nil {
 			t.SrcStorage = srcStorage
@@ -67,7 +87,10 @@ func (t *FileTransferTask) Run() error {
 	t.ClearEndTime()
 	t.SetStartTime(time.Now())
 	defer func() { t.SetEndTime(time.Now()) }()
-	return t.RunWithNextTaskCallback(func(nextTask *FileTransferTask) error {
+	if t.TaskType == verify {
+		return t.verify()
+	}
+	err = t.RunWithNextTaskCallback(func(nextTask *FileTransferTask) error {
 		task_group.TransferCoordinator.AddTask(t.groupID, nil)
 		if t.TaskType == copy || t.TaskType == merge {
 			CopyTaskManager.Add(nextTask)
@@ -76,6 +99,15 @@ func (t *FileTransferTask) Run() error {
 		}
 		return nil
 	})
+	// the checkpoint is set if a dir is copied
+	if err == nil && t.Root && t.Checkpoint != "" && t.TaskType != move && setting.GetBool(conf.TaskCopyVerify) {
+		task_group.TransferCoordinator.AppendPayload(t.groupID, task_group.GroupCallback(func(ctx context.Context, hasSuccess bool) {
+			if hasSuccess {
+				CopyTaskManager.Add(t.newVerifyTask())
+			}
+		}))
+	}
+	return err
 }
 
 func (t *FileTransferTask) OnSucceeded() {
@@ -88,7 +120,7 @@ func (t *FileTransferTask) OnFailed() {
 
 func (t *FileTransferTask) SetRetry(retry int, maxRetry int) {
 	t.TaskData.SetRetry(retry, maxRetry)
-	if retry == 0 &&
+	if t.TaskType != verify && retry == 0 &&
 		(len(t.groupID) == 0 || // 重启恢复
 			(t.GetErr() == nil && t.GetState() != tache.StatePending)) { // 手动重试
 		t.groupID = stdpath.Join(t.DstStorageMp, t.DstActualPath)
@@ -116,6 +148,9 @@ func transfer(ctx context.Context, taskType taskType, srcObjPath, dstDirPath str
 		}
 		if taskType == copy || taskType == merge {
 			err = op.Copy(ctx, srcStorage, srcObjActualPath, dstDirActualPath)
+			if err == nil {
+				keepModTime(ctx, srcStorage, srcObjActualPath, dstDirActualPath)
+			}
 			if !errors.Is(err, errs.NotImplement) && !errors.Is(err, errs.NotSupport) {
 				return nil, err
 			}
@@ -137,7 +172,9 @@ func transfer(ctx context.Context, taskType taskType, srcObjPath, dstDirPath str
 			SrcStorageMp:  srcStorage.GetStorage().MountPath,
 			DstStorageMp:  dstStorage.GetStorage().MountPath,
 		},
-		TaskType: taskType,
+		TaskType:     taskType,
+		Root:         true,
+		SkipSameHash: ctx.Value(conf.SkipSameHashKey) != nil,
 	}
 
 	t.groupID = stdpath.Join(t.DstStorageMp, t.DstActualPath)
@@ -184,6 +221,10 @@ func (t *FileTransferTask) RunWithNextTaskCallback(f func(nextTask *FileTransfer
 	}
 
 	if srcObj.IsDir() {
+		if t.Checkpoint == "" {
+			cleanCheckpoints()
+			t.Checkpoint = checkpointKey(t)
+		}
 		t.Status = "src object is dir, listing objs"
 		objs, err := op.List(t.Ctx(), t.SrcStorage, t.SrcActualPath, model.ListArgs{})
 		if err != nil {
@@ -224,8 +265,9 @@ func (t *FileTransferTask) RunWithNextTaskCallback(f func(nextTask *FileTransfer
 				TaskType: t.TaskType,
 				TaskData: TaskData{
 					TaskExtension: task.TaskExtension{
-						Creator: t.Creator,
-						ApiUrl:  t.ApiUrl,
+						Creator:  t.Creator,
+						ApiUrl:   t.ApiUrl,
+						Priority: t.Priority,
 					},
 					SrcStorage:    t.SrcStorage,
 					DstStorage:    t.DstStorage,
@@ -234,7 +276,9 @@ func (t *FileTransferTask) RunWithNextTaskCallback(f func(nextTask *FileTransfer
 					SrcStorageMp:  t.SrcStorageMp,
 					DstStorageMp:  t.DstStorageMp,
 				},
-				groupID: t.groupID,
+				Checkpoint:   t.Checkpoint,
+				SkipSameHash: t.SkipSameHash,
+				groupID:      t.groupID,
 			})
 			if err != nil {
 				return err
@@ -244,6 +288,17 @@ func (t *FileTransferTask) RunWithNextTaskCallback(f func(nextTask *FileTransfer
 		return nil
 	}
 
+	if dstObj, err := op.GetUnwrap(t.Ctx(), t.DstStorage, stdpath.Join(t.DstActualPath, srcObj.GetName())); err == nil && t.identical(srcObj, dstObj) {
+		t.Status = "skipped, the same file exists"
+		return nil
+	}
+	if t.SkipSameHash {
+		if name, ok := t.sameHashExists(srcObj); ok {
+			t.Status = fmt.Sprintf("skipped, the same file exists as [%s]", name)
+			return nil
+		}
+	}
+
 	t.Status = "getting src object link"
 	link, srcObj, err := op.Link(t.Ctx(), t.SrcStorage, t.SrcActualPath, model.LinkArgs{})
 	if err != nil {
@@ -260,10 +315,19 @@ func (t *FileTransferTask) RunWithNextTaskCallback(f func(nextTask *FileTransfer
 	}
 	t.SetTotalBytes(ss.GetSize())
 	t.Status = "uploading"
-	return op.Put(context.WithValue(t.Ctx(), conf.SkipHookKey, struct{}{}), t.DstStorage, t.DstActualPath, ss, t.SetProgress)
+	ctx := context.WithValue(t.Ctx(), conf.SkipHookKey, struct{}{})
+	ctx = context.WithValue(ctx, conf.ResumeUploadKey, struct{}{})
+	ctx = context.WithValue(ctx, conf.RapidUploadKey, struct{}{})
+	if err = op.Put(ctx, t.DstStorage, t.DstActualPath, ss, t.SetProgress); err != nil {
+		return err
+	}
+	t.saveCheckpoint(srcObj)
+	return nil
 }
 
 var (
 	CopyTaskManager *tache.Manager[*FileTransferTask]
 	MoveTaskManager *tache.Manager[*FileTransferTask]
+	CopyScheduler   = task.NewScheduler("copy")
+	MoveScheduler   = task.NewScheduler("move")
 )
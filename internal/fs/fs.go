@@ -3,6 +3,7 @@ package fs
 import (
 	"context"
 	"io"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -108,6 +109,38 @@ func Remove(ctx context.Context, path string) error {
 	return err
 }
 
+func ListVersions(ctx context.Context, path string) ([]op.FileVersion, error) {
+	res, err := listVersions(ctx, path)
+	if err != nil {
+		log.Errorf("获取版本列表失败 %s: %+v", path, err)
+	}
+	return res, err
+}
+
+func RestoreVersion(ctx context.Context, path, id string) error {
+	err := restoreVersion(ctx, path, id)
+	if err != nil {
+		log.Errorf("恢复版本失败 %s [%s]: %+v", path, id, err)
+	}
+	return err
+}
+
+func DeleteVersions(ctx context.Context, path string, ids []string) error {
+	err := deleteVersions(ctx, path, ids)
+	if err != nil {
+		log.Errorf("删除版本失败 %s: %+v", path, err)
+	}
+	return err
+}
+
+func SetModTime(ctx context.Context, path string, modified, created time.Time) error {
+	err := setModTime(ctx, path, modified, created)
+	if err != nil {
+		log.Errorf("设置修改时间失败 %s: %+v", path, err)
+	}
+	return err
+}
+
 func PutDirectly(ctx context.Context, dstDirPath string, file model.FileStreamer, skipHook ...bool) error {
 	err := putDirectly(ctx, dstDirPath, file, skipHook...)
 	if err != nil {
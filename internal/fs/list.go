@@ -36,6 +36,7 @@ func list(ctx context.Context, path string, args *ListArgs) ([]model.Obj, error)
 				return nil, errors.WithMessage(err, "对象获取失败")
 			}
 		}
+		_objs = hideVersionsDir(storage, utils.FixAndCleanPath(actualPath), _objs)
 	}
 
 	om := model.NewObjMerge()
@@ -2,6 +2,8 @@ package fs
 
 import (
 	"context"
+	stdpath "path"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
@@ -10,6 +12,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/task"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 func makeDir(ctx context.Context, path string) error {
@@ -39,6 +42,30 @@ func remove(ctx context.Context, path string) error {
 	return op.Remove(ctx, storage, actualPath)
 }
 
+func setModTime(ctx context.Context, path string, modified, created time.Time) error {
+	storage, actualPath, err := op.GetStorageAndActualPath(path)
+	if err != nil {
+		return errors.WithMessage(err, "存储获取失败")
+	}
+	return op.SetModTime(ctx, storage, actualPath, modified, created)
+}
+
+// keepModTime sets the times of the file copied in the storage to the src
+// one's, since most of the drivers copy a file as a new one.
+func keepModTime(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string) {
+	if _, ok := storage.(driver.SetModTime); !ok {
+		return
+	}
+	srcObj, err := op.Get(ctx, storage, srcPath)
+	if err != nil || srcObj.IsDir() {
+		return
+	}
+	dstPath := stdpath.Join(dstDirPath, srcObj.GetName())
+	if err = op.SetModTime(ctx, storage, dstPath, srcObj.ModTime(), srcObj.CreateTime()); err != nil {
+		log.Warnf("failed keep the modified time of %s: %+v", dstPath, err)
+	}
+}
+
 func other(ctx context.Context, args model.FsOtherArgs) (interface{}, error) {
 	storage, actualPath, err := op.GetStorageAndActualPath(args.Path)
 	if err != nil {
@@ -62,3 +89,11 @@ type TaskData struct {
 func (t *TaskData) GetStatus() string {
 	return t.Status
 }
+
+func (t *TaskData) GetSrcPath() string {
+	return stdpath.Join(t.SrcStorageMp, t.SrcActualPath)
+}
+
+func (t *TaskData) GetDstPath() string {
+	return stdpath.Join(t.DstStorageMp, t.DstActualPath)
+}
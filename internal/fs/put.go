@@ -2,8 +2,11 @@ package fs
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
+	"os"
 	stdpath "path"
+	"path/filepath"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
@@ -14,6 +17,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
 	"github.com/OpenListTeam/OpenList/v4/internal/task"
 	"github.com/OpenListTeam/OpenList/v4/internal/task_group"
 	"github.com/OpenListTeam/tache"
@@ -23,12 +27,27 @@ import (
 type UploadTask struct {
 	task.TaskExtension
 	storage          driver.Driver
-	dstDirActualPath string
+	DstStorageMp     string `json:"dst_storage_mp"`
+	DstDirActualPath string `json:"dst_path"`
 	file             model.FileStreamer
+	// the spooled source, the task can only be persisted and recovered with it
+	TmpFile  string    `json:"tmp_file,omitempty"`
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Mimetype string    `json:"mimetype"`
+	Modified time.Time `json:"modified"`
 }
 
 func (t *UploadTask) GetName() string {
-	return fmt.Sprintf("上传 %s 到: [%s](%s)", t.file.GetName(), t.storage.GetStorage().MountPath, t.dstDirActualPath)
+	return fmt.Sprintf("上传 %s 到: [%s](%s)", t.Name, t.DstStorageMp, t.DstDirActualPath)
+}
+
+func (t *UploadTask) GetSrcPath() string {
+	return ""
+}
+
+func (t *UploadTask) GetDstPath() string {
+	return stdpath.Join(t.DstStorageMp, t.DstDirActualPath, t.Name)
 }
 
 func (t *UploadTask) GetStatus() string {
@@ -36,29 +55,132 @@ func (t *UploadTask) GetStatus() string {
 }
 
 func (t *UploadTask) Run() error {
+	release, err := UploadScheduler.Acquire(&t.TaskExtension)
+	if err != nil {
+		return err
+	}
+	defer release()
 	t.ClearEndTime()
 	t.SetStartTime(time.Now())
 	defer func() { t.SetEndTime(time.Now()) }()
-	return op.Put(context.WithValue(t.Ctx(), conf.SkipHookKey, struct{}{}), t.storage, t.dstDirActualPath, t.file, t.SetProgress)
+	if t.storage == nil {
+		storage, _, err := op.GetStorageAndActualPath(t.DstStorageMp)
+		if err != nil {
+			return errors.WithMessage(err, "存储获取失败")
+		}
+		t.storage = storage
+	}
+	if t.file == nil {
+		// recovered after a restart, upload the spooled file again
+		f, err := os.Open(t.TmpFile)
+		if err != nil {
+			return errors.WithMessage(err, "临时文件打开失败")
+		}
+		s := &stream.FileStream{
+			Obj: &model.Object{
+				Name:     t.Name,
+				Size:     t.Size,
+				Modified: t.Modified,
+			},
+			Reader:       f,
+			Mimetype:     t.Mimetype,
+			WebPutAsTask: true,
+		}
+		s.Add(utils.CloseFunc(func() error {
+			return stderrors.Join(f.Close(), os.Remove(f.Name()))
+		}))
+		t.file = s
+	}
+	return op.Put(context.WithValue(t.Ctx(), conf.SkipHookKey, struct{}{}), t.storage, t.DstDirActualPath, t.file, t.SetProgress)
 }
 
 func (t *UploadTask) OnSucceeded() {
-	task_group.TransferCoordinator.Done(context.WithoutCancel(t.Ctx()), stdpath.Join(t.storage.GetStorage().MountPath, t.dstDirActualPath), true)
+	task_group.TransferCoordinator.Done(context.WithoutCancel(t.Ctx()), stdpath.Join(t.DstStorageMp, t.DstDirActualPath), true)
 }
 
 func (t *UploadTask) OnFailed() {
-	task_group.TransferCoordinator.Done(context.WithoutCancel(t.Ctx()), stdpath.Join(t.storage.GetStorage().MountPath, t.dstDirActualPath), false)
+	task_group.TransferCoordinator.Done(context.WithoutCancel(t.Ctx()), stdpath.Join(t.DstStorageMp, t.DstDirActualPath), false)
 }
 
 func (t *UploadTask) SetRetry(retry int, maxRetry int) {
 	t.TaskExtension.SetRetry(retry, maxRetry)
 	if retry == 0 &&
-		(t.GetErr() == nil && t.GetState() != tache.StatePending) { // 手动重试
-		task_group.TransferCoordinator.AddTask(stdpath.Join(t.storage.GetStorage().MountPath, t.dstDirActualPath), nil)
+		(t.file == nil || // 重启恢复
+			(t.GetErr() == nil && t.GetState() != tache.StatePending)) { // 手动重试
+		task_group.TransferCoordinator.AddTask(stdpath.Join(t.DstStorageMp, t.DstDirActualPath), nil)
 	}
 }
 
-var UploadTaskManager *tache.Manager[*UploadTask]
+// Persistable only keeps tasks whose source has been spooled to a file,
+// a stream of a client can't be read again after a restart.
+func (t *UploadTask) Persistable() bool {
+	return t.TmpFile != ""
+}
+
+func (t *UploadTask) Recoverable() bool {
+	return isFinished(t.GetState()) || utils.Exists(t.TmpFile)
+}
+
+// TmpFiles returns the temp files of the tasks which must survive a restart.
+func (m *uploadTaskManagerType) TmpFiles() []string {
+	var res []string
+	for _, t := range m.GetAll() {
+		if t.TmpFile != "" && !isFinished(t.GetState()) {
+			res = append(res, t.TmpFile)
+		}
+	}
+	return res
+}
+
+type uploadTaskManagerType struct {
+	*tache.Manager[*UploadTask]
+}
+
+var (
+	UploadTaskManager = &uploadTaskManagerType{}
+	UploadScheduler   = task.NewScheduler("upload")
+)
+
+func isFinished(state tache.State) bool {
+	switch state {
+	case tache.StateSucceeded, tache.StateCanceled, tache.StateErrored, tache.StateFailed:
+		return true
+	}
+	return false
+}
+
+func isTempFile(name string) bool {
+	rel, err := filepath.Rel(conf.Conf.TempDir, name)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// spoolFile makes sure the source of an upload task is a file in the temp dir,
+// so that the task can be recovered after a restart.
+func spoolFile(file model.FileStreamer) (model.FileStreamer, string, error) {
+	if f, ok := file.GetFile().(*os.File); ok && isTempFile(f.Name()) {
+		return file, f.Name(), nil
+	}
+	cache, err := file.CacheFullAndWriter(nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	tmpF, err := utils.CreateTempFile(cache, file.GetSize())
+	if err != nil {
+		return nil, "", err
+	}
+	s := &stream.FileStream{
+		Obj:          file,
+		Reader:       tmpF,
+		Mimetype:     file.GetMimetype(),
+		WebPutAsTask: true,
+	}
+	// the original stream, e.g. its memory cache, is released with the new one
+	s.Add(file)
+	s.Add(utils.CloseFunc(func() error {
+		return stderrors.Join(tmpF.Close(), os.Remove(tmpF.Name()))
+	}))
+	return s, tmpF.Name(), nil
+}
 
 // putAsTask add as a put task and return immediately
 func putAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer) (task.TaskExtensionInfo, error) {
@@ -77,6 +199,13 @@ func putAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer)
 		//file.SetReader(tempFile)
 		//file.SetTmpFile(tempFile)
 	}
+	var tmpFile string
+	if file.NeedStore() && conf.Conf.Tasks.Upload.TaskPersistant {
+		file, tmpFile, err = spoolFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "临时文件创建失败")
+		}
+	}
 	taskCreator, _ := ctx.Value(conf.UserKey).(*model.User) // taskCreator is nil when convert failed
 	t := &UploadTask{
 		TaskExtension: task.TaskExtension{
@@ -84,8 +213,14 @@ func putAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer)
 			ApiUrl:  common.GetApiUrl(ctx),
 		},
 		storage:          storage,
-		dstDirActualPath: dstDirActualPath,
+		DstStorageMp:     storage.GetStorage().MountPath,
+		DstDirActualPath: dstDirActualPath,
 		file:             file,
+		TmpFile:          tmpFile,
+		Name:             file.GetName(),
+		Size:             file.GetSize(),
+		Mimetype:         file.GetMimetype(),
+		Modified:         file.ModTime(),
 	}
 	t.SetTotalBytes(file.GetSize())
 	task_group.TransferCoordinator.AddTask(stdpath.Join(storage.GetStorage().MountPath, dstDirActualPath), nil)
@@ -0,0 +1,112 @@
+package fs
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/tache"
+)
+
+func TestSpoolFile(t *testing.T) {
+	withStageDir(t)
+	file := &stream.FileStream{
+		Obj:          &model.Object{Name: "a.txt", Size: 5},
+		Reader:       strings.NewReader("hello"),
+		WebPutAsTask: true,
+	}
+	spooled, tmpFile, err := spoolFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isTempFile(tmpFile) || spooled.GetName() != "a.txt" {
+		t.Fatalf("unexpected spooled %s", tmpFile)
+	}
+	if data, err := os.ReadFile(tmpFile); err != nil || string(data) != "hello" {
+		t.Errorf("unexpected spooled file %q, %v", data, err)
+	}
+	// a file in the temp dir is kept as is
+	if again, name, err := spoolFile(spooled); err != nil || again != spooled || name != tmpFile {
+		t.Errorf("expect the file kept, got %s, %v", name, err)
+	}
+	if err = spooled.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(tmpFile); !os.IsNotExist(err) {
+		t.Errorf("expect the spooled file removed, got %v", err)
+	}
+}
+
+func TestRecoverUploadTask(t *testing.T) {
+	withStageDir(t)
+	UploadScheduler.SetWorkers(1)
+	_, root := localStorage(t, "/upload", nil)
+	tmpFile := filepath.Join(t.TempDir(), "spooled")
+	if err := os.WriteFile(tmpFile, []byte("hello"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if (&UploadTask{}).Persistable() {
+		t.Error("a task without a spooled file shouldn't be persisted")
+	}
+	data, err := json.Marshal(&UploadTask{
+		DstStorageMp:     "/upload",
+		DstDirActualPath: "/",
+		TmpFile:          tmpFile,
+		Name:             "a.txt",
+		Size:             5,
+		Modified:         time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// as loaded after a restart, without the storage and the stream
+	task := &UploadTask{}
+	if err = json.Unmarshal(data, task); err != nil {
+		t.Fatal(err)
+	}
+	task.SetCtx(context.Background())
+	if !task.Persistable() || !task.Recoverable() {
+		t.Fatal("expect the task persisted and recovered")
+	}
+	if err = task.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if data, err = os.ReadFile(filepath.Join(root, "a.txt")); err != nil || string(data) != "hello" {
+		t.Errorf("unexpected file %q, %v", data, err)
+	}
+	if err = task.file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(tmpFile); !os.IsNotExist(err) {
+		t.Errorf("expect the spooled file removed, got %v", err)
+	}
+	// the file is gone, only a finished task may be recovered
+	if task.Recoverable() {
+		t.Error("expect the pending task without its file dropped")
+	}
+	task.SetState(tache.StateSucceeded)
+	if !task.Recoverable() {
+		t.Error("expect the finished task kept")
+	}
+}
+
+func TestUploadTaskTmpFiles(t *testing.T) {
+	old := UploadTaskManager.Manager
+	// the tasks added are never run by a stopped manager
+	UploadTaskManager.Manager = tache.NewManager[*UploadTask](tache.WithRunning(false))
+	t.Cleanup(func() { UploadTaskManager.Manager = old })
+	done := &UploadTask{TmpFile: "done", Name: "b.txt"}
+	done.SetState(tache.StateSucceeded)
+	UploadTaskManager.Add(&UploadTask{TmpFile: "pending", Name: "a.txt"})
+	UploadTaskManager.Add(&UploadTask{Name: "c.txt"})
+	UploadTaskManager.Add(done)
+	if files := UploadTaskManager.TmpFiles(); len(files) != 1 || files[0] != "pending" {
+		t.Errorf("expect only the file of the pending task kept, got %v", files)
+	}
+}
@@ -0,0 +1,435 @@
+package fs
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"os"
+	stdpath "path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrUploadStageNotFound  = errors.New("upload not found or expired")
+	ErrUploadOffsetMismatch = errors.New("upload offset mismatch")
+	ErrUploadStageBusy      = errors.New("upload is being written by another request")
+	ErrUploadStageOverflow  = errors.New("upload exceeds the declared size")
+	ErrUploadStageFull      = errors.New("not enough space to stage the upload")
+)
+
+// UploadStageTTL is how long an unfinished upload is kept after its last write.
+var UploadStageTTL = 24 * time.Hour
+
+// UploadStage is a resumable upload whose bytes are spooled under TempDir
+// until the whole file has arrived and can be put to the storage. It is
+// described by a json file beside the data, so that it survives a restart.
+type UploadStage struct {
+	ID       string            `json:"id"`
+	Creator  uint              `json:"creator"`
+	Path     string            `json:"path"`
+	Size     int64             `json:"size"`
+	Mimetype string            `json:"mimetype"`
+	Modified time.Time         `json:"modified"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// mu is held while writing, offset and expires can be read meanwhile
+	mu      sync.Mutex
+	offset  atomic.Int64
+	expires atomic.Int64
+	file    *os.File
+	removed bool
+}
+
+var (
+	stagesMu     sync.Mutex
+	stages       = make(map[string]*UploadStage)
+	stageJanitor sync.Once
+)
+
+const uploadStageMetaSuffix = ".json"
+
+func uploadStageDir() string {
+	return filepath.Join(conf.Conf.TempDir, "upload")
+}
+
+func startStageJanitor() {
+	stageJanitor.Do(func() {
+		go cleanUploadStages()
+	})
+}
+
+// NewUploadStage creates an empty stage for dstPath, a file of size bytes.
+func NewUploadStage(creator *model.User, dstPath string, size int64, mimetype string, modified time.Time) (*UploadStage, error) {
+	startStageJanitor()
+	if limit := UploadStageMaxSize(); limit > 0 && StagedBytes()+size > limit {
+		return nil, ErrUploadStageFull
+	}
+	if err := os.MkdirAll(uploadStageDir(), 0o777); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	id := uuid.NewString()
+	f, err := os.Create(filepath.Join(uploadStageDir(), id))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if mimetype == "" {
+		mimetype = utils.GetMimeType(dstPath)
+	}
+	if modified.IsZero() {
+		modified = time.Now()
+	}
+	s := &UploadStage{
+		ID:       id,
+		Path:     dstPath,
+		Size:     size,
+		Mimetype: mimetype,
+		Modified: modified,
+		file:     f,
+	}
+	s.touch()
+	if creator != nil {
+		s.Creator = creator.ID
+	}
+	if err = s.save(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return nil, err
+	}
+	stagesMu.Lock()
+	stages[id] = s
+	stagesMu.Unlock()
+	return s, nil
+}
+
+// LoadUploadStages restores the stages left by the last run, the offset is
+// the size of the data spooled. The expired ones are removed, the broken ones
+// are left to the cleaning of the temp dir.
+func LoadUploadStages() {
+	entries, err := os.ReadDir(uploadStageDir())
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	stagesMu.Lock()
+	defer stagesMu.Unlock()
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), uploadStageMetaSuffix)
+		if !ok {
+			continue
+		}
+		s, err := loadUploadStage(id)
+		if err != nil {
+			log.Warnf("failed load the upload stage %s: %v", id, err)
+			continue
+		}
+		if s.expired(now) {
+			s.removed = true
+			_ = s.file.Close()
+			_ = os.Remove(s.file.Name())
+			_ = os.Remove(s.metaPath())
+			continue
+		}
+		stages[id] = s
+	}
+	if len(stages) > 0 {
+		log.Infof("恢复了 %d 个未完成的上传", len(stages))
+		startStageJanitor()
+	}
+}
+
+// UploadStageFiles returns the files of the unfinished uploads, which are
+// kept when the temp dir is cleaned.
+func UploadStageFiles() []string {
+	stagesMu.Lock()
+	defer stagesMu.Unlock()
+	var files []string
+	for _, s := range stages {
+		files = append(files, s.file.Name(), s.metaPath())
+	}
+	return files
+}
+
+func loadUploadStage(id string) (*UploadStage, error) {
+	data, err := os.ReadFile(filepath.Join(uploadStageDir(), id+uploadStageMetaSuffix))
+	if err != nil {
+		return nil, err
+	}
+	s := &UploadStage{}
+	if err = json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if s.ID != id {
+		return nil, errors.Errorf("unexpected id %s", s.ID)
+	}
+	f, err := os.OpenFile(filepath.Join(uploadStageDir(), id), os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	s.file = f
+	s.offset.Store(min(info.Size(), s.Size))
+	// the data is written last when the stage is touched
+	s.expires.Store(info.ModTime().Add(UploadStageTTL).UnixNano())
+	return s, nil
+}
+
+// save writes the description of the stage, aside and renamed so that a
+// crash doesn't leave a broken one.
+func (s *UploadStage) save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	path := s.metaPath()
+	if err = os.WriteFile(path+".tmp", data, 0o666); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(path+".tmp", path))
+}
+
+func (s *UploadStage) metaPath() string {
+	return filepath.Join(uploadStageDir(), s.ID+uploadStageMetaSuffix)
+}
+
+// SetMetadata sets the metadata given by the client, kept with the stage.
+func (s *UploadStage) SetMetadata(meta map[string]string) error {
+	s.Metadata = meta
+	return s.save()
+}
+
+func GetUploadStage(id string) (*UploadStage, error) {
+	stagesMu.Lock()
+	defer stagesMu.Unlock()
+	s, ok := stages[id]
+	if !ok || s.expired(time.Now()) {
+		return nil, ErrUploadStageNotFound
+	}
+	return s, nil
+}
+
+// FindUploadStage returns the unfinished upload of creator to dstPath with the given size,
+// used by protocols which identify an upload by its destination.
+func FindUploadStage(creator uint, dstPath string, size int64) (*UploadStage, bool) {
+	stagesMu.Lock()
+	defer stagesMu.Unlock()
+	now := time.Now()
+	for _, s := range stages {
+		if s.Creator == creator && s.Path == dstPath && s.Size == size && !s.expired(now) {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// UploadStageMaxSize returns the configured cap of StagedBytes, 0 for no limit.
+func UploadStageMaxSize() int64 {
+	return int64(setting.GetInt(conf.UploadStageMaxSize, 0)) * utils.MB
+}
+
+// StagedBytes returns the number of bytes reserved by unfinished uploads.
+func StagedBytes() int64 {
+	stagesMu.Lock()
+	defer stagesMu.Unlock()
+	var n int64
+	for _, s := range stages {
+		n += s.Size
+	}
+	return n
+}
+
+// Write writes the data of r at offset, which may not be behind the bytes
+// received so far. The offset is advanced by what was written even if r fails,
+// so that the client can resume from there.
+func (s *UploadStage) Write(offset int64, r io.Reader) (int64, error) {
+	if !s.mu.TryLock() {
+		return 0, ErrUploadStageBusy
+	}
+	defer s.mu.Unlock()
+	if s.removed {
+		return 0, ErrUploadStageNotFound
+	}
+	if offset < 0 || offset > s.offset.Load() {
+		return 0, ErrUploadOffsetMismatch
+	}
+	remain := s.Size - offset
+	n, err := utils.CopyWithBuffer(io.NewOffsetWriter(s.file, offset), io.LimitReader(r, remain))
+	s.offset.Store(offset + n)
+	s.touch()
+	if err != nil {
+		return n, errors.WithStack(err)
+	}
+	if n == remain {
+		if m, _ := r.Read(make([]byte, 1)); m > 0 {
+			return n, ErrUploadStageOverflow
+		}
+	}
+	return n, nil
+}
+
+// GetOffset returns the number of bytes received in order.
+func (s *UploadStage) GetOffset() int64 {
+	return s.offset.Load()
+}
+
+// GetExpires returns when the stage is dropped if nothing is written to it.
+func (s *UploadStage) GetExpires() time.Time {
+	return time.Unix(0, s.expires.Load())
+}
+
+func (s *UploadStage) touch() {
+	s.expires.Store(time.Now().Add(UploadStageTTL).UnixNano())
+}
+
+func (s *UploadStage) expired(now time.Time) bool {
+	return now.UnixNano() > s.expires.Load()
+}
+
+func (s *UploadStage) Complete() bool {
+	return s.GetOffset() == s.Size
+}
+
+// Range returns the value of the Range header telling a client what has been
+// received, empty if nothing has.
+func (s *UploadStage) Range() string {
+	offset := s.GetOffset()
+	if offset == 0 {
+		return ""
+	}
+	return fmt.Sprintf("bytes=0-%d", offset-1)
+}
+
+// Commit puts the completed file to its destination, directly or as an
+// upload task. The stage is gone afterwards, whatever the result.
+func (s *UploadStage) Commit(ctx context.Context, asTask bool) (task.TaskExtensionInfo, error) {
+	s.mu.Lock()
+	if s.removed {
+		s.mu.Unlock()
+		return nil, ErrUploadStageNotFound
+	}
+	if offset := s.offset.Load(); offset != s.Size {
+		s.mu.Unlock()
+		return nil, errors.Errorf("upload is incomplete: %d/%d", offset, s.Size)
+	}
+	s.removed = true
+	f := s.file
+	s.mu.Unlock()
+	stagesMu.Lock()
+	delete(stages, s.ID)
+	stagesMu.Unlock()
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return nil, errors.WithStack(err)
+	}
+	dir, name := stdpath.Split(s.Path)
+	fs := &stream.FileStream{
+		Obj: &model.Object{
+			Name:     name,
+			Size:     s.Size,
+			Modified: s.Modified,
+		},
+		Reader:       f,
+		Mimetype:     s.Mimetype,
+		WebPutAsTask: asTask,
+	}
+	_ = os.Remove(s.metaPath())
+	fs.Add(utils.CloseFunc(func() error {
+		return stderrors.Join(f.Close(), os.Remove(f.Name()))
+	}))
+	if asTask {
+		t, err := PutAsTask(ctx, dir, fs)
+		if err != nil {
+			_ = fs.Close()
+		}
+		return t, err
+	}
+	return nil, PutDirectly(ctx, dir, fs)
+}
+
+// Remove discards the stage and its spooled data.
+func (s *UploadStage) Remove() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.removed {
+		return
+	}
+	s.removed = true
+	stagesMu.Lock()
+	delete(stages, s.ID)
+	stagesMu.Unlock()
+	_ = s.file.Close()
+	_ = os.Remove(s.file.Name())
+	_ = os.Remove(s.metaPath())
+}
+
+func cleanUploadStages() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		stagesMu.Lock()
+		var expired []*UploadStage
+		for _, s := range stages {
+			if s.expired(now) {
+				expired = append(expired, s)
+			}
+		}
+		stagesMu.Unlock()
+		for _, s := range expired {
+			log.Infof("删除过期的未完成上传 %s: %s", s.ID, s.Path)
+			s.Remove()
+		}
+	}
+}
+
+// PutRange writes the Content-Range chunk [start, end] of a file of size bytes
+// to the stage of dstPath, creating it for the first chunk. Protocols which
+// send partial PUTs to the destination, like webdav and /api/fs/put, share it.
+// The caller commits the stage once it is complete.
+func PutRange(ctx context.Context, dstPath string, r io.Reader, start, end, size int64, mimetype string, modified time.Time) (*UploadStage, error) {
+	user, _ := ctx.Value(conf.UserKey).(*model.User)
+	var creator uint
+	if user != nil {
+		creator = user.ID
+	}
+	s, ok := FindUploadStage(creator, dstPath, size)
+	if !ok {
+		if start != 0 {
+			return nil, ErrUploadOffsetMismatch
+		}
+		var err error
+		s, err = NewUploadStage(user, dstPath, size, mimetype, modified)
+		if err != nil {
+			return nil, err
+		}
+	}
+	length := end - start + 1
+	n, err := s.Write(start, io.LimitReader(r, length))
+	if err != nil {
+		return s, err
+	}
+	if n != length {
+		return s, errors.Errorf("incomplete chunk, got %d of %d bytes", n, length)
+	}
+	return s, nil
+}
@@ -0,0 +1,137 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+)
+
+// withStageDir spools the stages of the test under a temp dir of its own.
+func withStageDir(t *testing.T) {
+	old := conf.Conf.TempDir
+	conf.Conf.TempDir = t.TempDir()
+	t.Cleanup(func() { conf.Conf.TempDir = old })
+}
+
+// restart forgets the stages in memory, as if the server was restarted.
+func restart() {
+	stagesMu.Lock()
+	for id, s := range stages {
+		_ = s.file.Close()
+		delete(stages, id)
+	}
+	stagesMu.Unlock()
+	LoadUploadStages()
+}
+
+func TestUploadStage(t *testing.T) {
+	withStageDir(t)
+	_, root := localStorage(t, "/stage", nil)
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	s, err := NewUploadStage(nil, "/stage/a.txt", 10, "", modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SetMetadata(map[string]string{"filename": "a.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.Write(0, strings.NewReader("hello")); err != nil || n != 5 {
+		t.Fatalf("unexpected write %d, %v", n, err)
+	}
+	if _, err = s.Write(6, strings.NewReader("x")); !errors.Is(err, ErrUploadOffsetMismatch) {
+		t.Errorf("expect the offset mismatch, got %v", err)
+	}
+	if s.Range() != "bytes=0-4" || s.Complete() {
+		t.Errorf("unexpected range %s", s.Range())
+	}
+	if files := UploadStageFiles(); len(files) != 2 {
+		t.Errorf("expect the data and the description kept, got %v", files)
+	}
+
+	restart()
+	got, err := GetUploadStage(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GetOffset() != 5 || got.Path != s.Path || got.Size != 10 || !got.Modified.Equal(modified) ||
+		!strings.HasPrefix(got.Mimetype, "text/plain") || got.Metadata["filename"] != "a.txt" {
+		t.Errorf("unexpected stage restored %+v", got)
+	}
+	// rewrites the last bytes, then overflows
+	if n, err := got.Write(3, strings.NewReader("lo world!")); !errors.Is(err, ErrUploadStageOverflow) || n != 7 {
+		t.Fatalf("expect the overflow, got %d, %v", n, err)
+	}
+	if !got.Complete() {
+		t.Fatal("expect complete")
+	}
+	if _, err = got.Commit(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "a.txt"))
+	if err != nil || string(data) != "hello worl" {
+		t.Errorf("unexpected file %q, %v", data, err)
+	}
+	if _, err = GetUploadStage(s.ID); !errors.Is(err, ErrUploadStageNotFound) {
+		t.Errorf("expect the stage gone, got %v", err)
+	}
+	if entries, _ := os.ReadDir(uploadStageDir()); len(entries) != 0 {
+		t.Errorf("expect the stage dir empty, got %v", entries)
+	}
+}
+
+func TestLoadExpiredUploadStage(t *testing.T) {
+	withStageDir(t)
+	s, err := NewUploadStage(nil, "/stage/a.txt", 10, "", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Write(0, strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	// the broken description is left to the cleaning of the temp dir
+	broken := filepath.Join(uploadStageDir(), "broken"+uploadStageMetaSuffix)
+	if err = os.WriteFile(broken, []byte("{"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-UploadStageTTL - time.Minute)
+	if err = os.Chtimes(s.file.Name(), old, old); err != nil {
+		t.Fatal(err)
+	}
+	restart()
+	if _, err = GetUploadStage(s.ID); !errors.Is(err, ErrUploadStageNotFound) {
+		t.Errorf("expect the stage expired, got %v", err)
+	}
+	entries, _ := os.ReadDir(uploadStageDir())
+	if len(entries) != 1 || entries[0].Name() != filepath.Base(broken) {
+		t.Errorf("expect the files of the stage removed, got %v", entries)
+	}
+	if files := UploadStageFiles(); len(files) != 0 {
+		t.Errorf("expect nothing kept, got %v", files)
+	}
+}
+
+func TestPutRange(t *testing.T) {
+	withStageDir(t)
+	if _, err := PutRange(context.Background(), "/stage/b.txt", strings.NewReader("abc"), 3, 5, 6, "", time.Time{}); !errors.Is(err, ErrUploadOffsetMismatch) {
+		t.Errorf("expect the first chunk required, got %v", err)
+	}
+	s, err := PutRange(context.Background(), "/stage/b.txt", strings.NewReader("abc"), 0, 2, 6, "", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Remove()
+	// the next chunk goes to the same stage
+	next, err := PutRange(context.Background(), "/stage/b.txt", strings.NewReader("de"), 3, 5, 6, "", time.Time{})
+	if next != s {
+		t.Fatalf("expect the same stage, got %v", next)
+	}
+	if err == nil || s.GetOffset() != 5 {
+		t.Errorf("expect the short chunk refused at 5, got %d, %v", s.GetOffset(), err)
+	}
+}
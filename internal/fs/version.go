@@ -0,0 +1,66 @@
+package fs
+
+import (
+	"context"
+	stdpath "path"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pkg/errors"
+)
+
+func versionStorage(path string) (driver.Driver, string, error) {
+	storage, actualPath, err := op.GetStorageAndActualPath(path)
+	if err != nil {
+		return nil, "", errors.WithMessage(err, "存储获取失败")
+	}
+	if op.IsVersionPath(actualPath) {
+		return nil, "", errors.WithStack(errs.NotSupport)
+	}
+	return storage, actualPath, nil
+}
+
+func listVersions(ctx context.Context, path string) ([]op.FileVersion, error) {
+	storage, actualPath, err := versionStorage(path)
+	if err != nil {
+		return nil, err
+	}
+	return op.ListVersions(ctx, storage, actualPath)
+}
+
+func restoreVersion(ctx context.Context, path, id string) error {
+	storage, actualPath, err := versionStorage(path)
+	if err != nil {
+		return err
+	}
+	return op.RestoreVersion(ctx, storage, actualPath, id)
+}
+
+func deleteVersions(ctx context.Context, path string, ids []string) error {
+	storage, actualPath, err := versionStorage(path)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err = op.DeleteVersion(ctx, storage, actualPath, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hideVersionsDir hides the versions dir in the root of the storage.
+func hideVersionsDir(storage driver.Driver, actualPath string, objs []model.Obj) []model.Obj {
+	if actualPath != "/" || !storage.GetStorage().VersioningEnabled() {
+		return objs
+	}
+	res := make([]model.Obj, 0, len(objs))
+	for _, obj := range objs {
+		if obj.GetName() != stdpath.Base(op.VersionsDir) {
+			res = append(res, obj)
+		}
+	}
+	return res
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"net"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	ProtocolFTP  = "ftp"
+	ProtocolSFTP = "sftp"
+)
+
+// FTPPolicy holds the per-user settings of the ftp and sftp servers.
+// Zero values mean no restriction.
+type FTPPolicy struct {
+	Protocols      string `json:"protocols"`       // comma separated, e.g. "ftp,sftp"
+	AllowedCIDRs   string `json:"allowed_cidrs"`   // comma or newline separated source networks
+	MaxSessions    int    `json:"max_sessions"`    // concurrent sessions of the user
+	IdleTimeout    int    `json:"idle_timeout"`    // seconds without any file operation
+	SessionTimeout int    `json:"session_timeout"` // seconds since login
+	ChrootPath     string `json:"chroot_path"`     // root of the session, relative to the base path
+}
+
+func splitPolicyList(s string) []string {
+	var res []string
+	for _, v := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '\n' || r == ' '
+	}) {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func (p *FTPPolicy) Validate() error {
+	for _, proto := range splitPolicyList(p.Protocols) {
+		if proto != ProtocolFTP && proto != ProtocolSFTP {
+			return errors.Errorf("unknown protocol: %s", proto)
+		}
+	}
+	for _, cidr := range splitPolicyList(p.AllowedCIDRs) {
+		if _, err := parseCIDR(cidr); err != nil {
+			return err
+		}
+	}
+	if p.MaxSessions < 0 || p.IdleTimeout < 0 || p.SessionTimeout < 0 {
+		return errors.New("ftp limits can not be negative")
+	}
+	_, err := utils.JoinBasePath("/", p.ChrootPath)
+	return err
+}
+
+func (p *FTPPolicy) AllowProtocol(proto string) bool {
+	protocols := splitPolicyList(p.Protocols)
+	return len(protocols) == 0 || utils.SliceContains(protocols, proto)
+}
+
+// AllowAddr reports whether a client connecting from addr (ip or ip:port)
+// is within the allowed networks.
+func (p *FTPPolicy) AllowAddr(addr string) bool {
+	cidrs := splitPolicyList(p.AllowedCIDRs)
+	if len(cidrs) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range cidrs {
+		if n, err := parseCIDR(cidr); err == nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCIDR also accepts a single address as a network of one host
+func parseCIDR(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, errors.Errorf("invalid address: %s", s)
+		}
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, n, err := net.ParseCIDR(s)
+	return n, errors.WithStack(err)
+}
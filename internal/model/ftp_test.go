@@ -0,0 +1,38 @@
+package model
+
+import "testing"
+
+func TestFTPPolicy(t *testing.T) {
+	p := FTPPolicy{Protocols: "ftp, sftp", AllowedCIDRs: "10.0.0.0/8\n::1\n192.168.1.2", ChrootPath: "/home"}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for addr, expect := range map[string]bool{
+		"10.1.2.3:21":    true,
+		"[::1]:22":       true,
+		"192.168.1.2":    true,
+		"192.168.1.3:22": false,
+		"example.com:21": false,
+	} {
+		if got := p.AllowAddr(addr); got != expect {
+			t.Errorf("%s: expect allowed %v", addr, expect)
+		}
+	}
+	if !(&FTPPolicy{}).AllowAddr("1.2.3.4:5") || !(&FTPPolicy{}).AllowProtocol(ProtocolSFTP) {
+		t.Error("expect no restriction by default")
+	}
+	if (&FTPPolicy{Protocols: "ftp"}).AllowProtocol(ProtocolSFTP) {
+		t.Error("expect sftp refused")
+	}
+	for _, invalid := range []FTPPolicy{
+		{Protocols: "http"},
+		{AllowedCIDRs: "10.0.0.0/33"},
+		{AllowedCIDRs: "host"},
+		{MaxSessions: -1},
+		{ChrootPath: "../etc"},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("%+v: expect invalid", invalid)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package model
+
+// OfflineDownloadRule chooses the destination of the offline downloads added
+// without a path and post-processes the matched downloads. The enabled rules
+// are matched by Order, the empty conditions match anything.
+type OfflineDownloadRule struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Name     string `json:"name"`
+	Order    int    `json:"order"`
+	Disabled bool   `json:"disabled"`
+	// comma separated, "*.example.com" matches the subdomains
+	Hosts string `json:"hosts"`
+	// comma separated, without the dot
+	Extensions string `json:"extensions"`
+	// comma separated categories or tags given when adding the download
+	Categories string `json:"categories"`
+	// in bytes, 0 means no limit
+	MinSize int64 `json:"min_size"`
+	MaxSize int64 `json:"max_size"`
+
+	DstPath    string `json:"dst_path"`
+	Decompress bool   `json:"decompress"`
+	// e.g. "{date}-{name}.{ext}", supports {name} {ext} {host} {category} {date}
+	Rename string `json:"rename"`
+	// regexp of the names of the files not to be transferred
+	JunkPattern string `json:"junk_pattern"`
+}
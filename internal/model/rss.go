@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// RssFeed is a subscription whose new items are added as offline downloads.
+type RssFeed struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	UserId uint   `json:"user_id" gorm:"index"`
+	Name   string `json:"name"`
+	URL    string `json:"url" gorm:"type:text"`
+	// regexps matched against the titles of the items
+	Include      string `json:"include"`
+	Exclude      string `json:"exclude"`
+	Tool         string `json:"tool"`
+	DstPath      string `json:"dst_path"`
+	DeletePolicy string `json:"delete_policy"`
+	// minutes between two checks
+	Interval  int       `json:"interval"`
+	Disabled  bool      `json:"disabled"`
+	LastCheck time.Time `json:"last_check"`
+	LastError string    `json:"last_error" gorm:"type:text"`
+}
+
+// RssHistory records an item of a feed which has been handled, ItemKey is the
+// info-hash of magnets and torrents or the guid of the item.
+type RssHistory struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	FeedId    uint      `json:"feed_id" gorm:"index"`
+	UserId    uint      `json:"user_id" gorm:"uniqueIndex:idx_rss_history_user_key"`
+	ItemKey   string    `json:"item_key" gorm:"uniqueIndex:idx_rss_history_user_key;size:255"`
+	Title     string    `json:"title" gorm:"type:text"`
+	URL       string    `json:"url" gorm:"type:text"`
+	AddedTime time.Time `json:"added_time"`
+	Error     string    `json:"error" gorm:"type:text"`
+}
@@ -0,0 +1,9 @@
+package model
+
+// S3ObjectMeta keeps the metadata supplied by S3 clients on upload
+// (x-amz-meta-* and standard object headers), keyed by the full path.
+type S3ObjectMeta struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	Path    string `json:"path" gorm:"unique"`
+	MetaRaw string `json:"-" gorm:"type:text"`
+}
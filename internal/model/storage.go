@@ -21,6 +21,7 @@ type Storage struct {
 	EnableSign          bool      `json:"enable_sign"`
 	Sort
 	Proxy
+	Versioning
 }
 
 type Sort struct {
@@ -38,6 +39,13 @@ type Proxy struct {
 	DisableProxySign bool `json:"disable_proxy_sign"`
 }
 
+// Versioning keeps the previous versions of the overwritten files in the
+// hidden versions dir of the storage, it is enabled if any of them is set.
+type Versioning struct {
+	VersionsKeep int `json:"versions_keep"` // the previous versions kept of a file, 0 for no limit
+	VersionsDays int `json:"versions_days"` // the days a version is kept, 0 for no limit
+}
+
 func (s *Storage) GetStorage() *Storage {
 	return s
 }
@@ -50,6 +58,10 @@ func (s *Storage) SetStatus(status string) {
 	s.Status = status
 }
 
+func (v Versioning) VersioningEnabled() bool {
+	return v.VersionsKeep > 0 || v.VersionsDays > 0
+}
+
 func (p Proxy) Webdav302() bool {
 	return p.WebdavPolicy == "302_redirect"
 }
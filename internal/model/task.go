@@ -1,6 +1,69 @@
 package model
 
+import "time"
+
 type TaskItem struct {
 	Key         string `json:"key"`
 	PersistData string `gorm:"type:text" json:"persist_data"`
 }
+
+// TaskHistory records a finished task, which is kept after the task is
+// cleared from its manager.
+type TaskHistory struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Type       string    `json:"type" gorm:"index;size:64"`
+	TaskID     string    `json:"task_id" gorm:"size:64"`
+	Name       string    `json:"name" gorm:"type:text"`
+	CreatorId  uint      `json:"creator_id" gorm:"index"`
+	Creator    string    `json:"creator"`
+	SrcPath    string    `json:"src_path" gorm:"type:text"`
+	DstPath    string    `json:"dst_path" gorm:"type:text"`
+	Storage    string    `json:"storage" gorm:"index"`
+	TotalBytes int64     `json:"total_bytes"`
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time" gorm:"index"`
+	// in milliseconds
+	Duration int64  `json:"duration"`
+	Result   string `json:"result" gorm:"index;size:16"`
+	Error    string `json:"error" gorm:"type:text"`
+}
+
+const (
+	TaskResultSucceeded = "succeeded"
+	TaskResultFailed    = "failed"
+	TaskResultCanceled  = "canceled"
+)
+
+// TaskHistoryFilter filters the task histories, the zero values match any.
+type TaskHistoryFilter struct {
+	Type      string
+	Result    string
+	Storage   string
+	CreatorId uint
+	Keyword   string
+	Since     time.Time
+	Until     time.Time
+}
+
+// TaskHistorySummary sums up the task histories of a user or a storage, the
+// bytes and duration are of the succeeded tasks.
+type TaskHistorySummary struct {
+	Key       string `json:"key"`
+	Count     int64  `json:"count"`
+	Succeeded int64  `json:"succeeded"`
+	Bytes     int64  `json:"bytes"`
+	Duration  int64  `json:"duration"`
+	// bytes per second
+	Throughput int64 `json:"throughput" gorm:"-"`
+}
+
+// TransferCheckpoint records a file copied by a copy or move of a dir, which
+// is skipped when the copy is run again.
+type TransferCheckpoint struct {
+	ID       uint      `json:"id" gorm:"primaryKey"`
+	RootKey  string    `json:"root_key" gorm:"index:idx_transfer_checkpoint_src;size:64"`
+	SrcPath  string    `json:"src_path" gorm:"index:idx_transfer_checkpoint_src;size:512"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	Created  time.Time `json:"created" gorm:"index"`
+}
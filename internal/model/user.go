@@ -68,6 +68,8 @@ type User struct {
 	SsoID      string `json:"sso_id"` // unique by sso platform
 	Authn      string `gorm:"type:text" json:"-"`
 	AllowLdap  bool   `json:"allow_ldap" gorm:"default:true"`
+	// FTPPolicy further restricts the ftp/sftp access granted by bit 10 and 11
+	FTPPolicy FTPPolicy `json:"ftp_policy" gorm:"embedded;embeddedPrefix:ftp_"`
 }
 
 func (u *User) IsGuest() bool {
@@ -9,6 +9,7 @@ import (
 	_ "github.com/OpenListTeam/OpenList/v4/internal/offline_download/http"
 	_ "github.com/OpenListTeam/OpenList/v4/internal/offline_download/pikpak"
 	_ "github.com/OpenListTeam/OpenList/v4/internal/offline_download/qbit"
+	_ "github.com/OpenListTeam/OpenList/v4/internal/offline_download/remote"
 	_ "github.com/OpenListTeam/OpenList/v4/internal/offline_download/thunder"
 	_ "github.com/OpenListTeam/OpenList/v4/internal/offline_download/thunder_browser"
 	_ "github.com/OpenListTeam/OpenList/v4/internal/offline_download/thunderx"
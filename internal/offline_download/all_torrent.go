@@ -0,0 +1,9 @@
+//go:build nosqlite || !cgo
+
+package offline_download
+
+// the built-in bittorrent client embeds another sqlite which collides with
+// go-sqlite3 when linked with cgo, so it is only built with the nosqlite tag.
+import (
+	_ "github.com/OpenListTeam/OpenList/v4/internal/offline_download/torrent"
+)
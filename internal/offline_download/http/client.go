@@ -1,7 +1,9 @@
 package http
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"math/rand/v2"
 	"net/http"
 	"os"
@@ -11,10 +13,15 @@ import (
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/drivers/base"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/net"
 	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
 	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
 )
 
 type SimpleHttp struct {
@@ -26,7 +33,9 @@ func (s SimpleHttp) Name() string {
 }
 
 func (s SimpleHttp) Items() []model.SettingItem {
-	return nil
+	return []model.SettingItem{
+		{Key: conf.SimpleHttpThreads, Value: "4", Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE, Help: "the parallel range requests of a download, if the server supports range"},
+	}
 }
 
 func (s SimpleHttp) Init() (string, error) {
@@ -55,36 +64,45 @@ func (s SimpleHttp) Run(task *tool.DownloadTask) error {
 	if streamPut {
 		method = http.MethodHead
 	}
-	req, err := http.NewRequestWithContext(task.Ctx(), method, task.Url, nil)
-	if err != nil {
-		return err
-	}
-	req.Header.Set("User-Agent", base.UserAgent)
-	if streamPut {
-		req.Header.Set("Range", "bytes=0-")
+	header := http.Header{}
+	header.Set("User-Agent", base.UserAgent)
+	for k, v := range task.Headers {
+		header.Set(k, v)
 	}
-	resp, err := s.client.Do(req)
+	// the range tells whether the server supports segmented downloading
+	probe := header.Clone()
+	probe.Set("Range", "bytes=0-")
+	resp, err := s.request(task.Ctx(), method, task.Url, probe)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("http status code %d", resp.StatusCode)
-	}
 	filename, err := parseFilenameFromContentDisposition(resp.Header.Get("Content-Disposition"))
 	if err != nil {
 		filename = path.Base(resp.Request.URL.Path)
 	}
 	filename = strings.Trim(filename, "/")
+	// keep the name of an interrupted download, which may be a random one
+	if name, ok := findSegmentState(task.TempDir); ok {
+		filename = name
+	}
 	if len(filename) == 0 {
-		filename = fmt.Sprintf("%s-%d-%x", strings.ReplaceAll(req.URL.Host, ".", "_"), time.Now().UnixMilli(), rand.Uint32())
+		filename = fmt.Sprintf("%s-%d-%x", strings.ReplaceAll(resp.Request.URL.Host, ".", "_"), time.Now().UnixMilli(), rand.Uint32())
 	}
 	fileSize := resp.ContentLength
-	if streamPut {
-		if fileSize == 0 {
-			start, end, _ := http_range.ParseContentRange(resp.Header.Get("Content-Range"))
-			fileSize = start + end
+	var remote *segmentState
+	if resp.StatusCode == http.StatusPartialContent {
+		if _, _, size, err := http_range.ParseUploadContentRange(resp.Header.Get("Content-Range")); err == nil {
+			fileSize = size
+			remote = &segmentState{
+				URL:          task.Url,
+				Size:         size,
+				ETag:         resp.Header.Get("ETag"),
+				LastModified: resp.Header.Get("Last-Modified"),
+			}
 		}
+	}
+	if streamPut {
 		task.SetTotalBytes(fileSize)
 		task.TempDir = filename
 		return nil
@@ -93,13 +111,71 @@ func (s SimpleHttp) Run(task *tool.DownloadTask) error {
 	// save to temp dir
 	_ = os.MkdirAll(task.TempDir, os.ModePerm)
 	filePath := filepath.Join(task.TempDir, filename)
+	if remote != nil && remote.Size > 0 {
+		_ = resp.Body.Close()
+		threads := max(setting.GetInt(conf.SimpleHttpThreads, 4), 1)
+		err = s.downloadSegments(task, header, filePath, remote, threads)
+	} else {
+		err = s.downloadStream(task, resp.Body, filePath, fileSize)
+	}
+	if err != nil {
+		return err
+	}
+	return verifyChecksum(task.Checksum, filePath)
+}
+
+// downloadStream downloads the file by a single request, which can't be resumed.
+func (s SimpleHttp) downloadStream(task *tool.DownloadTask, body io.Reader, filePath string, fileSize int64) error {
 	file, err := os.Create(filePath)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
-	err = utils.CopyWithCtx(task.Ctx(), file, resp.Body, fileSize, task.SetProgress)
-	return err
+	return utils.CopyWithCtx(task.Ctx(), file, stream.TaskDownloadLimitReader(task.Ctx(), body), fileSize, task.SetProgress)
+}
+
+func (s SimpleHttp) request(ctx context.Context, method, url string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		_ = resp.Body.Close()
+		// net.Downloader retries by the status code
+		return nil, fmt.Errorf("http status code %w", net.HttpStatusCodeError(resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// verifyChecksum removes the file if its hash isn't the expected one, so
+// that it is downloaded again by the retry.
+func verifyChecksum(checksum, filePath string) error {
+	if checksum == "" {
+		return nil
+	}
+	ht, expected, err := tool.ParseChecksum(checksum)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	sum, err := utils.HashReader(ht, file)
+	_ = file.Close()
+	if err != nil {
+		return err
+	}
+	if sum != expected {
+		_ = os.Remove(filePath)
+		return errors.Errorf("文件校验失败, %s 应为 %s, 实际为 %s", ht.Name, expected, sum)
+	}
+	return nil
 }
 
 func init() {
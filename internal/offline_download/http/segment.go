@@ -0,0 +1,239 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/net"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// the size of the segments requested in parallel
+	segmentSize = 8 * 1024 * 1024
+	// the retries of a part before the download fails
+	segmentMaxRetries = 3
+	// the suffix of the file saving the finished segments next to the download
+	segmentStateSuffix = ".segments"
+	// the task is persisted only when its progress stays unchanged for a
+	// while, so the progress is reported at intervals to let it be saved
+	progressInterval = 5 * time.Second
+)
+
+// segmentState is saved while downloading, so that an interrupted download
+// only fetches the unfinished segments if the remote file is unchanged.
+type segmentState struct {
+	URL          string `json:"url"`
+	Size         int64  `json:"size"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	SegmentSize  int64  `json:"segment_size"`
+	Done         []bool `json:"done"`
+}
+
+func (s *segmentState) matches(remote *segmentState) bool {
+	return s.URL == remote.URL && s.Size == remote.Size && s.ETag == remote.ETag &&
+		s.LastModified == remote.LastModified && s.SegmentSize > 0 &&
+		int64(len(s.Done)) == (s.Size+s.SegmentSize-1)/s.SegmentSize
+}
+
+func (s *segmentState) segment(i int) http_range.Range {
+	start := int64(i) * s.SegmentSize
+	return http_range.Range{Start: start, Length: min(s.SegmentSize, s.Size-start)}
+}
+
+func loadSegmentState(path string) *segmentState {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var s segmentState
+	if json.Unmarshal(data, &s) != nil {
+		return nil
+	}
+	return &s
+}
+
+func (s *segmentState) save(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	// written aside and renamed, so that a crash doesn't leave a broken state
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// findSegmentState returns the name of the file being downloaded in dir.
+func findSegmentState(dir string) (string, bool) {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*"+segmentStateSuffix))
+	if len(matches) != 1 {
+		return "", false
+	}
+	return strings.TrimSuffix(filepath.Base(matches[0]), segmentStateSuffix), true
+}
+
+type segmentDownloader struct {
+	s         SimpleHttp
+	task      *tool.DownloadTask
+	header    http.Header
+	file      *os.File
+	state     *segmentState
+	statePath string
+	written   atomic.Int64
+	reported  atomic.Int64
+}
+
+// downloadSegments downloads the unfinished segments of the file by the
+// parallel range requests of net.Downloader, resuming from the state saved
+// by the last attempt.
+func (s SimpleHttp) downloadSegments(task *tool.DownloadTask, header http.Header, filePath string, remote *segmentState, threads int) error {
+	d := &segmentDownloader{
+		s:         s,
+		task:      task,
+		header:    header,
+		statePath: filePath + segmentStateSuffix,
+	}
+	flag := os.O_RDWR | os.O_CREATE
+	d.state = loadSegmentState(d.statePath)
+	if d.state == nil || !d.state.matches(remote) {
+		d.state = remote
+		d.state.SegmentSize = segmentSize
+		d.state.Done = make([]bool, (remote.Size+segmentSize-1)/segmentSize)
+		flag |= os.O_TRUNC
+	} else {
+		log.Infof("resume downloading %s", task.Url)
+	}
+	file, err := os.OpenFile(filePath, flag, 0o666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	d.file = file
+	if err = file.Truncate(d.state.Size); err != nil {
+		return err
+	}
+	if err = d.state.save(d.statePath); err != nil {
+		return err
+	}
+	for i, done := range d.state.Done {
+		if done {
+			d.written.Add(d.state.segment(i).Length)
+		}
+	}
+	d.progress(0)
+
+	down := net.NewDownloader(func(nd *net.Downloader) {
+		nd.Concurrency = threads
+		nd.PartSize = segmentSize
+		nd.PartBodyMaxRetries = segmentMaxRetries
+		nd.HttpClient = d.request
+	})
+	// each run of the unfinished segments is downloaded in order
+	for first := 0; first < len(d.state.Done); first++ {
+		if d.state.Done[first] {
+			continue
+		}
+		last := first
+		for last+1 < len(d.state.Done) && !d.state.Done[last+1] {
+			last++
+		}
+		if err = d.downloadRun(down, first, last); err != nil {
+			return err
+		}
+		first = last
+	}
+	return os.Remove(d.statePath)
+}
+
+// downloadRun downloads the segments from first to last, each is saved as
+// finished once all its bytes are written.
+func (d *segmentDownloader) downloadRun(down *net.Downloader, first, last int) error {
+	start := d.state.segment(first).Start
+	end := d.state.segment(last).Start + d.state.segment(last).Length
+	rc, err := down.Download(d.task.Ctx(), &net.HttpRequestParams{
+		URL:       d.task.Url,
+		Range:     http_range.Range{Start: start, Length: end - start},
+		HeaderRef: d.header,
+		Size:      d.state.Size,
+	})
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	w := &segmentWriter{d: d, off: start, next: first}
+	if _, err = io.CopyN(w, stream.TaskDownloadLimitReader(d.task.Ctx(), rc), end-start); err != nil {
+		return errors.WithMessagef(err, "failed download segment %d", w.next)
+	}
+	return nil
+}
+
+// request gets a range of the file for net.Downloader, the range must be
+// the one asked for.
+func (d *segmentDownloader) request(ctx context.Context, params *net.HttpRequestParams) (*http.Response, error) {
+	header := http_range.ApplyRangeToHttpHeader(params.Range, params.HeaderRef.Clone())
+	resp, err := d.s.request(ctx, http.MethodGet, params.URL, header)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		_ = resp.Body.Close()
+		return nil, errors.Errorf("unexpected status code %d for range request", resp.StatusCode)
+	}
+	if start, _, err := http_range.ParseContentRange(resp.Header.Get("Content-Range")); err != nil || start != params.Range.Start {
+		_ = resp.Body.Close()
+		return nil, errors.Errorf("unexpected content range: %s", resp.Header.Get("Content-Range"))
+	}
+	return resp, nil
+}
+
+func (d *segmentDownloader) progress(n int64) {
+	written := d.written.Add(n)
+	now, last := time.Now().UnixNano(), d.reported.Load()
+	if written < d.state.Size && now-last < int64(progressInterval) {
+		return
+	}
+	if d.reported.CompareAndSwap(last, now) {
+		d.task.SetProgress(float64(written) / float64(d.state.Size) * 100)
+	}
+}
+
+// segmentWriter writes the bytes of a run in order, and marks the segments
+// finished as their ends are passed.
+type segmentWriter struct {
+	d    *segmentDownloader
+	off  int64
+	next int
+}
+
+func (w *segmentWriter) Write(p []byte) (int, error) {
+	n, err := w.d.file.WriteAt(p, w.off)
+	w.off += int64(n)
+	w.d.progress(int64(n))
+	for w.next < len(w.d.state.Done) {
+		seg := w.d.state.segment(w.next)
+		if w.off < seg.Start+seg.Length {
+			break
+		}
+		w.d.state.Done[w.next] = true
+		w.next++
+		if e := w.d.state.save(w.d.statePath); e != nil && err == nil {
+			err = e
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,179 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+var modified = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// rangeServer serves a file supporting the range requests, and records the
+// ranges requested.
+type rangeServer struct {
+	content []byte
+	etag    string
+	mu      sync.Mutex
+	ranges  []string
+}
+
+func newRangeServer(t *testing.T, size int) (*rangeServer, *httptest.Server) {
+	rs := &rangeServer{content: make([]byte, size), etag: `"v1"`}
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := range rs.content {
+		rs.content[i] = byte(r.Uint32())
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rs.mu.Lock()
+		rs.ranges = append(rs.ranges, r.Header.Get("Range"))
+		rs.mu.Unlock()
+		w.Header().Set("ETag", rs.etag)
+		http.ServeContent(w, r, "f.bin", modified, bytes.NewReader(rs.content))
+	}))
+	t.Cleanup(ts.Close)
+	return rs, ts
+}
+
+func (rs *rangeServer) requested() []string {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return append([]string(nil), rs.ranges...)
+}
+
+func newTask(t *testing.T, url string) *tool.DownloadTask {
+	task := &tool.DownloadTask{Url: url, TempDir: t.TempDir()}
+	task.SetCtx(context.Background())
+	return task
+}
+
+func checkDownloaded(t *testing.T, task *tool.DownloadTask, content []byte) {
+	t.Helper()
+	filePath := filepath.Join(task.TempDir, "f.bin")
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Errorf("the file downloaded differs, %d bytes of %d", len(data), len(content))
+	}
+	if _, err = os.Stat(filePath + segmentStateSuffix); !os.IsNotExist(err) {
+		t.Errorf("the state should be removed: %v", err)
+	}
+}
+
+func TestDownloadSegments(t *testing.T) {
+	rs, ts := newRangeServer(t, 2*segmentSize+1000)
+	task := newTask(t, ts.URL+"/f.bin")
+	sum := sha1.Sum(rs.content)
+	task.Checksum = "sha1:" + hex.EncodeToString(sum[:])
+	if err := (SimpleHttp{}).Run(task); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, task, rs.content)
+	if got := task.GetTotalBytes(); got != int64(len(rs.content)) {
+		t.Errorf("unexpected total bytes %d", got)
+	}
+	// the probe and a request for each part
+	if ranges := rs.requested(); len(ranges) != 4 || ranges[0] != "bytes=0-" {
+		t.Errorf("unexpected ranges: %v", ranges)
+	}
+}
+
+func TestDownloadSegmentsResume(t *testing.T) {
+	rs, ts := newRangeServer(t, 2*segmentSize+1000)
+	for _, c := range []struct {
+		name   string
+		etag   string
+		resume bool
+	}{
+		{name: "unchanged", etag: rs.etag, resume: true},
+		{name: "changed", etag: `"v0"`},
+	} {
+		task := newTask(t, ts.URL+"/f.bin")
+		filePath := filepath.Join(task.TempDir, "f.bin")
+		// the first segment is downloaded by the last attempt
+		if err := os.WriteFile(filePath, rs.content[:segmentSize], 0o666); err != nil {
+			t.Fatal(err)
+		}
+		state := &segmentState{
+			URL:          task.Url,
+			Size:         int64(len(rs.content)),
+			ETag:         c.etag,
+			LastModified: modified.Format(http.TimeFormat),
+			SegmentSize:  segmentSize,
+			Done:         []bool{true, false, false},
+		}
+		if err := state.save(filePath + segmentStateSuffix); err != nil {
+			t.Fatal(err)
+		}
+		rs.ranges = nil
+		if err := (SimpleHttp{}).Run(task); err != nil {
+			t.Fatal(err)
+		}
+		checkDownloaded(t, task, rs.content)
+		resumed := true
+		for _, r := range rs.requested()[1:] {
+			if strings.HasPrefix(r, "bytes=0-") {
+				resumed = false
+			}
+		}
+		if resumed != c.resume {
+			t.Errorf("%s: expect resumed %v, got ranges %v", c.name, c.resume, rs.requested())
+		}
+	}
+}
+
+func TestDownloadStream(t *testing.T) {
+	content := []byte("no range supported")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content)
+	}))
+	defer ts.Close()
+	task := newTask(t, ts.URL+"/f.bin")
+	if err := (SimpleHttp{}).Run(task); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, task, content)
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "f.bin")
+	if err := os.WriteFile(filePath, []byte("hello"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyChecksum("5d41402abc4b2a76b9719d911017c592", filePath); err != nil {
+		t.Errorf("expect the md5 matched: %v", err)
+	}
+	if err := verifyChecksum("md5:00000000000000000000000000000000", filePath); err == nil {
+		t.Error("expect the mismatch")
+	}
+	// removed to be downloaded again
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("the file mismatched should be removed: %v", err)
+	}
+}
@@ -0,0 +1,300 @@
+package remote
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"os"
+	stdpath "path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// Remote fetches the files from ftp, sftp and webdav servers into the temp
+// dir, or streams them by the transfer tasks while uploading, the sftp
+// servers are trusted by the fingerprint of the host key in the query, and
+// openlist://[:<password>@]<path> from the storages, which is transferred from
+// the source storage directly with the password of the meta if any.
+type Remote struct{}
+
+func (r Remote) Name() string {
+	return "Remote"
+}
+
+func (r Remote) Items() []model.SettingItem {
+	return nil
+}
+
+func (r Remote) Init() (string, error) {
+	return "ok", nil
+}
+
+func (r Remote) IsReady() bool {
+	return true
+}
+
+func (r Remote) AddURL(args *tool.AddUrlArgs) (string, error) {
+	panic("should not be called")
+}
+
+func (r Remote) Remove(task *tool.DownloadTask) error {
+	panic("should not be called")
+}
+
+func (r Remote) Status(task *tool.DownloadTask) (*tool.Status, error) {
+	panic("should not be called")
+}
+
+func (r Remote) Run(task *tool.DownloadTask) error {
+	u, err := url.Parse(task.Url)
+	if err != nil {
+		return errors.Wrap(err, "无法解析 URL")
+	}
+	if u.Scheme == "openlist" {
+		return r.runStorage(task, u)
+	}
+	if task.DeletePolicy == tool.UploadDownloadStream {
+		// the files are read by the transfer tasks
+		return nil
+	}
+	src, err := newSource(task.Ctx(), u)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	p, e, err := stat(src, u)
+	if err != nil {
+		return err
+	}
+	_ = os.MkdirAll(task.TempDir, os.ModePerm)
+	if !e.IsDir {
+		dst, err := tempPath(task.TempDir, e.Name)
+		if err != nil {
+			return err
+		}
+		task.SetTotalBytes(e.Size)
+		return fetch(task, src, p, dst, e.Size, 0)
+	}
+	// the dir is fetched as a whole, the files are listed first for the progress
+	files := make(map[string]int64)
+	if err = walk(task.Ctx(), src, p, "", files); err != nil {
+		return err
+	}
+	var total, done int64
+	for _, size := range files {
+		total += size
+	}
+	task.SetTotalBytes(total)
+	for rel, size := range files {
+		dst, err := tempPath(task.TempDir, e.Name, filepath.FromSlash(rel))
+		if err != nil {
+			return err
+		}
+		if err = os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+			return err
+		}
+		if err = fetch(task, src, stdpath.Join(p, rel), dst, size, done); err != nil {
+			return err
+		}
+		done += size
+	}
+	return nil
+}
+
+// runStorage checks the source in the storages as the fs handlers do, which
+// is transferred by the transfer task without a local copy.
+func (r Remote) runStorage(task *tool.DownloadTask, u *url.URL) error {
+	if task.Creator == nil {
+		return errs.PermissionDenied
+	}
+	srcPath, err := task.Creator.JoinPath(stdpath.Join(u.Host, u.Path))
+	if err != nil {
+		return err
+	}
+	password, _ := u.User.Password()
+	ok, err := tool.CanAccess(task.Creator, srcPath, password)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errs.PermissionDenied
+	}
+	obj, err := fs.Get(task.Ctx(), srcPath, &fs.GetArgs{NoLog: true})
+	if err != nil {
+		return errors.WithMessagef(err, "failed get [%s]", srcPath)
+	}
+	task.SetTotalBytes(obj.GetSize())
+	task.SetProgress(100)
+	task.SrcPath = srcPath
+	return nil
+}
+
+func (r Remote) Files(ctx context.Context, rawURL string) ([]tool.StreamFile, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "无法解析 URL")
+	}
+	src, err := newSource(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	p, e, err := stat(src, u)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsDir {
+		return []tool.StreamFile{{Path: e.Name, Size: e.Size}}, nil
+	}
+	files := make(map[string]int64)
+	if err = walk(ctx, src, p, "", files); err != nil {
+		return nil, err
+	}
+	res := make([]tool.StreamFile, 0, len(files))
+	for rel, size := range files {
+		res = append(res, tool.StreamFile{Path: stdpath.Join(e.Name, rel), Size: size})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Path < res[j].Path })
+	return res, nil
+}
+
+func (r Remote) Open(ctx context.Context, rawURL, path string, size int64) (io.ReadCloser, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "无法解析 URL")
+	}
+	src, err := newSource(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	// the path is relative to the dir of the url
+	p := utils.FixAndCleanPath(u.Path)
+	if _, rel, ok := strings.Cut(path, "/"); ok {
+		p = stdpath.Join(p, rel)
+	}
+	rc, err := src.Open(p, 0, size)
+	if err != nil {
+		_ = src.Close()
+		return nil, errors.WithMessagef(err, "failed open [%s]", p)
+	}
+	return utils.NewReadCloser(rc, func() error {
+		err := rc.Close()
+		if e := src.Close(); err == nil {
+			err = e
+		}
+		return err
+	}), nil
+}
+
+// stat gets the file or the dir of the url, the root dir is named by the host.
+func stat(src source, u *url.URL) (string, *entry, error) {
+	p := u.Path
+	if p == "" {
+		p = "/"
+	}
+	e, err := src.Stat(p)
+	if err != nil {
+		return "", nil, errors.WithMessagef(err, "failed get [%s]", p)
+	}
+	if p == "/" {
+		e.Name = u.Hostname()
+	}
+	if err = checkName(e.Name); err != nil {
+		return "", nil, err
+	}
+	return p, e, nil
+}
+
+// checkName refuses the names from the server which may lead out of the dir.
+func checkName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return errors.Errorf("invalid name %q from the server", name)
+	}
+	return nil
+}
+
+// tempPath joins the path in the temp dir, and makes sure it stays there.
+func tempPath(dir string, elem ...string) (string, error) {
+	p := filepath.Join(append([]string{dir}, elem...)...)
+	if rel, err := filepath.Rel(dir, p); err != nil || rel == "." || !filepath.IsLocal(rel) {
+		return "", errors.Errorf("invalid path [%s] out of the temp dir", p)
+	}
+	return p, nil
+}
+
+func walk(ctx context.Context, src source, root, rel string, files map[string]int64) error {
+	if utils.IsCanceled(ctx) {
+		return ctx.Err()
+	}
+	entries, err := src.List(stdpath.Join(root, rel))
+	if err != nil {
+		return errors.WithMessagef(err, "failed list [%s]", stdpath.Join(root, rel))
+	}
+	for _, e := range entries {
+		if err = checkName(e.Name); err != nil {
+			return err
+		}
+		p := stdpath.Join(rel, e.Name)
+		if e.IsDir {
+			if err = walk(ctx, src, root, p, files); err != nil {
+				return err
+			}
+		} else {
+			files[p] = e.Size
+		}
+	}
+	return nil
+}
+
+// fetch downloads the file to dst, the part left by an interrupted attempt
+// is kept. done is the bytes of the other files fetched for the progress.
+func fetch(task *tool.DownloadTask, src source, p, dst string, size, done int64) error {
+	var offset int64
+	if info, err := os.Stat(dst); err == nil && info.Size() <= size {
+		offset = info.Size()
+	}
+	if offset == size && size > 0 {
+		return nil
+	}
+	rc, err := src.Open(p, offset, size)
+	if err != nil {
+		return errors.WithMessagef(err, "failed open [%s]", p)
+	}
+	defer rc.Close()
+	file, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE, 0o666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err = file.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	total := task.GetTotalBytes()
+	done += offset
+	return utils.CopyWithCtx(task.Ctx(), file, stream.TaskDownloadLimitReader(task.Ctx(), rc), total, func(p float64) {
+		if total > 0 {
+			task.SetProgress(min(float64(done)/float64(total)*100+p, 100))
+		}
+	})
+}
+
+var (
+	_ tool.Tool     = (*Remote)(nil)
+	_ tool.Streamer = (*Remote)(nil)
+)
+
+func init() {
+	tool.Tools.Add(&Remote{})
+}
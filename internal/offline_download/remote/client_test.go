@@ -0,0 +1,330 @@
+package remote
+
+import (
+	"context"
+	"crypto/ed25519"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	stdpath "path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/task_group"
+	"github.com/OpenListTeam/tache"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/webdav"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	tool.DownloadTaskManager = tache.NewManager[*tool.DownloadTask](tache.WithWorks(1))
+	tool.TransferTaskManager = tache.NewManager[*tool.TransferTask](tache.WithWorks(1))
+	tool.DownloadScheduler.SetWorkers(1)
+	tool.TransferScheduler.SetWorkers(1)
+}
+
+var user = &model.User{ID: 1, Username: "user", BasePath: "/", Role: model.GENERAL}
+
+// writeFiles writes the files of the paths relative to root, which are the
+// contents too.
+func writeFiles(t *testing.T, root string, files ...string) {
+	for _, name := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(name), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// localStorage mounts a local storage at mountPath, and returns its root.
+func localStorage(t *testing.T, mountPath string, files ...string) string {
+	root := t.TempDir()
+	writeFiles(t, root, files...)
+	ctx := context.Background()
+	id, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: mountPath,
+		Addition:  `{"root_folder_path":"` + root + `"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = op.DeleteStorageById(ctx, id) })
+	return root
+}
+
+func createMeta(t *testing.T, meta *model.Meta) {
+	if err := op.CreateMeta(meta); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = op.DeleteMetaById(meta.ID) })
+}
+
+// files lists the files under root by the paths relative to it.
+func files(t *testing.T, root string) []string {
+	var res []string
+	err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		res = append(res, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(res)
+	return res
+}
+
+func checkContents(t *testing.T, root string, expect ...string) {
+	t.Helper()
+	got := files(t, root)
+	if !slices.Equal(got, expect) {
+		t.Fatalf("expect files %v, got %v", expect, got)
+	}
+	for _, name := range got {
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the files are written with their paths under the src dir
+		if !strings.HasSuffix(string(data), strings.TrimPrefix(name, "d/")) {
+			t.Errorf("unexpected content of %s: %q", name, data)
+		}
+	}
+}
+
+// download runs the download task of the url into /dst, and waits for the
+// transfers.
+func download(t *testing.T, rawURL string, policy tool.DeletePolicy) error {
+	dt := &tool.DownloadTask{
+		Url:          rawURL,
+		DstDirPath:   "/dst",
+		TempDir:      t.TempDir(),
+		DeletePolicy: policy,
+		Toolname:     "Remote",
+	}
+	dt.Creator = user
+	dt.SetCtx(context.Background())
+	// the transfers of the download are a group of /dst, the states of the
+	// tasks aren't read as they are written by the workers meanwhile
+	done := make(chan bool, 1)
+	task_group.TransferCoordinator.AppendPayload("/dst", task_group.GroupCallback(func(_ context.Context, hasSuccess bool) {
+		done <- hasSuccess
+	}))
+	if err := dt.Run(); err != nil {
+		return err
+	}
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Error("the transfers failed")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the transfers aren't done")
+	}
+	tool.TransferTaskManager.RemoveAll()
+	return nil
+}
+
+func TestRunStorageAccess(t *testing.T) {
+	localStorage(t, "/src", "d/a.txt", "d/.secret", "d/p/b.txt")
+	createMeta(t, &model.Meta{Path: "/src/d", Hide: `^\.secret$`})
+	createMeta(t, &model.Meta{Path: "/src/d/p", Password: "pw", PSub: true})
+	cases := []struct {
+		url string
+		err error
+	}{
+		{"openlist://src/d/a.txt", nil},
+		{"openlist://src/d/.secret", errs.PermissionDenied},
+		{"openlist://src/d/p/b.txt", errs.PermissionDenied},
+		{"openlist://:wrong@src/d/p/b.txt", errs.PermissionDenied},
+		{"openlist://:pw@src/d/p/b.txt", nil},
+	}
+	for _, c := range cases {
+		dt := &tool.DownloadTask{Url: c.url, Toolname: "Remote"}
+		dt.Creator = user
+		dt.SetCtx(context.Background())
+		err := Remote{}.Run(dt)
+		if !errors.Is(err, c.err) {
+			t.Errorf("%s: expect %v, got %v", c.url, c.err, err)
+		}
+		if err == nil && !strings.HasPrefix(dt.SrcPath, "/src/d/") {
+			t.Errorf("%s: unexpected src path %q", c.url, dt.SrcPath)
+		}
+		if dt.TempDir != "" {
+			t.Errorf("%s: the temp dir should be kept, got %q", c.url, dt.TempDir)
+		}
+	}
+}
+
+func TestTransferStorageDir(t *testing.T) {
+	localStorage(t, "/src", "d/a.txt", "d/.secret", "d/p/b.txt", "d/q/c.txt")
+	dst := localStorage(t, "/dst")
+	createMeta(t, &model.Meta{Path: "/src/d", Hide: `^\.secret$`})
+	createMeta(t, &model.Meta{Path: "/src/d/p", Password: "pw"})
+
+	// the hidden file and the dir behind another password are skipped
+	if err := download(t, "openlist://src/d", tool.DeleteNever); err != nil {
+		t.Fatal(err)
+	}
+	checkContents(t, dst, "d/a.txt", "d/q/c.txt")
+}
+
+func webdavServer(t *testing.T, files ...string) string {
+	root := t.TempDir()
+	writeFiles(t, root, files...)
+	srv := httptest.NewServer(&webdav.Handler{
+		FileSystem: webdav.Dir(root),
+		LockSystem: webdav.NewMemLS(),
+	})
+	t.Cleanup(srv.Close)
+	return strings.Replace(srv.URL, "http://", "webdav://", 1)
+}
+
+func TestFilesAndOpen(t *testing.T) {
+	u := webdavServer(t, "d/a.txt", "d/sub/b.txt")
+	r := Remote{}
+	got, err := r.Files(context.Background(), u+"/d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []tool.StreamFile{{Path: "d/a.txt", Size: 7}, {Path: "d/sub/b.txt", Size: 11}}
+	if !slices.Equal(got, expect) {
+		t.Fatalf("expect %+v, got %+v", expect, got)
+	}
+	got, err = r.Files(context.Background(), u+"/d/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []tool.StreamFile{{Path: "a.txt", Size: 7}}) {
+		t.Fatalf("unexpected file: %+v", got)
+	}
+	for url, path := range map[string]string{u + "/d": "d/sub/b.txt", u + "/d/sub/b.txt": "b.txt"} {
+		rc, err := r.Open(context.Background(), url, path, 11)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil || string(data) != "d/sub/b.txt" {
+			t.Errorf("%s %s: unexpected content %q, %v", url, path, data, err)
+		}
+	}
+}
+
+func TestStreamDir(t *testing.T) {
+	u := webdavServer(t, "d/a.txt", "d/sub/b.txt")
+	dst := localStorage(t, "/dst")
+	if err := download(t, u+"/d", tool.UploadDownloadStream); err != nil {
+		t.Fatal(err)
+	}
+	checkContents(t, dst, "d/a.txt", "d/sub/b.txt")
+}
+
+// listing is a source of the entries listed by the dirs.
+type listing map[string][]entry
+
+func (l listing) Stat(p string) (*entry, error) {
+	return &entry{Name: stdpath.Base(p), IsDir: true}, nil
+}
+
+func (l listing) List(dir string) ([]entry, error) {
+	return l[dir], nil
+}
+
+func (l listing) Open(string, int64, int64) (io.ReadCloser, error) {
+	return nil, errors.New("not expected")
+}
+
+func (l listing) Close() error {
+	return nil
+}
+
+func TestMaliciousListing(t *testing.T) {
+	for _, name := range []string{"", ".", "..", "../../x", `..\x`, "a/b"} {
+		src := listing{"/d": {{Name: "ok", IsDir: true}}, "/d/ok": {{Name: name, Size: 1}}}
+		if err := walk(context.Background(), src, "/d", "", map[string]int64{}); err == nil {
+			t.Errorf("%q: expect the name refused", name)
+		}
+	}
+	files := map[string]int64{}
+	if err := walk(context.Background(), listing{"/d": {{Name: "a..b", Size: 1}}}, "/d", "", files); err != nil || files["a..b"] != 1 {
+		t.Errorf("unexpected files %v, %v", files, err)
+	}
+	// the root is named by the host
+	u, _ := url.Parse("ftp://../")
+	if _, _, err := stat(listing{}, u); err == nil {
+		t.Error("expect the host refused as the name")
+	}
+
+	dir := t.TempDir()
+	for _, elem := range [][]string{{".."}, {"d", "..", ".."}, {"d", "../../x"}, {"."}} {
+		if p, err := tempPath(dir, elem...); err == nil {
+			t.Errorf("%v: expect refused, got %s", elem, p)
+		}
+	}
+	if p, err := tempPath(dir, "d", "a/b"); err != nil || p != filepath.Join(dir, "d", "a", "b") {
+		t.Errorf("unexpected path %s, %v", p, err)
+	}
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint := ssh.FingerprintSHA256(key)
+	if _, err = hostKeyCallback(&url.URL{Scheme: "sftp", Host: "host"}); err == nil {
+		t.Error("expect the fingerprint required")
+	}
+	for _, query := range []string{
+		url.Values{"fingerprint": {fingerprint}}.Encode(),
+		// unescaped, and without the prefix
+		"fingerprint=" + strings.TrimPrefix(fingerprint, "SHA256:"),
+	} {
+		callback, err := hostKeyCallback(&url.URL{Scheme: "sftp", Host: "host", RawQuery: query})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = callback("host:22", nil, key); err != nil {
+			t.Errorf("%s: expect the key accepted, got %v", query, err)
+		}
+	}
+	callback, err := hostKeyCallback(&url.URL{Scheme: "sftp", Host: "host", RawQuery: "fingerprint=SHA256:other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = callback("host:22", nil, key); err == nil {
+		t.Error("expect the key refused")
+	}
+}
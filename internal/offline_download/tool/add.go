@@ -2,9 +2,11 @@ package tool
 
 import (
 	"context"
+	"encoding/hex"
 	"net/url"
 	stdpath "path"
 	"path/filepath"
+	"strings"
 
 	_115 "github.com/OpenListTeam/OpenList/v4/drivers/115"
 	_115_open "github.com/OpenListTeam/OpenList/v4/drivers/115_open"
@@ -21,6 +23,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
@@ -41,9 +44,26 @@ type AddURLArgs struct {
 	DstDirPath   string
 	Tool         string
 	DeletePolicy DeletePolicy
+	// the headers of the requests, e.g. Cookie and Referer, SimpleHttp only
+	Headers map[string]string
+	// the expected hash of the file, "sha256:<hex>" or the hex only, SimpleHttp only
+	Checksum string
+	// the category or tag matched by the rules
+	Category string
 }
 
 func AddURL(ctx context.Context, args *AddURLArgs) (task.TaskExtensionInfo, error) {
+	taskCreator, _ := ctx.Value(conf.UserKey).(*model.User) // taskCreator is nil when convert failed
+	// the dst is chosen by the rules if not given, and chosen again by the
+	// downloaded objects when transferring
+	autoRoute := args.DstDirPath == ""
+	if autoRoute {
+		rule := matchRule(taskCreator, urlHost(args.URL), args.Category, urlName(args.URL), -1, true)
+		if rule == nil {
+			return nil, errors.New("未指定目标路径且没有匹配的规则")
+		}
+		args.DstDirPath = rule.DstPath
+	}
 	// check storage
 	storage, dstDirActualPath, err := op.GetStorageAndActualPath(args.DstDirPath)
 	if err != nil {
@@ -65,8 +85,22 @@ func AddURL(ctx context.Context, args *AddURLArgs) (task.TaskExtensionInfo, erro
 			return nil, errors.WithStack(errs.NotFolder)
 		}
 	}
-	// try putting url
-	if args.Tool == "SimpleHttp" {
+	if len(args.Headers) > 0 || args.Checksum != "" {
+		if args.Tool != "SimpleHttp" {
+			return nil, errors.New("自定义请求头和校验和仅支持 SimpleHttp")
+		}
+		if args.Checksum != "" {
+			if _, _, err := ParseChecksum(args.Checksum); err != nil {
+				return nil, err
+			}
+			if args.DeletePolicy == UploadDownloadStream {
+				return nil, errors.New("边下边传时无法校验文件")
+			}
+		}
+	}
+	// try putting url, the storage fetches the url by itself so it is
+	// skipped if the requests need custom headers or the file is verified
+	if args.Tool == "SimpleHttp" && len(args.Headers) == 0 && args.Checksum == "" {
 		err = tryPutUrl(ctx, args.DstDirPath, args.URL)
 		if err == nil || !errors.Is(err, errs.NotImplement) {
 			return nil, err
@@ -86,6 +120,11 @@ func AddURL(ctx context.Context, args *AddURLArgs) (task.TaskExtensionInfo, erro
 		}
 	}
 
+	if args.Tool == "Remote" && strings.HasPrefix(args.URL, "openlist://") {
+		// the source in a storage is never deleted
+		args.DeletePolicy = DeleteNever
+	}
+
 	uid := uuid.NewString()
 	tempDir := filepath.Join(conf.Conf.TempDir, args.Tool, uid)
 	deletePolicy := args.DeletePolicy
@@ -144,7 +183,6 @@ func AddURL(ctx context.Context, args *AddURLArgs) (task.TaskExtensionInfo, erro
 		}
 	}
 
-	taskCreator, _ := ctx.Value(conf.UserKey).(*model.User) // taskCreator is nil when convert failed
 	t := &DownloadTask{
 		TaskExtension: task.TaskExtension{
 			Creator: taskCreator,
@@ -155,12 +193,41 @@ func AddURL(ctx context.Context, args *AddURLArgs) (task.TaskExtensionInfo, erro
 		TempDir:      tempDir,
 		DeletePolicy: deletePolicy,
 		Toolname:     args.Tool,
+		Headers:      args.Headers,
+		Checksum:     args.Checksum,
+		Category:     args.Category,
+		AutoRoute:    autoRoute,
 		tool:         tool,
 	}
 	DownloadTaskManager.Add(t)
 	return t, nil
 }
 
+// ParseChecksum parses "<hash>:<hex>", the hash is guessed by the length
+// if only the hex is given.
+func ParseChecksum(checksum string) (*utils.HashType, string, error) {
+	name, sum, ok := strings.Cut(strings.TrimSpace(checksum), ":")
+	if !ok {
+		sum = name
+		switch len(sum) {
+		case utils.MD5.Width:
+			name = utils.MD5.Name
+		case utils.SHA1.Width:
+			name = utils.SHA1.Name
+		case utils.SHA256.Width:
+			name = utils.SHA256.Name
+		}
+	}
+	ht, ok := utils.GetHashByName(strings.ToLower(name))
+	if !ok || ht.Width != len(sum) {
+		return nil, "", errors.Errorf("无效的校验和: %s", checksum)
+	}
+	if _, err := hex.DecodeString(sum); err != nil {
+		return nil, "", errors.Errorf("无效的校验和: %s", checksum)
+	}
+	return ht, strings.ToLower(sum), nil
+}
+
 func tryPutUrl(ctx context.Context, path, urlStr string) error {
 	var dstName string
 	u, err := url.Parse(urlStr)
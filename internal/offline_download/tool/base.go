@@ -1,6 +1,9 @@
 package tool
 
 import (
+	"context"
+	"io"
+
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 )
 
@@ -36,3 +39,18 @@ type Tool interface {
 	// Run for simple http download
 	Run(task *DownloadTask) error
 }
+
+// StreamFile is a file of the url, by the path relative to the dir of the url.
+type StreamFile struct {
+	Path string
+	Size int64
+}
+
+// Streamer is a tool which can upload while downloading, the files are read
+// from the source by the transfer tasks without the temp dir.
+type Streamer interface {
+	// Files lists the file of the url, or the files in the dir of the url
+	Files(ctx context.Context, url string) ([]StreamFile, error)
+	// Open reads the file of size at path relative to the dir of the url
+	Open(ctx context.Context, url, path string, size int64) (io.ReadCloser, error)
+}
@@ -0,0 +1,82 @@
+package tool
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Headers are the headers to request a url with, which may carry cookies or
+// credentials. They are persisted with the tasks encrypted by a key derived
+// from the jwt secret, so the database alone doesn't reveal them.
+type Headers map[string]string
+
+func headersCipher() (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte("task headers\n" + conf.Conf.JwtSecret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (h Headers) MarshalJSON() ([]byte, error) {
+	if h == nil {
+		return []byte("null"), nil
+	}
+	plain, err := json.Marshal(map[string]string(h))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := headersCipher()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return json.Marshal(base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plain, nil)))
+}
+
+// UnmarshalJSON also reads the plain headers of the tasks saved before. The
+// headers which can't be decrypted, as the jwt secret is changed, are
+// dropped instead of failing the task.
+func (h *Headers) UnmarshalJSON(data []byte) error {
+	var encrypted string
+	if json.Unmarshal(data, &encrypted) != nil {
+		return json.Unmarshal(data, (*map[string]string)(h))
+	}
+	*h = nil
+	if err := h.decrypt(encrypted); err != nil {
+		log.Warnf("failed decrypt the headers of the task, they are dropped: %v", err)
+	}
+	return nil
+}
+
+func (h *Headers) decrypt(encrypted string) error {
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return err
+	}
+	aead, err := headersCipher()
+	if err != nil {
+		return err
+	}
+	size := aead.NonceSize()
+	if len(sealed) < size {
+		return errors.New("invalid encrypted headers")
+	}
+	plain, err := aead.Open(nil, sealed[:size], sealed[size:], nil)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(plain, (*map[string]string)(h))
+}
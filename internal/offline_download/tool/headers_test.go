@@ -0,0 +1,77 @@
+package tool
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+func TestParseChecksum(t *testing.T) {
+	md5 := strings.Repeat("A", 32)
+	sha1 := strings.Repeat("b", 40)
+	tests := []struct {
+		checksum string
+		ht       *utils.HashType
+		sum      string
+	}{
+		{md5, utils.MD5, strings.ToLower(md5)},
+		{" sha1:" + sha1 + " ", utils.SHA1, sha1},
+		{"SHA256:" + strings.Repeat("c", 64), utils.SHA256, strings.Repeat("c", 64)},
+		{"md5:" + sha1, nil, ""},
+		{strings.Repeat("g", 32), nil, ""},
+		{"crc:1234", nil, ""},
+	}
+	for _, tt := range tests {
+		ht, sum, err := ParseChecksum(tt.checksum)
+		if tt.ht == nil {
+			if err == nil {
+				t.Errorf("%q: expect an error", tt.checksum)
+			}
+			continue
+		}
+		if err != nil || ht != tt.ht || sum != tt.sum {
+			t.Errorf("%q: expect %s %s, got %v %s %v", tt.checksum, tt.ht.Name, tt.sum, ht, sum, err)
+		}
+	}
+}
+
+func TestHeadersPersisted(t *testing.T) {
+	conf.Conf = conf.DefaultConfig("data")
+	task := &DownloadTask{Url: "https://example.com/a", Headers: Headers{"Cookie": "session=secret"}}
+	data, err := json.Marshal(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") || strings.Contains(string(data), "Cookie") {
+		t.Fatalf("the headers are persisted in plaintext: %s", data)
+	}
+	var got DownloadTask
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Headers["Cookie"] != "session=secret" {
+		t.Errorf("unexpected headers: %v", got.Headers)
+	}
+
+	// the tasks saved before keep their plain headers
+	got = DownloadTask{}
+	if err = json.Unmarshal([]byte(`{"url":"u","headers":{"Authorization":"Bearer x"}}`), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Headers["Authorization"] != "Bearer x" {
+		t.Errorf("unexpected plain headers: %v", got.Headers)
+	}
+
+	// dropped if the secret is changed, the task is still restored
+	conf.Conf.JwtSecret = "another"
+	got = DownloadTask{}
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Url != task.Url || got.Headers != nil {
+		t.Errorf("unexpected task: %+v", got)
+	}
+}
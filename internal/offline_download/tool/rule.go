@@ -0,0 +1,227 @@
+package tool
+
+import (
+	"encoding/json"
+	"net/url"
+	stdpath "path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/archive/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// PostProcess is done by the transfer tasks of a download matched by a rule.
+type PostProcess struct {
+	Decompress  bool   `json:"decompress,omitempty"`
+	Rename      string `json:"rename,omitempty"`
+	JunkPattern string `json:"junk_pattern,omitempty"`
+	// for the rename template
+	Host     string `json:"host,omitempty"`
+	Category string `json:"category,omitempty"`
+	// junk is compiled from JunkPattern once the post process is made or loaded
+	junk *regexp.Regexp
+}
+
+func newPostProcess(rule *model.OfflineDownloadRule, host, category string) *PostProcess {
+	if !rule.Decompress && rule.Rename == "" && rule.JunkPattern == "" {
+		return nil
+	}
+	return &PostProcess{
+		Decompress:  rule.Decompress,
+		Rename:      rule.Rename,
+		JunkPattern: rule.JunkPattern,
+		Host:        host,
+		Category:    category,
+		junk:        compileJunk(rule.JunkPattern),
+	}
+}
+
+// UnmarshalJSON compiles the junk pattern of the post process recovered.
+func (p *PostProcess) UnmarshalJSON(data []byte) error {
+	type postProcess PostProcess
+	if err := json.Unmarshal(data, (*postProcess)(p)); err != nil {
+		return err
+	}
+	p.junk = compileJunk(p.JunkPattern)
+	return nil
+}
+
+// compileJunk returns nil if the pattern is empty or invalid, nothing is junk then.
+func compileJunk(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		log.Warnf("invalid junk pattern %s: %v", pattern, err)
+		return nil
+	}
+	return re
+}
+
+// child returns the post process of the objects in a dir, only the top
+// object is renamed.
+func (p *PostProcess) child() *PostProcess {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	c.Rename = ""
+	return &c
+}
+
+func (p *PostProcess) isJunk(name string) bool {
+	return p != nil && p.junk != nil && p.junk.MatchString(name)
+}
+
+func (p *PostProcess) rename(name string) string {
+	if p == nil || p.Rename == "" {
+		return name
+	}
+	res := strings.NewReplacer(
+		"{name}", strings.TrimSuffix(name, stdpath.Ext(name)),
+		"{ext}", utils.SourceExt(name),
+		"{host}", p.Host,
+		"{category}", p.Category,
+		"{date}", time.Now().Format("2006-01-02"),
+	).Replace(p.Rename)
+	// the template can't make a path
+	res = strings.TrimSpace(strings.ReplaceAll(res, "/", "_"))
+	if res == "" || res == "." || res == ".." {
+		return name
+	}
+	return res
+}
+
+// shouldDecompress tells whether the file is an archive, the parts other than
+// the first one of a multipart archive are skipped.
+func (p *PostProcess) shouldDecompress(name string) bool {
+	if p == nil || !p.Decompress {
+		return false
+	}
+	for _, partExt := range tool.MultipartExtensions {
+		if m := partExt.PartFileFormat.FindStringSubmatch(name); len(m) == 2 {
+			i, err := strconv.Atoi(m[1])
+			return err == nil && i == partExt.SecondPartIndex-1
+		}
+	}
+	ext := name
+	for {
+		var found bool
+		if _, ext, found = strings.Cut(ext, "."); !found {
+			return false
+		}
+		if _, _, err := tool.GetArchiveTool("." + ext); err == nil {
+			return true
+		}
+	}
+}
+
+func splitList(s string) []string {
+	var res []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func matchHost(patterns []string, host string) bool {
+	for _, p := range patterns {
+		if p == host || (strings.HasPrefix(p, "*.") && strings.HasSuffix(host, p[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleMatches tells whether the download matches the rule, the conditions
+// unknown before downloading are ignored, i.e. the size if size < 0 and the
+// extension if the name is empty.
+func ruleMatches(rule *model.OfflineDownloadRule, host, category, name string, size int64) bool {
+	if hosts := splitList(rule.Hosts); len(hosts) > 0 && !matchHost(hosts, host) {
+		return false
+	}
+	if exts := splitList(rule.Extensions); len(exts) > 0 && name != "" {
+		if !utils.SliceContains(exts, utils.Ext(name)) {
+			return false
+		}
+	}
+	if categories := splitList(rule.Categories); len(categories) > 0 && !utils.SliceContains(categories, strings.ToLower(category)) {
+		return false
+	}
+	if size >= 0 {
+		if rule.MinSize > 0 && size < rule.MinSize {
+			return false
+		}
+		if rule.MaxSize > 0 && size > rule.MaxSize {
+			return false
+		}
+	}
+	return true
+}
+
+// matchRule returns the first enabled rule matched, the rules leading out of
+// the base path of the user are skipped, so are the rules without a dst if
+// withDst.
+func matchRule(user *model.User, host, category, name string, size int64, withDst bool) *model.OfflineDownloadRule {
+	rules, err := db.GetEnabledOfflineDownloadRules()
+	if err != nil {
+		log.Errorf("failed get offline download rules: %+v", err)
+		return nil
+	}
+	for i := range rules {
+		rule := &rules[i]
+		if withDst && rule.DstPath == "" {
+			continue
+		}
+		if rule.DstPath != "" && user != nil && !utils.IsSubPath(user.BasePath, rule.DstPath) {
+			continue
+		}
+		if ruleMatches(rule, host, category, name, size) {
+			return rule
+		}
+	}
+	return nil
+}
+
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// urlName returns the name of the file in the url, magnets are named by dn.
+func urlName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	if u.Scheme == "magnet" {
+		return u.Query().Get("dn")
+	}
+	return stdpath.Base(u.Path)
+}
+
+// route returns the dst dir and the post process of an object downloaded.
+func (t *DownloadTask) route(name string, size int64) (string, *PostProcess) {
+	host := urlHost(t.Url)
+	rule := matchRule(t.Creator, host, t.Category, name, size, false)
+	if rule == nil {
+		return t.DstDirPath, nil
+	}
+	dst := t.DstDirPath
+	if t.AutoRoute && rule.DstPath != "" {
+		dst = rule.DstPath
+	}
+	return dst, newPostProcess(rule, host, t.Category)
+}
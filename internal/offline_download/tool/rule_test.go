@@ -0,0 +1,87 @@
+package tool
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+func TestRuleMatches(t *testing.T) {
+	rule := &model.OfflineDownloadRule{
+		Hosts:      "*.example.com, files.test",
+		Extensions: "mkv,MP4",
+		MinSize:    100,
+	}
+	tests := []struct {
+		host, name string
+		size       int64
+		want       bool
+	}{
+		{"cdn.example.com", "a.mkv", 200, true},
+		{"files.test", "a.mp4", 200, true},
+		{"example.org", "a.mkv", 200, false},
+		{"cdn.example.com", "a.zip", 200, false},
+		{"cdn.example.com", "a.mkv", 50, false},
+		// unknown before downloading
+		{"cdn.example.com", "", -1, true},
+	}
+	for _, tt := range tests {
+		if got := ruleMatches(rule, tt.host, "", tt.name, tt.size); got != tt.want {
+			t.Errorf("ruleMatches(%s, %s, %d) = %v, want %v", tt.host, tt.name, tt.size, got, tt.want)
+		}
+	}
+	if ruleMatches(&model.OfflineDownloadRule{Categories: "movie"}, "", "tv", "a.mkv", 1) {
+		t.Errorf("category tv should not match movie")
+	}
+}
+
+func TestPostProcessRename(t *testing.T) {
+	p := &PostProcess{Rename: "[{host}] {name}.{ext}", Host: "example.com"}
+	if got := p.rename("a.b.mkv"); got != "[example.com] a.b.mkv" {
+		t.Errorf("rename = %s", got)
+	}
+	if got := (&PostProcess{Rename: "{category}"}).rename("a.mkv"); got != "a.mkv" {
+		t.Errorf("empty rename should keep the name, got %s", got)
+	}
+	if got := p.child().rename("a.mkv"); got != "a.mkv" {
+		t.Errorf("child should not be renamed, got %s", got)
+	}
+}
+
+func TestAddOutput(t *testing.T) {
+	dt := &DownloadTask{}
+	post := newPostProcess(&model.OfflineDownloadRule{Rename: "{name}-1.{ext}", JunkPattern: `\.url$`}, "", "")
+	dt.addOutput("/dst", "a.mkv", post)
+	dt.addOutput("/dst", "ad.url", post)
+	dt.addOutput("/other", "b.mkv", nil)
+	if !slices.Equal(dt.GetOutputs(), []string{"/dst/a-1.mkv", "/other/b.mkv"}) {
+		t.Errorf("unexpected outputs: %v", dt.GetOutputs())
+	}
+}
+
+func TestPostProcessJunk(t *testing.T) {
+	post := newPostProcess(&model.OfflineDownloadRule{JunkPattern: `(?i)\.(url|txt)$`}, "", "")
+	if !post.isJunk("AD.URL") || post.isJunk("a.mkv") {
+		t.Error("unexpected junk matched")
+	}
+	if !post.child().isJunk("b.txt") {
+		t.Error("the child should keep the junk pattern")
+	}
+	// the pattern is compiled again when the task is recovered
+	data, err := json.Marshal(post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recovered PostProcess
+	if err = json.Unmarshal(data, &recovered); err != nil {
+		t.Fatal(err)
+	}
+	if !recovered.isJunk("ad.url") || recovered.JunkPattern != post.JunkPattern {
+		t.Errorf("unexpected post process recovered %+v", recovered)
+	}
+	if newPostProcess(&model.OfflineDownloadRule{JunkPattern: `(`}, "", "").isJunk("(") {
+		t.Error("nothing is junk by an invalid pattern")
+	}
+}
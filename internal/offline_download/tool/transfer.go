@@ -3,13 +3,18 @@ package tool
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"path"
 	stdpath "path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
@@ -28,10 +33,22 @@ type TransferTask struct {
 	fs.TaskData
 	DeletePolicy DeletePolicy `json:"delete_policy"`
 	Url          string       `json:"url"`
-	groupID      string       `json:"-"`
+	// the headers to request the url with when streaming
+	Headers Headers `json:"headers,omitempty"`
+	// the Streamer tool the file is read by when streaming, SrcActualPath
+	// is the path relative to the dir of the url then
+	Tool string `json:"tool,omitempty"`
+	// the post process of the rule matched
+	Post    *PostProcess `json:"post,omitempty"`
+	groupID string       `json:"-"`
 }
 
 func (t *TransferTask) Run() error {
+	release, err := TransferScheduler.Acquire(&t.TaskExtension)
+	if err != nil {
+		return err
+	}
+	defer release()
 	if t.SrcStorage == nil && t.SrcStorageMp != "" {
 		if srcStorage, _, err := op.GetStorageAndActualPath(t.SrcStorageMp); err == nil {
 			t.SrcStorage = srcStorage
@@ -49,17 +66,17 @@ func (t *TransferTask) Run() error {
 	t.ClearEndTime()
 	t.SetStartTime(time.Now())
 	defer func() { t.SetEndTime(time.Now()) }()
+	if t.Post.isJunk(stdpath.Base(t.SrcActualPath)) {
+		t.Status = "skipped junk file"
+		return nil
+	}
 	if t.SrcStorage == nil {
 		if t.DeletePolicy == UploadDownloadStream {
-			rr, err := stream.GetRangeReaderFromLink(t.GetTotalBytes(), &model.Link{URL: t.Url})
-			if err != nil {
-				return err
-			}
-			r, err := rr.RangeRead(t.Ctx(), http_range.Range{Length: t.GetTotalBytes()})
+			r, err := t.openStream()
 			if err != nil {
 				return err
 			}
-			name := t.SrcActualPath
+			name := t.Post.rename(stdpath.Base(t.SrcActualPath))
 			mimetype := utils.GetMimeType(name)
 			s := &stream.FileStream{
 				Ctx: t.Ctx(),
@@ -73,20 +90,57 @@ func (t *TransferTask) Run() error {
 				Mimetype: mimetype,
 				Closers:  utils.NewClosers(r),
 			}
-			return op.Put(context.WithValue(t.Ctx(), conf.SkipHookKey, struct{}{}), t.DstStorage, t.DstActualPath, s, t.SetProgress)
+			if err = op.Put(context.WithValue(t.Ctx(), conf.SkipHookKey, struct{}{}), t.DstStorage, t.DstActualPath, s, t.SetProgress); err != nil {
+				return err
+			}
+			t.decompress(name)
+			return nil
 		}
 		return transferStdPath(t)
 	}
 	return transferObjPath(t)
 }
 
+// openStream opens the file of the url, by the tool or by the http requests.
+func (t *TransferTask) openStream() (io.ReadCloser, error) {
+	if t.Tool != "" {
+		tool, err := Tools.Get(t.Tool)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed get tool")
+		}
+		s, ok := tool.(Streamer)
+		if !ok {
+			return nil, errors.Errorf("tool %s can't upload while downloading", t.Tool)
+		}
+		return s.Open(t.Ctx(), t.Url, t.SrcActualPath, t.GetTotalBytes())
+	}
+	header := http.Header{}
+	for k, v := range t.Headers {
+		header.Set(k, v)
+	}
+	rr, err := stream.GetRangeReaderFromLink(t.GetTotalBytes(), &model.Link{URL: t.Url, Header: header})
+	if err != nil {
+		return nil, err
+	}
+	return rr.RangeRead(t.Ctx(), http_range.Range{Length: t.GetTotalBytes()})
+}
+
 func (t *TransferTask) GetName() string {
 	if t.DeletePolicy == UploadDownloadStream {
-		return fmt.Sprintf("upload [%s](%s) to [%s](%s)", t.SrcActualPath, t.Url, t.DstStorageMp, t.DstActualPath)
+		return fmt.Sprintf("upload [%s](%s) to [%s](%s)", t.SrcActualPath, t.GetSrcPath(), t.DstStorageMp, t.DstActualPath)
 	}
 	return fmt.Sprintf("transfer [%s](%s) to [%s](%s)", t.SrcStorageMp, t.SrcActualPath, t.DstStorageMp, t.DstActualPath)
 }
 
+func (t *TransferTask) GetSrcPath() string {
+	if t.DeletePolicy == UploadDownloadStream {
+		if u, err := url.Parse(t.Url); err == nil {
+			return u.Redacted()
+		}
+	}
+	return t.TaskData.GetSrcPath()
+}
+
 func (t *TransferTask) OnSucceeded() {
 	if t.DeletePolicy == DeleteOnUploadSucceed || t.DeletePolicy == DeleteAlways {
 		if t.SrcStorage == nil {
@@ -121,31 +175,37 @@ func (t *TransferTask) SetRetry(retry int, maxRetry int) {
 
 var (
 	TransferTaskManager *tache.Manager[*TransferTask]
+	TransferScheduler   = task.NewScheduler("offline_download_transfer")
 )
 
-func transferStd(ctx context.Context, tempDir, dstDirPath string, deletePolicy DeletePolicy) error {
-	dstStorage, dstDirActualPath, err := op.GetStorageAndActualPath(dstDirPath)
-	if err != nil {
-		return errors.WithMessage(err, "failed get dst storage")
-	}
+func transferStd(dt *DownloadTask) error {
+	ctx, tempDir := dt.Ctx(), dt.TempDir
 	entries, err := os.ReadDir(tempDir)
 	if err != nil {
 		return err
 	}
 	taskCreator, _ := ctx.Value(conf.UserKey).(*model.User)
 	for _, entry := range entries {
+		srcPath := stdpath.Join(tempDir, entry.Name())
+		dstDirPath, post := dt.route(entry.Name(), stdSize(srcPath))
+		dstStorage, dstDirActualPath, err := op.GetStorageAndActualPath(dstDirPath)
+		if err != nil {
+			return errors.WithMessage(err, "failed get dst storage")
+		}
+		dt.addOutput(dstDirPath, entry.Name(), post)
 		t := &TransferTask{
 			TaskData: fs.TaskData{
 				TaskExtension: task.TaskExtension{
 					Creator: taskCreator,
 					ApiUrl:  common.GetApiUrl(ctx),
 				},
-				SrcActualPath: stdpath.Join(tempDir, entry.Name()),
+				SrcActualPath: srcPath,
 				DstActualPath: dstDirActualPath,
 				DstStorage:    dstStorage,
 				DstStorageMp:  dstStorage.GetStorage().MountPath,
 			},
-			DeletePolicy: deletePolicy,
+			DeletePolicy: dt.DeletePolicy,
+			Post:         post,
 		}
 		t.groupID = path.Join(t.DstStorageMp, t.DstActualPath)
 		task_group.TransferCoordinator.AddTask(t.groupID, nil)
@@ -166,15 +226,16 @@ func transferStdPath(t *TransferTask) error {
 		if err != nil {
 			return err
 		}
-		dstDirActualPath := stdpath.Join(t.DstActualPath, info.Name())
+		dstDirActualPath := stdpath.Join(t.DstActualPath, t.Post.rename(info.Name()))
 		task_group.TransferCoordinator.AppendPayload(t.groupID, task_group.DstPathToHook(dstDirActualPath))
 		for _, entry := range entries {
 			srcRawPath := stdpath.Join(t.SrcActualPath, entry.Name())
 			task := &TransferTask{
 				TaskData: fs.TaskData{
 					TaskExtension: task.TaskExtension{
-						Creator: t.Creator,
-						ApiUrl:  t.ApiUrl,
+						Creator:  t.Creator,
+						ApiUrl:   t.ApiUrl,
+						Priority: t.Priority,
 					},
 					SrcActualPath: srcRawPath,
 					DstActualPath: dstDirActualPath,
@@ -184,6 +245,7 @@ func transferStdPath(t *TransferTask) error {
 				},
 				groupID:      t.groupID,
 				DeletePolicy: t.DeletePolicy,
+				Post:         t.Post.child(),
 			}
 			task_group.TransferCoordinator.AddTask(t.groupID, nil)
 			TransferTaskManager.Add(task)
@@ -203,11 +265,12 @@ func transferStdFile(t *TransferTask) error {
 	if err != nil {
 		return errors.Wrapf(err, "failed to get file %s", t.SrcActualPath)
 	}
-	mimetype := utils.GetMimeType(t.SrcActualPath)
+	name := t.Post.rename(filepath.Base(t.SrcActualPath))
+	mimetype := utils.GetMimeType(name)
 	s := &stream.FileStream{
 		Ctx: t.Ctx(),
 		Obj: &model.Object{
-			Name:     filepath.Base(t.SrcActualPath),
+			Name:     name,
 			Size:     info.Size(),
 			Modified: info.ModTime(),
 			IsFolder: false,
@@ -217,7 +280,25 @@ func transferStdFile(t *TransferTask) error {
 		Closers:  utils.NewClosers(rc),
 	}
 	t.SetTotalBytes(info.Size())
-	return op.Put(context.WithValue(t.Ctx(), conf.SkipHookKey, struct{}{}), t.DstStorage, t.DstActualPath, s, t.SetProgress)
+	if err = op.Put(context.WithValue(t.Ctx(), conf.SkipHookKey, struct{}{}), t.DstStorage, t.DstActualPath, s, t.SetProgress); err != nil {
+		return err
+	}
+	t.decompress(name)
+	return nil
+}
+
+// stdSize returns the size of the file, or the total size of the files in the dir.
+func stdSize(p string) int64 {
+	var size int64
+	_ = filepath.WalkDir(p, func(_ string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
 }
 
 func removeStdTemp(t *TransferTask) {
@@ -230,21 +311,24 @@ func removeStdTemp(t *TransferTask) {
 	}
 }
 
-func transferObj(ctx context.Context, tempDir, dstDirPath string, deletePolicy DeletePolicy) error {
+func transferObj(dt *DownloadTask) error {
+	ctx, tempDir := dt.Ctx(), dt.TempDir
 	srcStorage, srcObjActualPath, err := op.GetStorageAndActualPath(tempDir)
 	if err != nil {
 		return errors.WithMessage(err, "failed get src storage")
 	}
-	dstStorage, dstDirActualPath, err := op.GetStorageAndActualPath(dstDirPath)
-	if err != nil {
-		return errors.WithMessage(err, "failed get dst storage")
-	}
 	objs, err := op.List(ctx, srcStorage, srcObjActualPath, model.ListArgs{})
 	if err != nil {
 		return errors.WithMessagef(err, "failed list src [%s] objs", tempDir)
 	}
 	taskCreator, _ := ctx.Value(conf.UserKey).(*model.User) // taskCreator is nil when convert failed
 	for _, obj := range objs {
+		dstDirPath, post := dt.route(obj.GetName(), obj.GetSize())
+		dstStorage, dstDirActualPath, err := op.GetStorageAndActualPath(dstDirPath)
+		if err != nil {
+			return errors.WithMessage(err, "failed get dst storage")
+		}
+		dt.addOutput(dstDirPath, obj.GetName(), post)
 		t := &TransferTask{
 			TaskData: fs.TaskData{
 				TaskExtension: task.TaskExtension{
@@ -258,7 +342,8 @@ func transferObj(ctx context.Context, tempDir, dstDirPath string, deletePolicy D
 				SrcStorageMp:  srcStorage.GetStorage().MountPath,
 				DstStorageMp:  dstStorage.GetStorage().MountPath,
 			},
-			DeletePolicy: deletePolicy,
+			DeletePolicy: dt.DeletePolicy,
+			Post:         post,
 		}
 		t.groupID = path.Join(t.DstStorageMp, t.DstActualPath)
 		task_group.TransferCoordinator.AddTask(t.groupID, nil)
@@ -267,6 +352,161 @@ func transferObj(ctx context.Context, tempDir, dstDirPath string, deletePolicy D
 	return nil
 }
 
+// transferSrcObj transfers the object of the src path itself into the dst
+// dir, while transferObjPath transfers the objects in a dir. The objects in
+// the dir the creator can't access are skipped, as the fs handlers do.
+func transferSrcObj(dt *DownloadTask) error {
+	ctx := dt.Ctx()
+	srcStorage, srcObjActualPath, err := op.GetStorageAndActualPath(dt.SrcPath)
+	if err != nil {
+		return errors.WithMessage(err, "failed get src storage")
+	}
+	srcObj, err := op.Get(ctx, srcStorage, srcObjActualPath)
+	if err != nil {
+		return errors.WithMessagef(err, "failed get src [%s]", dt.SrcPath)
+	}
+	name := stdpath.Base(srcObjActualPath)
+	dstDirPath, post := dt.route(name, dt.GetTotalBytes())
+	dstStorage, dstDirActualPath, err := op.GetStorageAndActualPath(dstDirPath)
+	if err != nil {
+		return errors.WithMessage(err, "failed get dst storage")
+	}
+	dt.addOutput(dstDirPath, name, post)
+	taskCreator, _ := ctx.Value(conf.UserKey).(*model.User) // taskCreator is nil when convert failed
+	groupID := path.Join(dstStorage.GetStorage().MountPath, dstDirActualPath)
+	add := func(srcActualPath, dstDirActualPath string, post *PostProcess) {
+		t := &TransferTask{
+			TaskData: fs.TaskData{
+				TaskExtension: task.TaskExtension{
+					Creator: taskCreator,
+					ApiUrl:  common.GetApiUrl(ctx),
+				},
+				SrcActualPath: srcActualPath,
+				DstActualPath: dstDirActualPath,
+				SrcStorage:    srcStorage,
+				DstStorage:    dstStorage,
+				SrcStorageMp:  srcStorage.GetStorage().MountPath,
+				DstStorageMp:  dstStorage.GetStorage().MountPath,
+			},
+			DeletePolicy: dt.DeletePolicy,
+			Post:         post,
+			groupID:      groupID,
+		}
+		task_group.TransferCoordinator.AddTask(groupID, nil)
+		TransferTaskManager.Add(t)
+	}
+	if !srcObj.IsDir() {
+		add(srcObjActualPath, dstDirActualPath, post)
+		return nil
+	}
+	password := ""
+	if u, err := url.Parse(dt.Url); err == nil {
+		password, _ = u.User.Password()
+	}
+	var walk func(srcActualPath, dstDirActualPath string, post *PostProcess) error
+	walk = func(srcActualPath, dstDirActualPath string, post *PostProcess) error {
+		objs, err := op.List(ctx, srcStorage, srcActualPath, model.ListArgs{})
+		if err != nil {
+			return errors.WithMessagef(err, "failed list src [%s] objs", srcActualPath)
+		}
+		task_group.TransferCoordinator.AppendPayload(groupID, task_group.DstPathToHook(dstDirActualPath))
+		for _, obj := range objs {
+			if utils.IsCanceled(ctx) {
+				return ctx.Err()
+			}
+			objPath := stdpath.Join(srcActualPath, obj.GetName())
+			ok, err := CanAccess(dt.Creator, stdpath.Join(srcStorage.GetStorage().MountPath, objPath), password)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			if obj.IsDir() {
+				err = walk(objPath, stdpath.Join(dstDirActualPath, obj.GetName()), post.child())
+				if err != nil {
+					return err
+				}
+			} else {
+				add(objPath, dstDirActualPath, post.child())
+			}
+		}
+		return nil
+	}
+	return walk(srcObjActualPath, stdpath.Join(dstDirActualPath, post.rename(name)), post)
+}
+
+// CanAccess tells whether the user can access the path in the storages by
+// the hides and the password of the nearest meta, as the fs handlers check.
+func CanAccess(user *model.User, path, password string) (bool, error) {
+	if user == nil {
+		return false, nil
+	}
+	meta, err := op.GetNearestMeta(path)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return false, err
+	}
+	return common.CanAccess(user, meta, path, password), nil
+}
+
+// transferStream adds the transfer tasks reading the files of the url by
+// the tool, which are uploaded while downloading.
+func transferStream(dt *DownloadTask, s Streamer) error {
+	ctx := dt.Ctx()
+	files, err := s.Files(ctx, dt.Url)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	// the files are all in the file or the dir of the url
+	name, _, _ := strings.Cut(files[0].Path, "/")
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	dt.SetTotalBytes(total)
+	dstDirPath, post := dt.route(name, total)
+	dstStorage, dstDirActualPath, err := op.GetStorageAndActualPath(dstDirPath)
+	if err != nil {
+		return errors.WithMessage(err, "failed get dst storage")
+	}
+	dt.addOutput(dstDirPath, name, post)
+	taskCreator, _ := ctx.Value(conf.UserKey).(*model.User)
+	groupID := path.Join(dstStorage.GetStorage().MountPath, dstDirActualPath)
+	for _, f := range files {
+		dir, filePost := dstDirActualPath, post
+		if f.Path != name {
+			// the top dir is renamed only
+			dir = stdpath.Join(dstDirActualPath, post.rename(name), stdpath.Dir(strings.TrimPrefix(f.Path, name+"/")))
+			filePost = post.child()
+		}
+		t := &TransferTask{
+			TaskData: fs.TaskData{
+				TaskExtension: task.TaskExtension{
+					Creator:  taskCreator,
+					ApiUrl:   dt.ApiUrl,
+					Priority: dt.Priority,
+				},
+				SrcActualPath: f.Path,
+				DstActualPath: dir,
+				DstStorage:    dstStorage,
+				DstStorageMp:  dstStorage.GetStorage().MountPath,
+			},
+			DeletePolicy: dt.DeletePolicy,
+			Url:          dt.Url,
+			Tool:         dt.Toolname,
+			Post:         filePost,
+			groupID:      groupID,
+		}
+		t.SetTotalBytes(f.Size)
+		task_group.TransferCoordinator.AddTask(groupID, nil)
+		TransferTaskManager.Add(t)
+	}
+	return nil
+}
+
 func transferObjPath(t *TransferTask) error {
 	t.Status = "getting src object"
 	srcObj, err := op.Get(t.Ctx(), t.SrcStorage, t.SrcActualPath)
@@ -279,7 +519,7 @@ func transferObjPath(t *TransferTask) error {
 		if err != nil {
 			return errors.WithMessagef(err, "failed list src [%s] objs", t.SrcActualPath)
 		}
-		dstDirActualPath := stdpath.Join(t.DstActualPath, srcObj.GetName())
+		dstDirActualPath := stdpath.Join(t.DstActualPath, t.Post.rename(srcObj.GetName()))
 		task_group.TransferCoordinator.AppendPayload(t.groupID, task_group.DstPathToHook(dstDirActualPath))
 		for _, obj := range objs {
 			if utils.IsCanceled(t.Ctx()) {
@@ -290,8 +530,9 @@ func transferObjPath(t *TransferTask) error {
 			TransferTaskManager.Add(&TransferTask{
 				TaskData: fs.TaskData{
 					TaskExtension: task.TaskExtension{
-						Creator: t.Creator,
-						ApiUrl:  t.ApiUrl,
+						Creator:  t.Creator,
+						ApiUrl:   t.ApiUrl,
+						Priority: t.Priority,
 					},
 					SrcActualPath: srcObjPath,
 					DstActualPath: dstDirActualPath,
@@ -302,6 +543,7 @@ func transferObjPath(t *TransferTask) error {
 				},
 				groupID:      t.groupID,
 				DeletePolicy: t.DeletePolicy,
+				Post:         t.Post.child(),
 			})
 		}
 		t.Status = "src object is dir, added all transfer tasks of objs"
@@ -319,6 +561,10 @@ func transferObjFile(t *TransferTask) error {
 	if err != nil {
 		return errors.WithMessagef(err, "failed get [%s] link", t.SrcActualPath)
 	}
+	name := t.Post.rename(srcFile.GetName())
+	if name != srcFile.GetName() {
+		srcFile = &model.ObjWrapName{Name: name, Obj: srcFile}
+	}
 	// any link provided is seekable
 	ss, err := stream.NewSeekableStream(&stream.FileStream{
 		Obj: srcFile,
@@ -329,7 +575,28 @@ func transferObjFile(t *TransferTask) error {
 		return errors.WithMessagef(err, "failed get [%s] stream", t.SrcActualPath)
 	}
 	t.SetTotalBytes(ss.GetSize())
-	return op.Put(context.WithValue(t.Ctx(), conf.SkipHookKey, struct{}{}), t.DstStorage, t.DstActualPath, ss, t.SetProgress)
+	if err = op.Put(context.WithValue(t.Ctx(), conf.SkipHookKey, struct{}{}), t.DstStorage, t.DstActualPath, ss, t.SetProgress); err != nil {
+		return err
+	}
+	t.decompress(name)
+	return nil
+}
+
+// decompress decompresses the archive uploaded into a new dir next to it, a
+// failure is logged only since the archive itself is transferred.
+func (t *TransferTask) decompress(name string) {
+	if !t.Post.shouldDecompress(name) {
+		return
+	}
+	dstDir := stdpath.Join(t.DstStorageMp, t.DstActualPath)
+	_, err := fs.ArchiveDecompress(t.Ctx(), stdpath.Join(dstDir, name), dstDir, model.ArchiveDecompressArgs{
+		ArchiveInnerArgs: model.ArchiveInnerArgs{InnerPath: "/"},
+		CacheFull:        true,
+		PutIntoNewDir:    true,
+	})
+	if err != nil {
+		log.Errorf("failed to decompress %s: %+v", stdpath.Join(dstDir, name), err)
+	}
 }
 
 func removeObjTemp(t *TransferTask) {
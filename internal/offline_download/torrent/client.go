@@ -0,0 +1,420 @@
+package torrent
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/net"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// the .torrent files we fetch at most
+	maxTorrentFileSize = 32 * 1024 * 1024
+	// the files a range of the select-only indexes may cover at most
+	maxSelectOnlyRange = 1 << 16
+)
+
+// Torrent is the built-in bittorrent client, it is started by the first
+// download so that it doesn't listen for peers when it isn't used.
+type Torrent struct {
+	mu     sync.Mutex
+	client *torrent.Client
+	ready  bool
+	jobs   map[string]*job
+}
+
+type job struct {
+	t        *torrent.Torrent
+	dir      string
+	so       []int
+	filter   *regexp.Regexp
+	selected []*torrent.File
+	// closed when the files have been selected
+	started chan struct{}
+	// guarded by Torrent.mu
+	seedStart time.Time
+}
+
+// jobState is saved beside the temp dir of a job, so that the job recovered
+// after a restart needs no metadata from the peers, and keeps seeding for the
+// time left only.
+type jobState struct {
+	// the bencoded metainfo
+	MetaInfo  []byte    `json:"metainfo,omitempty"`
+	SeedStart time.Time `json:"seed_start,omitempty"`
+}
+
+func loadState(tempDir string) (*jobState, error) {
+	data, err := os.ReadFile(tool.StatePath(tempDir))
+	if err != nil {
+		return nil, err
+	}
+	state := &jobState{}
+	if err = utils.Json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (j *job) saveState(seedStart time.Time) {
+	var buf bytes.Buffer
+	mi := j.t.Metainfo()
+	if err := mi.Write(&buf); err != nil {
+		log.Warnf("failed encode metainfo of %s: %v", j.t.InfoHash().HexString(), err)
+		return
+	}
+	data, err := utils.Json.Marshal(jobState{MetaInfo: buf.Bytes(), SeedStart: seedStart})
+	if err == nil {
+		err = os.WriteFile(tool.StatePath(j.dir), data, 0o666)
+	}
+	if err != nil {
+		log.Warnf("failed save state of %s: %v", j.t.InfoHash().HexString(), err)
+	}
+}
+
+func (j *job) removeState() {
+	if err := os.Remove(tool.StatePath(j.dir)); err != nil && !os.IsNotExist(err) {
+		log.Warnf("failed remove state of %s: %v", j.t.InfoHash().HexString(), err)
+	}
+}
+
+func (t *Torrent) Run(task *tool.DownloadTask) error {
+	return errs.NotSupport
+}
+
+func (t *Torrent) Name() string {
+	return "BitTorrent"
+}
+
+func (t *Torrent) Items() []model.SettingItem {
+	return []model.SettingItem{
+		{Key: conf.TorrentListenPort, Value: "42069", Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+		{Key: conf.TorrentDHT, Value: "true", Type: conf.TypeBool, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+		{Key: conf.TorrentSeedRatio, Value: "0", Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE, Help: "seeding stops when the uploaded bytes reach this ratio of the downloaded ones, 0 for no limit"},
+		{Key: conf.TorrentSeedtime, Value: "0", Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE, Help: "minutes to seed after downloading, no seeding if both the ratio and the time are 0"},
+		{Key: conf.TorrentFileFilter, Value: "", Type: conf.TypeString, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE, Help: "only the files whose paths match this regexp are downloaded, empty for all"},
+	}
+}
+
+// Init applies the settings, the client is restarted by the next download
+// if there is no running one.
+func (t *Torrent) Init() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := regexp.Compile(setting.GetStr(conf.TorrentFileFilter)); err != nil {
+		return "", errors.Wrap(err, "文件过滤规则无效")
+	}
+	if t.client != nil {
+		if len(t.jobs) > 0 {
+			return "", errors.New("有正在下载的种子，新的设置将在其完成后生效")
+		}
+		t.client.Close()
+		t.client = nil
+	}
+	t.jobs = make(map[string]*job)
+	t.ready = true
+	return "ok", nil
+}
+
+func (t *Torrent) IsReady() bool {
+	return t.ready
+}
+
+// getClient starts the client if it isn't, t.mu must be held.
+func (t *Torrent) getClient() (*torrent.Client, error) {
+	if t.client != nil {
+		return t.client, nil
+	}
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.DataDir = filepath.Join(conf.Conf.TempDir, t.Name())
+	// every torrent has its own storage, this one only keeps the client from
+	// creating a piece completion database
+	cfg.DefaultStorage = storage.NewFileOpts(storage.NewFileClientOpts{
+		ClientBaseDir:   cfg.DataDir,
+		PieceCompletion: storage.NewMapPieceCompletion(),
+	})
+	cfg.ListenPort = setting.GetInt(conf.TorrentListenPort, 42069)
+	cfg.NoDHT = !setting.GetBool(conf.TorrentDHT)
+	cfg.Seed = setting.GetFloat(conf.TorrentSeedRatio, 0) > 0 || setting.GetInt(conf.TorrentSeedtime, 0) > 0
+	client, err := torrent.NewClient(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "bittorrent 客户端启动失败")
+	}
+	log.Infof("bittorrent client listening on port %d", client.LocalPort())
+	t.client = client
+	return client, nil
+}
+
+func (t *Torrent) AddURL(args *tool.AddUrlArgs) (string, error) {
+	u, err := url.Parse(args.Url)
+	if err != nil {
+		return "", errors.Wrap(err, "无法解析 URL")
+	}
+	var spec *torrent.TorrentSpec
+	var so string
+	switch u.Scheme {
+	case "magnet":
+		spec, err = torrent.TorrentSpecFromMagnetUri(args.Url)
+		so = u.Query().Get("so")
+	case "http", "https":
+		spec, err = fetchTorrentFile(args.Url)
+		// e.g. https://example.com/a.torrent#so=0,2-4
+		if fragment, e := url.ParseQuery(u.Fragment); e == nil {
+			so = fragment.Get("so")
+		}
+	default:
+		return "", errors.Errorf("不支持的链接: %s", args.Url)
+	}
+	if err != nil {
+		return "", err
+	}
+	// a job recovered after a restart takes the metainfo saved by the last run
+	var seedStart time.Time
+	if state, err := loadState(args.TempDir); err == nil {
+		if mi, err := metainfo.Load(bytes.NewReader(state.MetaInfo)); err == nil && mi.HashInfoBytes() == spec.InfoHash {
+			if s, err := torrent.TorrentSpecFromMetaInfoErr(mi); err == nil {
+				spec, seedStart = s, state.SeedStart
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		log.Warnf("failed load state of %s: %v", args.TempDir, err)
+	}
+	indexes, err := parseSelectOnly(so)
+	if err != nil {
+		return "", err
+	}
+	filter, err := regexp.Compile(setting.GetStr(conf.TorrentFileFilter))
+	if err != nil {
+		return "", errors.Wrap(err, "文件过滤规则无效")
+	}
+	// the files are written into the temp dir of the task, which is
+	// transferred to the dst storage once completed
+	if err = os.MkdirAll(args.TempDir, 0o777); err != nil {
+		return "", err
+	}
+	spec.Storage = storage.NewFileOpts(storage.NewFileClientOpts{
+		ClientBaseDir:   args.TempDir,
+		TorrentDirMaker: func(baseDir string, _ *metainfo.Info, _ metainfo.Hash) string { return baseDir },
+		PieceCompletion: storage.NewMapPieceCompletion(),
+	})
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	client, err := t.getClient()
+	if err != nil {
+		return "", err
+	}
+	gid := spec.InfoHash.HexString()
+	if j, ok := t.jobs[gid]; ok {
+		// the retried task takes the job it left
+		if j.dir == filepath.Clean(args.TempDir) {
+			return gid, nil
+		}
+		return "", errors.Errorf("种子 %s 已在下载中", gid)
+	}
+	tt, _, err := client.AddTorrentSpec(spec)
+	if err != nil {
+		return "", errors.Wrap(err, "添加种子失败")
+	}
+	j := &job{t: tt, dir: filepath.Clean(args.TempDir), so: indexes, filter: filter, started: make(chan struct{}), seedStart: seedStart}
+	t.jobs[gid] = j
+	go j.start(args.Signal)
+	return gid, nil
+}
+
+// start selects the files once the info of the torrent is known.
+func (j *job) start(signal chan int) {
+	select {
+	case <-j.t.GotInfo():
+	case <-j.t.Closed():
+		return
+	}
+	j.saveState(j.seedStart)
+	// the data left by an interrupted download is kept
+	j.t.VerifyData()
+	for i, f := range j.t.Files() {
+		if (len(j.so) == 0 || utils.SliceContains(j.so, i)) && j.filter.MatchString(f.DisplayPath()) {
+			j.selected = append(j.selected, f)
+			f.Download()
+		} else {
+			f.SetPriority(torrent.PiecePriorityNone)
+		}
+	}
+	close(j.started)
+	if signal != nil {
+		select {
+		case signal <- 1:
+		default:
+		}
+	}
+}
+
+func (t *Torrent) Remove(task *tool.DownloadTask) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	j, ok := t.jobs[task.GID]
+	if !ok {
+		return nil
+	}
+	delete(t.jobs, task.GID)
+	j.t.Drop()
+	j.removeState()
+	return nil
+}
+
+func (t *Torrent) Status(task *tool.DownloadTask) (*tool.Status, error) {
+	t.mu.Lock()
+	j, ok := t.jobs[task.GID]
+	t.mu.Unlock()
+	if !ok {
+		return nil, errors.Errorf("未找到种子 %s", task.GID)
+	}
+	s := &tool.Status{}
+	select {
+	case <-j.started:
+	default:
+		s.Status = "fetching metadata"
+		return s, nil
+	}
+	if len(j.selected) == 0 {
+		s.Err = errors.New("没有要下载的文件")
+		return s, nil
+	}
+	var completed int64
+	for _, f := range j.selected {
+		s.TotalBytes += f.Length()
+		completed += f.BytesCompleted()
+	}
+	stats := j.t.Stats()
+	s.Progress = float64(completed) / float64(s.TotalBytes) * 100
+	s.Status = fmt.Sprintf("downloading, %d peers", stats.ActivePeers)
+	if completed < s.TotalBytes {
+		return s, nil
+	}
+	t.mu.Lock()
+	seedStart, started := j.seedStart, j.seedStart.IsZero()
+	if started {
+		seedStart = time.Now()
+		j.seedStart = seedStart
+	}
+	t.mu.Unlock()
+	if started {
+		j.saveState(seedStart)
+	}
+	if !seeded(seedStart, stats.BytesWrittenData.Int64(), s.TotalBytes) {
+		s.Status = fmt.Sprintf("seeding, %d peers, uploaded %d bytes", stats.ActivePeers, stats.BytesWrittenData.Int64())
+		return s, nil
+	}
+	// close the files before they are transferred
+	t.mu.Lock()
+	delete(t.jobs, task.GID)
+	t.mu.Unlock()
+	j.t.Drop()
+	<-j.t.Closed()
+	j.removeState()
+	j.removeUnselected()
+	s.Completed = true
+	return s, nil
+}
+
+// seeded reports whether either seeding limit is reached, no seeding if none is set.
+func seeded(seedStart time.Time, uploaded, total int64) bool {
+	ratio := setting.GetFloat(conf.TorrentSeedRatio, 0)
+	seedtime := setting.GetInt(conf.TorrentSeedtime, 0)
+	if ratio <= 0 && seedtime <= 0 {
+		return true
+	}
+	if ratio > 0 && float64(uploaded) >= ratio*float64(total) {
+		return true
+	}
+	return seedtime > 0 && time.Since(seedStart) >= time.Duration(seedtime)*time.Minute
+}
+
+// removeUnselected deletes the parts of the skipped files written along with
+// the pieces they share with the selected ones, so they aren't transferred.
+func (j *job) removeUnselected() {
+	for _, f := range j.t.Files() {
+		if utils.SliceContains(j.selected, f) {
+			continue
+		}
+		p := filepath.Join(j.dir, filepath.FromSlash(f.Path()))
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			log.Warnf("failed remove skipped file %s: %v", p, err)
+		}
+		// remove the dirs left empty
+		for dir := filepath.Dir(p); dir != j.dir && strings.HasPrefix(dir, j.dir); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+}
+
+func fetchTorrentFile(u string) (*torrent.TorrentSpec, error) {
+	res, err := net.NewHttpClient().Get(u)
+	if err != nil {
+		return nil, errors.Wrap(err, "获取 .torrent 文件失败")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("获取 .torrent 文件失败: %s", res.Status)
+	}
+	mi, err := metainfo.Load(io.LimitReader(res.Body, maxTorrentFileSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "解析 .torrent 文件失败")
+	}
+	return torrent.TorrentSpecFromMetaInfoErr(mi)
+}
+
+// parseSelectOnly parses the file indexes of BEP 53, e.g. "0,2,4-6".
+func parseSelectOnly(so string) ([]int, error) {
+	var res []int
+	for _, part := range strings.Split(so, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		start, end, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(start)
+		if err != nil || from < 0 {
+			return nil, errors.Errorf("无效的文件序号: %s", part)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(end); err != nil || to < from || to-from > maxSelectOnlyRange {
+				return nil, errors.Errorf("无效的文件序号: %s", part)
+			}
+		}
+		for i := from; i <= to; i++ {
+			res = append(res, i)
+		}
+	}
+	return res, nil
+}
+
+var _ tool.Tool = (*Torrent)(nil)
+
+func init() {
+	tool.Tools.Add(&Torrent{})
+}
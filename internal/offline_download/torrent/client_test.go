@@ -0,0 +1,327 @@
+//go:build nosqlite || !cgo
+
+// the tests link go-sqlite3 for the database, which collides with the sqlite
+// of the torrent storage unless it's left out by the nosqlite tag
+
+package torrent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	// a random port, and the peers are only added by the tests
+	setSetting(conf.TorrentListenPort, "0", conf.TypeNumber)
+	setSetting(conf.TorrentDHT, "false", conf.TypeBool)
+}
+
+func setSetting(key, value, typ string) {
+	if err := op.SaveSettingItem(&model.SettingItem{Key: key, Value: value, Type: typ, Group: model.OFFLINE_DOWNLOAD}); err != nil {
+		panic(err)
+	}
+}
+
+func TestParseSelectOnly(t *testing.T) {
+	res, err := parseSelectOnly("0, 2,4-6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res, []int{0, 2, 4, 5, 6}) {
+		t.Errorf("unexpected indexes: %v", res)
+	}
+	for _, so := range []string{"a", "-1", "3-2", "0-100000000"} {
+		if _, err := parseSelectOnly(so); err == nil {
+			t.Errorf("expect error for %s", so)
+		}
+	}
+}
+
+func TestSeeded(t *testing.T) {
+	defer setSetting(conf.TorrentSeedRatio, "0", conf.TypeNumber)
+	defer setSetting(conf.TorrentSeedtime, "0", conf.TypeNumber)
+	now := time.Now()
+	cases := []struct {
+		ratio, seedtime string
+		seedStart       time.Time
+		uploaded        int64
+		expect          bool
+	}{
+		{"0", "0", now, 0, true},
+		{"1.5", "0", now, 149, false},
+		{"1.5", "0", now, 150, true},
+		{"0", "10", now, 1000, false},
+		{"0", "10", now.Add(-10 * time.Minute), 0, true},
+		{"2", "10", now.Add(-10 * time.Minute), 0, true},
+	}
+	for _, c := range cases {
+		setSetting(conf.TorrentSeedRatio, c.ratio, conf.TypeNumber)
+		setSetting(conf.TorrentSeedtime, c.seedtime, conf.TypeNumber)
+		if got := seeded(c.seedStart, c.uploaded, 100); got != c.expect {
+			t.Errorf("ratio %s, seedtime %s, started %s ago, uploaded %d: expect %v", c.ratio, c.seedtime, time.Since(c.seedStart), c.uploaded, c.expect)
+		}
+	}
+}
+
+var torrentFiles = map[string]string{
+	"d/a.txt":     "aaaaaaaaaa",
+	"d/sub/b.txt": "bbbbbbbbbbbbbbbbbbbb",
+}
+
+// newSeeder seeds the torrent of torrentFiles, and serves its .torrent file.
+func newSeeder(t *testing.T) (*torrent.Client, *metainfo.MetaInfo, string) {
+	root := t.TempDir()
+	for name, content := range torrentFiles {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info := metainfo.Info{PieceLength: 16}
+	if err := info.BuildFromFilePath(filepath.Join(root, "d")); err != nil {
+		t.Fatal(err)
+	}
+	mi := &metainfo.MetaInfo{}
+	var err error
+	if mi.InfoBytes, err = bencode.Marshal(info); err != nil {
+		t.Fatal(err)
+	}
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.DataDir = root
+	cfg.DefaultStorage = storage.NewFileOpts(storage.NewFileClientOpts{
+		ClientBaseDir:   root,
+		PieceCompletion: storage.NewMapPieceCompletion(),
+	})
+	cfg.Seed = true
+	cfg.NoDHT = true
+	cfg.ListenPort = 0
+	cfg.NoDefaultPortForwarding = true
+	client, err := torrent.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	tt, err := client.AddTorrent(mi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt.VerifyData()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = mi.Write(w)
+	}))
+	t.Cleanup(srv.Close)
+	return client, mi, srv.URL + "/d.torrent"
+}
+
+func newTorrent(t *testing.T) *Torrent {
+	tr := &Torrent{}
+	if _, err := tr.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		tr.mu.Lock()
+		defer tr.mu.Unlock()
+		if tr.client != nil {
+			tr.client.Close()
+		}
+	})
+	return tr
+}
+
+func getJob(t *testing.T, tr *Torrent, gid string) *job {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	j, ok := tr.jobs[gid]
+	if !ok {
+		t.Fatalf("no job of %s", gid)
+	}
+	return j
+}
+
+func waitStarted(t *testing.T, j *job) {
+	select {
+	case <-j.started:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the files aren't selected")
+	}
+}
+
+// waitCompleted polls the status until the download is completed.
+func waitCompleted(t *testing.T, tr *Torrent, task *tool.DownloadTask) {
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		s, err := tr.Status(task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Err != nil {
+			t.Fatal(s.Err)
+		}
+		if s.Completed {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("the download isn't completed")
+}
+
+func checkFiles(t *testing.T, dir string, expect map[string]string) {
+	t.Helper()
+	got := make(map[string]string)
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		got[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("expect files %v, got %v", expect, got)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	seeder, _, u := newSeeder(t)
+	tr := newTorrent(t)
+	dir := filepath.Join(t.TempDir(), "task")
+	// only the 2nd file
+	gid, err := tr.AddURL(&tool.AddUrlArgs{Url: u + "#so=1", TempDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := getJob(t, tr, gid)
+	waitStarted(t, j)
+	j.t.AddClientPeer(seeder)
+	waitCompleted(t, tr, &tool.DownloadTask{GID: gid})
+
+	checkFiles(t, dir, map[string]string{"d/sub/b.txt": torrentFiles["d/sub/b.txt"]})
+	if _, err = os.Stat(tool.StatePath(dir)); !os.IsNotExist(err) {
+		t.Errorf("the state should be removed: %v", err)
+	}
+	if _, err = tr.Status(&tool.DownloadTask{GID: gid}); err == nil {
+		t.Error("the completed job should be dropped")
+	}
+}
+
+func TestResume(t *testing.T) {
+	seeder, mi, u := newSeeder(t)
+	dir := filepath.Join(t.TempDir(), "task")
+	tr := newTorrent(t)
+	gid, err := tr.AddURL(&tool.AddUrlArgs{Url: u, TempDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitStarted(t, getJob(t, tr, gid))
+	// the task is retried by the same client
+	if again, err := tr.AddURL(&tool.AddUrlArgs{Url: u, TempDir: dir}); err != nil || again != gid {
+		t.Fatalf("expect the job taken again, got %s, %v", again, err)
+	}
+	if _, err = tr.AddURL(&tool.AddUrlArgs{Url: u, TempDir: dir + "2"}); err == nil {
+		t.Error("expect the torrent already downloading")
+	}
+
+	// restarted, the job is recovered from the magnet without any peer for the metadata
+	tr.mu.Lock()
+	tr.client.Close()
+	tr.mu.Unlock()
+	tr = newTorrent(t)
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	magnet := mi.Magnet(nil, &info).String()
+	if gid, err = tr.AddURL(&tool.AddUrlArgs{Url: magnet, TempDir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	j := getJob(t, tr, gid)
+	waitStarted(t, j)
+	j.t.AddClientPeer(seeder)
+	waitCompleted(t, tr, &tool.DownloadTask{GID: gid})
+	checkFiles(t, dir, map[string]string{"d/a.txt": torrentFiles["d/a.txt"], "d/sub/b.txt": torrentFiles["d/sub/b.txt"]})
+}
+
+func TestSeedStartKept(t *testing.T) {
+	setSetting(conf.TorrentSeedtime, "10", conf.TypeNumber)
+	defer setSetting(conf.TorrentSeedtime, "0", conf.TypeNumber)
+	seeder, _, u := newSeeder(t)
+	dir := filepath.Join(t.TempDir(), "task")
+	tr := newTorrent(t)
+	gid, err := tr.AddURL(&tool.AddUrlArgs{Url: u, TempDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := getJob(t, tr, gid)
+	waitStarted(t, j)
+	j.t.AddClientPeer(seeder)
+	task := &tool.DownloadTask{GID: gid}
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		s, err := tr.Status(task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Completed {
+			t.Fatal("the torrent should be seeded for 10 minutes")
+		}
+		state, err := loadState(dir)
+		if err == nil && !state.SeedStart.IsZero() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the seeding isn't started")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// restarted after the seeding time, the job is completed at once
+	tr.mu.Lock()
+	tr.client.Close()
+	tr.mu.Unlock()
+	state, err := loadState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.SeedStart = state.SeedStart.Add(-10 * time.Minute)
+	j.saveState(state.SeedStart)
+	tr = newTorrent(t)
+	if _, err = tr.AddURL(&tool.AddUrlArgs{Url: u, TempDir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	waitStarted(t, getJob(t, tr, gid))
+	waitCompleted(t, tr, task)
+}
@@ -161,6 +161,19 @@ func getMainItems(config driver.Config) []driver.Item {
 		Type:    conf.TypeSelect,
 		Options: "front,back",
 	})
+	if !config.NoUpload {
+		items = append(items, []driver.Item{{
+			Name:    "versions_keep",
+			Type:    conf.TypeNumber,
+			Default: "0",
+			Help:    "Keep the previous versions of the overwritten files, 0 for no limit if versions_days is set",
+		}, {
+			Name:    "versions_days",
+			Type:    conf.TypeNumber,
+			Default: "0",
+			Help:    "The days the previous versions are kept, 0 for no limit if versions_keep is set",
+		}}...)
+	}
 	items = append(items, driver.Item{
 		Name:     "disable_index",
 		Type:     conf.TypeBool,
@@ -602,6 +602,26 @@ func Remove(ctx context.Context, storage driver.Driver, path string) error {
 	return errors.WithStack(err)
 }
 
+func SetModTime(ctx context.Context, storage driver.Driver, path string, modified, created time.Time) error {
+	if storage.Config().CheckStatus && storage.GetStorage().Status != WORK {
+		return errors.WithMessagef(errs.StorageNotInit, "storage status: %s", storage.GetStorage().Status)
+	}
+	s, ok := storage.(driver.SetModTime)
+	if !ok {
+		return errs.NotImplement
+	}
+	path = utils.FixAndCleanPath(path)
+	rawObj, err := Get(ctx, storage, path, true)
+	if err != nil {
+		return errors.WithMessage(err, "failed to get object")
+	}
+	if err = s.SetModTime(ctx, model.UnwrapObjName(rawObj), modified, created); err != nil {
+		return errors.WithStack(err)
+	}
+	Cache.DeleteDirectory(storage, stdpath.Dir(path))
+	return nil
+}
+
 func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file model.FileStreamer, up driver.UpdateProgress) error {
 	defer func() {
 		if err := file.Close(); err != nil {
@@ -622,6 +642,7 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 	dstPath := stdpath.Join(dstDirPath, file.GetName())
 	tempName := file.GetName() + ".openlist_to_delete"
 	tempPath := stdpath.Join(dstDirPath, tempName)
+	var version string
 	fi, err := GetUnwrap(ctx, storage, dstPath)
 	if err == nil {
 		if fi.GetSize() == 0 {
@@ -629,6 +650,11 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 			if err != nil {
 				return errors.WithMessagef(err, "while uploading, failed remove existing file which size = 0")
 			}
+		} else if version, err = keepVersion(ctx, storage, dstPath); err != nil {
+			return errors.WithMessage(err, "while uploading, failed save version")
+		} else if version != "" {
+			// the old obj is kept as a version, which is moved back if failed
+			fi = nil
 		} else if storage.Config().NoOverwriteUpload {
 			// try to rename old obj
 			err = Rename(ctx, storage, dstPath, tempName)
@@ -663,15 +689,28 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 	}
 
 	var newObj model.Obj
-	switch s := storage.(type) {
-	case driver.PutResult:
-		newObj, err = s.Put(ctx, parentDir, file, up)
-	case driver.Put:
-		err = s.Put(ctx, parentDir, file, up)
-	default:
-		return errs.NotImplement
+	rapid := false
+	if s, ok := storage.(driver.PutRapid); ok && ctx.Value(conf.RapidUploadKey) != nil {
+		newObj, rapid = putRapid(ctx, s, parentDir, file, dstPath)
+	}
+	if rapid {
+		up(100)
+	} else if s, ok := storage.(driver.ResumablePut); ok && ctx.Value(conf.ResumeUploadKey) != nil {
+		err = s.PutResumable(ctx, parentDir, file, up)
+	} else {
+		switch s := storage.(type) {
+		case driver.PutResult:
+			newObj, err = s.Put(ctx, parentDir, file, up)
+		case driver.Put:
+			err = s.Put(ctx, parentDir, file, up)
+		default:
+			return errs.NotImplement
+		}
 	}
 	if err == nil {
+		if !storage.Config().OnlyIndices {
+			keepPutModTime(ctx, storage, dstPath, file, newObj)
+		}
 		Cache.linkCache.DeleteKey(Key(storage, dstPath))
 		if !storage.Config().NoCache {
 			if cache, exist := Cache.dirCache.Get(Key(storage, dstDirPath)); exist {
@@ -694,6 +733,19 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 		}
 	}
 	log.Debugf("put file [%s] done", file.GetName())
+	if version != "" {
+		if err != nil {
+			// the obj left by the failed upload is replaced
+			if err := Remove(ctx, storage, dstPath); err != nil {
+				log.Warnf("failed remove the failed upload: %+v", err)
+			}
+			if err := moveVersion(ctx, storage, dstPath, version); err != nil {
+				log.Errorf("failed recover old obj from version: %+v", err)
+			}
+		} else {
+			pruneVersions(ctx, storage, dstPath)
+		}
+	}
 	if storage.Config().NoOverwriteUpload && fi != nil && fi.GetSize() > 0 {
 		if err != nil {
 			// upload failed, recover old obj
@@ -709,6 +761,55 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 	return errors.WithStack(err)
 }
 
+// putRapid tries the rapid upload, the file is uploaded as usual if it's
+// not done.
+// keepPutModTime sets the ModTime of the stream on the obj just put, for the
+// drivers which can change it but don't keep it in Put. It only warns on
+// failure since the file itself is uploaded.
+func keepPutModTime(ctx context.Context, storage driver.Driver, dstPath string, file model.FileStreamer, newObj model.Obj) {
+	s, ok := storage.(driver.SetModTime)
+	modified := file.ModTime()
+	if !ok || modified.IsZero() {
+		return
+	}
+	obj := newObj
+	if obj == nil {
+		if g, ok := storage.(driver.Getter); ok {
+			obj, _ = g.Get(ctx, dstPath)
+		}
+	}
+	if obj == nil {
+		// the listing cached doesn't have the obj just put
+		Cache.DeleteDirectory(storage, stdpath.Dir(dstPath))
+		var err error
+		if obj, err = GetUnwrap(ctx, storage, dstPath); err != nil {
+			log.Warnf("failed get [%s] to set its modified time: %+v", dstPath, err)
+			return
+		}
+	}
+	obj = model.UnwrapObjName(obj)
+	if d := obj.ModTime().Sub(modified); d > -time.Second && d < time.Second {
+		return
+	}
+	if err := s.SetModTime(ctx, obj, modified, file.CreateTime()); err != nil {
+		log.Warnf("failed set the modified time of [%s]: %+v", dstPath, err)
+		return
+	}
+	Cache.DeleteDirectory(storage, stdpath.Dir(dstPath))
+}
+
+func putRapid(ctx context.Context, s driver.PutRapid, parentDir model.Obj, file model.FileStreamer, dstPath string) (model.Obj, bool) {
+	newObj, err := s.PutRapid(ctx, parentDir, file)
+	if err == nil {
+		log.Debugf("rapid upload [%s] done", dstPath)
+		return newObj, true
+	}
+	if !errors.Is(err, errs.NotImplement) {
+		log.Warnf("failed rapid upload [%s], upload it instead: %+v", dstPath, err)
+	}
+	return nil, false
+}
+
 func PutURL(ctx context.Context, storage driver.Driver, dstDirPath, dstName, url string) error {
 	if storage.Config().CheckStatus && storage.GetStorage().Status != WORK {
 		return errors.WithMessagef(errs.StorageNotInit, "storage status: %s", storage.GetStorage().Status)
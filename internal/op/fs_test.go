@@ -0,0 +1,185 @@
+package op_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+// putNoModTime writes the files without their ModTime, as the drivers which
+// only set it by SetModTime
+type putNoModTime struct {
+	*local.Local
+	setModTimes *int
+}
+
+func (d putNoModTime) Put(ctx context.Context, dstDir model.Obj, file model.FileStreamer, up driver.UpdateProgress) error {
+	f, err := os.Create(filepath.Join(dstDir.GetPath(), file.GetName()))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, file)
+	return err
+}
+
+func (d putNoModTime) SetModTime(ctx context.Context, obj model.Obj, modified, created time.Time) error {
+	*d.setModTimes++
+	return d.Local.SetModTime(ctx, obj, modified, created)
+}
+
+// listOnly hides the Get of the local driver, so the obj put is listed
+type listOnly struct {
+	putNoModTime
+}
+
+func (d listOnly) Get() {}
+
+func localStorage(t *testing.T, mountPath string) (*local.Local, string) {
+	root := t.TempDir()
+	ctx := context.Background()
+	id, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: mountPath,
+		Addition:  `{"root_folder_path":"` + root + `"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = op.DeleteStorageById(ctx, id) })
+	storage, err := op.GetStorageByMountPath(mountPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage.(*local.Local), root
+}
+
+func TestPutSetsModTime(t *testing.T) {
+	d, root := localStorage(t, "/mtime")
+	var setModTimes int
+	for _, storage := range []driver.Driver{
+		d,
+		putNoModTime{Local: d, setModTimes: &setModTimes},
+		listOnly{putNoModTime{Local: d, setModTimes: &setModTimes}},
+	} {
+		setModTimes = 0
+		// listed before, so the put obj isn't in the cache
+		if _, err := op.List(context.Background(), storage, "/", model.ListArgs{}); err != nil {
+			t.Fatal(err)
+		}
+		modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		name := "a.txt"
+		err := op.Put(context.Background(), storage, "/", &stream.FileStream{
+			Obj:    &model.Object{Name: name, Size: 1, Modified: modified},
+			Reader: strings.NewReader("1"),
+		}, nil)
+		if err != nil {
+			t.Fatalf("%T: %+v", storage, err)
+		}
+		fi, err := os.Stat(filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fi.ModTime().Equal(modified) {
+			t.Errorf("%T: expect the modified time %v, got %v", storage, modified, fi.ModTime())
+		}
+		_, isLocal := storage.(*local.Local)
+		if isLocal && setModTimes != 0 || !isLocal && setModTimes != 1 {
+			t.Errorf("%T: unexpected SetModTime calls: %d", storage, setModTimes)
+		}
+		obj, err := op.Get(context.Background(), storage, "/"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !obj.ModTime().Equal(modified) {
+			t.Errorf("%T: the cached obj has the modified time %v", storage, obj.ModTime())
+		}
+		if err = op.Remove(context.Background(), storage, "/"+name); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// rapidLocal creates the files whose content the server has, known by their
+// md5, as the drivers supporting rapid upload.
+type rapidLocal struct {
+	*local.Local
+	known map[string]string
+	err   error
+	calls *[2]int // PutRapid and Put
+}
+
+func (d rapidLocal) PutRapid(ctx context.Context, dstDir model.Obj, file model.FileStreamer) (model.Obj, error) {
+	d.calls[0]++
+	if d.err != nil {
+		return nil, d.err
+	}
+	content, ok := d.known[file.GetHash().GetHash(utils.MD5)]
+	if !ok {
+		return nil, errs.NotImplement
+	}
+	path := filepath.Join(dstDir.GetPath(), file.GetName())
+	if err := os.WriteFile(path, []byte(content), 0o666); err != nil {
+		return nil, err
+	}
+	return &model.Object{Name: file.GetName(), Path: path, Size: int64(len(content)), Modified: time.Now()}, nil
+}
+
+func (d rapidLocal) Put(ctx context.Context, dstDir model.Obj, file model.FileStreamer, up driver.UpdateProgress) error {
+	d.calls[1]++
+	return d.Local.Put(ctx, dstDir, file, up)
+}
+
+func TestPutRapid(t *testing.T) {
+	d, root := localStorage(t, "/rapid")
+	md5 := func(s string) string { return utils.GetMD5EncodeStr(s) }
+	rapidCtx := context.WithValue(context.Background(), conf.RapidUploadKey, struct{}{})
+	for _, c := range []struct {
+		name    string
+		ctx     context.Context
+		content string
+		err     error
+		calls   [2]int
+	}{
+		{name: "hit", ctx: rapidCtx, content: "known", calls: [2]int{1, 0}},
+		{name: "miss", ctx: rapidCtx, content: "unknown", calls: [2]int{1, 1}},
+		{name: "failed", ctx: rapidCtx, content: "known", err: errors.New("server error"), calls: [2]int{1, 1}},
+		{name: "not asked", ctx: context.Background(), content: "known", calls: [2]int{0, 1}},
+	} {
+		var calls [2]int
+		storage := rapidLocal{Local: d, known: map[string]string{md5("known"): "known"}, err: c.err, calls: &calls}
+		file := &stream.FileStream{
+			Obj: &model.Object{
+				Name:     c.name + ".txt",
+				Size:     int64(len(c.content)),
+				Modified: time.Now(),
+				HashInfo: utils.NewHashInfo(utils.MD5, md5(c.content)),
+			},
+			Reader: strings.NewReader(c.content),
+		}
+		if err := op.Put(c.ctx, storage, "/", file, nil); err != nil {
+			t.Fatalf("%s: %+v", c.name, err)
+		}
+		if calls != c.calls {
+			t.Errorf("%s: expect PutRapid and Put called %v, got %v", c.name, c.calls, calls)
+		}
+		data, err := os.ReadFile(filepath.Join(root, c.name+".txt"))
+		if err != nil || string(data) != c.content {
+			t.Errorf("%s: unexpected content %q, %v", c.name, data, err)
+		}
+	}
+}
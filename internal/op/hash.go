@@ -0,0 +1,34 @@
+package op
+
+import (
+	"context"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// GetObjHash returns the hash of the file given by the driver, or computes it
+// by reading the file if it's not larger than sizeLimit, an empty string is
+// returned if the hash is unknown.
+func GetObjHash(ctx context.Context, storage driver.Driver, path string, obj model.Obj, ht *utils.HashType, sizeLimit int64) (string, error) {
+	if sum := obj.GetHash().GetHash(ht); sum != "" {
+		return sum, nil
+	}
+	if obj.IsDir() || obj.GetSize() > sizeLimit {
+		return "", nil
+	}
+	link, file, err := Link(ctx, storage, path, model.LinkArgs{})
+	if err != nil {
+		return "", errors.WithMessagef(err, "failed get [%s] link", path)
+	}
+	ss, err := stream.NewSeekableStream(&stream.FileStream{Ctx: ctx, Obj: file}, link)
+	if err != nil {
+		_ = link.Close()
+		return "", errors.WithMessagef(err, "failed get [%s] stream", path)
+	}
+	defer ss.Close()
+	return utils.HashReader(ht, ss)
+}
@@ -0,0 +1,258 @@
+package op
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the health states of the storages, they are also the types of the storage
+// hooks called when a storage turns into the state, except that turning
+// into HealthOK is called HealthRecovered
+const (
+	HealthOK        = "ok"
+	HealthDegraded  = "degraded"
+	HealthFailed    = "failed"
+	HealthRecovered = "recovered"
+)
+
+const (
+	// a degraded storage is reloaded after the probes failed in a row
+	healthFailThreshold = 3
+	healthMaxBackoff    = time.Hour
+	healthProbeTimeout  = time.Minute
+	healthHistorySize   = 20
+)
+
+// HealthEvent is a change of the health state
+type HealthEvent struct {
+	Time  time.Time `json:"time"`
+	State string    `json:"state"`
+	Error string    `json:"error,omitempty"`
+}
+
+type StorageHealth struct {
+	ID        uint          `json:"id"`
+	MountPath string        `json:"mount_path"`
+	State     string        `json:"state"`
+	Since     time.Time     `json:"since"`
+	LastCheck time.Time     `json:"last_check"`
+	LastError string        `json:"last_error"`
+	Failures  int           `json:"failures"`
+	NextRetry time.Time     `json:"next_retry"`
+	History   []HealthEvent `json:"history"`
+}
+
+var (
+	healthMu sync.Mutex
+	healths  = make(map[uint]*StorageHealth)
+	// healthChecking holds the ids of the storages being checked
+	healthChecking sync.Map
+	healthCron     *cron.Cron
+)
+
+// StartHealthMonitor checks the storages every minute, a healthy storage is
+// probed when the interval in the settings has passed since the last check,
+// and a sick one is retried with the exponential backoff.
+func StartHealthMonitor() {
+	healthCron = cron.NewCron(time.Minute)
+	healthCron.Do(checkAllHealth)
+}
+
+func StopHealthMonitor() {
+	if healthCron != nil {
+		healthCron.Stop()
+	}
+}
+
+func healthInterval() time.Duration {
+	item, err := GetSettingItemByKey(conf.StorageHealthInterval)
+	if err != nil {
+		return 0
+	}
+	minutes, _ := strconv.Atoi(item.Value)
+	return time.Duration(minutes) * time.Minute
+}
+
+func checkAllHealth() {
+	interval := healthInterval()
+	if interval <= 0 || !conf.StoragesLoaded {
+		return
+	}
+	storages := GetAllStorages()
+	ids := make(map[uint]struct{}, len(storages))
+	now := time.Now()
+	for _, storage := range storages {
+		s := storage.GetStorage()
+		ids[s.ID] = struct{}{}
+		if s.Disabled {
+			continue
+		}
+		h := GetStorageHealth(storage)
+		if (h.State == HealthOK && now.Sub(h.LastCheck) < interval) ||
+			(h.State != HealthOK && now.Before(h.NextRetry)) {
+			continue
+		}
+		go CheckStorageHealth(context.Background(), storage)
+	}
+	healthMu.Lock()
+	for id := range healths {
+		if _, ok := ids[id]; !ok {
+			delete(healths, id)
+		}
+	}
+	healthMu.Unlock()
+}
+
+// CheckStorageHealth probes the storage, and reloads it if it failed to
+// init or the probes failed too many times in a row.
+func CheckStorageHealth(ctx context.Context, storage driver.Driver) StorageHealth {
+	s := storage.GetStorage()
+	if _, loaded := healthChecking.LoadOrStore(s.ID, struct{}{}); loaded {
+		return GetStorageHealth(storage)
+	}
+	defer healthChecking.Delete(s.ID)
+	failures := GetStorageHealth(storage).Failures
+	var err error
+	reload := s.Status != WORK
+	if !reload {
+		err = probeStorage(ctx, storage)
+		reload = err != nil && failures+1 >= healthFailThreshold
+	}
+	if reload {
+		err = reloadStorage(ctx, storage)
+	}
+	return updateHealth(storage, err, reload)
+}
+
+func probeStorage(ctx context.Context, storage driver.Driver) error {
+	ctx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+	defer cancel()
+	if d, ok := storage.(driver.WithDetails); ok {
+		_, err := d.GetDetails(ctx)
+		if !errors.Is(err, errs.NotImplement) {
+			return err
+		}
+	}
+	root, err := Get(ctx, storage, "/")
+	if err != nil {
+		return err
+	}
+	_, err = storage.List(ctx, root, model.ListArgs{ReqPath: storage.GetStorage().MountPath})
+	return err
+}
+
+// reloadStorage inits the storage again as it's saved in the database.
+func reloadStorage(ctx context.Context, storageDriver driver.Driver) error {
+	storage, err := db.GetStorageById(storageDriver.GetStorage().ID)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
+	}
+	// the storage may be disabled or deleted meanwhile
+	if current, ok := storagesMap.Load(storage.MountPath); storage.Disabled || !ok || current != storageDriver {
+		return errors.New("the storage is not mounted")
+	}
+	if err := storageDriver.Drop(ctx); err != nil {
+		log.Warnf("failed drop storage [%s] before reload: %+v", storage.MountPath, err)
+	}
+	Cache.DeleteDirectoryTree(storageDriver, "/")
+	Cache.InvalidateStorageDetails(storageDriver)
+	log.Infof("reload storage [%s] by the health check", storage.MountPath)
+	err = initStorage(ctx, *storage, storageDriver)
+	go callStorageHooks("update", storageDriver)
+	return err
+}
+
+func updateHealth(storage driver.Driver, err error, reloaded bool) StorageHealth {
+	s := storage.GetStorage()
+	now := time.Now()
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	h, ok := healths[s.ID]
+	if !ok {
+		h = &StorageHealth{ID: s.ID, State: HealthOK, Since: now}
+		healths[s.ID] = h
+	}
+	h.MountPath = s.MountPath
+	h.LastCheck = now
+	state := HealthOK
+	if err != nil {
+		h.Failures++
+		h.LastError = err.Error()
+		h.NextRetry = now.Add(min(time.Minute<<min(h.Failures-1, 10), healthMaxBackoff))
+		state = HealthDegraded
+		if reloaded || s.Status != WORK {
+			state = HealthFailed
+		}
+	} else {
+		h.Failures = 0
+		h.LastError = ""
+		h.NextRetry = time.Time{}
+	}
+	if state != h.State {
+		h.State = state
+		h.Since = now
+		h.History = append(h.History, HealthEvent{Time: now, State: state, Error: h.LastError})
+		if len(h.History) > healthHistorySize {
+			h.History = h.History[len(h.History)-healthHistorySize:]
+		}
+		typ := state
+		if state == HealthOK {
+			typ = HealthRecovered
+			log.Infof("storage [%s] is recovered", s.MountPath)
+		} else {
+			log.Warnf("storage [%s] is %s: %s", s.MountPath, state, h.LastError)
+		}
+		go callStorageHooks(typ, storage)
+	}
+	return h.copy()
+}
+
+func (h *StorageHealth) copy() StorageHealth {
+	c := *h
+	c.History = append([]HealthEvent(nil), h.History...)
+	return c
+}
+
+// GetStorageHealth returns the health of the storage, a storage not checked
+// yet is healthy if it's working.
+func GetStorageHealth(storage driver.Driver) StorageHealth {
+	s := storage.GetStorage()
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	if h, ok := healths[s.ID]; ok {
+		return h.copy()
+	}
+	h := StorageHealth{ID: s.ID, MountPath: s.MountPath, State: HealthOK}
+	if s.Status != WORK {
+		h.State = HealthFailed
+		h.LastError = s.Status
+	}
+	return h
+}
+
+func GetStoragesHealth() []StorageHealth {
+	storages := GetAllStorages()
+	sort.Slice(storages, func(i, j int) bool {
+		return storages[i].GetStorage().Order < storages[j].GetStorage().Order
+	})
+	res := make([]StorageHealth, 0, len(storages))
+	for _, storage := range storages {
+		if storage.GetStorage().Disabled {
+			continue
+		}
+		res = append(res, GetStorageHealth(storage))
+	}
+	return res
+}
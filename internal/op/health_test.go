@@ -0,0 +1,84 @@
+package op_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestStorageHealth(t *testing.T) {
+	root := t.TempDir()
+	ctx := context.Background()
+	id, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/health",
+		Addition:  `{"root_folder_path":"` + root + `"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = op.DeleteStorageById(ctx, id) })
+	storage, err := op.GetStorageByMountPath("/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := op.GetStorageHealth(storage); h.State != op.HealthOK || !h.LastCheck.IsZero() {
+		t.Errorf("expect a working storage healthy before checked, got %+v", h)
+	}
+	if h := op.CheckStorageHealth(ctx, storage); h.State != op.HealthOK || h.LastCheck.IsZero() || len(h.History) != 0 {
+		t.Errorf("unexpected health %+v", h)
+	}
+
+	if err = os.Remove(root); err != nil {
+		t.Fatal(err)
+	}
+	// degraded by the probes failed, then failed to reload after the third one,
+	// retried with the backoff doubled each time up to an hour
+	for i, expect := range []struct {
+		state   string
+		backoff time.Duration
+	}{
+		{op.HealthDegraded, time.Minute},
+		{op.HealthDegraded, 2 * time.Minute},
+		{op.HealthFailed, 4 * time.Minute},
+		{op.HealthFailed, 8 * time.Minute},
+		{op.HealthFailed, 16 * time.Minute},
+		{op.HealthFailed, 32 * time.Minute},
+		{op.HealthFailed, time.Hour},
+		{op.HealthFailed, time.Hour},
+	} {
+		h := op.CheckStorageHealth(ctx, storage)
+		if h.State != expect.state || h.Failures != i+1 || h.LastError == "" {
+			t.Fatalf("check %d: expect %s, got %+v", i+1, expect.state, h)
+		}
+		if backoff := h.NextRetry.Sub(h.LastCheck); backoff != expect.backoff {
+			t.Errorf("check %d: expect the backoff %v, got %v", i+1, expect.backoff, backoff)
+		}
+	}
+	if s := storage.GetStorage(); s.Status == op.WORK {
+		t.Error("expect the storage failed to reload")
+	}
+
+	// reloaded as it's not working
+	if err = os.Mkdir(root, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	h := op.CheckStorageHealth(ctx, storage)
+	if h.State != op.HealthOK || h.Failures != 0 || h.LastError != "" || !h.NextRetry.IsZero() {
+		t.Fatalf("expect recovered, got %+v", h)
+	}
+	var states []string
+	for _, e := range h.History {
+		states = append(states, e.State)
+	}
+	if len(states) != 3 || states[0] != op.HealthDegraded || states[1] != op.HealthFailed || states[2] != op.HealthOK {
+		t.Errorf("unexpected history %v", states)
+	}
+	if s := storage.GetStorage(); s.Status != op.WORK {
+		t.Errorf("expect the storage working, got %s", s.Status)
+	}
+}
@@ -97,6 +97,8 @@ func HandleSettingItemHook(item *model.SettingItem) (hasHook bool, err error) {
 }
 
 // Storage
+// StorageHook is called with the typ "add", "update" and "del" when a storage
+// is changed, and the health states when its health changes, see health.go
 type StorageHook func(typ string, storage driver.Driver)
 
 var storageHooks = make([]StorageHook, 0)
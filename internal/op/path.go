@@ -27,6 +27,11 @@ func GetStorageAndActualPath(rawPath string) (storage driver.Driver, actualPath
 	log.Debugln("use storage: ", storage.GetStorage().MountPath)
 	mountPath := utils.GetActualMountPath(storage.GetStorage().MountPath)
 	actualPath = utils.FixAndCleanPath(strings.TrimPrefix(rawPath, mountPath))
+	if storage.GetStorage().VersioningEnabled() && IsVersionPath(actualPath) {
+		// the versions are only reachable by the version APIs, which check
+		// the meta of the original path
+		err = errs.NewErr(errs.ObjectNotFound, "原始路径: %s", rawPath)
+	}
 	return
 }
 
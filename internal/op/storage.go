@@ -263,8 +263,9 @@ func DeleteStorageById(ctx context.Context, id uint) error {
 		return errors.WithMessage(err, "failed get storage")
 	}
 	var dropErr error = nil
+	var storageDriver driver.Driver
 	if !storage.Disabled {
-		storageDriver, err := GetStorageByMountPath(storage.MountPath)
+		storageDriver, err = GetStorageByMountPath(storage.MountPath)
 		if err != nil {
 			return errors.WithMessage(err, "failed get storage driver")
 		}
@@ -282,9 +283,31 @@ func DeleteStorageById(ctx context.Context, id uint) error {
 	if err := db.DeleteStorageById(id); err != nil {
 		return errors.WithMessage(err, "failed delete storage in database")
 	}
+	purgeStorage(ctx, *storage, storageDriver)
 	return dropErr
 }
 
+// purgeStorage removes the local data of the storage deleted, a driver is
+// made for a disabled storage which isn't loaded.
+func purgeStorage(ctx context.Context, storage model.Storage, storageDriver driver.Driver) {
+	if storageDriver == nil {
+		driverNew, err := GetDriver(storage.Driver)
+		if err != nil {
+			return
+		}
+		storageDriver = driverNew()
+		storageDriver.SetStorage(storage)
+		if err = utils.Json.UnmarshalFromString(storage.Addition, storageDriver.GetAddition()); err != nil {
+			return
+		}
+	}
+	if p, ok := storageDriver.(driver.Purge); ok {
+		if err := p.Purge(ctx); err != nil {
+			log.Warnf("failed purge storage [%s]: %+v", storage.MountPath, err)
+		}
+	}
+}
+
 // MustSaveDriverStorage call from specific driver
 func MustSaveDriverStorage(driver driver.Driver) {
 	err := saveDriverStorage(driver)
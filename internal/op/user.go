@@ -82,6 +82,9 @@ func DeleteUserById(id uint) error {
 	if err := DeleteSharingsByCreatorId(id); err != nil {
 		return errors.WithMessage(err, "failed to delete user's sharings")
 	}
+	if err := db.DeleteRssFeedsByUserId(id); err != nil {
+		return errors.WithMessage(err, "failed to delete user's rss feeds")
+	}
 	return db.DeleteUserById(id)
 }
 
@@ -0,0 +1,249 @@
+package op
+
+import (
+	"context"
+	stdpath "path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// VersionsDir is the hidden dir in the root of a storage keeping the
+	// previous versions, a file's versions are in the dir of its path.
+	VersionsDir = "/.versions"
+	// the version is named by the time it was replaced and the file name
+	versionTimeFormat = "20060102-150405.000"
+)
+
+// FileVersion is a previous version of a file
+type FileVersion struct {
+	ID       string    `json:"id"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	Created  time.Time `json:"created"` // when the version was replaced
+}
+
+func IsVersionPath(path string) bool {
+	path = utils.FixAndCleanPath(path)
+	return path == VersionsDir || strings.HasPrefix(path, VersionsDir+"/")
+}
+
+func versioned(storage driver.Driver, path string) bool {
+	return storage.GetStorage().VersioningEnabled() && !IsVersionPath(path)
+}
+
+// keepVersion saves the file at path as a version before it's overwritten,
+// nothing is kept if the driver can't move or copy it.
+func keepVersion(ctx context.Context, storage driver.Driver, path string) (string, error) {
+	if !versioned(storage, path) {
+		return "", nil
+	}
+	if !versionable(storage) {
+		log.Warnf("the version of [%s] is not kept, the driver %s can't rename or move", path, storage.Config().Name)
+		return "", nil
+	}
+	id, err := saveVersion(ctx, storage, path)
+	if errors.Is(err, errs.NotImplement) {
+		log.Warnf("the version of [%s] is not kept: %+v", path, err)
+		return "", nil
+	}
+	return id, err
+}
+
+// versionable reports whether the driver can keep the versions, which are
+// moved, or copied and removed, into the versions dir and renamed.
+func versionable(storage driver.Driver) bool {
+	switch storage.(type) {
+	case driver.Rename, driver.RenameResult:
+	default:
+		return false
+	}
+	switch storage.(type) {
+	case driver.Move, driver.MoveResult, driver.Copy, driver.CopyResult:
+		return true
+	}
+	return false
+}
+
+// moveFile moves the file at srcPath into dstDirPath, it's copied and
+// removed if the driver can't move.
+func moveFile(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string) error {
+	err := Move(ctx, storage, srcPath, dstDirPath)
+	if !errors.Is(err, errs.NotImplement) {
+		return err
+	}
+	if err = Copy(ctx, storage, srcPath, dstDirPath); err != nil {
+		return err
+	}
+	if err = Remove(ctx, storage, srcPath); err != nil {
+		if err := Remove(ctx, storage, stdpath.Join(dstDirPath, stdpath.Base(srcPath))); err != nil {
+			log.Errorf("failed remove the copy of [%s]: %+v", srcPath, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func versionDirPath(path string) string {
+	return stdpath.Join(VersionsDir, utils.FixAndCleanPath(path))
+}
+
+func parseVersionID(id string) (time.Time, bool) {
+	ts, _, ok := strings.Cut(id, "_")
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(versionTimeFormat, ts, time.Local)
+	return t, err == nil
+}
+
+// saveVersion moves the file at path into its versions dir.
+func saveVersion(ctx context.Context, storage driver.Driver, path string) (string, error) {
+	dir := versionDirPath(path)
+	if err := MakeDir(ctx, storage, dir); err != nil {
+		return "", errors.WithMessagef(err, "failed to make versions dir [%s]", dir)
+	}
+	if err := moveFile(ctx, storage, path, dir); err != nil {
+		return "", errors.WithMessage(err, "failed to move the file into versions dir")
+	}
+	id, err := newVersionID(ctx, storage, path)
+	if err == nil {
+		err = Rename(ctx, storage, stdpath.Join(dir, stdpath.Base(path)), id)
+	}
+	if err != nil {
+		if err := moveFile(ctx, storage, stdpath.Join(dir, stdpath.Base(path)), stdpath.Dir(path)); err != nil {
+			log.Errorf("failed recover file [%s] from versions dir: %+v", path, err)
+		}
+		return "", errors.WithMessage(err, "failed to rename the version")
+	}
+	return id, nil
+}
+
+// newVersionID names the version by the time it is saved, the versions saved
+// within the same millisecond are named by the next free ones.
+func newVersionID(ctx context.Context, storage driver.Driver, path string) (string, error) {
+	versions, err := ListVersions(ctx, storage, path)
+	if err != nil {
+		return "", err
+	}
+	t := time.Now().Truncate(time.Millisecond)
+	if len(versions) > 0 && !versions[0].Created.Before(t) {
+		t = versions[0].Created.Add(time.Millisecond)
+	}
+	return t.Format(versionTimeFormat) + "_" + stdpath.Base(path), nil
+}
+
+// moveVersion moves the version back to path, which must not exist.
+func moveVersion(ctx context.Context, storage driver.Driver, path, id string) error {
+	dir := versionDirPath(path)
+	name := stdpath.Base(path)
+	if err := Rename(ctx, storage, stdpath.Join(dir, id), name); err != nil {
+		return err
+	}
+	if err := moveFile(ctx, storage, stdpath.Join(dir, name), stdpath.Dir(path)); err != nil {
+		if err := Rename(ctx, storage, stdpath.Join(dir, name), id); err != nil {
+			log.Errorf("failed recover version [%s] of [%s]: %+v", id, path, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// ListVersions returns the versions of the file at path, the newest first.
+func ListVersions(ctx context.Context, storage driver.Driver, path string) ([]FileVersion, error) {
+	objs, err := List(ctx, storage, versionDirPath(path), model.ListArgs{Refresh: true, SkipHook: true})
+	if err != nil {
+		if errs.IsObjectNotFound(err) {
+			return []FileVersion{}, nil
+		}
+		return nil, err
+	}
+	res := make([]FileVersion, 0, len(objs))
+	for _, obj := range objs {
+		created, ok := parseVersionID(obj.GetName())
+		if !ok || obj.IsDir() {
+			continue
+		}
+		res = append(res, FileVersion{
+			ID:       obj.GetName(),
+			Size:     obj.GetSize(),
+			Modified: obj.ModTime(),
+			Created:  created,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Created.After(res[j].Created)
+	})
+	return res, nil
+}
+
+// RestoreVersion replaces the file at path with the version, the current
+// file is kept as a version so that the restore can be undone.
+func RestoreVersion(ctx context.Context, storage driver.Driver, path, id string) error {
+	path = utils.FixAndCleanPath(path)
+	if _, ok := parseVersionID(id); !ok || stdpath.Base(id) != id {
+		return errors.Errorf("无效的版本: %s", id)
+	}
+	if _, err := GetUnwrap(ctx, storage, stdpath.Join(versionDirPath(path), id)); err != nil {
+		return errors.WithMessagef(err, "failed to get version [%s]", id)
+	}
+	current, err := GetUnwrap(ctx, storage, path)
+	if err != nil && !errs.IsObjectNotFound(err) {
+		return err
+	}
+	var saved string
+	if current != nil {
+		if current.IsDir() {
+			return errors.WithStack(errs.NotFile)
+		}
+		if saved, err = saveVersion(ctx, storage, path); err != nil {
+			return err
+		}
+	}
+	if err = moveVersion(ctx, storage, path, id); err != nil {
+		if saved != "" {
+			if err := moveVersion(ctx, storage, path, saved); err != nil {
+				log.Errorf("failed recover file [%s]: %+v", path, err)
+			}
+		}
+		return errors.WithMessagef(err, "failed to restore version [%s]", id)
+	}
+	pruneVersions(ctx, storage, path)
+	return nil
+}
+
+func DeleteVersion(ctx context.Context, storage driver.Driver, path, id string) error {
+	if _, ok := parseVersionID(id); !ok || stdpath.Base(id) != id {
+		return errors.Errorf("无效的版本: %s", id)
+	}
+	return Remove(ctx, storage, stdpath.Join(versionDirPath(path), id))
+}
+
+// pruneVersions removes the versions beyond the limits of the storage.
+func pruneVersions(ctx context.Context, storage driver.Driver, path string) {
+	s := storage.GetStorage()
+	if !s.VersioningEnabled() {
+		return
+	}
+	versions, err := ListVersions(ctx, storage, path)
+	if err != nil {
+		log.Warnf("failed list versions of [%s]: %+v", path, err)
+		return
+	}
+	expire := time.Now().AddDate(0, 0, -s.VersionsDays)
+	for i, v := range versions {
+		if (s.VersionsKeep > 0 && i >= s.VersionsKeep) || (s.VersionsDays > 0 && v.Created.Before(expire)) {
+			if err := DeleteVersion(ctx, storage, path, v.ID); err != nil {
+				log.Warnf("failed remove version [%s] of [%s]: %+v", v.ID, path, err)
+			}
+		}
+	}
+}
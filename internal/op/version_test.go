@@ -0,0 +1,167 @@
+package op_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+)
+
+// noMoveOrCopy hides the Move and the Copy of the local driver, so it can
+// keep no version
+type noMoveOrCopy struct {
+	*local.Local
+}
+
+func (d noMoveOrCopy) Move() {}
+
+func (d noMoveOrCopy) Copy() {}
+
+// noMove copies the files itself, as the local driver copies them by the tasks
+type noMove struct {
+	noMoveOrCopy
+}
+
+func (d noMove) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	data, err := os.ReadFile(srcObj.GetPath())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dstDir.GetPath(), srcObj.GetName()), data, 0o666)
+}
+
+func versionedStorage(t *testing.T, mountPath string) (driver.Driver, string) {
+	root := t.TempDir()
+	ctx := context.Background()
+	id, err := op.CreateStorage(ctx, model.Storage{
+		Driver:     "Local",
+		MountPath:  mountPath,
+		Addition:   `{"root_folder_path":"` + root + `"}`,
+		Versioning: model.Versioning{VersionsKeep: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = op.DeleteStorageById(ctx, id) })
+	storage, err := op.GetStorageByMountPath(mountPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage, root
+}
+
+func putFile(t *testing.T, storage driver.Driver, content string) {
+	// the versions are named by the time in milliseconds
+	time.Sleep(2 * time.Millisecond)
+	err := op.Put(context.Background(), storage, "/", &stream.FileStream{
+		Obj:    &model.Object{Name: "a.txt", Size: int64(len(content)), Modified: time.Now()},
+		Reader: strings.NewReader(content),
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to put %q: %+v", content, err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func checkVersions(t *testing.T, storage driver.Driver, root string, expect ...string) []op.FileVersion {
+	versions, err := op.ListVersions(context.Background(), storage, "/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != len(expect) {
+		t.Fatalf("expect %d versions, got %+v", len(expect), versions)
+	}
+	for i, v := range versions {
+		if got := readFile(t, filepath.Join(root, op.VersionsDir, "a.txt", v.ID)); got != expect[i] {
+			t.Errorf("version %d: expect %q, got %q", i, expect[i], got)
+		}
+	}
+	return versions
+}
+
+func TestPutKeepsVersions(t *testing.T) {
+	storage, root := versionedStorage(t, "/versions")
+	for _, content := range []string{"1", "22", "333", "4444"} {
+		putFile(t, storage, content)
+	}
+	if got := readFile(t, filepath.Join(root, "a.txt")); got != "4444" {
+		t.Fatalf("unexpected content: %q", got)
+	}
+	// only the 2 newest versions are kept
+	versions := checkVersions(t, storage, root, "333", "22")
+
+	if err := op.RestoreVersion(context.Background(), storage, "/a.txt", versions[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(root, "a.txt")); got != "22" {
+		t.Errorf("expect the restored content, got %q", got)
+	}
+	checkVersions(t, storage, root, "4444", "333")
+}
+
+func TestPutVersionWithoutMove(t *testing.T) {
+	d, root := versionedStorage(t, "/versions_copy")
+	storage := noMove{noMoveOrCopy{d.(*local.Local)}}
+	putFile(t, storage, "1")
+	putFile(t, storage, "22")
+	if got := readFile(t, filepath.Join(root, "a.txt")); got != "22" {
+		t.Fatalf("unexpected content: %q", got)
+	}
+	checkVersions(t, storage, root, "1")
+}
+
+func TestPutVersionWithoutMoveOrCopy(t *testing.T) {
+	d, root := versionedStorage(t, "/versions_none")
+	storage := noMoveOrCopy{d.(*local.Local)}
+	putFile(t, storage, "1")
+	putFile(t, storage, "22")
+	if got := readFile(t, filepath.Join(root, "a.txt")); got != "22" {
+		t.Fatalf("the file should be overwritten, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, op.VersionsDir)); !os.IsNotExist(err) {
+		t.Errorf("no version should be kept: %v", err)
+	}
+}
+
+func TestVersionsDirBlocked(t *testing.T) {
+	versionedStorage(t, "/versions_blocked")
+	for _, path := range []string{"/versions_blocked/.versions", "/versions_blocked/.versions/a.txt/x"} {
+		if _, _, err := op.GetStorageAndActualPath(path); !errs.IsObjectNotFound(err) {
+			t.Errorf("%s: expect object not found, got %v", path, err)
+		}
+	}
+	if _, actualPath, err := op.GetStorageAndActualPath("/versions_blocked/a/.versions"); err != nil || actualPath != "/a/.versions" {
+		t.Errorf("the versions dir is only in the root, got %s, %v", actualPath, err)
+	}
+}
+
+func TestPutVersionsWithinMillisecond(t *testing.T) {
+	storage, root := versionedStorage(t, "/versions_fast")
+	for _, content := range []string{"1", "22", "333"} {
+		err := op.Put(context.Background(), storage, "/", &stream.FileStream{
+			Obj:    &model.Object{Name: "a.txt", Size: int64(len(content)), Modified: time.Now()},
+			Reader: strings.NewReader(content),
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// no version is overwritten by the next one saved at the same time
+	checkVersions(t, storage, root, "22", "1")
+}
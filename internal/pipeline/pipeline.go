@@ -0,0 +1,249 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/tache"
+	"github.com/pkg/errors"
+)
+
+type StepType string
+
+const (
+	OfflineDownload StepType = "offline_download"
+	Decompress      StepType = "decompress"
+	Copy            StepType = "copy"
+	Move            StepType = "move"
+	Rename          StepType = "rename"
+	DeleteSource    StepType = "delete_source"
+	Webhook         StepType = "webhook"
+)
+
+type StepStatus string
+
+const (
+	StepPending   StepStatus = "pending"
+	StepRunning   StepStatus = "running"
+	StepSucceeded StepStatus = "succeeded"
+	StepFailed    StepStatus = "failed"
+)
+
+// Step is a stage of a pipeline, it runs on the outputs of the previous one.
+type Step struct {
+	Type StepType `json:"type"`
+	// dst dir of offline_download, decompress, copy and move
+	Path string `json:"path,omitempty"`
+	// offline_download
+	URLs         []string `json:"urls,omitempty"`
+	Tool         string   `json:"tool,omitempty"`
+	DeletePolicy string   `json:"delete_policy,omitempty"`
+	// decompress
+	Password      string `json:"password,omitempty"`
+	PutIntoNewDir bool   `json:"put_into_new_dir,omitempty"`
+	// decompress, copy and move
+	Overwrite bool `json:"overwrite,omitempty"`
+	// rename, the name of each input matching Pattern is replaced by Replace
+	Pattern string `json:"pattern,omitempty"`
+	Replace string `json:"replace,omitempty"`
+	// webhook
+	URL string `json:"url,omitempty"`
+
+	Status  StepStatus `json:"status"`
+	Error   string     `json:"error,omitempty"`
+	Retries int        `json:"retries"`
+	Inputs  []string   `json:"inputs,omitempty"`
+	Outputs []string   `json:"outputs,omitempty"`
+}
+
+// Validate checks the arguments of the step, paths must have been joined
+// with the base path of the creator.
+func (s *Step) Validate() error {
+	switch s.Type {
+	case OfflineDownload:
+		if len(s.URLs) == 0 {
+			return errors.New("offline_download needs urls")
+		}
+	case Decompress, Copy, Move:
+	case Rename:
+		if _, err := regexp.Compile(s.Pattern); err != nil || s.Pattern == "" {
+			return errors.Errorf("invalid rename pattern: %s", s.Pattern)
+		}
+		if strings.ContainsAny(s.Replace, `/\`) {
+			return errors.New("the new name can't contain a path separator")
+		}
+	case DeleteSource:
+	case Webhook:
+		u, err := url.Parse(s.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return errors.Errorf("invalid webhook url: %s", s.URL)
+		}
+	default:
+		return errors.Errorf("unknown step type: %s", s.Type)
+	}
+	switch s.Type {
+	case OfflineDownload, Decompress, Copy, Move:
+		if s.Path == "" {
+			return errors.Errorf("%s needs a path", s.Type)
+		}
+	}
+	return nil
+}
+
+// PipelineTask runs its steps in order, each on the outputs of the previous
+// one. A retry resumes from the step which failed.
+type PipelineTask struct {
+	task.TaskExtension
+	Title   string   `json:"title"`
+	Paths   []string `json:"paths"`
+	Steps   []*Step  `json:"steps"`
+	Current int      `json:"current"`
+}
+
+func (t *PipelineTask) GetName() string {
+	return fmt.Sprintf("pipeline [%s] with %d steps", t.Title, len(t.Steps))
+}
+
+func (t *PipelineTask) GetSrcPath() string {
+	return strings.Join(t.Paths, ",")
+}
+
+func (t *PipelineTask) GetDstPath() string {
+	return ""
+}
+
+func (t *PipelineTask) GetStatus() string {
+	if t.Current >= len(t.Steps) {
+		return "finished"
+	}
+	s := t.Steps[t.Current]
+	return fmt.Sprintf("step %d/%d %s: %s", t.Current+1, len(t.Steps), s.Type, s.Status)
+}
+
+func (t *PipelineTask) Run() error {
+	release, err := Scheduler.Acquire(&t.TaskExtension)
+	if err != nil {
+		return err
+	}
+	defer release()
+	t.ClearEndTime()
+	t.SetStartTime(time.Now())
+	defer func() { t.SetEndTime(time.Now()) }()
+	inputs := t.Paths
+	if t.Current > 0 {
+		inputs = t.Steps[t.Current-1].Outputs
+	}
+	for t.Current < len(t.Steps) {
+		s := t.Steps[t.Current]
+		if s.Status == StepRunning {
+			// it was interrupted by a restart
+			s.Retries++
+		}
+		s.Status = StepRunning
+		s.Error = ""
+		s.Inputs = inputs
+		t.Persist()
+		outputs, err := t.runStep(s, inputs)
+		if err != nil {
+			s.Status = StepFailed
+			s.Error = err.Error()
+			s.Retries++
+			t.Persist()
+			return errors.WithMessagef(err, "step %d (%s) failed", t.Current+1, s.Type)
+		}
+		s.Status = StepSucceeded
+		s.Outputs = outputs
+		inputs = outputs
+		t.Current++
+		t.SetProgress(float64(t.Current) / float64(len(t.Steps)) * 100)
+		t.Persist()
+	}
+	return nil
+}
+
+func (t *PipelineTask) runStep(s *Step, inputs []string) ([]string, error) {
+	ctx := t.Ctx()
+	switch s.Type {
+	case OfflineDownload:
+		return offlineDownload(ctx, s)
+	case Decompress:
+		return decompress(ctx, s, inputs)
+	case Copy, Move:
+		return transfer(ctx, s, inputs)
+	case Rename:
+		return rename(ctx, s, inputs)
+	case DeleteSource:
+		// removes what the previous step worked on, e.g. the archives after decompressing
+		sources := inputs
+		if t.Current > 0 {
+			sources = t.Steps[t.Current-1].Inputs
+			if t.Steps[t.Current-1].Type == OfflineDownload {
+				// the downloads have no source in the storages
+				sources = nil
+			}
+		}
+		return deleteSource(ctx, sources, inputs)
+	case Webhook:
+		return webhook(ctx, t, s, inputs)
+	}
+	return nil, errors.Errorf("unknown step type: %s", s.Type)
+}
+
+var (
+	TaskManager *tache.Manager[*PipelineTask]
+	Scheduler   = task.NewScheduler("pipeline")
+)
+
+// Add validates and queues a pipeline, the paths of the steps and the inputs
+// must have been joined with the base path of the creator.
+func Add(ctx context.Context, title string, paths []string, steps []*Step) (*PipelineTask, error) {
+	if len(steps) == 0 {
+		return nil, errors.New("the pipeline has no steps")
+	}
+	for i, p := range paths {
+		paths[i] = utils.FixAndCleanPath(p)
+	}
+	for i, s := range steps {
+		if err := s.Validate(); err != nil {
+			return nil, errors.WithMessagef(err, "step %d", i+1)
+		}
+		if s.Path != "" {
+			s.Path = utils.FixAndCleanPath(s.Path)
+		}
+		s.Status = StepPending
+		s.Error = ""
+		s.Retries = 0
+		s.Inputs, s.Outputs = nil, nil
+	}
+	if steps[0].Type != OfflineDownload && len(paths) == 0 {
+		return nil, errors.New("the pipeline has no input")
+	}
+	if title == "" {
+		types := make([]string, len(steps))
+		for i, s := range steps {
+			types[i] = string(s.Type)
+		}
+		title = strings.Join(types, " → ")
+	}
+	taskCreator, _ := ctx.Value(conf.UserKey).(*model.User)
+	t := &PipelineTask{
+		TaskExtension: task.TaskExtension{
+			Creator: taskCreator,
+			ApiUrl:  common.GetApiUrl(ctx),
+		},
+		Title: title,
+		Paths: paths,
+		Steps: steps,
+	}
+	TaskManager.Add(t)
+	return t, nil
+}
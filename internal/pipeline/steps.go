@@ -0,0 +1,319 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	stdpath "path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/net"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/internal/task_group"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/tache"
+	"github.com/pkg/errors"
+)
+
+// runInGroup runs start, which queues tasks writing to dstDir, and waits
+// until they and every task they spawn are done. A pending task is held in
+// the transfer group of dstDir meanwhile, so the group can't complete before
+// all the tasks are queued.
+func runInGroup(ctx context.Context, dstDir string, start func() ([]task.TaskExtensionInfo, error)) error {
+	storage, actualPath, err := op.GetStorageAndActualPath(dstDir)
+	if err != nil {
+		return errors.WithMessage(err, "failed get dst storage")
+	}
+	groupID := stdpath.Join(storage.GetStorage().MountPath, actualPath)
+	done := make(chan bool, 1)
+	task_group.TransferCoordinator.AddTask(groupID, task_group.GroupCallback(func(ctx context.Context, hasSuccess bool) {
+		done <- hasSuccess
+	}))
+	tasks, err := start()
+	if err == nil {
+		err = waitTasks(ctx, tasks)
+	}
+	task_group.TransferCoordinator.Done(context.WithoutCancel(ctx), groupID, false)
+	if err != nil {
+		return err
+	}
+	select {
+	case hasSuccess := <-done:
+		if len(tasks) > 0 && !hasSuccess {
+			return errors.New("no task of the step succeeded")
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitTasks waits until the tasks are finished, it fails if any of them failed.
+func waitTasks(ctx context.Context, tasks []task.TaskExtensionInfo) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		finished := true
+		for _, t := range tasks {
+			switch t.GetState() {
+			case tache.StateSucceeded:
+			case tache.StateFailed:
+				return errors.WithMessagef(t.GetErr(), "task [%s] failed", t.GetName())
+			case tache.StateCanceled:
+				return errors.Errorf("task [%s] was canceled", t.GetName())
+			default:
+				finished = false
+			}
+		}
+		if finished {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// outputsTask is implemented by the tasks which record the paths they write to.
+type outputsTask interface {
+	GetOutputs() []string
+}
+
+// taskOutputs returns the paths the tasks have written to.
+func taskOutputs(tasks []task.TaskExtensionInfo) []string {
+	var outputs []string
+	for _, t := range tasks {
+		if o, ok := t.(outputsTask); ok {
+			for _, p := range o.GetOutputs() {
+				if !utils.SliceContains(outputs, p) {
+					outputs = append(outputs, p)
+				}
+			}
+		}
+	}
+	return outputs
+}
+
+func offlineDownload(ctx context.Context, s *Step) ([]string, error) {
+	var tasks []task.TaskExtensionInfo
+	err := runInGroup(ctx, s.Path, func() ([]task.TaskExtensionInfo, error) {
+		for _, u := range s.URLs {
+			t, err := tool.AddURL(ctx, &tool.AddURLArgs{
+				URL:          u,
+				DstDirPath:   s.Path,
+				Tool:         s.Tool,
+				DeletePolicy: tool.DeletePolicy(s.DeletePolicy),
+			})
+			if err != nil {
+				return tasks, errors.WithMessagef(err, "failed add [%s]", u)
+			}
+			if t != nil {
+				tasks = append(tasks, t)
+			}
+		}
+		return tasks, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	outputs := taskOutputs(tasks)
+	if len(outputs) == 0 {
+		// the tools downloading into the dst dir directly don't tell what they wrote
+		return nil, errors.New("nothing was downloaded, or the tool downloaded into the dst dir directly")
+	}
+	return outputs, nil
+}
+
+// archiveOutputs returns the paths the archive is decompressed to by the
+// storage itself, which runs no task.
+func archiveOutputs(ctx context.Context, s *Step, archive string) ([]string, error) {
+	if s.PutIntoNewDir {
+		name := stdpath.Base(archive)
+		return []string{stdpath.Join(s.Path, strings.TrimSuffix(name, stdpath.Ext(name)))}, nil
+	}
+	args := model.ArchiveArgs{Password: s.Password}
+	meta, err := fs.ArchiveMeta(ctx, archive, model.ArchiveMetaArgs{ArchiveArgs: args})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed get the meta of [%s]", archive)
+	}
+	var names []string
+	if tree := meta.GetTree(); tree != nil {
+		for _, obj := range tree {
+			names = append(names, obj.GetName())
+		}
+	} else {
+		objs, err := fs.ArchiveList(ctx, archive, model.ArchiveListArgs{
+			ArchiveInnerArgs: model.ArchiveInnerArgs{ArchiveArgs: args, InnerPath: "/"},
+		})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed list [%s]", archive)
+		}
+		for _, obj := range objs {
+			names = append(names, obj.GetName())
+		}
+	}
+	outputs := make([]string, 0, len(names))
+	for _, name := range names {
+		outputs = append(outputs, stdpath.Join(s.Path, name))
+	}
+	return outputs, nil
+}
+
+func decompress(ctx context.Context, s *Step, inputs []string) ([]string, error) {
+	var tasks []task.TaskExtensionInfo
+	var outputs []string
+	err := runInGroup(ctx, s.Path, func() ([]task.TaskExtensionInfo, error) {
+		for _, in := range inputs {
+			t, err := fs.ArchiveDecompress(ctx, in, s.Path, model.ArchiveDecompressArgs{
+				ArchiveInnerArgs: model.ArchiveInnerArgs{
+					ArchiveArgs: model.ArchiveArgs{Password: s.Password},
+					InnerPath:   "/",
+				},
+				CacheFull:     true,
+				PutIntoNewDir: s.PutIntoNewDir,
+				Overwrite:     s.Overwrite,
+			})
+			if err != nil {
+				return tasks, errors.WithMessagef(err, "failed decompress [%s]", in)
+			}
+			if t != nil {
+				tasks = append(tasks, t)
+				continue
+			}
+			paths, err := archiveOutputs(ctx, s, in)
+			if err != nil {
+				return tasks, err
+			}
+			outputs = append(outputs, paths...)
+		}
+		return tasks, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range taskOutputs(tasks) {
+		if !utils.SliceContains(outputs, p) {
+			outputs = append(outputs, p)
+		}
+	}
+	if len(outputs) == 0 {
+		return nil, errors.New("nothing was decompressed")
+	}
+	return outputs, nil
+}
+
+func transfer(ctx context.Context, s *Step, inputs []string) ([]string, error) {
+	outputs := make([]string, 0, len(inputs))
+	err := runInGroup(ctx, s.Path, func() ([]task.TaskExtensionInfo, error) {
+		var tasks []task.TaskExtensionInfo
+		for _, in := range inputs {
+			dstPath := stdpath.Join(s.Path, stdpath.Base(in))
+			if dstPath == in {
+				outputs = append(outputs, in)
+				continue
+			}
+			// a retried step may have copied it already
+			if !s.Overwrite && s.Retries == 0 {
+				if res, _ := fs.Get(ctx, dstPath, &fs.GetArgs{NoLog: true}); res != nil {
+					return tasks, errors.Errorf("[%s] already exists", dstPath)
+				}
+			}
+			var t task.TaskExtensionInfo
+			var err error
+			if s.Type == Move {
+				t, err = fs.Move(ctx, in, s.Path)
+			} else {
+				t, err = fs.Copy(ctx, in, s.Path)
+			}
+			if err != nil {
+				return tasks, errors.WithMessagef(err, "failed %s [%s]", s.Type, in)
+			}
+			if t != nil {
+				tasks = append(tasks, t)
+			}
+			outputs = append(outputs, dstPath)
+		}
+		return tasks, nil
+	})
+	return outputs, err
+}
+
+func rename(ctx context.Context, s *Step, inputs []string) ([]string, error) {
+	re, err := regexp.Compile(s.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	outputs := make([]string, 0, len(inputs))
+	for _, in := range inputs {
+		name := stdpath.Base(in)
+		newName := re.ReplaceAllString(name, s.Replace)
+		if newName == name {
+			outputs = append(outputs, in)
+			continue
+		}
+		if newName == "" {
+			return outputs, errors.Errorf("the new name of [%s] is empty", in)
+		}
+		if err = fs.Rename(ctx, in, newName); err != nil {
+			return outputs, errors.WithMessagef(err, "failed rename [%s]", in)
+		}
+		outputs = append(outputs, stdpath.Join(stdpath.Dir(in), newName))
+	}
+	return outputs, nil
+}
+
+func deleteSource(ctx context.Context, sources, inputs []string) ([]string, error) {
+	for _, src := range sources {
+		if utils.SliceContains(inputs, src) {
+			// the previous step worked in place
+			continue
+		}
+		if err := fs.Remove(ctx, src); err != nil && !errs.IsObjectNotFound(err) {
+			return nil, errors.WithMessagef(err, "failed remove [%s]", src)
+		}
+	}
+	return inputs, nil
+}
+
+type webhookBody struct {
+	ID    string   `json:"id"`
+	Title string   `json:"title"`
+	Step  int      `json:"step"`
+	Paths []string `json:"paths"`
+}
+
+func webhook(ctx context.Context, t *PipelineTask, s *Step, inputs []string) ([]string, error) {
+	body, err := utils.Json.Marshal(webhookBody{
+		ID:    t.GetID(),
+		Title: t.Title,
+		Step:  t.Current + 1,
+		Paths: inputs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := net.NewHttpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	_ = res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, fmt.Errorf("webhook responded with %s", res.Status)
+	}
+	return inputs, nil
+}
@@ -0,0 +1,200 @@
+package pipeline
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	_ "github.com/OpenListTeam/OpenList/v4/internal/archive"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+// setup mounts a local storage at /local with the files, and returns its root.
+func setup(t *testing.T, files ...string) string {
+	root := t.TempDir()
+	for _, name := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+	id, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/local",
+		Addition:  `{"root_folder_path":"` + root + `"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = op.DeleteStorageById(ctx, id) })
+	return root
+}
+
+func exists(root, name string) bool {
+	_, err := os.Stat(filepath.Join(root, name))
+	return err == nil
+}
+
+func TestRename(t *testing.T) {
+	root := setup(t, "a.txt", "b.log")
+	s := &Step{Type: Rename, Pattern: `\.txt$`, Replace: ".md"}
+	outputs, err := rename(context.Background(), s, []string{"/local/a.txt", "/local/b.log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(outputs, []string{"/local/a.md", "/local/b.log"}) {
+		t.Errorf("unexpected outputs: %v", outputs)
+	}
+	if !exists(root, "a.md") || exists(root, "a.txt") {
+		t.Error("a.txt should be renamed to a.md")
+	}
+}
+
+func TestDeleteSource(t *testing.T) {
+	root := setup(t, "a.zip", "b.txt", "other.txt")
+	outputs, err := deleteSource(context.Background(), []string{"/local/a.zip", "/local/b.txt", "/local/gone"}, []string{"/local/b.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(outputs, []string{"/local/b.txt"}) {
+		t.Errorf("unexpected outputs: %v", outputs)
+	}
+	if exists(root, "a.zip") {
+		t.Error("the source should be removed")
+	}
+	if !exists(root, "b.txt") || !exists(root, "other.txt") {
+		t.Error("the inputs and the other files should be kept")
+	}
+}
+
+func TestDeleteSourceAfterDownload(t *testing.T) {
+	root := setup(t, "input.txt", "downloaded.bin")
+	p := &PipelineTask{
+		Paths: []string{"/local/input.txt"},
+		Steps: []*Step{
+			{Type: OfflineDownload, Inputs: []string{"/local/input.txt"}, Outputs: []string{"/local/downloaded.bin"}},
+			{Type: DeleteSource},
+		},
+		Current: 1,
+	}
+	p.SetCtx(context.Background())
+	outputs, err := p.runStep(p.Steps[1], p.Steps[0].Outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(outputs, []string{"/local/downloaded.bin"}) {
+		t.Errorf("unexpected outputs: %v", outputs)
+	}
+	if !exists(root, "input.txt") || !exists(root, "downloaded.bin") {
+		t.Error("nothing should be removed after a download")
+	}
+}
+
+type outputsTestTask struct {
+	task.TaskExtension
+	outputs []string
+}
+
+func (t *outputsTestTask) GetName() string      { return "test" }
+func (t *outputsTestTask) GetStatus() string    { return "" }
+func (t *outputsTestTask) Run() error           { return nil }
+func (t *outputsTestTask) GetOutputs() []string { return t.outputs }
+
+func TestTaskOutputs(t *testing.T) {
+	outputs := taskOutputs([]task.TaskExtensionInfo{
+		&outputsTestTask{outputs: []string{"/dst/a", "/dst/b"}},
+		&outputsTestTask{},
+		// the same url downloaded twice
+		&outputsTestTask{outputs: []string{"/dst/a"}},
+	})
+	if !slices.Equal(outputs, []string{"/dst/a", "/dst/b"}) {
+		t.Errorf("unexpected outputs: %v", outputs)
+	}
+}
+
+func TestArchiveOutputs(t *testing.T) {
+	root := setup(t)
+	f, err := os.Create(filepath.Join(root, "t.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	for _, name := range []string{"a.txt", "dir/b.txt"} {
+		if _, err = w.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	outputs, err := archiveOutputs(context.Background(), &Step{Path: "/local/out"}, "/local/t.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(outputs)
+	if !slices.Equal(outputs, []string{"/local/out/a.txt", "/local/out/dir"}) {
+		t.Errorf("unexpected outputs: %v", outputs)
+	}
+	outputs, err = archiveOutputs(context.Background(), &Step{Path: "/local/out", PutIntoNewDir: true}, "/local/t.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(outputs, []string{"/local/out/t"}) {
+		t.Errorf("unexpected outputs: %v", outputs)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var body webhookBody
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		if err := utils.Json.Unmarshal(data, &body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	p := &PipelineTask{Title: "t", Current: 1}
+	p.SetID("id")
+	inputs := []string{"/a", "/b"}
+	outputs, err := webhook(context.Background(), p, &Step{URL: srv.URL}, inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(outputs, inputs) {
+		t.Errorf("the inputs should be passed on, got %v", outputs)
+	}
+	if body.ID != "id" || body.Title != "t" || body.Step != 2 || !slices.Equal(body.Paths, inputs) {
+		t.Errorf("unexpected body: %+v", body)
+	}
+
+	srv.Config.Handler = http.NotFoundHandler()
+	if _, err = webhook(context.Background(), p, &Step{URL: srv.URL}, inputs); err == nil {
+		t.Error("expect the error of the status")
+	}
+}
@@ -0,0 +1,167 @@
+package rss
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/hex"
+	"encoding/xml"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html/charset"
+)
+
+// Item is an entry of a RSS or Atom feed.
+type Item struct {
+	GUID     string
+	Title    string
+	URL      string
+	InfoHash string
+}
+
+// Key identifies the item for deduplication, the same torrent published
+// by different feeds or with different guids has the same key.
+func (i Item) Key() string {
+	key := i.GUID
+	if i.InfoHash != "" {
+		key = "btih:" + i.InfoHash
+	} else if key == "" {
+		key = i.URL
+	}
+	if len(key) > 255 {
+		sum := sha1.Sum([]byte(key))
+		key = "sha1:" + hex.EncodeToString(sum[:])
+	}
+	return key
+}
+
+type rssEnclosure struct {
+	URL  string `xml:"url,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title     string         `xml:"title"`
+	Link      string         `xml:"link"`
+	GUID      string         `xml:"guid"`
+	Enclosure []rssEnclosure `xml:"enclosure"`
+	// e.g. nyaa:infoHash or torrent:infoHash
+	InfoHash string `xml:"infoHash"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type atomEntry struct {
+	Title    string     `xml:"title"`
+	ID       string     `xml:"id"`
+	Links    []atomLink `xml:"link"`
+	InfoHash string     `xml:"infoHash"`
+}
+
+type feedDoc struct {
+	XMLName xml.Name
+	// rss 2.0 and rss 1.0 (rdf)
+	ChannelItems []rssItem `xml:"channel>item"`
+	RdfItems     []rssItem `xml:"item"`
+	// atom
+	Entries []atomEntry `xml:"entry"`
+}
+
+// Parse parses a RSS or Atom document.
+func Parse(data []byte) ([]Item, error) {
+	var doc feedDoc
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.CharsetReader = charset.NewReaderLabel
+	if err := d.Decode(&doc); err != nil {
+		return nil, errors.WithMessage(err, "invalid feed")
+	}
+	var items []Item
+	switch doc.XMLName.Local {
+	case "rss", "RDF":
+		for _, it := range append(doc.ChannelItems, doc.RdfItems...) {
+			urls := []string{strings.TrimSpace(it.Link), strings.TrimSpace(it.GUID)}
+			for _, e := range it.Enclosure {
+				urls = append([]string{strings.TrimSpace(e.URL)}, urls...)
+			}
+			items = append(items, newItem(it.GUID, it.Title, it.InfoHash, urls))
+		}
+	case "feed":
+		for _, e := range doc.Entries {
+			var urls []string
+			for _, l := range e.Links {
+				if l.Rel == "enclosure" {
+					urls = append([]string{strings.TrimSpace(l.Href)}, urls...)
+				} else if l.Rel == "" || l.Rel == "alternate" {
+					urls = append(urls, strings.TrimSpace(l.Href))
+				}
+			}
+			items = append(items, newItem(e.ID, e.Title, e.InfoHash, urls))
+		}
+	default:
+		return nil, errors.Errorf("unknown feed type: %s", doc.XMLName.Local)
+	}
+	return items, nil
+}
+
+// newItem picks the url to download from urls, a magnet goes first, then the
+// enclosures which are put before the links.
+func newItem(guid, title, infoHash string, urls []string) Item {
+	item := Item{
+		GUID:     strings.TrimSpace(guid),
+		Title:    strings.TrimSpace(title),
+		InfoHash: normalizeInfoHash(infoHash),
+	}
+	for _, u := range urls {
+		if strings.HasPrefix(u, "magnet:") {
+			item.URL = u
+			if item.InfoHash == "" {
+				item.InfoHash = MagnetInfoHash(u)
+			}
+			return item
+		}
+	}
+	for _, u := range urls {
+		if strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+			item.URL = u
+			break
+		}
+	}
+	return item
+}
+
+// MagnetInfoHash returns the lower case hex info-hash of a magnet link.
+func MagnetInfoHash(magnet string) string {
+	u, err := url.Parse(magnet)
+	if err != nil {
+		return ""
+	}
+	for _, xt := range u.Query()["xt"] {
+		if h, ok := strings.CutPrefix(strings.ToLower(xt), "urn:btih:"); ok {
+			return normalizeInfoHash(h)
+		}
+	}
+	return ""
+}
+
+func normalizeInfoHash(h string) string {
+	h = strings.TrimSpace(h)
+	if len(h) == 32 {
+		// base32 encoded
+		if b, err := base32.StdEncoding.DecodeString(strings.ToUpper(h)); err == nil {
+			return hex.EncodeToString(b)
+		}
+	}
+	if len(h) != 40 {
+		return ""
+	}
+	if _, err := hex.DecodeString(h); err != nil {
+		return ""
+	}
+	return strings.ToLower(h)
+}
@@ -0,0 +1,54 @@
+package rss
+
+import "testing"
+
+func TestParseRss(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:nyaa="https://nyaa.si/xmlns/nyaa">
+<channel>
+<item>
+	<title>Show - 01 [1080p]</title>
+	<link>https://example.com/1.torrent</link>
+	<guid isPermaLink="true">https://example.com/view/1</guid>
+	<nyaa:infoHash>0123456789ABCDEF0123456789ABCDEF01234567</nyaa:infoHash>
+</item>
+<item>
+	<title>Show - 02 [1080p]</title>
+	<link>https://example.com/view/2</link>
+	<enclosure url="magnet:?xt=urn:btih:MFRGGZDFMZTWQ2LKNNWG23TPOBYXE43U&amp;dn=2" type="application/x-bittorrent"/>
+</item>
+</channel>
+</rss>`
+	items, err := Parse([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expect 2 items, got %d", len(items))
+	}
+	if items[0].URL != "https://example.com/1.torrent" || items[0].Key() != "btih:0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("unexpected item: %+v, key: %s", items[0], items[0].Key())
+	}
+	if items[1].URL[:7] != "magnet:" || items[1].Key() != "btih:6162636465666768696a6b6c6d6e6f7071727374" {
+		t.Errorf("unexpected item: %+v, key: %s", items[1], items[1].Key())
+	}
+}
+
+func TestParseAtom(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+<entry>
+	<title>Release 1.0</title>
+	<id>tag:example.com,2024:1</id>
+	<link rel="alternate" href="https://example.com/release/1"/>
+	<link rel="enclosure" href="https://example.com/release-1.0.zip"/>
+</entry>
+</feed>`
+	items, err := Parse([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].URL != "https://example.com/release-1.0.zip" || items[0].Key() != "tag:example.com,2024:1" {
+		t.Errorf("unexpected items: %+v", items)
+	}
+}
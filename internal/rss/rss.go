@@ -0,0 +1,205 @@
+package rss
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/net"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultInterval = 30
+	MinInterval     = 5
+	// the feed documents we read at most
+	maxFeedSize = 16 * 1024 * 1024
+)
+
+var (
+	poller *cron.Cron
+	// checking holds the ids of the feeds being checked
+	checking sync.Map
+)
+
+// Validate checks and normalizes a feed before saving it.
+func Validate(f *model.RssFeed) error {
+	f.URL = strings.TrimSpace(f.URL)
+	if !strings.HasPrefix(f.URL, "http://") && !strings.HasPrefix(f.URL, "https://") {
+		return errors.New("feed url must be http or https")
+	}
+	if _, err := regexp.Compile(f.Include); err != nil {
+		return errors.WithMessage(err, "invalid include regexp")
+	}
+	if _, err := regexp.Compile(f.Exclude); err != nil {
+		return errors.WithMessage(err, "invalid exclude regexp")
+	}
+	if _, err := tool.Tools.Get(f.Tool); err != nil {
+		return err
+	}
+	if f.Interval == 0 {
+		f.Interval = DefaultInterval
+	}
+	if f.Interval < MinInterval {
+		f.Interval = MinInterval
+	}
+	if f.DstPath == "" {
+		return errors.New("dst path is required")
+	}
+	f.DstPath = utils.FixAndCleanPath(f.DstPath)
+	if f.Name == "" {
+		f.Name = f.URL
+	}
+	return nil
+}
+
+// Start polls the enabled feeds every minute, each is checked when its
+// interval has passed since the last check.
+func Start() {
+	poller = cron.NewCron(time.Minute)
+	poller.Do(pollAll)
+}
+
+func Stop() {
+	if poller != nil {
+		poller.Stop()
+	}
+}
+
+func pollAll() {
+	feeds, err := db.GetEnabledRssFeeds()
+	if err != nil {
+		log.Errorf("failed get rss feeds: %+v", err)
+		return
+	}
+	now := time.Now()
+	for i := range feeds {
+		f := &feeds[i]
+		if now.Sub(f.LastCheck) < time.Duration(f.Interval)*time.Minute {
+			continue
+		}
+		go func() {
+			if _, err := Check(context.Background(), f); err != nil {
+				log.Warnf("failed check rss feed [%s]: %v", f.Name, err)
+			}
+		}()
+	}
+}
+
+// Check fetches the feed and adds its new matching items as offline
+// downloads, it returns the histories of the added items.
+func Check(ctx context.Context, f *model.RssFeed) ([]model.RssHistory, error) {
+	if _, loaded := checking.LoadOrStore(f.ID, struct{}{}); loaded {
+		return nil, errors.New("the feed is being checked")
+	}
+	defer checking.Delete(f.ID)
+	added, err := check(ctx, f)
+	f.LastCheck = time.Now()
+	f.LastError = ""
+	if err != nil {
+		f.LastError = err.Error()
+	}
+	if e := db.UpdateRssFeed(f); e != nil {
+		log.Errorf("failed update rss feed: %+v", e)
+	}
+	return added, err
+}
+
+func check(ctx context.Context, f *model.RssFeed) ([]model.RssHistory, error) {
+	user, err := op.GetUserById(f.UserId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get the owner of the feed")
+	}
+	if !user.CanAddOfflineDownloadTasks() {
+		return nil, errors.New("the owner can't add offline download tasks")
+	}
+	dstPath, err := user.JoinPath(f.DstPath)
+	if err != nil {
+		return nil, err
+	}
+	include, err := regexp.Compile(f.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := regexp.Compile(f.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	items, err := fetch(ctx, f.URL)
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, conf.UserKey, user)
+	var added []model.RssHistory
+	for _, item := range items {
+		if item.URL == "" || !include.MatchString(item.Title) ||
+			(f.Exclude != "" && exclude.MatchString(item.Title)) {
+			continue
+		}
+		key := item.Key()
+		exist, err := db.ExistRssHistory(user.ID, key)
+		if err != nil {
+			return added, err
+		}
+		if exist {
+			continue
+		}
+		h := model.RssHistory{
+			FeedId:    f.ID,
+			UserId:    user.ID,
+			ItemKey:   key,
+			Title:     item.Title,
+			URL:       item.URL,
+			AddedTime: time.Now(),
+		}
+		_, err = tool.AddURL(ctx, &tool.AddURLArgs{
+			URL:          item.URL,
+			DstDirPath:   dstPath,
+			Tool:         f.Tool,
+			DeletePolicy: tool.DeletePolicy(f.DeletePolicy),
+		})
+		if err != nil {
+			// recorded as well, the failed items are retried after clearing the history
+			h.Error = err.Error()
+			log.Warnf("failed add rss item [%s]: %v", item.Title, err)
+		}
+		if err = db.CreateRssHistory(&h); err != nil {
+			return added, err
+		}
+		added = append(added, h)
+	}
+	return added, nil
+}
+
+func fetch(ctx context.Context, url string) ([]Item, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "OpenList")
+	res, err := net.NewHttpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed fetch feed: %s", res.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxFeedSize))
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
@@ -0,0 +1,185 @@
+package rss
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/tache"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	tool.Tools.Add(fakeTool{})
+	// the tasks added wait for workers forever, they are only counted
+	tool.DownloadTaskManager = tache.NewManager[*tool.DownloadTask](tache.WithWorks(1))
+}
+
+// fakeTool accepts any url, the tasks are never run by the tests.
+type fakeTool struct{}
+
+func (fakeTool) Name() string                                    { return "RssTest" }
+func (fakeTool) Items() []model.SettingItem                      { return nil }
+func (fakeTool) Init() (string, error)                           { return "", nil }
+func (fakeTool) IsReady() bool                                   { return true }
+func (fakeTool) AddURL(*tool.AddUrlArgs) (string, error)         { return "", nil }
+func (fakeTool) Remove(*tool.DownloadTask) error                 { return nil }
+func (fakeTool) Status(*tool.DownloadTask) (*tool.Status, error) { return nil, nil }
+func (fakeTool) Run(*tool.DownloadTask) error                    { return nil }
+
+const testFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+<channel>
+<item><title>Show - 01 [1080p]</title><link>https://example.com/1.torrent</link><guid>1</guid></item>
+<item><title>Show - 02 [720p]</title><link>https://example.com/2.torrent</link><guid>2</guid></item>
+<item><title>Show - 03 [1080p]</title><link>https://example.com/3.torrent</link><guid>3</guid></item>
+<item><title>Show - 04 [1080p]</title><guid>4</guid></item>
+</channel>
+</rss>`
+
+// feedServer serves testFeed, or fails while failing is set.
+func feedServer(t *testing.T) (*httptest.Server, *atomic.Bool) {
+	var failing atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(testFeed))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &failing
+}
+
+// feedOwner mounts a local storage at /rss, and returns a user who may add
+// offline downloads to it.
+func feedOwner(t *testing.T) *model.User {
+	ctx := context.Background()
+	id, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/rss",
+		Addition:  `{"root_folder_path":"` + t.TempDir() + `"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = op.DeleteStorageById(ctx, id) })
+	user := &model.User{Username: "rss-" + t.Name(), BasePath: "/", Permission: 0xffff}
+	if err = op.CreateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = op.DeleteUserById(user.ID) })
+	return user
+}
+
+func newFeed(t *testing.T, user *model.User, url string) *model.RssFeed {
+	f := &model.RssFeed{UserId: user.ID, URL: url, Include: `1080p`, Exclude: `03`, Tool: "RssTest", DstPath: "/rss"}
+	if err := Validate(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateRssFeed(f); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestCheck(t *testing.T) {
+	ts, failing := feedServer(t)
+	f := newFeed(t, feedOwner(t), ts.URL)
+	tasks := len(tool.DownloadTaskManager.GetAll())
+	added, err := Check(context.Background(), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 02 isn't included, 03 is excluded and 04 has no url
+	if len(added) != 1 || added[0].URL != "https://example.com/1.torrent" || added[0].Error != "" {
+		t.Fatalf("unexpected added %+v", added)
+	}
+	if n := len(tool.DownloadTaskManager.GetAll()) - tasks; n != 1 {
+		t.Errorf("expect a task added, got %d", n)
+	}
+	// the items handled are skipped
+	if added, err = Check(context.Background(), f); err != nil || len(added) != 0 {
+		t.Errorf("expect nothing added again, got %+v, %v", added, err)
+	}
+	histories, count, err := db.GetRssHistories(f.ID, 1, 10)
+	if err != nil || count != 1 || histories[0].ItemKey != "1" {
+		t.Errorf("unexpected histories %+v, %v", histories, err)
+	}
+
+	failing.Store(true)
+	if _, err = Check(context.Background(), f); err == nil {
+		t.Fatal("expect the fetch failed")
+	}
+	saved, err := db.GetRssFeedById(f.ID)
+	if err != nil || saved.LastError == "" || saved.LastCheck.IsZero() {
+		t.Errorf("expect the error saved, got %+v, %v", saved, err)
+	}
+	failing.Store(false)
+	if _, err = Check(context.Background(), f); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ = db.GetRssFeedById(f.ID); saved.LastError != "" {
+		t.Errorf("expect the error cleared, got %s", saved.LastError)
+	}
+
+	// a feed is checked once at a time
+	checking.Store(f.ID, struct{}{})
+	_, err = Check(context.Background(), f)
+	checking.Delete(f.ID)
+	if err == nil {
+		t.Error("expect the concurrent check refused")
+	}
+}
+
+func TestPollAll(t *testing.T) {
+	ts, _ := feedServer(t)
+	user := feedOwner(t)
+	due := newFeed(t, user, ts.URL)
+	recent := newFeed(t, user, ts.URL+"/recent")
+	recent.LastCheck = time.Now()
+	disabled := newFeed(t, user, ts.URL+"/disabled")
+	disabled.Disabled = true
+	for _, f := range []*model.RssFeed{recent, disabled} {
+		if err := db.UpdateRssFeed(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pollAll()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		f, err := db.GetRssFeedById(due.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !f.LastCheck.IsZero() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the due feed isn't checked")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if f, _ := db.GetRssFeedById(recent.ID); !f.LastCheck.Equal(recent.LastCheck) {
+		t.Errorf("the feed checked recently shouldn't be checked again, got %v", f.LastCheck)
+	}
+	if f, _ := db.GetRssFeedById(disabled.ID); !f.LastCheck.IsZero() {
+		t.Errorf("the disabled feed shouldn't be checked, got %v", f.LastCheck)
+	}
+}
@@ -34,6 +34,95 @@ var (
 	ServerUploadLimit   Limiter
 )
 
+type (
+	taskUploadLimiterKey   struct{}
+	taskDownloadLimiterKey struct{}
+)
+
+// WaitLimiter is the part of Limiter the bandwidth cap of a task needs.
+type WaitLimiter interface {
+	WaitN(context.Context, int) error
+}
+
+// WithTaskLimiter returns a ctx whose uploads to storages are also limited by
+// upload, and whose downloads from storages or urls by download.
+func WithTaskLimiter(ctx context.Context, upload, download WaitLimiter) context.Context {
+	ctx = context.WithValue(ctx, taskUploadLimiterKey{}, upload)
+	return context.WithValue(ctx, taskDownloadLimiterKey{}, download)
+}
+
+func taskLimiterFrom(ctx context.Context, key any) WaitLimiter {
+	l, _ := ctx.Value(key).(WaitLimiter)
+	return l
+}
+
+type chainedLimiter struct {
+	Limiter
+	task WaitLimiter
+}
+
+func (l chainedLimiter) WaitN(ctx context.Context, n int) error {
+	if l.Limiter != nil {
+		if err := l.Limiter.WaitN(ctx, n); err != nil {
+			return err
+		}
+	}
+	return l.task.WaitN(ctx, n)
+}
+
+// UploadLimiter returns ServerUploadLimit, chained with the upload limiter of
+// the task ctx belongs to if there is one.
+func UploadLimiter(ctx context.Context) Limiter {
+	if l := taskLimiterFrom(ctx, taskUploadLimiterKey{}); l != nil {
+		return chainedLimiter{Limiter: ServerUploadLimit, task: l}
+	}
+	return ServerUploadLimit
+}
+
+// TaskLimitReader applies only the upload limiter of the task of ctx to r,
+// for storages which aren't subject to ServerUploadLimit.
+func TaskLimitReader(ctx context.Context, r io.Reader) io.Reader {
+	return taskLimitReader(ctx, r, taskUploadLimiterKey{})
+}
+
+// TaskDownloadLimitReader applies the download limiter of the task of ctx to
+// r, which is read from a storage or an url.
+func TaskDownloadLimitReader(ctx context.Context, r io.Reader) io.Reader {
+	return taskLimitReader(ctx, r, taskDownloadLimiterKey{})
+}
+
+func taskLimitReader(ctx context.Context, r io.Reader, key any) io.Reader {
+	if l := taskLimiterFrom(ctx, key); l != nil {
+		return &RateLimitReader{Reader: r, Limiter: chainedLimiter{task: l}, Ctx: ctx}
+	}
+	return r
+}
+
+// taskDownloadLimit limits rc read by a task with its download limiter, the
+// server limit is applied by the drivers already. A model.File is kept one.
+func taskDownloadLimit(ctx context.Context, rc io.ReadCloser) io.ReadCloser {
+	l := taskLimiterFrom(ctx, taskDownloadLimiterKey{})
+	if l == nil {
+		return rc
+	}
+	if f, ok := rc.(model.File); ok {
+		return &RateLimitFile{File: f, Limiter: chainedLimiter{task: l}, Ctx: ctx}
+	}
+	return &RateLimitReader{Reader: rc, Limiter: chainedLimiter{task: l}, Ctx: ctx}
+}
+
+type taskDownloadRangeReader struct {
+	model.RangeReaderIF
+}
+
+func (r taskDownloadRangeReader) RangeRead(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+	rc, err := r.RangeReaderIF.RangeRead(ctx, httpRange)
+	if err != nil {
+		return nil, err
+	}
+	return taskDownloadLimit(ctx, rc), nil
+}
+
 type RateLimitReader struct {
 	io.Reader
 	Limiter Limiter
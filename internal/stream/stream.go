@@ -321,12 +321,12 @@ func NewSeekableStream(fs *FileStream, link *model.Link) (*SeekableStream, error
 			if err != nil {
 				return nil, err
 			}
-			fs.Reader = rc
+			fs.Reader = taskDownloadLimit(fs.Ctx, rc)
 			fs.Add(rc)
 		}
 		fs.size = size
 		fs.Add(link)
-		return &SeekableStream{FileStream: fs, rangeReader: rr}, nil
+		return &SeekableStream{FileStream: fs, rangeReader: taskDownloadRangeReader{rr}}, nil
 	}
 	return nil, fmt.Errorf("illegal seekableStream")
 }
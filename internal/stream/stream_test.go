@@ -2,6 +2,7 @@ package stream
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -107,3 +108,36 @@ func TestFileStream_With_PreHash(t *testing.T) {
 		t.Errorf("fullHash and fileFullHash should match: fullHash=%s fileFullHash=%s", fullHash, fileFullHash)
 	}
 }
+
+// countLimiter counts the bytes waited for.
+type countLimiter struct{ n int }
+
+func (l *countLimiter) WaitN(_ context.Context, n int) error {
+	l.n += n
+	return nil
+}
+
+func TestSeekableStream_TaskLimit(t *testing.T) {
+	buf := []byte("github.com/OpenListTeam/OpenList")
+	rangeReader := RangeReaderFunc(func(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf[httpRange.Start:])), nil
+	})
+	for name, link := range map[string]*model.Link{
+		"range reader": {RangeReader: rangeReader},
+		"file":         {RangeReader: GetRangeReaderFromMFile(int64(len(buf)), bytes.NewReader(buf))},
+	} {
+		up, down := &countLimiter{}, &countLimiter{}
+		ctx := WithTaskLimiter(context.Background(), up, down)
+		ss, err := NewSeekableStream(&FileStream{Ctx: ctx, Obj: &model.Object{Name: "a", Size: int64(len(buf))}}, link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(ss)
+		if err != nil || !bytes.Equal(got, buf) {
+			t.Fatalf("%s: unexpected content %q, %v", name, got, err)
+		}
+		if down.n != len(buf) || up.n != 0 {
+			t.Errorf("%s: expect %d bytes downloaded, got %d, uploaded %d", name, len(buf), down.n, up.n)
+		}
+	}
+}
@@ -6,7 +6,9 @@ import (
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
 	"github.com/OpenListTeam/tache"
+	"golang.org/x/time/rate"
 )
 
 type TaskExtension struct {
@@ -16,6 +18,17 @@ type TaskExtension struct {
 	endTime    *time.Time
 	TotalBytes int64
 	ApiUrl     string
+	Priority   int  `json:"priority,omitempty"`
+	Paused     bool `json:"paused,omitempty"`
+	SpeedLimit int  `json:"speed_limit,omitempty"` // KB/s
+
+	// scheduling state, guarded by schedMu
+	scheduler *Scheduler
+	seq       uint64
+	wake      chan struct{}
+	granted   bool
+	// the speed limit applies to the uploads and the downloads separately
+	limiters [2]*rate.Limiter
 }
 
 func (t *TaskExtension) SetCtx(ctx context.Context) {
@@ -25,6 +38,7 @@ func (t *TaskExtension) SetCtx(ctx context.Context) {
 	if len(t.ApiUrl) > 0 {
 		ctx = context.WithValue(ctx, conf.ApiUrlKey, t.ApiUrl)
 	}
+	ctx = stream.WithTaskLimiter(ctx, throttle{t: t}, throttle{t: t, download: true})
 	t.Base.SetCtx(ctx)
 }
 
@@ -85,4 +99,11 @@ type TaskExtensionInfo interface {
 	GetStartTime() *time.Time
 	GetEndTime() *time.Time
 	GetTotalBytes() int64
+	GetPriority() int
+	SetPriority(priority int)
+	IsPaused() bool
+	SetPaused(paused bool)
+	GetSpeedLimit() int
+	SetSpeedLimit(limit int)
+	GetQueueStatus() string
 }
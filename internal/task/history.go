@@ -0,0 +1,35 @@
+package task
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/tache"
+)
+
+// PathsInfo is implemented by the tasks with a source or a destination,
+// which are recorded in the task history.
+type PathsInfo interface {
+	GetSrcPath() string
+	GetDstPath() string
+}
+
+var finished = make(chan struct{}, 1)
+
+// Finished notifies that a task has succeeded, failed or been canceled.
+func Finished() <-chan struct{} {
+	return finished
+}
+
+func (t *TaskExtension) SetState(state tache.State) {
+	// the tasks canceled before running have no end time
+	if state == tache.StateCanceled && t.endTime == nil {
+		t.SetEndTime(time.Now())
+	}
+	t.Base.SetState(state)
+	if state == tache.StateSucceeded || state == tache.StateFailed || state == tache.StateCanceled {
+		select {
+		case finished <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Scheduler decides when the tasks of a manager actually run. The tache
+// managers pop every queued task at once, each Run then waits in Acquire for
+// a free worker, the waiting task with the highest priority goes first.
+// A scheduler may be paused and limited to time windows, both only hold back
+// tasks which haven't started, while pausing also blocks the transfer of
+// running ones through their throttle.
+type Scheduler struct {
+	Name    string
+	workers int
+	running int
+	paused  bool
+	windows []TimeWindow
+	waiting []*TaskExtension
+	seq     uint64
+	timer   *time.Timer
+}
+
+var (
+	// schedMu guards the scheduling state of all schedulers and tasks
+	schedMu sync.Mutex
+	// resumed is closed and replaced whenever a task or scheduler is resumed
+	resumed = make(chan struct{})
+)
+
+func NewScheduler(name string) *Scheduler {
+	return &Scheduler{Name: name}
+}
+
+type SchedulerInfo struct {
+	Workers int    `json:"workers"`
+	Running int    `json:"running"`
+	Waiting int    `json:"waiting"`
+	Paused  bool   `json:"paused"`
+	Window  string `json:"window"`
+	InTime  bool   `json:"in_time"`
+}
+
+func (s *Scheduler) Info() SchedulerInfo {
+	schedMu.Lock()
+	defer schedMu.Unlock()
+	return SchedulerInfo{
+		Workers: s.workers,
+		Running: s.running,
+		Waiting: len(s.waiting),
+		Paused:  s.paused,
+		Window:  FormatTimeWindows(s.windows),
+		InTime:  s.inWindow(time.Now()),
+	}
+}
+
+// SetWorkers sets how many tasks may run at the same time.
+func (s *Scheduler) SetWorkers(n int) {
+	schedMu.Lock()
+	defer schedMu.Unlock()
+	s.workers = n
+	s.dispatch()
+}
+
+func (s *Scheduler) SetWindows(windows []TimeWindow) {
+	schedMu.Lock()
+	defer schedMu.Unlock()
+	s.windows = windows
+	s.dispatch()
+}
+
+func (s *Scheduler) Pause() {
+	schedMu.Lock()
+	defer schedMu.Unlock()
+	s.paused = true
+}
+
+func (s *Scheduler) Resume() {
+	schedMu.Lock()
+	defer schedMu.Unlock()
+	s.paused = false
+	broadcastResumed()
+	s.dispatch()
+}
+
+// Acquire blocks until t may run, the returned func must be called when t is done.
+func (s *Scheduler) Acquire(t *TaskExtension) (func(), error) {
+	schedMu.Lock()
+	t.scheduler = s
+	t.granted = false
+	t.wake = make(chan struct{}, 1)
+	s.seq++
+	t.seq = s.seq
+	s.waiting = append(s.waiting, t)
+	s.dispatch()
+	wake := t.wake
+	schedMu.Unlock()
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			schedMu.Lock()
+			defer schedMu.Unlock()
+			s.running--
+			s.dispatch()
+		})
+	}
+	select {
+	case <-wake:
+		return release, nil
+	case <-t.Ctx().Done():
+		schedMu.Lock()
+		granted := t.granted
+		if !granted {
+			s.remove(t)
+		}
+		schedMu.Unlock()
+		if granted {
+			release()
+		}
+		return nil, t.Ctx().Err()
+	}
+}
+
+func (s *Scheduler) remove(t *TaskExtension) {
+	for i, w := range s.waiting {
+		if w == t {
+			s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatch starts the waiting tasks which may run now, schedMu must be held.
+func (s *Scheduler) dispatch() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if s.paused || len(s.waiting) == 0 {
+		return
+	}
+	now := time.Now()
+	if !s.inWindow(now) {
+		if d, ok := untilWindow(s.windows, now); ok {
+			s.timer = time.AfterFunc(d, func() {
+				schedMu.Lock()
+				defer schedMu.Unlock()
+				s.dispatch()
+			})
+		}
+		return
+	}
+	for s.running < s.workers {
+		var next *TaskExtension
+		for _, t := range s.waiting {
+			if t.Paused {
+				continue
+			}
+			if next == nil || t.Priority > next.Priority ||
+				(t.Priority == next.Priority && t.seq < next.seq) {
+				next = t
+			}
+		}
+		if next == nil {
+			return
+		}
+		s.remove(next)
+		s.running++
+		next.granted = true
+		next.wake <- struct{}{}
+	}
+}
+
+func (s *Scheduler) inWindow(now time.Time) bool {
+	if len(s.windows) == 0 {
+		return true
+	}
+	for _, w := range s.windows {
+		if w.Contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+func broadcastResumed() {
+	close(resumed)
+	resumed = make(chan struct{})
+}
+
+// GetPriority returns the priority of the task, higher ones are run first.
+func (t *TaskExtension) GetPriority() int {
+	schedMu.Lock()
+	defer schedMu.Unlock()
+	return t.Priority
+}
+
+func (t *TaskExtension) SetPriority(priority int) {
+	schedMu.Lock()
+	t.Priority = priority
+	if t.scheduler != nil {
+		t.scheduler.dispatch()
+	}
+	schedMu.Unlock()
+	t.Persist()
+}
+
+func (t *TaskExtension) IsPaused() bool {
+	schedMu.Lock()
+	defer schedMu.Unlock()
+	return t.Paused
+}
+
+// SetPaused pauses or resumes the task. A paused task isn't started, and
+// blocks in its throttle if it is running already.
+func (t *TaskExtension) SetPaused(paused bool) {
+	schedMu.Lock()
+	t.Paused = paused
+	if !paused {
+		broadcastResumed()
+		if t.scheduler != nil {
+			t.scheduler.dispatch()
+		}
+	}
+	schedMu.Unlock()
+	t.Persist()
+}
+
+func (t *TaskExtension) GetSpeedLimit() int {
+	schedMu.Lock()
+	defer schedMu.Unlock()
+	return t.SpeedLimit
+}
+
+// SetSpeedLimit caps the bandwidth of the task in KB/s, each of its uploads
+// and downloads, 0 for no limit.
+func (t *TaskExtension) SetSpeedLimit(limit int) {
+	if limit < 0 {
+		limit = 0
+	}
+	schedMu.Lock()
+	t.SpeedLimit = limit
+	t.limiters = [2]*rate.Limiter{}
+	schedMu.Unlock()
+	t.Persist()
+}
+
+// GetQueueStatus tells why the task is waiting to run, empty if it isn't.
+func (t *TaskExtension) GetQueueStatus() string {
+	schedMu.Lock()
+	defer schedMu.Unlock()
+	s := t.scheduler
+	if s == nil || t.granted || t.wake == nil {
+		if t.Paused {
+			return "已暂停"
+		}
+		return ""
+	}
+	switch {
+	case t.Paused:
+		return "已暂停"
+	case s.paused:
+		return "队列已暂停"
+	case !s.inWindow(time.Now()):
+		return "等待执行时段"
+	default:
+		return "排队中"
+	}
+}
+
+// throttle is the limiter of the upload or download streams of a task, it
+// blocks while the task or its scheduler is paused and applies the speed
+// limit of the task.
+type throttle struct {
+	t        *TaskExtension
+	download bool
+}
+
+func (th throttle) WaitN(ctx context.Context, n int) error {
+	t := th.t
+	i := 0
+	if th.download {
+		i = 1
+	}
+	for {
+		schedMu.Lock()
+		paused := t.Paused || (t.scheduler != nil && t.scheduler.paused)
+		ch := resumed
+		limiter := t.limiters[i]
+		if limiter == nil && t.SpeedLimit > 0 {
+			limit := t.SpeedLimit * 1024
+			limiter = rate.NewLimiter(rate.Limit(limit), limit)
+			t.limiters[i] = limiter
+		}
+		schedMu.Unlock()
+		if !paused {
+			if limiter == nil {
+				return nil
+			}
+			for n > 0 {
+				m := min(n, limiter.Burst())
+				if err := limiter.WaitN(ctx, m); err != nil {
+					return err
+				}
+				n -= m
+			}
+			return nil
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// TimeWindow is a daily period from Start to End, minutes since midnight.
+// It wraps around midnight if End is before Start.
+type TimeWindow struct {
+	Start int
+	End   int
+}
+
+func (w TimeWindow) Contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if w.Start <= w.End {
+		return m >= w.Start && m < w.End
+	}
+	return m >= w.Start || m < w.End
+}
+
+func (w TimeWindow) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.Start/60, w.Start%60, w.End/60, w.End%60)
+}
+
+// ParseTimeWindows parses comma separated windows like "01:00-07:00,22:00-23:30",
+// an empty string means no restriction.
+func ParseTimeWindows(s string) ([]TimeWindow, error) {
+	var res []TimeWindow
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		start, end, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid time window: %s", part)
+		}
+		var w TimeWindow
+		var err error
+		if w.Start, err = parseClock(start); err != nil {
+			return nil, err
+		}
+		if w.End, err = parseClock(end); err != nil {
+			return nil, err
+		}
+		if w.Start == w.End {
+			return nil, fmt.Errorf("empty time window: %s", part)
+		}
+		res = append(res, w)
+	}
+	return res, nil
+}
+
+func FormatTimeWindows(windows []TimeWindow) string {
+	s := make([]string, len(windows))
+	for i, w := range windows {
+		s[i] = w.String()
+	}
+	return strings.Join(s, ",")
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time: %s", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// untilWindow returns how long it is until the next window opens.
+func untilWindow(windows []TimeWindow, now time.Time) (time.Duration, bool) {
+	var res time.Duration
+	found := false
+	for _, w := range windows {
+		start := time.Date(now.Year(), now.Month(), now.Day(), w.Start/60, w.Start%60, 0, 0, now.Location())
+		if !start.After(now) {
+			start = start.AddDate(0, 0, 1)
+		}
+		if d := start.Sub(now); !found || d < res {
+			res, found = d, true
+		}
+	}
+	return res, found
+}
@@ -0,0 +1,206 @@
+package task
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseTimeWindows(t *testing.T) {
+	windows, err := ParseTimeWindows(" 01:00-07:00, 22:00-23:30 ,, 23:00-1:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatTimeWindows(windows); got != "01:00-07:00,22:00-23:30,23:00-01:30" {
+		t.Errorf("unexpected windows %s", got)
+	}
+	if windows, err = ParseTimeWindows(""); err != nil || len(windows) != 0 {
+		t.Errorf("expect no windows, got %v, %v", windows, err)
+	}
+	for _, s := range []string{"01:00", "01:00-25:00", "a-b", "07:00-07:00", "01:00-07:00,8"} {
+		if _, err = ParseTimeWindows(s); err == nil {
+			t.Errorf("%q: expect an error", s)
+		}
+	}
+}
+
+func TestTimeWindow(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2025, 1, 2, h, m, 0, 0, time.Local) }
+	day := TimeWindow{Start: 60, End: 7 * 60}
+	night := TimeWindow{Start: 22 * 60, End: 90}
+	for _, c := range []struct {
+		w      TimeWindow
+		t      time.Time
+		expect bool
+	}{
+		{day, at(1, 0), true},
+		{day, at(6, 59), true},
+		{day, at(7, 0), false},
+		{day, at(0, 59), false},
+		{night, at(23, 0), true},
+		{night, at(0, 30), true},
+		{night, at(1, 30), false},
+		{night, at(12, 0), false},
+	} {
+		if got := c.w.Contains(c.t); got != c.expect {
+			t.Errorf("%s contains %s: expect %v", c.w, c.t.Format("15:04"), c.expect)
+		}
+	}
+
+	windows := []TimeWindow{day, night}
+	if d, ok := untilWindow(windows, at(12, 0)); !ok || d != 10*time.Hour {
+		t.Errorf("expect 10h until the night, got %v", d)
+	}
+	if d, ok := untilWindow(windows, at(23, 0)); !ok || d != 2*time.Hour {
+		t.Errorf("expect 2h until the day of tomorrow, got %v", d)
+	}
+	if _, ok := untilWindow(nil, at(12, 0)); ok {
+		t.Error("expect no window")
+	}
+}
+
+func newTask(ctx context.Context, priority int) *TaskExtension {
+	t := &TaskExtension{Priority: priority}
+	t.SetCtx(ctx)
+	return t
+}
+
+// acquire runs Acquire in the background, and waits until t is queued.
+func acquire(t *testing.T, s *Scheduler, task *TaskExtension, done chan<- *TaskExtension) {
+	t.Helper()
+	waiting := s.Info().Waiting
+	go func() {
+		release, err := s.Acquire(task)
+		if err != nil {
+			done <- nil
+			return
+		}
+		done <- task
+		release()
+	}()
+	deadline := time.Now().Add(time.Second)
+	for s.Info().Waiting == waiting {
+		if time.Now().After(deadline) {
+			t.Fatal("the task isn't queued")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulerPriority(t *testing.T) {
+	s := NewScheduler("test")
+	s.SetWorkers(1)
+	ctx := context.Background()
+	release, err := s.Acquire(newTask(ctx, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	low, high, later := newTask(ctx, 0), newTask(ctx, 5), newTask(ctx, 5)
+	done := make(chan *TaskExtension, 3)
+	for _, task := range []*TaskExtension{low, high, later} {
+		acquire(t, s, task, done)
+	}
+	if info := s.Info(); info.Running != 1 || info.Waiting != 3 {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	if status := low.GetQueueStatus(); status != "排队中" {
+		t.Errorf("unexpected status %s", status)
+	}
+	release()
+	// released one by one, the earlier one first among the same priority
+	for _, expect := range []*TaskExtension{high, later, low} {
+		if got := <-done; got != expect {
+			t.Fatalf("expect the task of priority %d, got %+v", expect.Priority, got)
+		}
+	}
+}
+
+func TestSchedulerPause(t *testing.T) {
+	s := NewScheduler("test")
+	s.SetWorkers(1)
+	s.Pause()
+	done := make(chan *TaskExtension, 2)
+	task := newTask(context.Background(), 0)
+	acquire(t, s, task, done)
+	if status := task.GetQueueStatus(); status != "队列已暂停" {
+		t.Errorf("unexpected status %s", status)
+	}
+
+	// a paused task is skipped
+	paused := newTask(context.Background(), 9)
+	paused.SetPaused(true)
+	acquire(t, s, paused, done)
+	s.Resume()
+	if got := <-done; got != task {
+		t.Fatalf("expect the task not paused, got %+v", got)
+	}
+	paused.SetPaused(false)
+	if got := <-done; got != paused {
+		t.Fatalf("expect the task resumed, got %+v", got)
+	}
+
+	// the task canceled while waiting leaves the queue
+	s.Pause()
+	ctx, cancel := context.WithCancel(context.Background())
+	acquire(t, s, newTask(ctx, 0), done)
+	cancel()
+	if got := <-done; got != nil {
+		t.Fatalf("expect the task canceled, got %+v", got)
+	}
+	if info := s.Info(); info.Waiting != 0 || info.Running != 0 {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
+
+func TestSchedulerWindows(t *testing.T) {
+	s := NewScheduler("test")
+	s.SetWorkers(1)
+	now := time.Now()
+	start := (now.Hour()*60 + now.Minute() + 120) % (24 * 60)
+	s.SetWindows([]TimeWindow{{Start: start, End: (start + 60) % (24 * 60)}})
+	done := make(chan *TaskExtension, 1)
+	task := newTask(context.Background(), 0)
+	acquire(t, s, task, done)
+	if info := s.Info(); info.InTime || info.Running != 0 {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if status := task.GetQueueStatus(); status != "等待执行时段" {
+		t.Errorf("unexpected status %s", status)
+	}
+	s.SetWindows(nil)
+	if got := <-done; got != task {
+		t.Fatalf("expect the task started, got %+v", got)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	task := newTask(context.Background(), 0)
+	task.SetSpeedLimit(1)
+	up, down := throttle{t: task}, throttle{t: task, download: true}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	// the burst of a second, the uploads and the downloads don't share it
+	if err := up.WaitN(ctx, 1024); err != nil {
+		t.Fatal(err)
+	}
+	if err := down.WaitN(ctx, 1024); err != nil {
+		t.Fatal(err)
+	}
+	if err := up.WaitN(ctx, 1024); err == nil {
+		t.Error("expect the upload limited")
+	}
+
+	task.SetSpeedLimit(0)
+	task.SetPaused(true)
+	resumed := make(chan error, 1)
+	go func() { resumed <- down.WaitN(context.Background(), 1<<20) }()
+	select {
+	case err := <-resumed:
+		t.Fatalf("expect blocked while paused, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	task.SetPaused(false)
+	if err := <-resumed; err != nil {
+		t.Fatal(err)
+	}
+}
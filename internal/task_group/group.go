@@ -8,6 +8,11 @@ import (
 )
 
 type OnCompletionFunc func(ctx context.Context, groupID string, payloads ...any)
+
+// GroupCallback is a payload called once all tasks of its group are done,
+// after onCompletion and whether or not any of them succeeded.
+type GroupCallback func(ctx context.Context, hasSuccess bool)
+
 type TaskGroupCoordinator struct {
 	name string
 	mu   sync.Mutex
@@ -69,12 +74,17 @@ func (tgc *TaskGroupCoordinator) Done(ctx context.Context, groupID string, succe
 		payloads := tgc.groupPayloads[groupID]
 		delete(tgc.groupStates, groupID)
 		delete(tgc.groupPayloads, groupID)
+		tgc.mu.Unlock()
 		if tgc.onCompletion != nil && state.hasSuccess {
 			logrus.Debugf("OnCompletion:%s", groupID)
-			tgc.mu.Unlock()
 			tgc.onCompletion(ctx, groupID, payloads...)
-			tgc.mu.Lock()
 		}
+		for _, payload := range payloads {
+			if f, ok := payload.(GroupCallback); ok {
+				f(ctx, state.hasSuccess)
+			}
+		}
+		tgc.mu.Lock()
 		return
 	}
 	state.pending--
@@ -0,0 +1,165 @@
+package task_history
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/OpenListTeam/tache"
+	log "github.com/sirupsen/logrus"
+)
+
+// the managers are scanned when a task finishes, and at the interval in
+// case a notification is dropped
+const scanInterval = 30 * time.Second
+
+var (
+	mu       sync.Mutex
+	scanners []func()
+	stop     chan struct{}
+	cleaner  *cron.Cron
+)
+
+// Watch records the tasks of the manager once they have succeeded, failed or
+// been canceled.
+// Only the tasks run by this process are recorded, the end time of the ones
+// recovered from the persisted data is unknown.
+func Watch[T task.TaskExtensionInfo](typ string, m task.Manager[T]) {
+	recorded := make(map[string]time.Time)
+	mu.Lock()
+	defer mu.Unlock()
+	scanners = append(scanners, func() {
+		finished := make(map[string]struct{})
+		for _, t := range m.GetByState(tache.StateSucceeded, tache.StateFailed, tache.StateCanceled) {
+			end := t.GetEndTime()
+			if end == nil {
+				continue
+			}
+			finished[t.GetID()] = struct{}{}
+			// a retried task is recorded again by its new end time
+			if last, ok := recorded[t.GetID()]; ok && last.Equal(*end) {
+				continue
+			}
+			recorded[t.GetID()] = *end
+			if err := db.CreateTaskHistory(newHistory(typ, t, *end)); err != nil {
+				log.Errorf("failed record task history: %+v", err)
+			}
+		}
+		for id := range recorded {
+			if _, ok := finished[id]; !ok {
+				delete(recorded, id)
+			}
+		}
+	})
+}
+
+func newHistory(typ string, t task.TaskExtensionInfo, end time.Time) *model.TaskHistory {
+	h := &model.TaskHistory{
+		Type:       typ,
+		TaskID:     t.GetID(),
+		Name:       t.GetName(),
+		TotalBytes: t.GetTotalBytes(),
+		StartTime:  end,
+		EndTime:    end,
+		Result:     model.TaskResultSucceeded,
+	}
+	if start := t.GetStartTime(); start != nil {
+		h.StartTime = *start
+		h.Duration = end.Sub(*start).Milliseconds()
+	}
+	if creator := t.GetCreator(); creator != nil {
+		h.CreatorId = creator.ID
+		h.Creator = creator.Username
+	}
+	if state := t.GetState(); state == tache.StateFailed || state == tache.StateCanceled {
+		h.Result = model.TaskResultFailed
+		if state == tache.StateCanceled || errors.Is(t.GetErr(), context.Canceled) {
+			h.Result = model.TaskResultCanceled
+		}
+		if err := t.GetErr(); err != nil {
+			h.Error = err.Error()
+		}
+	}
+	if p, ok := t.(task.PathsInfo); ok {
+		h.SrcPath, h.DstPath = p.GetSrcPath(), p.GetDstPath()
+		h.Storage = storageOf(h.DstPath)
+		if h.Storage == "" {
+			h.Storage = storageOf(h.SrcPath)
+		}
+	}
+	return h
+}
+
+// storageOf returns the mount path of the storage of the path.
+func storageOf(path string) string {
+	if path == "" {
+		return ""
+	}
+	storage, _, err := op.GetStorageAndActualPath(path)
+	if err != nil {
+		return ""
+	}
+	return storage.GetStorage().MountPath
+}
+
+func scan() {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, s := range scanners {
+		s()
+	}
+}
+
+// Start records the finished tasks of the watched managers and removes the
+// histories out of the retention hourly.
+func Start() {
+	stop = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(scanInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-task.Finished():
+				scan()
+			case <-ticker.C:
+				scan()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	cleaner = cron.NewCron(time.Hour)
+	cleaner.Do(clean)
+	go clean()
+}
+
+func Stop() {
+	if stop == nil {
+		return
+	}
+	close(stop)
+	cleaner.Stop()
+	// the tasks finished just before stopping
+	scan()
+}
+
+func clean() {
+	if days := setting.GetInt(conf.TaskHistoryRetentionDays, 30); days > 0 {
+		if err := db.DeleteTaskHistoriesBefore(time.Now().AddDate(0, 0, -days)); err != nil {
+			log.Errorf("failed clean task histories: %+v", err)
+		}
+	}
+	if max := setting.GetInt(conf.TaskHistoryMaxRecords, 100000); max > 0 {
+		if err := db.TrimTaskHistories(max); err != nil {
+			log.Errorf("failed trim task histories: %+v", err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package task_history
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/tache"
+)
+
+type testTask struct {
+	task.TaskExtension
+}
+
+func (t *testTask) GetName() string   { return "test" }
+func (t *testTask) GetStatus() string { return "" }
+func (t *testTask) Run() error        { return nil }
+
+func TestNewHistory(t *testing.T) {
+	cases := []struct {
+		state  tache.State
+		err    error
+		result string
+	}{
+		{tache.StateSucceeded, nil, model.TaskResultSucceeded},
+		{tache.StateFailed, errors.New("failed"), model.TaskResultFailed},
+		{tache.StateFailed, context.Canceled, model.TaskResultCanceled},
+		{tache.StateCanceled, context.Canceled, model.TaskResultCanceled},
+	}
+	for _, c := range cases {
+		tsk := &testTask{}
+		tsk.SetErr(c.err)
+		tsk.SetState(c.state)
+		h := newHistory("copy", tsk, time.Now())
+		if h.Result != c.result {
+			t.Errorf("state %v, err %v: expect %s, got %s", c.state, c.err, c.result, h.Result)
+		}
+		if c.err != nil && h.Error != c.err.Error() {
+			t.Errorf("state %v: expect the error %q, got %q", c.state, c.err, h.Error)
+		}
+	}
+}
+
+func TestCanceledEndTime(t *testing.T) {
+	tsk := &testTask{}
+	tsk.SetState(tache.StateCanceled)
+	if tsk.GetEndTime() == nil {
+		t.Fatal("the task canceled before running should have an end time")
+	}
+	end := time.Unix(1, 0)
+	tsk = &testTask{}
+	tsk.SetEndTime(end)
+	tsk.SetState(tache.StateCanceled)
+	if !tsk.GetEndTime().Equal(end) {
+		t.Errorf("the end time of the run should be kept, got %v", tsk.GetEndTime())
+	}
+}
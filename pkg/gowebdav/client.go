@@ -463,6 +463,16 @@ func (c *Client) Write(path string, data []byte, _ os.FileMode) (err error) {
 	return newPathError("Write", path, s)
 }
 
+// SetModTime sets the modified time by the properties known by the servers,
+// lastmodified of ownCloud and Win32LastModifiedTime of IIS and Windows
+func (c *Client) SetModTime(path string, modTime time.Time) error {
+	body := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8" ?>
+<d:propertyupdate xmlns:d="DAV:" xmlns:z="urn:schemas-microsoft-com:">
+<d:set><d:prop><d:lastmodified>%d</d:lastmodified><z:Win32LastModifiedTime>%s</z:Win32LastModifiedTime></d:prop></d:set>
+</d:propertyupdate>`, modTime.Unix(), modTime.UTC().Format(http.TimeFormat))
+	return c.proppatch(path, body)
+}
+
 // WriteStream writes a stream
 func (c *Client) WriteStream(path string, stream io.Reader, _ os.FileMode, callback func(r *http.Request)) (err error) {
 
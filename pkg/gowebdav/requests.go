@@ -141,6 +141,21 @@ func (c *Client) propfind(path string, self bool, body string, resp interface{},
 	return parseXML(rs.Body, resp, parse)
 }
 
+func (c *Client) proppatch(path string, body string) error {
+	rs, err := c.req("PROPPATCH", path, strings.NewReader(body), func(rq *http.Request) {
+		rq.Header.Add("Content-Type", "application/xml;charset=UTF-8")
+	})
+	if err != nil {
+		return err
+	}
+	defer rs.Body.Close()
+
+	if rs.StatusCode != 200 && rs.StatusCode != 207 {
+		return newPathError("PROPPATCH", path, rs.StatusCode)
+	}
+	return nil
+}
+
 func (c *Client) doCopyMove(
 	method string,
 	oldpath string,
@@ -128,6 +128,41 @@ func ParseContentRange(s string) (start, end int64, err error) {
 	return start, end, errors.Join(startErr, endErr)
 }
 
+// ParseUploadContentRange parses the Content-Range header of a partial upload,
+// "bytes start-end/size" or "bytes */size" which asks for the received range,
+// in that case start and end are -1. The size must be known.
+func ParseUploadContentRange(s string) (start, end, size int64, err error) {
+	const b = "bytes "
+	if !strings.HasPrefix(s, b) {
+		return 0, 0, 0, ErrInvalid
+	}
+	rng, sizeStr, ok := strings.Cut(s[len(b):], "/")
+	if !ok {
+		return 0, 0, 0, ErrInvalid
+	}
+	size, err = strconv.ParseInt(textproto.TrimString(sizeStr), 10, 64)
+	if err != nil || size < 0 {
+		return 0, 0, 0, ErrInvalid
+	}
+	rng = textproto.TrimString(rng)
+	if rng == "*" {
+		return -1, -1, size, nil
+	}
+	startStr, endStr, ok := strings.Cut(rng, "-")
+	if !ok {
+		return 0, 0, 0, ErrInvalid
+	}
+	start, err = strconv.ParseInt(textproto.TrimString(startStr), 10, 64)
+	if err != nil {
+		return 0, 0, 0, ErrInvalid
+	}
+	end, err = strconv.ParseInt(textproto.TrimString(endStr), 10, 64)
+	if err != nil || start < 0 || start > end || end >= size {
+		return 0, 0, 0, ErrInvalid
+	}
+	return start, end, size, nil
+}
+
 func (r Range) MimeHeader(contentType string, size int64) textproto.MIMEHeader {
 	return textproto.MIMEHeader{
 		"Content-Range": {r.ContentRange(size)},
@@ -0,0 +1,36 @@
+package http_range
+
+import "testing"
+
+func TestParseUploadContentRange(t *testing.T) {
+	for _, c := range []struct {
+		s                string
+		start, end, size int64
+	}{
+		{"bytes 0-99/200", 0, 99, 200},
+		{"bytes 100-199/200", 100, 199, 200},
+		{"bytes  5 - 5 / 6", 5, 5, 6},
+		{"bytes */200", -1, -1, 200},
+		{"bytes */0", -1, -1, 0},
+	} {
+		start, end, size, err := ParseUploadContentRange(c.s)
+		if err != nil || start != c.start || end != c.end || size != c.size {
+			t.Errorf("%q: expect %d-%d/%d, got %d-%d/%d, %v", c.s, c.start, c.end, c.size, start, end, size, err)
+		}
+	}
+	for _, s := range []string{
+		"",
+		"0-99/200",
+		"bytes 0-99",
+		"bytes 0-99/*",
+		"bytes 0-200/200",
+		"bytes 100-99/200",
+		"bytes -1-5/200",
+		"bytes 0/200",
+		"bytes 0-99/-1",
+	} {
+		if _, _, _, err := ParseUploadContentRange(s); err != ErrInvalid {
+			t.Errorf("%q: expect invalid, got %v", s, err)
+		}
+	}
+}
@@ -110,6 +110,11 @@ func (d *FtpMainDriver) ClientDisconnected(cc ftpserver.ClientContext) {
 		utils.Log.Errorf("failed to close client: %v", err)
 	}
 	delete(d.clients, cc.ID())
+	ftp.RemoveSession(ftpSessionID(cc))
+}
+
+func ftpSessionID(cc ftpserver.ClientContext) string {
+	return fmt.Sprintf("ftp-%d", cc.ID())
 }
 
 func (d *FtpMainDriver) AuthUser(cc ftpserver.ClientContext, user, pass string) (ftpserver.ClientDriver, error) {
@@ -146,9 +151,21 @@ func (d *FtpMainDriver) AuthUser(cc ftpserver.ClientContext, user, pass string)
 		return nil, errors.New("user is not allowed to access via FTP")
 	}
 	model.LoginCache.Del(ip)
+	if err = ftp.CheckPolicy(userObj, model.ProtocolFTP, ip); err != nil {
+		return nil, err
+	}
+	rootUser, err := ftp.ChrootUser(userObj)
+	if err != nil {
+		return nil, err
+	}
+	session, err := ftp.NewSession(ftpSessionID(cc), model.ProtocolFTP, userObj, ip, cc.Close)
+	if err != nil {
+		return nil, err
+	}
 
 	ctx := context.Background()
-	ctx = context.WithValue(ctx, conf.UserKey, userObj)
+	ctx = ftp.WithSession(ctx, session)
+	ctx = context.WithValue(ctx, conf.UserKey, rootUser)
 	if user == "anonymous" || user == "guest" {
 		ctx = context.WithValue(ctx, conf.MetaPassKey, pass)
 	} else {
@@ -32,6 +32,7 @@ func (a *AferoAdapter) Create(_ string) (afero.File, error) {
 }
 
 func (a *AferoAdapter) Mkdir(name string, _ os.FileMode) error {
+	touchSession(a.ctx)
 	return Mkdir(a.ctx, name)
 }
 
@@ -50,6 +51,7 @@ func (a *AferoAdapter) OpenFile(_ string, _ int, _ os.FileMode) (afero.File, err
 }
 
 func (a *AferoAdapter) Remove(name string) error {
+	touchSession(a.ctx)
 	return Remove(a.ctx, name)
 }
 
@@ -58,10 +60,12 @@ func (a *AferoAdapter) RemoveAll(path string) error {
 }
 
 func (a *AferoAdapter) Rename(oldName, newName string) error {
+	touchSession(a.ctx)
 	return Rename(a.ctx, oldName, newName)
 }
 
 func (a *AferoAdapter) Stat(name string) (os.FileInfo, error) {
+	touchSession(a.ctx)
 	return Stat(a.ctx, name)
 }
 
@@ -77,15 +81,18 @@ func (a *AferoAdapter) Chown(_ string, _, _ int) error {
 	return errs.NotSupport
 }
 
-func (a *AferoAdapter) Chtimes(_ string, _ time.Time, _ time.Time) error {
-	return errs.NotSupport
+func (a *AferoAdapter) Chtimes(name string, _ time.Time, mtime time.Time) error {
+	touchSession(a.ctx)
+	return Chtimes(a.ctx, name, mtime)
 }
 
 func (a *AferoAdapter) ReadDir(name string) ([]os.FileInfo, error) {
+	touchSession(a.ctx)
 	return List(a.ctx, name)
 }
 
 func (a *AferoAdapter) GetHandle(name string, flags int, offset int64) (ftpserver.FileTransfer, error) {
+	touchSession(a.ctx)
 	fileSize := a.nextFileSize
 	a.nextFileSize = 0
 	if (flags & os.O_SYNC) != 0 {
@@ -61,6 +61,7 @@ func OpenDownload(ctx context.Context, reqPath string, offset int64) (*FileDownl
 }
 
 func (f *FileDownloadProxy) Read(p []byte) (n int, err error) {
+	touchSession(f.ctx)
 	n, err = f.File.Read(p)
 	if err != nil {
 		return n, err
@@ -70,6 +71,7 @@ func (f *FileDownloadProxy) Read(p []byte) (n int, err error) {
 }
 
 func (f *FileDownloadProxy) ReadAt(p []byte, off int64) (n int, err error) {
+	touchSession(f.ctx)
 	n, err = f.File.ReadAt(p, off)
 	if err != nil {
 		return n, err
@@ -68,6 +68,7 @@ func (f *FileUploadProxy) Read(p []byte) (n int, err error) {
 }
 
 func (f *FileUploadProxy) Write(p []byte) (n int, err error) {
+	touchSession(f.ctx)
 	n, err = f.buffer.Write(p)
 	if err != nil {
 		return n, err
@@ -90,7 +91,8 @@ func (f *FileUploadProxy) Close() error {
 		return err
 	}
 	arr := make([]byte, 512)
-	if _, err := f.buffer.Read(arr); err != nil {
+	// an empty file reads io.EOF
+	if _, err := f.buffer.Read(arr); err != nil && err != io.EOF {
 		return err
 	}
 	contentType := http.DetectContentType(arr)
@@ -220,6 +222,7 @@ func (f *FileUploadWithLengthProxy) write(p []byte) (n int, err error) {
 }
 
 func (f *FileUploadWithLengthProxy) Write(p []byte) (n int, err error) {
+	touchSession(f.ctx)
 	n, err = f.write(p)
 	if err != nil {
 		return n, err
@@ -0,0 +1,223 @@
+package ftp
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// Session is a logged in ftp or sftp connection.
+type Session struct {
+	ID         string    `json:"id"`
+	Protocol   string    `json:"protocol"`
+	UserID     uint      `json:"user_id"`
+	Username   string    `json:"username"`
+	RemoteAddr string    `json:"remote_addr"`
+	LoginTime  time.Time `json:"login_time"`
+	LastActive time.Time `json:"last_active"`
+
+	lastActive     atomic.Int64
+	idleTimeout    time.Duration
+	sessionTimeout time.Duration
+	closer         func() error
+}
+
+type sessionKey struct{}
+
+var (
+	sessionsMu    sync.Mutex
+	sessions      = make(map[string]*Session)
+	watchdogStart sync.Once
+)
+
+var ErrTooManySessions = errors.New("too many concurrent sessions for this user")
+
+// CheckPolicy verifies that user may log in via protocol from addr.
+func CheckPolicy(user *model.User, protocol, addr string) error {
+	if !user.FTPPolicy.AllowProtocol(protocol) {
+		return errors.Errorf("user is not allowed to access via %s", protocol)
+	}
+	if !user.FTPPolicy.AllowAddr(addr) {
+		return errors.Errorf("user is not allowed to access from %s", addr)
+	}
+	if limit := user.FTPPolicy.MaxSessions; limit > 0 && countSessions(user.ID) >= limit {
+		return ErrTooManySessions
+	}
+	return nil
+}
+
+// ChrootUser returns a copy of user whose base path is moved into the chroot
+// path of its ftp policy, so that every path of the session resolves there.
+func ChrootUser(user *model.User) (*model.User, error) {
+	if user.FTPPolicy.ChrootPath == "" {
+		return user, nil
+	}
+	basePath, err := user.JoinPath(user.FTPPolicy.ChrootPath)
+	if err != nil {
+		return nil, err
+	}
+	u := *user
+	u.BasePath = basePath
+	return &u, nil
+}
+
+// NewSession registers a session of user; closer is called to kick it.
+func NewSession(id, protocol string, user *model.User, addr string, closer func() error) (*Session, error) {
+	watchdogStart.Do(func() {
+		go watchdog()
+	})
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if limit := user.FTPPolicy.MaxSessions; limit > 0 && countSessionsLocked(user.ID) >= limit {
+		return nil, ErrTooManySessions
+	}
+	now := time.Now()
+	s := &Session{
+		ID:             id,
+		Protocol:       protocol,
+		UserID:         user.ID,
+		Username:       user.Username,
+		RemoteAddr:     addr,
+		LoginTime:      now,
+		idleTimeout:    time.Duration(user.FTPPolicy.IdleTimeout) * time.Second,
+		sessionTimeout: time.Duration(user.FTPPolicy.SessionTimeout) * time.Second,
+		closer:         closer,
+	}
+	s.lastActive.Store(now.UnixNano())
+	sessions[id] = s
+	return s, nil
+}
+
+// RemoveSession unregisters a session once its connection has gone.
+func RemoveSession(id string) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	delete(sessions, id)
+}
+
+func GetSession(id string) (*Session, bool) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	s, ok := sessions[id]
+	return s, ok
+}
+
+// ListSessions returns a snapshot of the live sessions, oldest first.
+func ListSessions() []*Session {
+	sessionsMu.Lock()
+	res := make([]*Session, 0, len(sessions))
+	for _, s := range sessions {
+		c := &Session{
+			ID:         s.ID,
+			Protocol:   s.Protocol,
+			UserID:     s.UserID,
+			Username:   s.Username,
+			RemoteAddr: s.RemoteAddr,
+			LoginTime:  s.LoginTime,
+			LastActive: time.Unix(0, s.lastActive.Load()),
+		}
+		res = append(res, c)
+	}
+	sessionsMu.Unlock()
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].LoginTime.Before(res[j].LoginTime)
+	})
+	return res
+}
+
+// KickSession disconnects the session with the given id.
+func KickSession(id string) error {
+	s, ok := GetSession(id)
+	if !ok {
+		return errors.New("session not found")
+	}
+	return s.Kick()
+}
+
+// KickUserSessions disconnects all sessions of a user, e.g. after it
+// has been disabled or deleted.
+func KickUserSessions(userID uint) {
+	sessionsMu.Lock()
+	var list []*Session
+	for _, s := range sessions {
+		if s.UserID == userID {
+			list = append(list, s)
+		}
+	}
+	sessionsMu.Unlock()
+	for _, s := range list {
+		_ = s.Kick()
+	}
+}
+
+func (s *Session) Kick() error {
+	RemoveSession(s.ID)
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer()
+}
+
+// Touch marks the session as active.
+func (s *Session) Touch() {
+	s.lastActive.Store(time.Now().UnixNano())
+}
+
+// WithSession attaches s to ctx so that file operations keep it alive.
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+func touchSession(ctx context.Context) {
+	if s, ok := ctx.Value(sessionKey{}).(*Session); ok {
+		s.Touch()
+	}
+}
+
+func countSessions(userID uint) int {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	return countSessionsLocked(userID)
+}
+
+func countSessionsLocked(userID uint) int {
+	n := 0
+	for _, s := range sessions {
+		if s.UserID == userID {
+			n++
+		}
+	}
+	return n
+}
+
+// watchdog kicks sessions that exceed the idle or session timeout of their user
+func watchdog() {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		kickExpired(now)
+	}
+}
+
+func kickExpired(now time.Time) {
+	sessionsMu.Lock()
+	var expired []*Session
+	for _, s := range sessions {
+		idle := now.Sub(time.Unix(0, s.lastActive.Load()))
+		if (s.idleTimeout > 0 && idle > s.idleTimeout) ||
+			(s.sessionTimeout > 0 && now.Sub(s.LoginTime) > s.sessionTimeout) {
+			expired = append(expired, s)
+		}
+	}
+	sessionsMu.Unlock()
+	for _, s := range expired {
+		utils.Log.Infof("[%s] session %s of %s(%s) timed out", s.Protocol, s.ID, s.Username, s.RemoteAddr)
+		_ = s.Kick()
+	}
+}
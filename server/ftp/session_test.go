@@ -0,0 +1,122 @@
+package ftp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+func TestCheckPolicy(t *testing.T) {
+	user := &model.User{ID: 100, Username: "policy", BasePath: "/data", FTPPolicy: model.FTPPolicy{
+		Protocols:    "sftp",
+		AllowedCIDRs: "10.0.0.0/8, 192.168.1.2",
+		MaxSessions:  1,
+		ChrootPath:   "/home",
+	}}
+	if err := CheckPolicy(user, model.ProtocolFTP, "10.1.2.3:21"); err == nil {
+		t.Error("expect ftp refused")
+	}
+	if err := CheckPolicy(user, model.ProtocolSFTP, "192.168.1.3:22"); err == nil {
+		t.Error("expect the address refused")
+	}
+	if err := CheckPolicy(user, model.ProtocolSFTP, "10.1.2.3:22"); err != nil {
+		t.Errorf("expect allowed, got %v", err)
+	}
+	s, err := NewSession("test-policy", model.ProtocolSFTP, user, "10.1.2.3:22", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveSession(s.ID)
+	if err = CheckPolicy(user, model.ProtocolSFTP, "192.168.1.2"); err != ErrTooManySessions {
+		t.Errorf("expect too many sessions, got %v", err)
+	}
+	if _, err = NewSession("test-policy-2", model.ProtocolSFTP, user, "10.1.2.3:22", nil); err != ErrTooManySessions {
+		t.Errorf("expect too many sessions, got %v", err)
+	}
+
+	root, err := ChrootUser(user)
+	if err != nil || root.BasePath != "/data/home" || user.BasePath != "/data" {
+		t.Errorf("unexpected chroot %v, %v", root, err)
+	}
+	user.FTPPolicy.ChrootPath = "../etc"
+	if _, err = ChrootUser(user); err == nil {
+		t.Error("expect the chroot out of the base path refused")
+	}
+}
+
+func TestSessionTimeout(t *testing.T) {
+	idle := &model.User{ID: 101, Username: "idle", FTPPolicy: model.FTPPolicy{IdleTimeout: 60}}
+	long := &model.User{ID: 102, Username: "long", FTPPolicy: model.FTPPolicy{SessionTimeout: 3600}}
+	kicked := make(map[string]bool)
+	closer := func(id string) func() error {
+		return func() error {
+			kicked[id] = true
+			return nil
+		}
+	}
+	idleSession, err := NewSession("test-idle", model.ProtocolFTP, idle, "127.0.0.1", closer("test-idle"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	longSession, err := NewSession("test-long", model.ProtocolSFTP, long, "127.0.0.1", closer("test-long"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer KickUserSessions(idle.ID)
+	defer KickUserSessions(long.ID)
+
+	// the operations keep the idle session alive
+	later := time.Now().Add(2 * time.Minute)
+	idleSession.lastActive.Store(later.Add(-30 * time.Second).UnixNano())
+	touchSession(WithSession(context.Background(), longSession))
+	kickExpired(later)
+	if len(kicked) != 0 {
+		t.Fatalf("unexpected kicked %v", kicked)
+	}
+	kickExpired(later.Add(time.Minute))
+	if !kicked["test-idle"] || kicked["test-long"] {
+		t.Errorf("expect the idle session kicked, got %v", kicked)
+	}
+	if _, ok := GetSession("test-idle"); ok {
+		t.Error("the session kicked should be removed")
+	}
+	kickExpired(time.Now().Add(2 * time.Hour))
+	if !kicked["test-long"] {
+		t.Error("expect the session timed out")
+	}
+}
+
+func TestKickSessions(t *testing.T) {
+	user := &model.User{ID: 103, Username: "kick"}
+	closed := 0
+	for _, id := range []string{"test-kick-1", "test-kick-2"} {
+		if _, err := NewSession(id, model.ProtocolFTP, user, "127.0.0.1", func() error {
+			closed++
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	var listed []string
+	for _, s := range ListSessions() {
+		if s.UserID == user.ID {
+			listed = append(listed, s.ID)
+		}
+	}
+	if len(listed) != 2 || listed[0] != "test-kick-1" {
+		t.Errorf("expect the sessions listed by login, got %v", listed)
+	}
+	if err := KickSession("test-kick-1"); err != nil || closed != 1 {
+		t.Errorf("expect a session kicked, got %d, %v", closed, err)
+	}
+	if err := KickSession("test-kick-1"); err == nil {
+		t.Error("expect the session gone")
+	}
+	KickUserSessions(user.ID)
+	if closed != 2 || countSessions(user.ID) != 0 {
+		t.Errorf("expect all the sessions kicked, got %d", closed)
+	}
+}
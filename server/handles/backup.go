@@ -0,0 +1,45 @@
+package handles
+
+import (
+	"fmt"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/backup"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+func ExportBackup(c *gin.Context) {
+	var req backup.ExportOptions
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	archive, err := backup.Export(req)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	// the archive is downloaded as it is, so that it can be imported again
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="openlist-backup-%s.json"`, archive.Created.Format("20060102-150405")))
+	c.JSON(200, archive)
+}
+
+type ImportBackupReq struct {
+	backup.ImportOptions
+	Archive *backup.Archive `json:"archive" binding:"required"`
+}
+
+func ImportBackup(c *gin.Context) {
+	var req ImportBackupReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Mount = true
+	report, err := backup.Import(c.Request.Context(), req.Archive, req.ImportOptions)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, report)
+}
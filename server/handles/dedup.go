@@ -0,0 +1,74 @@
+package handles
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/dedup"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type StartDedupReq struct {
+	Paths []string `json:"paths"`
+}
+
+func StartDedup(c *gin.Context) {
+	var req StartDedupReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := dedup.Start(req.Paths); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func StopDedup(c *gin.Context) {
+	if !dedup.Running() {
+		common.ErrorStrResp(c, "重复文件查找未在运行", 400)
+		return
+	}
+	dedup.Stop()
+	common.SuccessResp(c)
+}
+
+func GetDedupProgress(c *gin.Context) {
+	common.SuccessResp(c, dedup.GetProgress())
+}
+
+func ListDedupGroups(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	groups := dedup.GetGroups()
+	total := len(groups)
+	start, end := total, total
+	if req.Page-1 <= total/req.PerPage {
+		start = min((req.Page-1)*req.PerPage, total)
+	}
+	if req.PerPage < end-start {
+		end = start + req.PerPage
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: groups[start:end],
+		Total:   int64(total),
+	})
+}
+
+func DedupAction(c *gin.Context) {
+	var req dedup.ActionReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	res, err := dedup.Act(c.Request.Context(), req)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, res)
+}
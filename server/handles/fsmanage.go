@@ -1,6 +1,7 @@
 package handles
 
 import (
+	"context"
 	"fmt"
 	stdpath "path"
 	"strings"
@@ -63,6 +64,8 @@ type MoveCopyReq struct {
 	Overwrite    bool     `json:"overwrite"`
 	SkipExisting bool     `json:"skip_existing"`
 	Merge        bool     `json:"merge"`
+	// the copy skips the files whose content exists in the dst dir
+	SkipSameHash bool `json:"skip_same_hash"`
 }
 
 func FsMove(c *gin.Context) {
@@ -188,15 +191,19 @@ func FsCopy(c *gin.Context) {
 		validPaths = append(validPaths, srcPath)
 	}
 
+	ctx := c.Request.Context()
+	if req.SkipSameHash {
+		ctx = context.WithValue(ctx, conf.SkipSameHashKey, struct{}{})
+	}
 	// Create all tasks immediately without any synchronous validation
 	// All validation will be done asynchronously in the background
 	var addedTasks []task.TaskExtensionInfo
 	for i, p := range validPaths {
 		var t task.TaskExtensionInfo
 		if req.Merge {
-			t, err = fs.Merge(c.Request.Context(), p, dstDir, len(validPaths) > i+1)
+			t, err = fs.Merge(ctx, p, dstDir, len(validPaths) > i+1)
 		} else {
-			t, err = fs.Copy(c.Request.Context(), p, dstDir, len(validPaths) > i+1)
+			t, err = fs.Copy(ctx, p, dstDir, len(validPaths) > i+1)
 		}
 		if t != nil {
 			addedTasks = append(addedTasks, t)
@@ -0,0 +1,261 @@
+package handles
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	stdpath "path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// tus 1.0 resumable upload, see https://tus.io/protocols/resumable-upload
+// The upload is staged by fs.UploadStage and put as a task when complete.
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,expiration,termination"
+)
+
+// tusResumable checks the protocol version of the request.
+func tusResumable(c *gin.Context) bool {
+	c.Header("Tus-Resumable", tusVersion)
+	if c.GetHeader("Tus-Resumable") != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		c.String(http.StatusPreconditionFailed, "unsupported tus version")
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// parseTusMetadata parses Upload-Metadata, comma separated "key base64(value)" pairs.
+func parseTusMetadata(s string) (map[string]string, error) {
+	meta := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, " ")
+		v, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		meta[key] = string(v)
+	}
+	return meta, nil
+}
+
+func setTusExpires(c *gin.Context, st *fs.UploadStage) {
+	c.Header("Upload-Expires", st.GetExpires().UTC().Format(http.TimeFormat))
+}
+
+// FsTusOptions answers the discovery request of tus clients.
+func FsTusOptions(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	if limit := fs.UploadStageMaxSize(); limit > 0 {
+		c.Header("Tus-Max-Size", strconv.FormatInt(limit, 10))
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// FsTusPath sets the File-Path header from the upload metadata for clients
+// which can't send custom headers, so that middlewares.FsUp checks it. The
+// metadata may hold the full "filepath", or "filename" and optionally "dir".
+func FsTusPath(c *gin.Context) {
+	if !tusResumable(c) {
+		return
+	}
+	if c.GetHeader("File-Path") != "" {
+		return
+	}
+	meta, err := parseTusMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	path := meta["filepath"]
+	if path == "" && meta["filename"] != "" {
+		path = stdpath.Join("/", meta["dir"], meta["filename"])
+	}
+	if path == "" {
+		c.String(http.StatusBadRequest, "missing File-Path")
+		c.Abort()
+		return
+	}
+	c.Request.Header.Set("File-Path", url.PathEscape(path))
+}
+
+// FsTusCreate creates an upload (creation extension).
+func FsTusCreate(c *gin.Context) {
+	if c.GetHeader("Upload-Defer-Length") != "" {
+		c.String(http.StatusBadRequest, "Upload-Defer-Length is not supported")
+		return
+	}
+	size, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		c.String(http.StatusBadRequest, "invalid Upload-Length")
+		return
+	}
+	if limit := fs.UploadStageMaxSize(); limit > 0 && size > limit {
+		c.Status(http.StatusRequestEntityTooLarge)
+		return
+	}
+	meta, err := parseTusMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid Upload-Metadata")
+		return
+	}
+	path, err := url.PathUnescape(c.GetHeader("File-Path"))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx := c.Request.Context()
+	user := ctx.Value(conf.UserKey).(*model.User)
+	path, err = user.JoinPath(path)
+	if err != nil {
+		c.String(http.StatusForbidden, err.Error())
+		return
+	}
+	if shouldIgnoreSystemFile(stdpath.Base(path)) {
+		c.String(http.StatusForbidden, errs.IgnoredSystemFile.Error())
+		return
+	}
+	if c.GetHeader("Overwrite") == "false" {
+		if res, _ := fs.Get(ctx, path, &fs.GetArgs{NoLog: true}); res != nil {
+			c.String(http.StatusConflict, "文件已存在")
+			return
+		}
+	}
+	modified := time.Now()
+	if ms, err := strconv.ParseInt(meta["lastModified"], 10, 64); err == nil {
+		modified = time.UnixMilli(ms)
+	}
+	st, err := fs.NewUploadStage(user, path, size, meta["filetype"], modified)
+	if err != nil {
+		if errors.Is(err, fs.ErrUploadStageFull) {
+			c.String(http.StatusInsufficientStorage, err.Error())
+			return
+		}
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err = st.SetMetadata(meta); err != nil {
+		st.Remove()
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	if size == 0 {
+		if _, err = st.Commit(ctx, true); err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+	} else {
+		setTusExpires(c, st)
+	}
+	c.Header("Location", common.GetApiUrl(ctx)+"/api/fs/tus/"+st.ID)
+	c.Status(http.StatusCreated)
+}
+
+// getTusStage returns the upload of the url, only its creator may access it.
+func getTusStage(c *gin.Context) (*fs.UploadStage, bool) {
+	if !tusResumable(c) {
+		return nil, false
+	}
+	st, err := fs.GetUploadStage(c.Param("id"))
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if err != nil || st.Creator != user.ID {
+		c.Status(http.StatusNotFound)
+		return nil, false
+	}
+	return st, true
+}
+
+// FsTusHead returns the offset to resume the upload from.
+func FsTusHead(c *gin.Context) {
+	st, ok := getTusStage(c)
+	if !ok {
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(st.GetOffset(), 10))
+	c.Header("Upload-Length", strconv.FormatInt(st.Size, 10))
+	setTusExpires(c, st)
+	c.Status(http.StatusOK)
+}
+
+// FsTusPatch appends a chunk at Upload-Offset, the file is put as a task after the last one.
+func FsTusPatch(c *gin.Context) {
+	defer func() {
+		_ = c.Request.Body.Close()
+	}()
+	st, ok := getTusStage(c)
+	if !ok {
+		return
+	}
+	if c.ContentType() != "application/offset+octet-stream" {
+		c.Status(http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.String(http.StatusBadRequest, "invalid Upload-Offset")
+		return
+	}
+	if offset != st.GetOffset() {
+		c.Status(http.StatusConflict)
+		return
+	}
+	_, err = st.Write(offset, c.Request.Body)
+	c.Header("Upload-Offset", strconv.FormatInt(st.GetOffset(), 10))
+	if err != nil {
+		switch {
+		case errors.Is(err, fs.ErrUploadStageBusy):
+			c.Status(http.StatusLocked)
+		case errors.Is(err, fs.ErrUploadOffsetMismatch):
+			c.Status(http.StatusConflict)
+		case errors.Is(err, fs.ErrUploadStageOverflow):
+			c.String(http.StatusBadRequest, err.Error())
+		case errors.Is(err, fs.ErrUploadStageNotFound):
+			c.Status(http.StatusNotFound)
+		default:
+			// most likely the client has gone, it resumes from Upload-Offset
+			log.Warnf("tus upload %s interrupted at %d: %v", st.ID, st.GetOffset(), err)
+			c.String(http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	if st.Complete() {
+		if _, err = st.Commit(c.Request.Context(), true); err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+	} else {
+		setTusExpires(c, st)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// FsTusDelete terminates an upload (termination extension).
+func FsTusDelete(c *gin.Context) {
+	st, ok := getTusStage(c)
+	if !ok {
+		return
+	}
+	st.Remove()
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,205 @@
+package handles
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/tache"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	gin.SetMode(gin.TestMode)
+}
+
+// tusServer serves the tus routes to the user returned by user, with a local
+// storage mounted at /tus whose root is returned.
+func tusServer(t *testing.T, user func() *model.User) (*httptest.Server, string) {
+	oldTempDir := conf.Conf.TempDir
+	conf.Conf.TempDir = t.TempDir()
+	t.Cleanup(func() { conf.Conf.TempDir = oldTempDir })
+	root := t.TempDir()
+	ctx := context.Background()
+	id, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/tus",
+		Addition:  `{"root_folder_path":"` + root + `"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = op.DeleteStorageById(ctx, id) })
+	if fs.UploadTaskManager.Manager == nil {
+		fs.UploadTaskManager.Manager = tache.NewManager[*fs.UploadTask](tache.WithWorks(1))
+		fs.UploadScheduler.SetWorkers(1)
+	}
+
+	r := gin.New()
+	tus := r.Group("/api/fs/tus", func(c *gin.Context) {
+		ctx := context.WithValue(c.Request.Context(), conf.UserKey, user())
+		ctx = context.WithValue(ctx, conf.MetaPassKey, "")
+		c.Request = c.Request.WithContext(ctx)
+	})
+	tus.OPTIONS("", FsTusOptions)
+	tus.POST("", FsTusPath, FsTusCreate)
+	tus.HEAD("/:id", FsTusHead)
+	tus.PATCH("/:id", FsTusPatch)
+	tus.DELETE("/:id", FsTusDelete)
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	return ts, root
+}
+
+func tusRequest(t *testing.T, method, url string, header map[string]string, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Tus-Resumable", tusVersion)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	_ = res.Body.Close()
+	return res
+}
+
+func tusMetadata(kv ...string) string {
+	var pairs []string
+	for i := 0; i < len(kv); i += 2 {
+		pairs = append(pairs, kv[i]+" "+base64.StdEncoding.EncodeToString([]byte(kv[i+1])))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func TestParseTusMetadata(t *testing.T) {
+	meta, err := parseTusMetadata(tusMetadata("filename", "a b.txt", "dir", "/x") + ", empty")
+	if err != nil || meta["filename"] != "a b.txt" || meta["dir"] != "/x" || meta["empty"] != "" {
+		t.Errorf("unexpected metadata %v, %v", meta, err)
+	}
+	if _, err = parseTusMetadata("filename !!"); err == nil {
+		t.Error("expect an error")
+	}
+}
+
+func TestFsTus(t *testing.T) {
+	admin := &model.User{ID: 1, Username: "admin", BasePath: "/", Role: model.ADMIN, Permission: 0xffff}
+	guest := &model.User{ID: 2, Username: "guest", BasePath: "/", Permission: 0xffff}
+	user := admin
+	ts, root := tusServer(t, func() *model.User { return user })
+	api := ts.URL + "/api/fs/tus"
+
+	res := tusRequest(t, http.MethodOptions, api, nil, "")
+	if res.StatusCode != http.StatusNoContent || res.Header.Get("Tus-Version") != tusVersion {
+		t.Errorf("unexpected discovery %d %v", res.StatusCode, res.Header)
+	}
+	res = tusRequest(t, http.MethodPost, api, map[string]string{"Tus-Resumable": "0.2.2", "Upload-Length": "11"}, "")
+	if res.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("expect the version refused, got %d", res.StatusCode)
+	}
+	res = tusRequest(t, http.MethodPost, api, map[string]string{"Upload-Length": "11"}, "")
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expect the path required, got %d", res.StatusCode)
+	}
+
+	res = tusRequest(t, http.MethodPost, api, map[string]string{
+		"Upload-Length":   "11",
+		"Upload-Metadata": tusMetadata("filename", "a.txt", "dir", "/tus"),
+	}, "")
+	location := res.Header.Get("Location")
+	if res.StatusCode != http.StatusCreated || !strings.HasPrefix(location, "/api/fs/tus/") || res.Header.Get("Upload-Expires") == "" {
+		t.Fatalf("unexpected creation %d %v", res.StatusCode, res.Header)
+	}
+	upload := ts.URL + location
+	patch := func(offset, body string) *http.Response {
+		return tusRequest(t, http.MethodPatch, upload, map[string]string{
+			"Content-Type":  "application/offset+octet-stream",
+			"Upload-Offset": offset,
+		}, body)
+	}
+
+	if res = tusRequest(t, http.MethodPatch, upload, map[string]string{"Upload-Offset": "0"}, "hello "); res.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("expect the content type refused, got %d", res.StatusCode)
+	}
+	if res = patch("3", "hello "); res.StatusCode != http.StatusConflict {
+		t.Errorf("expect the offset refused, got %d", res.StatusCode)
+	}
+	if res = patch("0", "hello "); res.StatusCode != http.StatusNoContent || res.Header.Get("Upload-Offset") != "6" {
+		t.Fatalf("unexpected patch %d %v", res.StatusCode, res.Header)
+	}
+	res = tusRequest(t, http.MethodHead, upload, nil, "")
+	if res.StatusCode != http.StatusOK || res.Header.Get("Upload-Offset") != "6" || res.Header.Get("Upload-Length") != "11" {
+		t.Errorf("unexpected head %d %v", res.StatusCode, res.Header)
+	}
+	// only the creator sees the upload
+	user = guest
+	if res = tusRequest(t, http.MethodHead, upload, nil, ""); res.StatusCode != http.StatusNotFound {
+		t.Errorf("expect the upload hidden from others, got %d", res.StatusCode)
+	}
+	user = admin
+
+	if res = patch("6", "world"); res.StatusCode != http.StatusNoContent {
+		t.Fatalf("unexpected patch %d", res.StatusCode)
+	}
+	// put by an upload task
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, err := os.ReadFile(filepath.Join(root, "a.txt"))
+		if err == nil && string(data) == "hello world" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the file isn't uploaded: %q, %v", data, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if res = tusRequest(t, http.MethodHead, upload, nil, ""); res.StatusCode != http.StatusNotFound {
+		t.Errorf("expect the upload finished, got %d", res.StatusCode)
+	}
+
+	// terminated
+	res = tusRequest(t, http.MethodPost, api, map[string]string{
+		"Upload-Length":   "5",
+		"Upload-Metadata": tusMetadata("filepath", "/tus/b.txt"),
+	}, "")
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("unexpected creation %d", res.StatusCode)
+	}
+	upload = ts.URL + res.Header.Get("Location")
+	if res = tusRequest(t, http.MethodDelete, upload, nil, ""); res.StatusCode != http.StatusNoContent {
+		t.Errorf("unexpected termination %d", res.StatusCode)
+	}
+	if res = tusRequest(t, http.MethodHead, upload, nil, ""); res.StatusCode != http.StatusNotFound {
+		t.Errorf("expect the upload terminated, got %d", res.StatusCode)
+	}
+	if _, err := os.Stat(filepath.Join(root, "b.txt")); !os.IsNotExist(err) {
+		t.Errorf("nothing should be uploaded: %v", err)
+	}
+}
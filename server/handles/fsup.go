@@ -1,6 +1,7 @@
 package handles
 
 import (
+	"errors"
 	"io"
 	"net/url"
 	stdpath "path"
@@ -14,6 +15,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
 	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/gin-gonic/gin"
@@ -71,6 +73,10 @@ func FsStream(c *gin.Context) {
 		common.ErrorStrResp(c, errs.IgnoredSystemFile.Error(), 403)
 		return
 	}
+	if contentRange := c.GetHeader("Content-Range"); contentRange != "" {
+		fsStreamRange(c, path, contentRange, asTask)
+		return
+	}
 	// 如果请求头 Content-Length 和 X-File-Size 都没有，则 size=-1，表示未知大小的流式上传
 	size := c.Request.ContentLength
 	if size < 0 {
@@ -127,6 +133,62 @@ func FsStream(c *gin.Context) {
 	})
 }
 
+// fsStreamRange receives one chunk of a resumable upload sent with a
+// Content-Range header, the file is put once its last chunk has arrived.
+// The response tells the offset to continue from.
+func fsStreamRange(c *gin.Context, path, contentRange string, asTask bool) {
+	start, end, size, err := http_range.ParseUploadContentRange(contentRange)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	ctx := c.Request.Context()
+	user := ctx.Value(conf.UserKey).(*model.User)
+	if start < 0 {
+		var offset int64
+		if st, ok := fs.FindUploadStage(user.ID, path, size); ok {
+			offset = st.GetOffset()
+		}
+		common.SuccessResp(c, gin.H{"offset": offset, "complete": false})
+		return
+	}
+	st, err := fs.PutRange(ctx, path, c.Request.Body, start, end, size, c.GetHeader("Content-Type"), getLastModified(c))
+	if err != nil {
+		var offset int64
+		if st != nil {
+			offset = st.GetOffset()
+			if errors.Is(err, fs.ErrUploadStageOverflow) {
+				st.Remove()
+				offset = 0
+			}
+		}
+		code := 500
+		if errors.Is(err, fs.ErrUploadOffsetMismatch) {
+			code = 416
+		} else if errors.Is(err, fs.ErrUploadStageBusy) {
+			code = 409
+		} else if errors.Is(err, fs.ErrUploadStageFull) {
+			code = 507
+		}
+		common.ErrorWithDataResp(c, err, code, gin.H{"offset": offset})
+		return
+	}
+	if !st.Complete() {
+		common.SuccessResp(c, gin.H{"offset": st.GetOffset(), "complete": false})
+		return
+	}
+	t, err := st.Commit(ctx, asTask)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	res := gin.H{"offset": size, "complete": true}
+	if t != nil {
+		res["task"] = getTaskInfo(t)
+	}
+	common.SuccessResp(c, res)
+}
+
 func FsForm(c *gin.Context) {
 	defer func() {
 		if n, _ := io.ReadFull(c.Request.Body, []byte{0}); n == 1 {
@@ -0,0 +1,39 @@
+package handles
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/OpenList/v4/server/ftp"
+	"github.com/gin-gonic/gin"
+)
+
+// ListFTPSessions lists the live ftp and sftp sessions
+func ListFTPSessions(c *gin.Context) {
+	common.SuccessResp(c, ftp.ListSessions())
+}
+
+type KickFTPSessionReq struct {
+	ID     string `json:"id"`
+	UserID uint   `json:"user_id"`
+}
+
+// KickFTPSession disconnects a session, or all sessions of a user
+func KickFTPSession(c *gin.Context) {
+	var req KickFTPSessionReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if req.ID == "" && req.UserID == 0 {
+		common.ErrorStrResp(c, "请指定会话或用户", 400)
+		return
+	}
+	if req.ID != "" {
+		if err := ftp.KickSession(req.ID); err != nil {
+			common.ErrorResp(c, err, 404)
+			return
+		}
+	} else {
+		ftp.KickUserSessions(req.UserID)
+	}
+	common.SuccessResp(c)
+}
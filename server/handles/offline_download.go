@@ -1,6 +1,8 @@
 package handles
 
 import (
+	"maps"
+	"strconv"
 	"strings"
 
 	_115 "github.com/OpenListTeam/OpenList/v4/drivers/115"
@@ -114,6 +116,43 @@ func SetTransmission(c *gin.Context) {
 	common.SuccessResp(c, "ok")
 }
 
+type SetTorrentReq struct {
+	ListenPort string `json:"listen_port" form:"listen_port"`
+	DHT        bool   `json:"dht" form:"dht"`
+	SeedRatio  string `json:"seed_ratio" form:"seed_ratio"`
+	Seedtime   string `json:"seedtime" form:"seedtime"`
+	FileFilter string `json:"file_filter" form:"file_filter"`
+}
+
+func SetTorrent(c *gin.Context) {
+	var req SetTorrentReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	items := []model.SettingItem{
+		{Key: conf.TorrentListenPort, Value: req.ListenPort, Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+		{Key: conf.TorrentDHT, Value: strconv.FormatBool(req.DHT), Type: conf.TypeBool, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+		{Key: conf.TorrentSeedRatio, Value: req.SeedRatio, Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+		{Key: conf.TorrentSeedtime, Value: req.Seedtime, Type: conf.TypeNumber, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+		{Key: conf.TorrentFileFilter, Value: req.FileFilter, Type: conf.TypeString, Group: model.OFFLINE_DOWNLOAD, Flag: model.PRIVATE},
+	}
+	if err := op.SaveSettingItems(items); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	_tool, err := tool.Tools.Get("BitTorrent")
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	if _, err := _tool.Init(); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, "ok")
+}
+
 type Set115Req struct {
 	TempDir string `json:"temp_dir" form:"temp_dir"`
 }
@@ -479,6 +518,13 @@ type AddOfflineDownloadReq struct {
 	Path         string   `json:"path"`
 	Tool         string   `json:"tool"`
 	DeletePolicy string   `json:"delete_policy"`
+	// matched by the rules, the dst is chosen by the rules if path is empty
+	Category string `json:"category"`
+	// the following are for SimpleHttp only
+	Headers  map[string]string `json:"headers"`
+	Cookie   string            `json:"cookie"`
+	Referer  string            `json:"referer"`
+	Checksum string            `json:"checksum"`
 }
 
 func AddOfflineDownload(c *gin.Context) {
@@ -493,10 +539,27 @@ func AddOfflineDownload(c *gin.Context) {
 		common.ErrorResp(c, err, 400)
 		return
 	}
-	reqPath, err := user.JoinPath(req.Path)
-	if err != nil {
-		common.ErrorResp(c, err, 403)
-		return
+	var reqPath string
+	if req.Path != "" {
+		var err error
+		reqPath, err = user.JoinPath(req.Path)
+		if err != nil {
+			common.ErrorResp(c, err, 403)
+			return
+		}
+	}
+	headers := req.Headers
+	if req.Cookie != "" || req.Referer != "" {
+		headers = maps.Clone(headers)
+		if headers == nil {
+			headers = map[string]string{}
+		}
+		if req.Cookie != "" {
+			headers["Cookie"] = req.Cookie
+		}
+		if req.Referer != "" {
+			headers["Referer"] = req.Referer
+		}
 	}
 	var tasks []task.TaskExtensionInfo
 	for _, url := range req.Urls {
@@ -511,6 +574,9 @@ func AddOfflineDownload(c *gin.Context) {
 			DstDirPath:   reqPath,
 			Tool:         req.Tool,
 			DeletePolicy: tool.DeletePolicy(req.DeletePolicy),
+			Headers:      headers,
+			Checksum:     req.Checksum,
+			Category:     req.Category,
 		})
 		if err != nil {
 			common.ErrorResp(c, err, 500)
@@ -0,0 +1,98 @@
+package handles
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+func ListOfflineDownloadRules(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	rules, total, err := db.GetOfflineDownloadRules(req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: rules,
+		Total:   total,
+	})
+}
+
+func validOfflineDownloadRule(c *gin.Context, rule *model.OfflineDownloadRule) bool {
+	if rule.JunkPattern != "" {
+		if _, err := regexp.Compile(rule.JunkPattern); err != nil {
+			common.ErrorStrResp(c, "垃圾文件规则无效: "+err.Error(), 400)
+			return false
+		}
+	}
+	if rule.MinSize < 0 || rule.MaxSize < 0 || (rule.MaxSize > 0 && rule.MinSize > rule.MaxSize) {
+		common.ErrorStrResp(c, "文件大小范围无效", 400)
+		return false
+	}
+	if rule.DstPath != "" {
+		rule.DstPath = utils.FixAndCleanPath(rule.DstPath)
+	}
+	return true
+}
+
+func CreateOfflineDownloadRule(c *gin.Context) {
+	var req model.OfflineDownloadRule
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if !validOfflineDownloadRule(c, &req) {
+		return
+	}
+	req.ID = 0
+	if err := db.CreateOfflineDownloadRule(&req); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, req)
+}
+
+func UpdateOfflineDownloadRule(c *gin.Context) {
+	var req model.OfflineDownloadRule
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if !validOfflineDownloadRule(c, &req) {
+		return
+	}
+	if _, err := db.GetOfflineDownloadRuleById(req.ID); err != nil {
+		common.ErrorStrResp(c, "未找到规则", 404)
+		return
+	}
+	if err := db.UpdateOfflineDownloadRule(&req); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, req)
+}
+
+func DeleteOfflineDownloadRule(c *gin.Context) {
+	idStr := c.Query("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := db.DeleteOfflineDownloadRuleById(uint(id)); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
@@ -0,0 +1,102 @@
+package handles
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/pipeline"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type AddPipelineReq struct {
+	Title string           `json:"title"`
+	Paths []string         `json:"paths"`
+	Steps []*pipeline.Step `json:"steps"`
+}
+
+type PipelineInfo struct {
+	TaskInfo
+	Title   string           `json:"title"`
+	Paths   []string         `json:"paths"`
+	Current int              `json:"current"`
+	Steps   []*pipeline.Step `json:"steps"`
+}
+
+func canRunStep(user *model.User, s *pipeline.Step) bool {
+	switch s.Type {
+	case pipeline.OfflineDownload:
+		return user.CanAddOfflineDownloadTasks()
+	case pipeline.Decompress:
+		return user.CanDecompress()
+	case pipeline.Copy:
+		return user.CanCopy()
+	case pipeline.Move:
+		return user.CanMove()
+	case pipeline.Rename:
+		return user.CanRename()
+	case pipeline.DeleteSource:
+		return user.CanRemove()
+	case pipeline.Webhook:
+		// the server requests the url, so only admins may set it
+		return user.IsAdmin()
+	}
+	return true
+}
+
+func AddPipeline(c *gin.Context) {
+	var req AddPipelineReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	var err error
+	for i, p := range req.Paths {
+		if req.Paths[i], err = user.JoinPath(p); err != nil {
+			common.ErrorResp(c, err, 403)
+			return
+		}
+	}
+	for _, s := range req.Steps {
+		if s == nil {
+			common.ErrorStrResp(c, "步骤无效", 400)
+			return
+		}
+		if !canRunStep(user, s) {
+			common.ErrorResp(c, errs.PermissionDenied, 403)
+			return
+		}
+		if s.Path != "" {
+			if s.Path, err = user.JoinPath(s.Path); err != nil {
+				common.ErrorResp(c, err, 403)
+				return
+			}
+		}
+	}
+	t, err := pipeline.Add(c.Request.Context(), req.Title, req.Paths, req.Steps)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"task": getPipelineInfo(t),
+	})
+}
+
+func getPipelineInfo(t *pipeline.PipelineTask) PipelineInfo {
+	return PipelineInfo{
+		TaskInfo: getTaskInfo(t),
+		Title:    t.Title,
+		Paths:    t.Paths,
+		Current:  t.Current,
+		Steps:    t.Steps,
+	}
+}
+
+func pipelineRoute(g *gin.RouterGroup) {
+	taskRoute(g, pipeline.TaskManager, pipeline.Scheduler, conf.TaskPipelineTimeWindow)
+	g.POST("/steps", getTargetedHandler(pipeline.TaskManager, func(c *gin.Context, t *pipeline.PipelineTask) {
+		common.SuccessResp(c, getPipelineInfo(t))
+	}))
+}
@@ -0,0 +1,205 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/rss"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type RssFeedReq struct {
+	ID           uint   `json:"id"`
+	Name         string `json:"name"`
+	URL          string `json:"url" binding:"required"`
+	Include      string `json:"include"`
+	Exclude      string `json:"exclude"`
+	Tool         string `json:"tool" binding:"required"`
+	DstPath      string `json:"dst_path" binding:"required"`
+	DeletePolicy string `json:"delete_policy"`
+	Interval     int    `json:"interval"`
+	Disabled     bool   `json:"disabled"`
+}
+
+// getRssFeed returns the feed of the id, admins may access any feed.
+func getRssFeed(c *gin.Context, rawId string) (*model.RssFeed, bool) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	id, err := strconv.Atoi(rawId)
+	if err != nil {
+		common.ErrorStrResp(c, "ID格式无效", 400)
+		return nil, false
+	}
+	feed, err := db.GetRssFeedById(uint(id))
+	if err != nil || (!user.IsAdmin() && feed.UserId != user.ID) {
+		common.ErrorStrResp(c, "未找到订阅", 404)
+		return nil, false
+	}
+	return feed, true
+}
+
+func ListRssFeeds(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	feeds, total, err := db.GetRssFeedsByUserId(user.ID, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: feeds,
+		Total:   total,
+	})
+}
+
+func ListAllRssFeeds(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	feeds, total, err := db.GetRssFeeds(req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: feeds,
+		Total:   total,
+	})
+}
+
+func saveRssFeed(c *gin.Context, create bool) {
+	var req RssFeedReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	feed := &model.RssFeed{UserId: user.ID}
+	if !create {
+		var ok bool
+		if feed, ok = getRssFeed(c, strconv.Itoa(int(req.ID))); !ok {
+			return
+		}
+	}
+	// the downloads are added on behalf of the owner
+	owner := user
+	if feed.UserId != user.ID {
+		var err error
+		if owner, err = op.GetUserById(feed.UserId); err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+	}
+	if !owner.CanAddOfflineDownloadTasks() {
+		common.ErrorStrResp(c, "permission denied", 403)
+		return
+	}
+	if _, err := owner.JoinPath(req.DstPath); err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	feed.Name = req.Name
+	feed.URL = req.URL
+	feed.Include = req.Include
+	feed.Exclude = req.Exclude
+	feed.Tool = req.Tool
+	feed.DstPath = req.DstPath
+	feed.DeletePolicy = req.DeletePolicy
+	feed.Interval = req.Interval
+	feed.Disabled = req.Disabled
+	if err := rss.Validate(feed); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	var err error
+	if create {
+		err = db.CreateRssFeed(feed)
+	} else {
+		err = db.UpdateRssFeed(feed)
+	}
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, feed)
+}
+
+func CreateRssFeed(c *gin.Context) {
+	saveRssFeed(c, true)
+}
+
+func UpdateRssFeed(c *gin.Context) {
+	saveRssFeed(c, false)
+}
+
+func DeleteRssFeed(c *gin.Context) {
+	feed, ok := getRssFeed(c, c.Query("id"))
+	if !ok {
+		return
+	}
+	if err := db.DeleteRssFeedById(feed.ID); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// CheckRssFeed checks the feed right away and returns the added items.
+func CheckRssFeed(c *gin.Context) {
+	feed, ok := getRssFeed(c, c.Query("id"))
+	if !ok {
+		return
+	}
+	added, err := rss.Check(c.Request.Context(), feed)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, added)
+}
+
+func ListRssHistories(c *gin.Context) {
+	feed, ok := getRssFeed(c, c.Query("id"))
+	if !ok {
+		return
+	}
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	histories, total, err := db.GetRssHistories(feed.ID, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: histories,
+		Total:   total,
+	})
+}
+
+// ClearRssHistories forgets the handled items, they are added again by the next check.
+func ClearRssHistories(c *gin.Context) {
+	feed, ok := getRssFeed(c, c.Query("id"))
+	if !ok {
+		return
+	}
+	if err := db.ClearRssHistories(feed.ID); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
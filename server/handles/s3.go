@@ -0,0 +1,83 @@
+package handles
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/OpenList/v4/server/s3"
+	"github.com/gin-gonic/gin"
+)
+
+type S3PresignReq struct {
+	Bucket   string `json:"bucket" binding:"required"`
+	Key      string `json:"key" binding:"required"`
+	Method   string `json:"method"`
+	Expires  int64  `json:"expires"`
+	Endpoint string `json:"endpoint"`
+}
+
+// S3Presign generates a presigned GET or PUT url for an object of the s3 server
+func S3Presign(c *gin.Context) {
+	if !conf.Conf.S3.Enable {
+		common.ErrorStrResp(c, "S3 服务未启用", 403)
+		return
+	}
+	var req S3PresignReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if req.Method == "" {
+		req.Method = "GET"
+	}
+	if req.Method != "GET" && req.Method != "PUT" && req.Method != "HEAD" {
+		common.ErrorStrResp(c, "仅支持 GET、PUT 和 HEAD 方法", 400)
+		return
+	}
+	if req.Expires == 0 {
+		req.Expires = 3600
+	}
+	if req.Endpoint == "" {
+		req.Endpoint = s3Endpoint(c)
+	}
+	link, err := s3.Presign(s3.PresignArgs{
+		Endpoint: req.Endpoint,
+		Method:   req.Method,
+		Bucket:   req.Bucket,
+		Key:      req.Key,
+		Expires:  time.Duration(req.Expires) * time.Second,
+	})
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"url":     link,
+		"expires": time.Now().Add(time.Duration(req.Expires) * time.Second),
+	})
+}
+
+// s3Endpoint guesses the address clients use to reach the s3 server
+func s3Endpoint(c *gin.Context) string {
+	api := common.GetApiUrl(c)
+	if conf.Conf.S3.Port == -1 {
+		return api + "/s3"
+	}
+	scheme := "http"
+	if conf.Conf.S3.SSL {
+		scheme = "https"
+	}
+	host := c.Request.Host
+	if u, err := url.Parse(api); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	if h, err := url.Parse("//" + host); err == nil {
+		host = h.Hostname()
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, strconv.Itoa(conf.Conf.S3.Port)))
+}
@@ -0,0 +1,30 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+func ListStoragesHealth(c *gin.Context) {
+	common.SuccessResp(c, op.GetStoragesHealth())
+}
+
+// CheckStorageHealth checks the storage at once, and reloads it if it's
+// failed, without waiting for the backoff.
+func CheckStorageHealth(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	for _, storage := range op.GetAllStorages() {
+		if storage.GetStorage().ID == uint(id) {
+			common.SuccessResp(c, op.CheckStorageHealth(c.Request.Context(), storage))
+			return
+		}
+	}
+	common.ErrorStrResp(c, "存储未挂载", 404)
+}
@@ -28,6 +28,9 @@ type TaskInfo struct {
 	EndTime     *time.Time  `json:"end_time"`
 	TotalBytes  int64       `json:"total_bytes"`
 	Error       string      `json:"error"`
+	Priority    int         `json:"priority"`
+	Paused      bool        `json:"paused"`
+	SpeedLimit  int         `json:"speed_limit"`
 }
 
 func getTaskInfo[T task.TaskExtensionInfo](task T) TaskInfo {
@@ -46,18 +49,25 @@ func getTaskInfo[T task.TaskExtensionInfo](task T) TaskInfo {
 		creatorName = task.GetCreator().Username
 		creatorRole = task.GetCreator().Role
 	}
+	status := task.GetStatus()
+	if queued := task.GetQueueStatus(); queued != "" {
+		status = queued
+	}
 	return TaskInfo{
 		ID:          task.GetID(),
 		Name:        task.GetName(),
 		Creator:     creatorName,
 		CreatorRole: creatorRole,
 		State:       task.GetState(),
-		Status:      task.GetStatus(),
+		Status:      status,
 		Progress:    progress,
 		StartTime:   task.GetStartTime(),
 		EndTime:     task.GetEndTime(),
 		TotalBytes:  task.GetTotalBytes(),
 		Error:       errMsg,
+		Priority:    task.GetPriority(),
+		Paused:      task.IsPaused(),
+		SpeedLimit:  task.GetSpeedLimit(),
 	}
 }
 
@@ -124,7 +134,7 @@ func getBatchHandler[T task.TaskExtensionInfo](manager task.Manager[T], callback
 	}
 }
 
-func taskRoute[T task.TaskExtensionInfo](g *gin.RouterGroup, manager task.Manager[T]) {
+func taskRoute[T task.TaskExtensionInfo](g *gin.RouterGroup, manager task.Manager[T], scheduler *task.Scheduler, windowKey string) {
 	g.GET("/undone", func(c *gin.Context) {
 		isAdmin, uid, ok := getUserInfo(c)
 		if !ok {
@@ -215,14 +225,18 @@ func taskRoute[T task.TaskExtensionInfo](g *gin.RouterGroup, manager task.Manage
 		}
 		common.SuccessResp(c)
 	})
+	taskScheduleRoute(g, manager, scheduler, windowKey)
 }
 
 func SetupTaskRoute(g *gin.RouterGroup) {
-	taskRoute(g.Group("/upload"), fs.UploadTaskManager)
-	taskRoute(g.Group("/copy"), fs.CopyTaskManager)
-	taskRoute(g.Group("/move"), fs.MoveTaskManager)
-	taskRoute(g.Group("/offline_download"), tool.DownloadTaskManager)
-	taskRoute(g.Group("/offline_download_transfer"), tool.TransferTaskManager)
-	taskRoute(g.Group("/decompress"), fs.ArchiveDownloadTaskManager)
-	taskRoute(g.Group("/decompress_upload"), fs.ArchiveContentUploadTaskManager)
+	taskRoute(g.Group("/upload"), fs.UploadTaskManager, fs.UploadScheduler, conf.TaskUploadTimeWindow)
+	taskRoute(g.Group("/copy"), fs.CopyTaskManager, fs.CopyScheduler, conf.TaskCopyTimeWindow)
+	taskRoute(g.Group("/move"), fs.MoveTaskManager, fs.MoveScheduler, conf.TaskMoveTimeWindow)
+	taskRoute(g.Group("/offline_download"), tool.DownloadTaskManager, tool.DownloadScheduler, conf.TaskOfflineDownloadTimeWindow)
+	taskRoute(g.Group("/offline_download_transfer"), tool.TransferTaskManager, tool.TransferScheduler, conf.TaskOfflineDownloadTransferTimeWindow)
+	taskRoute(g.Group("/decompress"), fs.ArchiveDownloadTaskManager, fs.ArchiveDownloadScheduler, conf.TaskDecompressDownloadTimeWindow)
+	taskRoute(g.Group("/decompress_upload"), fs.ArchiveContentUploadTaskManager, fs.ArchiveContentUploadScheduler, conf.TaskDecompressUploadTimeWindow)
+	pipelineRoute(g.Group("/pipeline"))
+	g.GET("/history", ListTaskHistories)
+	g.GET("/history/summary", SummarizeTaskHistories)
 }
@@ -0,0 +1,110 @@
+package handles
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type TaskHistoryReq struct {
+	model.PageReq
+	Type    string `json:"type" form:"type"`
+	Result  string `json:"result" form:"result"`
+	Storage string `json:"storage" form:"storage"`
+	// the username, admins only
+	Creator string `json:"creator" form:"creator"`
+	Keyword string `json:"keyword" form:"keyword"`
+	// unix timestamps of the end time
+	Since int64 `json:"since" form:"since"`
+	Until int64 `json:"until" form:"until"`
+}
+
+// filter returns the filter of the req, users other than admins only see
+// their own tasks.
+func (r *TaskHistoryReq) filter(c *gin.Context) (model.TaskHistoryFilter, bool) {
+	f := model.TaskHistoryFilter{
+		Type:    r.Type,
+		Result:  r.Result,
+		Storage: r.Storage,
+		Keyword: r.Keyword,
+	}
+	if r.Since > 0 {
+		f.Since = time.Unix(r.Since, 0)
+	}
+	if r.Until > 0 {
+		f.Until = time.Unix(r.Until, 0)
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if !user.IsAdmin() {
+		f.CreatorId = user.ID
+	} else if r.Creator != "" {
+		creator, err := op.GetUserByName(r.Creator)
+		if err != nil {
+			common.ErrorStrResp(c, "未找到用户", 404)
+			return f, false
+		}
+		f.CreatorId = creator.ID
+	}
+	return f, true
+}
+
+func ListTaskHistories(c *gin.Context) {
+	var req TaskHistoryReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	f, ok := req.filter(c)
+	if !ok {
+		return
+	}
+	histories, total, err := db.GetTaskHistories(f, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: histories,
+		Total:   total,
+	})
+}
+
+// SummarizeTaskHistories sums up the tasks by "user" or "storage" given by
+// the by query, with the average throughput of the succeeded tasks.
+func SummarizeTaskHistories(c *gin.Context) {
+	var req TaskHistoryReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	column := "creator"
+	switch c.Query("by") {
+	case "", "user":
+	case "storage":
+		column = "storage"
+	default:
+		common.ErrorStrResp(c, "无效的统计方式", 400)
+		return
+	}
+	f, ok := req.filter(c)
+	if !ok {
+		return
+	}
+	summaries, err := db.SummarizeTaskHistories(f, column)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	for i := range summaries {
+		if summaries[i].Duration > 0 {
+			summaries[i].Throughput = summaries[i].Bytes * 1000 / summaries[i].Duration
+		}
+	}
+	common.SuccessResp(c, summaries)
+}
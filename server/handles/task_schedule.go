@@ -0,0 +1,100 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type SetTaskWindowReq struct {
+	Window string `json:"window" form:"window"`
+}
+
+// getAdminHandler only lets admins manage the scheduler shared by all users.
+func getAdminHandler(callback gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		isAdmin, _, ok := getUserInfo(c)
+		if !ok {
+			common.ErrorStrResp(c, "当前用户无效", 401)
+			return
+		}
+		if !isAdmin {
+			common.ErrorStrResp(c, "您不是管理员哦", 403)
+			return
+		}
+		callback(c)
+	}
+}
+
+func taskScheduleRoute[T task.TaskExtensionInfo](g *gin.RouterGroup, manager task.Manager[T], scheduler *task.Scheduler, windowKey string) {
+	g.POST("/priority", getTargetedHandler(manager, func(c *gin.Context, task T) {
+		priority, err := strconv.Atoi(c.Query("priority"))
+		if err != nil {
+			common.ErrorStrResp(c, "优先级无效", 400)
+			return
+		}
+		task.SetPriority(priority)
+		common.SuccessResp(c)
+	}))
+	g.POST("/pause", getTargetedHandler(manager, func(c *gin.Context, task T) {
+		task.SetPaused(true)
+		common.SuccessResp(c)
+	}))
+	g.POST("/resume", getTargetedHandler(manager, func(c *gin.Context, task T) {
+		task.SetPaused(false)
+		common.SuccessResp(c)
+	}))
+	g.POST("/pause_some", getBatchHandler(manager, func(task T) {
+		task.SetPaused(true)
+	}))
+	g.POST("/resume_some", getBatchHandler(manager, func(task T) {
+		task.SetPaused(false)
+	}))
+	g.POST("/speed_limit", getTargetedHandler(manager, func(c *gin.Context, task T) {
+		limit, err := strconv.Atoi(c.Query("limit"))
+		if err != nil || limit < 0 {
+			common.ErrorStrResp(c, "限速无效", 400)
+			return
+		}
+		task.SetSpeedLimit(limit)
+		common.SuccessResp(c)
+	}))
+	g.GET("/scheduler", getAdminHandler(func(c *gin.Context) {
+		common.SuccessResp(c, scheduler.Info())
+	}))
+	g.POST("/scheduler/pause", getAdminHandler(func(c *gin.Context) {
+		scheduler.Pause()
+		common.SuccessResp(c)
+	}))
+	g.POST("/scheduler/resume", getAdminHandler(func(c *gin.Context) {
+		scheduler.Resume()
+		common.SuccessResp(c)
+	}))
+	g.POST("/scheduler/window", getAdminHandler(func(c *gin.Context) {
+		var req SetTaskWindowReq
+		if err := c.ShouldBind(&req); err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
+		windows, err := task.ParseTimeWindows(req.Window)
+		if err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
+		item, err := op.GetSettingItemByKey(windowKey)
+		if err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+		// saving the setting applies it to the scheduler
+		item.Value = task.FormatTimeWindows(windows)
+		if err = op.SaveSettingItem(item); err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+		common.SuccessResp(c, scheduler.Info())
+	}))
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/OpenList/v4/server/ftp"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 )
@@ -39,6 +40,10 @@ func CreateUser(c *gin.Context) {
 		common.ErrorStrResp(c, "无法创建 admin 或 guest 用户", 400, true)
 		return
 	}
+	if err := req.FTPPolicy.Validate(); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
 	req.SetPassword(req.Password)
 	req.Password = ""
 	req.Authn = "[]"
@@ -78,9 +83,16 @@ func UpdateUser(c *gin.Context) {
 		common.ErrorStrResp(c, "无法禁用管理员用户", 400)
 		return
 	}
+	if err := req.FTPPolicy.Validate(); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
 	if err := op.UpdateUser(&req); err != nil {
 		common.ErrorResp(c, err, 500)
 	} else {
+		if req.Disabled {
+			ftp.KickUserSessions(req.ID)
+		}
 		common.SuccessResp(c)
 	}
 }
@@ -96,6 +108,7 @@ func DeleteUser(c *gin.Context) {
 		common.ErrorResp(c, err, 500)
 		return
 	}
+	ftp.KickUserSessions(uint(id))
 	common.SuccessResp(c)
 }
 
@@ -105,6 +105,7 @@ func Init(e *gin.Engine) {
 	fsAndShare(api.Group("/fs", middlewares.Auth(true)))
 	_task(auth.Group("/task", middlewares.AuthNotGuest))
 	_sharing(auth.Group("/share", middlewares.AuthNotGuest))
+	_rss(auth.Group("/rss", middlewares.AuthNotGuest))
 	admin(auth.Group("/admin", middlewares.AuthAdmin))
 	if flags.Debug || flags.Dev {
 		debug(g.Group("/debug"))
@@ -142,6 +143,12 @@ func admin(g *gin.RouterGroup) {
 	storage.POST("/enable", handles.EnableStorage)
 	storage.POST("/disable", handles.DisableStorage)
 	storage.POST("/load_all", handles.LoadAllStorages)
+	storage.GET("/health", handles.ListStoragesHealth)
+	storage.POST("/health/check", handles.CheckStorageHealth)
+
+	bak := g.Group("/backup")
+	bak.POST("/export", handles.ExportBackup)
+	bak.POST("/import", handles.ImportBackup)
 
 	driver := g.Group("/driver")
 	driver.GET("/list", handles.ListDriverInfo)
@@ -158,6 +165,7 @@ func admin(g *gin.RouterGroup) {
 	setting.POST("/set_aria2", handles.SetAria2)
 	setting.POST("/set_qbit", handles.SetQbittorrent)
 	setting.POST("/set_transmission", handles.SetTransmission)
+	setting.POST("/set_torrent", handles.SetTorrent)
 	setting.POST("/set_115", handles.Set115)
 	setting.POST("/set_115_open", handles.Set115Open)
 	setting.POST("/set_123_pan", handles.Set123Pan)
@@ -185,6 +193,32 @@ func admin(g *gin.RouterGroup) {
 	scan.POST("/start", handles.StartManualScan)
 	scan.POST("/stop", handles.StopManualScan)
 	scan.GET("/progress", handles.GetManualScanProgress)
+
+	dup := g.Group("/dedup")
+	dup.POST("/start", handles.StartDedup)
+	dup.POST("/stop", handles.StopDedup)
+	dup.GET("/progress", handles.GetDedupProgress)
+	dup.GET("/groups", handles.ListDedupGroups)
+	dup.POST("/action", handles.DedupAction)
+
+	ftpSession := g.Group("/ftp/session")
+	ftpSession.GET("/list", handles.ListFTPSessions)
+	ftpSession.POST("/kick", handles.KickFTPSession)
+
+	s3 := g.Group("/s3")
+	s3.POST("/presign", handles.S3Presign)
+
+	rss := g.Group("/rss")
+	rss.GET("/list", handles.ListAllRssFeeds)
+	rss.POST("/delete", handles.DeleteRssFeed)
+	rss.POST("/check", handles.CheckRssFeed)
+	rss.GET("/history", handles.ListRssHistories)
+
+	rule := g.Group("/offline_download_rule")
+	rule.GET("/list", handles.ListOfflineDownloadRules)
+	rule.POST("/create", handles.CreateOfflineDownloadRule)
+	rule.POST("/update", handles.UpdateOfflineDownloadRule)
+	rule.POST("/delete", handles.DeleteOfflineDownloadRule)
 }
 
 func fsAndShare(g *gin.RouterGroup) {
@@ -208,19 +242,40 @@ func _fs(g *gin.RouterGroup) {
 	g.POST("/copy", handles.FsCopy)
 	g.POST("/remove", handles.FsRemove)
 	g.POST("/remove_empty_directory", handles.FsRemoveEmptyDirectory)
+	versions := g.Group("/versions")
+	versions.Any("/list", handles.FsListVersions)
+	versions.POST("/restore", handles.FsRestoreVersion)
+	versions.POST("/delete", handles.FsDeleteVersions)
 	uploadLimiter := middlewares.UploadRateLimiter(stream.ClientUploadLimit)
 	g.PUT("/put", middlewares.FsUp, uploadLimiter, handles.FsStream)
 	g.PUT("/form", middlewares.FsUp, uploadLimiter, handles.FsForm)
+	tus := g.Group("/tus")
+	tus.OPTIONS("", handles.FsTusOptions)
+	tus.POST("", handles.FsTusPath, middlewares.FsUp, handles.FsTusCreate)
+	tus.HEAD("/:id", handles.FsTusHead)
+	tus.PATCH("/:id", uploadLimiter, handles.FsTusPatch)
+	tus.DELETE("/:id", handles.FsTusDelete)
 	g.POST("/link", middlewares.AuthAdmin, handles.Link)
 	// g.POST("/add_aria2", handles.AddOfflineDownload)
 	// g.POST("/add_qbit", handles.AddQbittorrent)
 	// g.POST("/add_transmission", handles.SetTransmission)
 	g.POST("/add_offline_download", handles.AddOfflineDownload)
 	g.POST("/archive/decompress", handles.FsArchiveDecompress)
+	g.POST("/pipeline", handles.AddPipeline)
 	// Direct upload (client-side upload to storage)
 	g.POST("/get_direct_upload_info", middlewares.FsUp, handles.FsGetDirectUploadInfo)
 }
 
+func _rss(g *gin.RouterGroup) {
+	g.GET("/list", handles.ListRssFeeds)
+	g.POST("/create", handles.CreateRssFeed)
+	g.POST("/update", handles.UpdateRssFeed)
+	g.POST("/delete", handles.DeleteRssFeed)
+	g.POST("/check", handles.CheckRssFeed)
+	g.GET("/history", handles.ListRssHistories)
+	g.POST("/clear_history", handles.ClearRssHistories)
+}
+
 func _task(g *gin.RouterGroup) {
 	handles.SetupTaskRoute(g)
 }
@@ -241,6 +296,9 @@ func Cors(r *gin.Engine) {
 	config.AllowOrigins = conf.Conf.Cors.AllowOrigins
 	config.AllowHeaders = conf.Conf.Cors.AllowHeaders
 	config.AllowMethods = conf.Conf.Cors.AllowMethods
+	// let browsers resume tus uploads
+	config.ExposeHeaders = []string{"Location", "Upload-Offset", "Upload-Length", "Upload-Expires",
+		"Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size"}
 	r.Use(cors.New(config))
 }
 
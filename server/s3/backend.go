@@ -9,7 +9,6 @@ import (
 	"io"
 	"path"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -35,15 +34,11 @@ var (
 
 // s3Backend implements the gofacess3.Backend interface to make an S3
 // backend for gofakes3
-type s3Backend struct {
-	meta *sync.Map
-}
+type s3Backend struct{}
 
 // newBackend creates a new SimpleBucketBackend.
 func newBackend() gofakes3.Backend {
-	return &s3Backend{
-		meta: new(sync.Map),
-	}
+	return &s3Backend{}
 }
 
 // ListBuckets always returns the default bucket.
@@ -78,29 +73,28 @@ func (b *s3Backend) ListBucket(ctx context.Context, bucketName string, prefix *g
 
 	// workaround
 	if strings.TrimSpace(prefix.Prefix) == "" {
+		prefix.Prefix = ""
 		prefix.HasPrefix = false
 	}
 	if strings.TrimSpace(prefix.Delimiter) == "" {
 		prefix.HasDelimiter = false
 	}
 
-	response := gofakes3.NewObjectList()
-	path, remaining := prefixParser(prefix)
+	p := newPager(page)
+	path, _ := prefixParser(prefix)
 
-	err = b.entryListR(bucketPath, path, remaining, prefix.HasDelimiter, response)
+	err = b.entryListR(ctx, bucketPath, path, prefix, p)
 	if err == gofakes3.ErrNoSuchKey {
 		// AWS just returns an empty list
-		response = gofakes3.NewObjectList()
+		return gofakes3.NewObjectList(), nil
 	} else if err != nil {
 		return nil, err
 	}
 
-	return b.pager(response, page)
+	return p.list, nil
 }
 
 // HeadObject returns the fileinfo for the given object name.
-//
-// Note that the metadata is not supported yet.
 func (b *s3Backend) HeadObject(ctx context.Context, bucketName, objectName string) (*gofakes3.Object, error) {
 	bucket, err := getBucketByName(bucketName)
 	if err != nil {
@@ -120,23 +114,19 @@ func (b *s3Backend) HeadObject(ctx context.Context, bucketName, objectName strin
 	}
 
 	size := node.GetSize()
-	// hash := getFileHashByte(fobj)
 
 	meta := map[string]string{
 		"Last-Modified": node.ModTime().Format(timeFormat),
 		"Content-Type":  utils.GetMimeType(fp),
 	}
 
-	if val, ok := b.meta.Load(fp); ok {
-		metaMap := val.(map[string]string)
-		for k, v := range metaMap {
-			meta[k] = v
-		}
+	for k, v := range loadObjectMeta(fp) {
+		meta[k] = v
 	}
 
 	return &gofakes3.Object{
-		Name: objectName,
-		// Hash:     hash,
+		Name:     objectName,
+		Hash:     getFileHashByte(node),
 		Metadata: meta,
 		Size:     size,
 		Contents: noOpReadCloser{},
@@ -202,17 +192,19 @@ func (b *s3Backend) GetObject(ctx context.Context, bucketName, objectName string
 		"Content-Type":        utils.GetMimeType(fp),
 	}
 
-	if val, ok := b.meta.Load(fp); ok {
-		metaMap := val.(map[string]string)
-		for k, v := range metaMap {
-			meta[k] = v
-		}
+	for k, v := range loadObjectMeta(fp) {
+		meta[k] = v
+	}
+
+	hash := getFileHashByte(file)
+	if hash == nil {
+		hash = getFileHashByte(node)
 	}
 
 	return &gofakes3.Object{
 		// Name: gofakes3.URLEncode(objectName),
-		Name: objectName,
-		// Hash:     "",
+		Name:     objectName,
+		Hash:     hash,
 		Metadata: meta,
 		Size:     size,
 		Range:    rnge,
@@ -313,7 +305,7 @@ func (b *s3Backend) PutObject(
 	// 	return result, err
 	// }
 
-	b.meta.Store(fp, meta)
+	storeObjectMeta(fp, meta)
 
 	return result, nil
 }
@@ -360,6 +352,7 @@ func (b *s3Backend) deleteObject(ctx context.Context, bucketName, objectName str
 	}
 
 	fs.Remove(ctx, fp)
+	deleteObjectMeta(fp)
 	return nil
 }
 
@@ -389,11 +382,6 @@ func (b *s3Backend) BucketExists(ctx context.Context, name string) (exists bool,
 
 // CopyObject copy specified object from srcKey to dstKey.
 func (b *s3Backend) CopyObject(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string, meta map[string]string) (result gofakes3.CopyObjectResult, err error) {
-	if srcBucket == dstBucket && srcKey == dstKey {
-		//TODO: update meta
-		return result, nil
-	}
-
 	srcB, err := getBucketByName(srcBucket)
 	if err != nil {
 		return result, err
@@ -403,6 +391,28 @@ func (b *s3Backend) CopyObject(ctx context.Context, srcBucket, srcKey, dstBucket
 	srcFp := path.Join(srcBucketPath, srcKey)
 	fmeta, _ := op.GetNearestMeta(srcFp)
 	srcNode, err := fs.Get(context.WithValue(ctx, conf.MetaKey, fmeta), srcFp, &fs.GetArgs{})
+	if err != nil {
+		return result, gofakes3.KeyNotFound(srcKey)
+	}
+
+	if srcBucket == dstBucket && srcKey == dstKey {
+		// copying an object onto itself is how S3 clients replace its metadata
+		storeObjectMeta(srcFp, meta)
+		modified := srcNode.ModTime()
+		if val, ok := meta["mtime"]; ok {
+			if ti, err := swift.FloatStringToTime(val); err == nil && !ti.Equal(modified) {
+				if err := fs.SetModTime(ctx, srcFp, ti, time.Time{}); err != nil {
+					log.Warnf("failed set modified time of %s: %v", srcFp, err)
+				} else {
+					modified = ti
+				}
+			}
+		}
+		return gofakes3.CopyObjectResult{
+			ETag:         `"` + hex.EncodeToString(getFileHashByte(srcNode)) + `"`,
+			LastModified: gofakes3.NewContentTime(modified),
+		}, nil
+	}
 
 	c, err := b.GetObject(ctx, srcBucket, srcKey, nil)
 	if err != nil {
@@ -3,67 +3,102 @@
 package s3
 
 import (
+	"context"
 	"path"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/itsHenry35/gofakes3"
 	log "github.com/sirupsen/logrus"
 )
 
-func (b *s3Backend) entryListR(bucket, fdPath, name string, addPrefix bool, response *gofakes3.ObjectList) error {
-	fp := path.Join(bucket, fdPath)
+// listEntry is a directory entry together with the key it occupies in the
+// bucket. Directories are keyed with a trailing slash so that sorting by key
+// yields the same order as a flat S3 listing.
+type listEntry struct {
+	key string
+	obj model.Obj
+}
+
+// sortedEntries returns the entries of a directory sorted by their bucket key.
+func sortedEntries(dir string, objs []model.Obj) []listEntry {
+	entries := make([]listEntry, 0, len(objs))
+	for _, obj := range objs {
+		key := path.Join(dir, obj.GetName())
+		if obj.IsDir() {
+			key += "/"
+		}
+		entries = append(entries, listEntry{key: key, obj: obj})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+	return entries
+}
 
-	dirEntries, err := getDirEntries(fp)
+// entryListR walks the bucket in lexicographic key order starting at fdPath,
+// feeding matching keys and common prefixes into the pager until it is full.
+// Subtrees that are entirely outside the prefix or before the marker are not
+// listed at all.
+func (b *s3Backend) entryListR(ctx context.Context, bucket, fdPath string, prefix *gofakes3.Prefix, p *pager) error {
+	dirEntries, err := getDirEntries(ctx, path.Join(bucket, fdPath))
 	if err != nil {
 		return err
 	}
 
 	// workaround as s3 can't have empty files in directories, useful in deletions
 	if len(dirEntries) == 0 {
-		item := &gofakes3.Content{
-			// Key:          gofakes3.URLEncode(path.Join(fdPath, emptyObjectName)),
-			Key:          path.Join(fdPath, emptyObjectName),
-			LastModified: gofakes3.NewContentTime(time.Now()),
-			ETag:         getFileHash(nil), // No entry, so no hash
-			Size:         0,
-			StorageClass: gofakes3.StorageStandard,
+		key := path.Join(fdPath, emptyObjectName)
+		if strings.HasPrefix(key, prefix.Prefix) {
+			log.Debugf("Adding empty object %s to response", key)
+			p.addContent(&gofakes3.Content{
+				Key:          key,
+				LastModified: gofakes3.NewContentTime(time.Now()),
+				ETag:         getFileHash(nil), // No entry, so no hash
+				Size:         0,
+				StorageClass: gofakes3.StorageStandard,
+			})
 		}
-		response.Add(item)
-		log.Debugf("Adding empty object %s to response", item.Key)
 		return nil
 	}
 
-	for _, entry := range dirEntries {
-		object := entry.GetName()
-
-		// workround for control-chars detect
-		objectPath := path.Join(fdPath, object)
-
-		if !strings.HasPrefix(object, name) {
-			continue
+	for _, entry := range sortedEntries(fdPath, dirEntries) {
+		if p.full() {
+			return nil
 		}
-
-		if entry.IsDir() {
-			if addPrefix {
-				// response.AddPrefix(gofakes3.URLEncode(objectPath))
-				response.AddPrefix(objectPath)
+		if !entry.obj.IsDir() {
+			var match gofakes3.PrefixMatch
+			if !prefix.Match(entry.key, &match) {
 				continue
 			}
-			err := b.entryListR(bucket, path.Join(fdPath, object), "", false, response)
-			if err != nil {
-				return err
+			if match.CommonPrefix {
+				p.addPrefix(match.MatchedPart)
+				continue
 			}
-		} else {
-			item := &gofakes3.Content{
-				// Key:          gofakes3.URLEncode(objectPath),
-				Key:          objectPath,
-				LastModified: gofakes3.NewContentTime(entry.ModTime()),
-				ETag:         getFileHash(entry),
-				Size:         entry.GetSize(),
+			p.addContent(&gofakes3.Content{
+				Key:          entry.key,
+				LastModified: gofakes3.NewContentTime(entry.obj.ModTime()),
+				ETag:         getFileHash(entry.obj),
+				Size:         entry.obj.GetSize(),
 				StorageClass: gofakes3.StorageStandard,
-			}
-			response.Add(item)
+			})
+			continue
+		}
+		if !strings.HasPrefix(entry.key, prefix.Prefix) && !strings.HasPrefix(prefix.Prefix, entry.key) {
+			continue
+		}
+		if p.before(entry.key) {
+			continue
+		}
+		if prefix.HasDelimiter && prefix.Delimiter == "/" && strings.HasPrefix(entry.key, prefix.Prefix) {
+			p.addPrefix(entry.key)
+			continue
+		}
+		err := b.entryListR(ctx, bucket, strings.TrimSuffix(entry.key, "/"), prefix, p)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
@@ -0,0 +1,83 @@
+// Package s3 implements a fake s3 server for openlist
+package s3
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	log "github.com/sirupsen/logrus"
+)
+
+const userMetaPrefix = "X-Amz-Meta-"
+
+// storedHeaders are the standard object headers that S3 returns as they
+// were given on upload, in addition to the user metadata.
+var storedHeaders = []string{
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Language",
+	"Content-Type",
+	"Expires",
+}
+
+// filterObjectMeta keeps only the metadata that belongs to the object itself,
+// dropping request specific headers such as signatures and dates.
+func filterObjectMeta(meta map[string]string) map[string]string {
+	res := make(map[string]string)
+	for k, v := range meta {
+		k = http.CanonicalHeaderKey(k)
+		if strings.HasPrefix(k, userMetaPrefix) {
+			res[k] = v
+			continue
+		}
+		for _, h := range storedHeaders {
+			if k == h {
+				res[k] = v
+				break
+			}
+		}
+	}
+	return res
+}
+
+// loadObjectMeta returns the metadata persisted for the object at fp.
+func loadObjectMeta(fp string) map[string]string {
+	m, err := db.GetS3ObjectMetaByPath(fp)
+	if err != nil {
+		return nil
+	}
+	var meta map[string]string
+	if err := json.Unmarshal([]byte(m.MetaRaw), &meta); err != nil {
+		log.Warnf("failed to unmarshal s3 meta of %s: %+v", fp, err)
+		return nil
+	}
+	return meta
+}
+
+// storeObjectMeta persists the object metadata of fp, replacing any
+// previous value. Nothing is stored when there is nothing worth keeping.
+func storeObjectMeta(fp string, meta map[string]string) {
+	meta = filterObjectMeta(meta)
+	if len(meta) == 0 {
+		deleteObjectMeta(fp)
+		return
+	}
+	raw, err := json.Marshal(meta)
+	if err != nil {
+		log.Warnf("failed to marshal s3 meta of %s: %+v", fp, err)
+		return
+	}
+	if err := db.SaveS3ObjectMeta(&model.S3ObjectMeta{Path: fp, MetaRaw: string(raw)}); err != nil {
+		log.Warnf("failed to save s3 meta of %s: %+v", fp, err)
+	}
+}
+
+func deleteObjectMeta(fp string) {
+	if err := db.DeleteS3ObjectMetaByPath(fp); err != nil {
+		log.Warnf("failed to delete s3 meta of %s: %+v", fp, err)
+	}
+}
@@ -3,65 +3,69 @@
 package s3
 
 import (
-	"sort"
+	"strings"
 
 	"github.com/itsHenry35/gofakes3"
 )
 
-// pager splits the object list into smulitply pages.
-func (db *s3Backend) pager(list *gofakes3.ObjectList, page gofakes3.ListBucketPage) (*gofakes3.ObjectList, error) {
-	// sort by alphabet
-	sort.Slice(list.CommonPrefixes, func(i, j int) bool {
-		return list.CommonPrefixes[i].Prefix < list.CommonPrefixes[j].Prefix
-	})
-	// sort by modtime
-	sort.Slice(list.Contents, func(i, j int) bool {
-		return list.Contents[i].LastModified.Before(list.Contents[j].LastModified.Time)
-	})
-	tokens := page.MaxKeys
-	if tokens == 0 {
-		tokens = 1000
+// pager collects one page of a bucket listing. Items must be added in
+// lexicographic key order; anything not after the marker is dropped and the
+// walk can stop as soon as one item more than the page size has been seen.
+type pager struct {
+	list      *gofakes3.ObjectList
+	marker    string
+	hasMarker bool
+	maxKeys   int64
+	count     int64
+	last      string
+}
+
+func newPager(page gofakes3.ListBucketPage) *pager {
+	maxKeys := page.MaxKeys
+	if maxKeys <= 0 {
+		maxKeys = gofakes3.DefaultMaxBucketKeys
 	}
-	if page.HasMarker {
-		for i, obj := range list.Contents {
-			if obj.Key == page.Marker {
-				list.Contents = list.Contents[i+1:]
-				break
-			}
-		}
-		for i, obj := range list.CommonPrefixes {
-			if obj.Prefix == page.Marker {
-				list.CommonPrefixes = list.CommonPrefixes[i+1:]
-				break
-			}
-		}
+	return &pager{
+		list:      gofakes3.NewObjectList(),
+		marker:    page.Marker,
+		hasMarker: page.HasMarker && page.Marker != "",
+		maxKeys:   maxKeys,
 	}
+}
 
-	response := gofakes3.NewObjectList()
-	for _, obj := range list.CommonPrefixes {
-		if tokens <= 0 {
-			break
-		}
-		response.AddPrefix(obj.Prefix)
-		tokens--
-	}
+// before reports whether every key starting with dirKey sorts at or before
+// the marker, so the whole subtree can be skipped.
+func (p *pager) before(dirKey string) bool {
+	return p.hasMarker && dirKey <= p.marker && !strings.HasPrefix(p.marker, dirKey)
+}
 
-	for _, obj := range list.Contents {
-		if tokens <= 0 {
-			break
-		}
-		response.Add(obj)
-		tokens--
+// full reports whether the page already holds more items than requested.
+func (p *pager) full() bool {
+	return p.list.IsTruncated
+}
+
+func (p *pager) accept(key string) bool {
+	if p.full() || (p.hasMarker && key <= p.marker) || key == p.last {
+		return false
+	}
+	if p.count >= p.maxKeys {
+		p.list.IsTruncated = true
+		p.list.NextMarker = p.last
+		return false
 	}
+	p.count++
+	p.last = key
+	return true
+}
 
-	if len(list.CommonPrefixes)+len(list.Contents) > int(page.MaxKeys) {
-		response.IsTruncated = true
-		if len(response.Contents) > 0 {
-			response.NextMarker = response.Contents[len(response.Contents)-1].Key
-		} else {
-			response.NextMarker = response.CommonPrefixes[len(response.CommonPrefixes)-1].Prefix
-		}
+func (p *pager) addContent(item *gofakes3.Content) {
+	if p.accept(item.Key) {
+		p.list.Add(item)
 	}
+}
 
-	return response, nil
+func (p *pager) addPrefix(prefix string) {
+	if p.accept(prefix) {
+		p.list.AddPrefix(prefix)
+	}
 }
@@ -0,0 +1,72 @@
+package s3
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/itsHenry35/gofakes3/signature"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	err = op.SaveSettingItems([]model.SettingItem{
+		{Key: conf.S3AccessKeyId, Value: "access", Type: conf.TypeString, Group: model.S3, Flag: model.PRIVATE},
+		{Key: conf.S3SecretAccessKey, Value: "secret", Type: conf.TypeString, Group: model.S3, Flag: model.PRIVATE},
+		{Key: conf.S3Buckets, Value: `[{"name":"bucket","path":"/"}]`, Type: conf.TypeString, Group: model.S3, Flag: model.PRIVATE},
+	})
+	if err != nil {
+		panic(err)
+	}
+	signature.StoreKeys(map[string]string{"access": "secret"})
+}
+
+func TestPresign(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		method   string
+		key      string
+	}{
+		{endpoint: "http://127.0.0.1:5246", method: "GET", key: "dir/file.txt"},
+		{endpoint: "https://example.com/s3", method: "PUT", key: "中文 目录/a+b (1).bin"},
+	}
+	for _, tt := range tests {
+		link, err := Presign(PresignArgs{
+			Endpoint: tt.endpoint,
+			Method:   tt.method,
+			Bucket:   "bucket",
+			Key:      tt.key,
+			Expires:  time.Minute,
+		})
+		if err != nil {
+			t.Fatalf("failed to presign: %+v", err)
+		}
+		req, err := http.NewRequest(tt.method, link, nil)
+		if err != nil {
+			t.Fatalf("invalid presigned url %s: %+v", link, err)
+		}
+		// the server sees the path relative to its mount point
+		req.URL.Path = "/bucket/" + tt.key
+		if code := signature.V4SignVerify(req); code != signature.ErrNone {
+			t.Errorf("presigned url %s rejected: %v", link, code)
+		}
+		req.Method = "DELETE"
+		if code := signature.V4SignVerify(req); code == signature.ErrNone {
+			t.Errorf("presigned url %s accepted for another method", link)
+		}
+	}
+	if _, err := Presign(PresignArgs{Endpoint: "http://127.0.0.1", Bucket: "none", Key: "a", Expires: time.Minute}); err == nil {
+		t.Errorf("presign of unknown bucket should fail")
+	}
+}
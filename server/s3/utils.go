@@ -4,6 +4,7 @@ package s3
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"strings"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/itsHenry35/gofakes3"
 )
 
@@ -42,8 +44,7 @@ func getBucketByName(name string) (Bucket, error) {
 	return Bucket{}, gofakes3.BucketNotFound(name)
 }
 
-func getDirEntries(path string) ([]model.Obj, error) {
-	ctx := context.Background()
+func getDirEntries(ctx context.Context, path string) ([]model.Obj, error) {
 	meta, _ := op.GetNearestMeta(path)
 	fi, err := fs.Get(context.WithValue(ctx, conf.MetaKey, meta), path, &fs.GetArgs{})
 	if errs.IsNotFoundError(err) {
@@ -64,50 +65,26 @@ func getDirEntries(path string) ([]model.Obj, error) {
 	return dirEntries, nil
 }
 
-// func getFileHashByte(node interface{}) []byte {
-// 	b, err := hex.DecodeString(getFileHash(node))
-// 	if err != nil {
-// 		return nil
-// 	}
-// 	return b
-// }
+// getFileHashByte returns the md5 of the object provided by the driver,
+// which is what S3 clients expect as the ETag of a non-multipart object.
+func getFileHashByte(obj model.Obj) []byte {
+	if obj == nil {
+		return nil
+	}
+	b, err := hex.DecodeString(obj.GetHash().GetHash(utils.MD5))
+	if err != nil {
+		return nil
+	}
+	return b
+}
 
-func getFileHash(node interface{}) string {
-	// var o fs.Object
-
-	// switch b := node.(type) {
-	// case vfs.Node:
-	// 	fsObj, ok := b.DirEntry().(fs.Object)
-	// 	if !ok {
-	// 		fs.Debugf("serve s3", "File uploading - reading hash from VFS cache")
-	// 		in, err := b.Open(os.O_RDONLY)
-	// 		if err != nil {
-	// 			return ""
-	// 		}
-	// 		defer func() {
-	// 			_ = in.Close()
-	// 		}()
-	// 		h, err := hash.NewMultiHasherTypes(hash.NewHashSet(hash.MD5))
-	// 		if err != nil {
-	// 			return ""
-	// 		}
-	// 		_, err = io.Copy(h, in)
-	// 		if err != nil {
-	// 			return ""
-	// 		}
-	// 		return h.Sums()[hash.MD5]
-	// 	}
-	// 	o = fsObj
-	// case fs.Object:
-	// 	o = b
-	// }
-
-	// hash, err := o.Hash(context.Background(), hash.MD5)
-	// if err != nil {
-	// 	return ""
-	// }
-	// return hash
-	return ""
+// getFileHash returns the quoted ETag for listings, or "" when unknown.
+func getFileHash(obj model.Obj) string {
+	b := getFileHashByte(obj)
+	if len(b) == 0 {
+		return ""
+	}
+	return `"` + hex.EncodeToString(b) + `"`
 }
 
 func prefixParser(p *gofakes3.Prefix) (path, remaining string) {
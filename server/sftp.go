@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"encoding/hex"
 	"net/http"
 	"time"
 
@@ -67,14 +68,42 @@ func (d *SftpDriver) GetFileSystem(sc *ssh.ServerConn) (sftpd.FileSystem, error)
 	if err != nil {
 		return nil, err
 	}
+	session, err := sftpSession(sc, userObj)
+	if err != nil {
+		_ = sc.Close()
+		return nil, err
+	}
+	rootUser, err := ftp.ChrootUser(userObj)
+	if err != nil {
+		return nil, err
+	}
 	ctx := context.Background()
-	ctx = context.WithValue(ctx, conf.UserKey, userObj)
+	ctx = ftp.WithSession(ctx, session)
+	ctx = context.WithValue(ctx, conf.UserKey, rootUser)
 	ctx = context.WithValue(ctx, conf.MetaPassKey, "")
 	ctx = context.WithValue(ctx, conf.ClientIPKey, sc.RemoteAddr().String())
 	ctx = context.WithValue(ctx, conf.ProxyHeaderKey, d.proxyHeader)
 	return &sftp.DriverAdapter{FtpDriver: ftp.NewAferoAdapter(ctx)}, nil
 }
 
+// sftpSession returns the session of the ssh connection, registering it
+// on the first channel that is opened.
+func sftpSession(sc *ssh.ServerConn, user *model.User) (*ftp.Session, error) {
+	id := "sftp-" + hex.EncodeToString(sc.SessionID()[:8])
+	if s, ok := ftp.GetSession(id); ok {
+		return s, nil
+	}
+	s, err := ftp.NewSession(id, model.ProtocolSFTP, user, sc.RemoteAddr().String(), sc.Close)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		_ = sc.Wait()
+		ftp.RemoveSession(id)
+	}()
+	return s, nil
+}
+
 func (d *SftpDriver) Close() {
 }
 
@@ -89,7 +118,7 @@ func (d *SftpDriver) NoClientAuth(conn ssh.ConnMetadata) (*ssh.Permissions, erro
 	if guest.Disabled || !guest.CanFTPAccess() {
 		return nil, errors.New("user is not allowed to access via SFTP")
 	}
-	return nil, nil
+	return nil, ftp.CheckPolicy(guest, model.ProtocolSFTP, conn.RemoteAddr().String())
 }
 
 func (d *SftpDriver) PasswordAuth(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
@@ -118,7 +147,7 @@ func (d *SftpDriver) PasswordAuth(conn ssh.ConnMetadata, password []byte) (*ssh.
 		return nil, errors.New("user is not allowed to access via SFTP")
 	}
 	model.LoginCache.Del(ip)
-	return nil, nil
+	return nil, ftp.CheckPolicy(userObj, model.ProtocolSFTP, ip)
 }
 
 func (d *SftpDriver) PublicKeyAuth(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
@@ -141,6 +170,9 @@ func (d *SftpDriver) PublicKeyAuth(conn ssh.ConnMetadata, key ssh.PublicKey) (*s
 				continue
 			}
 		}
+		if err = ftp.CheckPolicy(userObj, model.ProtocolSFTP, conn.RemoteAddr().String()); err != nil {
+			return nil, err
+		}
 		sk.LastUsedTime = time.Now()
 		_ = op.UpdateSSHPublicKey(&sk)
 		return nil, nil
@@ -0,0 +1,558 @@
+package sftp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	stdpath "path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/ftp"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// serveExec runs an exec request and returns its exit status.
+// Only the legacy scp protocol (scp -O, or scp of OpenSSH before 9.0) is
+// supported, rsync has to fall back to sftp based tools.
+func serveExec(channel ssh.Channel, fs *ftp.AferoAdapter, command string) uint32 {
+	args, err := splitCommand(command)
+	if err != nil || len(args) == 0 {
+		_, _ = fmt.Fprintf(channel.Stderr(), "invalid command: %s\n", command)
+		return 1
+	}
+	switch stdpath.Base(args[0]) {
+	case "scp":
+		s, err := newSCP(channel, fs, args[1:])
+		if err != nil {
+			_, _ = fmt.Fprintf(channel.Stderr(), "scp: %s\n", err)
+			return 1
+		}
+		if err = s.run(); err != nil {
+			utils.Log.Debugf("[SFTP] scp failed: %+v", err)
+			return 1
+		}
+		if s.failed {
+			return 1
+		}
+		return 0
+	case "rsync":
+		_, _ = fmt.Fprintln(channel.Stderr(), "rsync is not supported by this server, please use scp or sftp")
+		return 1
+	default:
+		_, _ = fmt.Fprintf(channel.Stderr(), "%s: command not supported, only sftp and scp are available\n", args[0])
+		return 127
+	}
+}
+
+// splitCommand splits a command line into words the way a posix shell does
+// for quotes and backslashes, scp quotes the paths it sends.
+func splitCommand(s string) ([]string, error) {
+	var (
+		args    []string
+		cur     strings.Builder
+		inWord  bool
+		quote   byte
+		escaped bool
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escaped:
+			cur.WriteByte(c)
+			escaped = false
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				cur.WriteByte(c)
+			}
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else if c == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`", s[i+1]) >= 0 {
+				i++
+				cur.WriteByte(s[i])
+			} else {
+				cur.WriteByte(c)
+			}
+		case c == '\\':
+			escaped, inWord = true, true
+		case c == '\'' || c == '"':
+			quote, inWord = c, true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				args = append(args, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteByte(c)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
+type scp struct {
+	channel ssh.Channel
+	in      *bufio.Reader
+	fs      *ftp.AferoAdapter
+
+	sink      bool
+	recursive bool
+	preserve  bool
+	targetDir bool
+	paths     []string
+	// failed records that a warning has been sent, which makes the exit status 1
+	failed bool
+}
+
+func newSCP(channel ssh.Channel, fs *ftp.AferoAdapter, args []string) (*scp, error) {
+	s := &scp{channel: channel, in: bufio.NewReader(channel), fs: fs}
+	var mode bool
+	i := 0
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			i++
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			break
+		}
+		for _, f := range arg[1:] {
+			switch f {
+			case 't':
+				s.sink, mode = true, true
+			case 'f':
+				s.sink, mode = false, true
+			case 'r':
+				s.recursive = true
+			case 'p':
+				s.preserve = true
+			case 'd':
+				s.targetDir = true
+			case 'v', 'q':
+			default:
+				return nil, errors.Errorf("unsupported option -%c", f)
+			}
+		}
+	}
+	s.paths = args[i:]
+	if !mode {
+		return nil, errors.New("either -t or -f is required")
+	}
+	if len(s.paths) == 0 || (s.sink && len(s.paths) != 1) {
+		return nil, errors.New("bad number of paths")
+	}
+	return s, nil
+}
+
+func (s *scp) run() error {
+	if s.sink {
+		return s.receive()
+	}
+	return s.send()
+}
+
+// warn reports a non fatal error to the other side, the transfer goes on.
+func (s *scp) warn(format string, args ...any) error {
+	s.failed = true
+	msg := strings.ReplaceAll(fmt.Sprintf(format, args...), "\n", " ")
+	_, err := fmt.Fprintf(s.channel, "\x01scp: %s\n", msg)
+	return err
+}
+
+func (s *scp) ack() error {
+	_, err := s.channel.Write([]byte{0})
+	return err
+}
+
+// readAck waits for the response of the other side. A warning is returned as
+// warnError, anything else is fatal.
+func (s *scp) readAck() error {
+	b, err := s.in.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch b {
+	case 0:
+		return nil
+	case 1, 2:
+		msg, _ := s.in.ReadString('\n')
+		msg = strings.TrimSuffix(msg, "\n")
+		if b == 1 {
+			return warnError(msg)
+		}
+		return errors.New(msg)
+	default:
+		return errors.Errorf("unexpected response %q", b)
+	}
+}
+
+type warnError string
+
+func (w warnError) Error() string { return string(w) }
+
+func isWarn(err error) bool {
+	_, ok := err.(warnError)
+	return ok
+}
+
+// receive is the sink side (scp -t): the client pushes files to us.
+func (s *scp) receive() error {
+	target := utils.FixAndCleanPath(s.paths[0])
+	targetIsDir := false
+	if fi, err := s.fs.Stat(target); err == nil {
+		targetIsDir = fi.IsDir()
+	}
+	if s.targetDir && !targetIsDir {
+		_ = s.warn("%s: Not a directory", target)
+		return nil
+	}
+	if err := s.ack(); err != nil {
+		return err
+	}
+	var dirs []scpDir
+	// the modified time of the next file or dir sent with -p
+	var mtime time.Time
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line == "" {
+				return nil
+			}
+			return err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return errors.New("empty control line")
+		}
+		switch line[0] {
+		case 0x01:
+			// warnings of the client go to its own stderr, nothing to do
+			s.failed = true
+			continue
+		case 0x02:
+			return errors.New(line[1:])
+		case 'E':
+			if len(dirs) == 0 {
+				return errors.New("unexpected end of directory")
+			}
+			s.setModTime(dirs[len(dirs)-1].path, dirs[len(dirs)-1].mtime)
+			dirs = dirs[:len(dirs)-1]
+			if err = s.ack(); err != nil {
+				return err
+			}
+			continue
+		case 'T':
+			// the access time is dropped, the storages only keep the modified time
+			var sec, usec, asec, ausec int64
+			if _, err = fmt.Sscanf(line[1:], "%d %d %d %d", &sec, &usec, &asec, &ausec); err != nil {
+				return errors.Errorf("protocol error: %s", line)
+			}
+			mtime = time.Unix(sec, usec*int64(time.Microsecond))
+			if err = s.ack(); err != nil {
+				return err
+			}
+			continue
+		case 'C', 'D':
+		default:
+			return errors.Errorf("protocol error: %s", line)
+		}
+		parts := strings.SplitN(line[1:], " ", 3)
+		if len(parts) != 3 {
+			return errors.Errorf("protocol error: %s", line)
+		}
+		size, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || size < 0 {
+			return errors.Errorf("protocol error: bad size %s", parts[1])
+		}
+		name := parts[2]
+		if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+			return errors.Errorf("protocol error: unexpected filename %s", name)
+		}
+		var dst string
+		switch {
+		case len(dirs) > 0:
+			dst = stdpath.Join(dirs[len(dirs)-1].path, name)
+		case targetIsDir:
+			dst = stdpath.Join(target, name)
+		default:
+			dst = target
+		}
+		if line[0] == 'D' {
+			if !s.recursive {
+				return errors.New("received directory without -r")
+			}
+			// on a warning the client skips the whole directory
+			if fi, err := s.fs.Stat(dst); err != nil {
+				if err = s.fs.Mkdir(dst, 0755); err != nil {
+					if err = s.warn("%s: %s", dst, err); err != nil {
+						return err
+					}
+					continue
+				}
+			} else if !fi.IsDir() {
+				if err = s.warn("%s: Not a directory", dst); err != nil {
+					return err
+				}
+				continue
+			}
+			dirs = append(dirs, scpDir{path: dst, mtime: mtime})
+			mtime = time.Time{}
+			if err = s.ack(); err != nil {
+				return err
+			}
+			continue
+		}
+		err = s.receiveFile(dst, size, mtime)
+		mtime = time.Time{}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// scpDir is a dir being received, its time is set once it's done.
+type scpDir struct {
+	path  string
+	mtime time.Time
+}
+
+// setModTime keeps the time sent with -p as far as the storage allows.
+func (s *scp) setModTime(dst string, mtime time.Time) {
+	if mtime.IsZero() {
+		return
+	}
+	if err := s.fs.Chtimes(dst, mtime, mtime); err != nil {
+		utils.Log.Debugf("[SFTP] scp failed set the time of %s: %+v", dst, err)
+	}
+}
+
+func (s *scp) receiveFile(dst string, size int64, mtime time.Time) error {
+	s.fs.SetNextFileSize(size)
+	h, err := s.fs.GetHandle(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0)
+	if err != nil {
+		// the client skips the data once it gets the warning
+		return s.warn("%s: %s", dst, err)
+	}
+	if err = s.ack(); err != nil {
+		_ = h.Close()
+		return err
+	}
+	w := &drainWriter{w: h}
+	if _, err = io.CopyN(w, s.in, size); err != nil {
+		_ = h.Close()
+		return err
+	}
+	werr := w.err
+	if cerr := h.Close(); werr == nil {
+		werr = cerr
+	}
+	if err = s.readAck(); err != nil && !isWarn(err) {
+		return err
+	}
+	if werr != nil {
+		return s.warn("%s: %s", dst, werr)
+	}
+	s.setModTime(dst, mtime)
+	return s.ack()
+}
+
+// drainWriter keeps consuming the data after the first write error, so that
+// the stream stays in sync with the protocol.
+type drainWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (d *drainWriter) Write(p []byte) (int, error) {
+	if d.err == nil {
+		_, d.err = d.w.Write(p)
+	}
+	return len(p), nil
+}
+
+// send is the source side (scp -f): the client pulls files from us.
+func (s *scp) send() error {
+	if err := s.readAck(); err != nil {
+		return err
+	}
+	for _, p := range s.paths {
+		p = utils.FixAndCleanPath(p)
+		matches, err := s.glob(p)
+		if err != nil {
+			if err = s.warn("%s: %s", p, err); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, m := range matches {
+			if err = s.sendEntry(m.path, m.info); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type scpEntry struct {
+	path string
+	info os.FileInfo
+}
+
+// glob expands wildcards in the last element of p, which a shell would have
+// done for the scp started by a regular ssh server.
+func (s *scp) glob(p string) ([]scpEntry, error) {
+	dir, pattern := stdpath.Split(p)
+	if !strings.ContainsAny(pattern, "*?[") {
+		fi, err := s.fs.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		return []scpEntry{{path: p, info: fi}}, nil
+	}
+	list, err := s.fs.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var res []scpEntry
+	for _, fi := range list {
+		if ok, _ := stdpath.Match(pattern, fi.Name()); ok {
+			res = append(res, scpEntry{path: stdpath.Join(dir, fi.Name()), info: fi})
+		}
+	}
+	if len(res) == 0 {
+		return nil, errors.New("No such file or directory")
+	}
+	return res, nil
+}
+
+func (s *scp) sendEntry(p string, fi os.FileInfo) error {
+	name := stdpath.Base(p)
+	if fi.IsDir() {
+		if !s.recursive {
+			return s.warn("%s: not a regular file", p)
+		}
+		return s.sendDir(p, name, fi)
+	}
+	return s.sendFile(p, name, fi)
+}
+
+func (s *scp) sendTimes(fi os.FileInfo) error {
+	if !s.preserve {
+		return nil
+	}
+	t := fi.ModTime().Unix()
+	if _, err := fmt.Fprintf(s.channel, "T%d 0 %d 0\n", t, t); err != nil {
+		return err
+	}
+	return s.readAck()
+}
+
+func (s *scp) sendDir(p, name string, fi os.FileInfo) error {
+	list, err := s.fs.ReadDir(p)
+	if err != nil {
+		return s.warn("%s: %s", p, err)
+	}
+	if err = s.sendTimes(fi); err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(s.channel, "D%04o 0 %s\n", 0755, name); err != nil {
+		return err
+	}
+	if err = s.readAck(); err != nil {
+		if isWarn(err) {
+			return nil
+		}
+		return err
+	}
+	for _, child := range list {
+		if err = s.sendEntry(stdpath.Join(p, child.Name()), child); err != nil {
+			return err
+		}
+	}
+	if _, err = fmt.Fprint(s.channel, "E\n"); err != nil {
+		return err
+	}
+	return s.readAck()
+}
+
+func (s *scp) sendFile(p, name string, fi os.FileInfo) error {
+	h, err := s.fs.GetHandle(p, os.O_RDONLY, 0)
+	if err != nil {
+		return s.warn("%s: %s", p, err)
+	}
+	defer func() { _ = h.Close() }()
+	if err = s.sendTimes(fi); err != nil {
+		return err
+	}
+	size := fi.Size()
+	if _, err = fmt.Fprintf(s.channel, "C%04o %d %s\n", 0644, size, name); err != nil {
+		return err
+	}
+	if err = s.readAck(); err != nil {
+		if isWarn(err) {
+			return nil
+		}
+		return err
+	}
+	r := &errReader{r: h}
+	n, err := io.CopyN(s.channel, r, size)
+	if err != nil && r.err == nil {
+		// the channel is broken
+		return err
+	}
+	if n < size {
+		// the size has been announced, pad the file and tell the client it is broken
+		if _, err = io.CopyN(s.channel, zeroReader{}, size-n); err != nil {
+			return err
+		}
+		rerr := r.err
+		if rerr == nil || rerr == io.EOF {
+			rerr = errors.New("file has shrunk")
+		}
+		if err = s.warn("%s: %s", p, rerr); err != nil {
+			return err
+		}
+	} else if err = s.ack(); err != nil {
+		return err
+	}
+	if err = s.readAck(); err != nil && !isWarn(err) {
+		return err
+	}
+	return nil
+}
+
+// errReader remembers the error of the file, to tell it apart from errors of the channel.
+type errReader struct {
+	r   io.Reader
+	err error
+}
+
+func (e *errReader) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	if err != nil {
+		e.err = err
+	}
+	return n, err
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,182 @@
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/server/ftp"
+	"golang.org/x/time/rate"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	stream.ClientUploadLimit = rate.NewLimiter(rate.Inf, 0)
+	stream.ClientDownloadLimit = rate.NewLimiter(rate.Inf, 0)
+	ftp.InitStage()
+}
+
+// fakeChannel plays the client with the input written in advance, which
+// doesn't depend on the responses.
+type fakeChannel struct {
+	io.Reader
+	out    bytes.Buffer
+	stderr bytes.Buffer
+}
+
+func (c *fakeChannel) Write(p []byte) (int, error) { return c.out.Write(p) }
+func (c *fakeChannel) Close() error                { return nil }
+func (c *fakeChannel) CloseWrite() error           { return nil }
+func (c *fakeChannel) SendRequest(string, bool, []byte) (bool, error) {
+	return true, nil
+}
+func (c *fakeChannel) Stderr() io.ReadWriter { return &c.stderr }
+
+// localFS mounts a local storage at /local, and returns the file system of
+// an admin with its root.
+func localFS(t *testing.T) (*ftp.AferoAdapter, string) {
+	root := t.TempDir()
+	ctx := context.Background()
+	id, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/local",
+		Addition:  `{"root_folder_path":"` + root + `"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = op.DeleteStorageById(ctx, id) })
+	ctx = context.WithValue(ctx, conf.UserKey, &model.User{Username: "admin", BasePath: "/", Role: model.ADMIN, Permission: 0xffff})
+	ctx = context.WithValue(ctx, conf.MetaPassKey, "")
+	return ftp.NewAferoAdapter(ctx), root
+}
+
+func TestSplitCommand(t *testing.T) {
+	for cmd, expect := range map[string][]string{
+		`scp -t /a`:                            {"scp", "-t", "/a"},
+		`scp  -r -t   '/a b/c'`:                {"scp", "-r", "-t", "/a b/c"},
+		`scp -f "/a \"b\" \$c"`:                {"scp", "-f", `/a "b" $c`},
+		`scp -f /a\ b 'it'\''s'`:               {"scp", "-f", "/a b", "it's"},
+		`rsync --server -logDtpre.iLsfxC . ''`: {"rsync", "--server", "-logDtpre.iLsfxC", ".", ""},
+	} {
+		got, err := splitCommand(cmd)
+		if err != nil || !reflect.DeepEqual(got, expect) {
+			t.Errorf("%s: expect %q, got %q, %v", cmd, expect, got, err)
+		}
+	}
+	for _, cmd := range []string{`scp -t '/a`, `scp -t "/a`, `scp -t /a\`} {
+		if _, err := splitCommand(cmd); err == nil {
+			t.Errorf("%s: expect an error", cmd)
+		}
+	}
+}
+
+func TestSCPReceive(t *testing.T) {
+	fs, root := localFS(t)
+	// scp -rp dir host:/local, with a file and a sub dir
+	channel := &fakeChannel{Reader: strings.NewReader("" +
+		"T1500000000 0 1500000000 0\n" +
+		"D0755 0 dir\n" +
+		"T1577934245 0 1577934245 0\n" +
+		"C0644 5 a.txt\nhello\x00" +
+		"D0755 0 sub\n" +
+		"C0644 3 b.txt\nabc\x00" +
+		"E\n" +
+		"E\n")}
+	s, err := newSCP(channel, fs, []string{"-r", "-p", "-t", "/local"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.run(); err != nil {
+		t.Fatalf("%v: %q", err, channel.out.String())
+	}
+	if s.failed || channel.out.String() != strings.Repeat("\x00", 11) {
+		t.Fatalf("unexpected response %q, failed %v", channel.out.String(), s.failed)
+	}
+	for name, expect := range map[string]string{"dir/a.txt": "hello", "dir/sub/b.txt": "abc"} {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil || string(data) != expect {
+			t.Errorf("%s: expect %q, got %q, %v", name, expect, data, err)
+		}
+	}
+	// the times of -p are kept, the file without one has the time of now
+	for name, expect := range map[string]int64{"dir": 1500000000, "dir/a.txt": 1577934245} {
+		fi, err := os.Stat(filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.ModTime().Unix() != expect {
+			t.Errorf("%s: expect the time %d, got %d", name, expect, fi.ModTime().Unix())
+		}
+	}
+	if fi, err := os.Stat(filepath.Join(root, "dir", "sub", "b.txt")); err != nil || time.Since(fi.ModTime()) > time.Minute {
+		t.Errorf("expect b.txt written now, got %v", err)
+	}
+
+	// a file into a missing dir is refused with a warning
+	channel = &fakeChannel{Reader: strings.NewReader("")}
+	s, err = newSCP(channel, fs, []string{"-d", "-t", "/local/missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.run(); err != nil {
+		t.Fatal(err)
+	}
+	if !s.failed || !strings.HasPrefix(channel.out.String(), "\x01scp: ") {
+		t.Errorf("expect a warning, got %q", channel.out.String())
+	}
+}
+
+func TestSCPSend(t *testing.T) {
+	fs, root := localFS(t)
+	if err := os.MkdirAll(filepath.Join(root, "dir"), 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "dir", "a.txt"), []byte("hello"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	// the client acks the start, the dir, the file header, the data and the end
+	channel := &fakeChannel{Reader: strings.NewReader(strings.Repeat("\x00", 5))}
+	s, err := newSCP(channel, fs, []string{"-r", "-f", "/local/d*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.run(); err != nil {
+		t.Fatal(err)
+	}
+	expect := "D0755 0 dir\nC0644 5 a.txt\nhello\x00E\n"
+	if s.failed || channel.out.String() != expect {
+		t.Errorf("expect %q, got %q", expect, channel.out.String())
+	}
+
+	channel = &fakeChannel{Reader: strings.NewReader("\x00")}
+	s, err = newSCP(channel, fs, []string{"-f", "/local/dir"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.run(); err != nil {
+		t.Fatal(err)
+	}
+	if !s.failed || !strings.Contains(channel.out.String(), "not a regular file") {
+		t.Errorf("expect the dir refused without -r, got %q", channel.out.String())
+	}
+}
@@ -0,0 +1,165 @@
+package sftp
+
+import (
+	"net"
+	"sync"
+
+	"github.com/OpenListTeam/sftpd-openlist"
+	"golang.org/x/crypto/ssh"
+)
+
+// Server is the ssh endpoint of openlist. It serves the sftp subsystem through
+// sftpd and the scp protocol through exec requests, sharing the
+// authentication and file system of the driver.
+type Server struct {
+	driver sftpd.SftpDriver
+
+	mu       sync.Mutex
+	listener net.Listener
+	closed   bool
+}
+
+func NewServer(driver sftpd.SftpDriver) *Server {
+	return &Server{driver: driver}
+}
+
+// RunServer listens on the configured address and blocks until the server is closed.
+func (s *Server) RunServer() error {
+	l, err := net.Listen("tcp", s.driver.GetConfig().HostPort)
+	if err != nil {
+		s.logError("sftpd server failed:", err)
+		return err
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return l.Close()
+	}
+	s.listener = l
+	s.mu.Unlock()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			s.logError("sftpd server failed:", err)
+			return err
+		}
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	l := s.listener
+	s.mu.Unlock()
+	var err error
+	if l != nil {
+		err = l.Close()
+	}
+	s.driver.Close()
+	return err
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	sc, chans, reqs, err := ssh.NewServerConn(conn, &s.driver.GetConfig().ServerConfig)
+	if err != nil {
+		s.logError("sftpd connection error:", err)
+		return
+	}
+	defer func() { _ = sc.Close() }()
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			s.logError("sftpd connection error:", err)
+			return
+		}
+		go s.handleSession(sc, channel, requests)
+	}
+}
+
+// handleSession serves the first sftp or exec request of a session channel,
+// the channel is closed once that is done.
+func (s *Server) handleSession(sc *ssh.ServerConn, channel ssh.Channel, requests <-chan *ssh.Request) {
+	started := false
+	for req := range requests {
+		if started {
+			// only window-change and such may follow, none of them matter to us
+			if req.WantReply {
+				_ = req.Reply(false, nil)
+			}
+			continue
+		}
+		switch {
+		case sftpd.IsSftpRequest(req):
+			started = true
+			_ = req.Reply(true, nil)
+			go func() {
+				defer func() { _ = channel.Close() }()
+				fs, err := s.driver.GetFileSystem(sc)
+				if err == nil {
+					err = sftpd.ServeChannel(channel, fs, s.debugf())
+				}
+				if err != nil {
+					s.logError("sftpd servechannel failed:", err)
+				}
+			}()
+		case req.Type == "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			started = true
+			_ = req.Reply(true, nil)
+			go func() {
+				defer func() { _ = channel.Close() }()
+				fs, err := s.driver.GetFileSystem(sc)
+				if err != nil {
+					s.logError("sftpd exec failed:", err)
+					exit(channel, 1)
+					return
+				}
+				adapter, ok := fs.(*DriverAdapter)
+				if !ok {
+					exit(channel, 1)
+					return
+				}
+				exit(channel, serveExec(channel, adapter.FtpDriver, payload.Command))
+			}()
+		default:
+			// env, pty-req and shell are not supported
+			if req.WantReply {
+				_ = req.Reply(false, nil)
+			}
+		}
+	}
+}
+
+func (s *Server) debugf() sftpd.DebugLogger {
+	if f := s.driver.GetConfig().DebugLogFunc; f != nil {
+		return f
+	}
+	return func(string, ...interface{}) {}
+}
+
+func (s *Server) logError(v ...interface{}) {
+	if f := s.driver.GetConfig().ErrorLogFunc; f != nil {
+		f(v...)
+	}
+}
+
+func exit(channel ssh.Channel, status uint32) {
+	_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+}
@@ -16,6 +16,8 @@ import (
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
@@ -281,9 +283,100 @@ func allprop(ctx context.Context, ls LockSystem, fi model.Obj, include []xml.Nam
 	return props(ctx, ls, fi, pnames)
 }
 
+// timeProps are the properties set by the clients to keep the times of the
+// uploaded files, which are applied by the storage instead of being stored.
+var timeProps = map[xml.Name]struct {
+	parse   func(string) (time.Time, error)
+	created bool
+}{
+	{Space: "DAV:", Local: "getlastmodified"}:                             {parse: http.ParseTime},
+	{Space: "DAV:", Local: "lastmodified"}:                                {parse: parseUnixTime},
+	{Space: "urn:schemas-microsoft-com:", Local: "Win32LastModifiedTime"}: {parse: http.ParseTime},
+	{Space: "urn:schemas-microsoft-com:", Local: "Win32CreationTime"}:     {parse: http.ParseTime, created: true},
+}
+
+func parseUnixTime(s string) (time.Time, error) {
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// patchTimes applies the time properties set by the patches, and returns
+// the propstats of them and the patches left.
+func patchTimes(ctx context.Context, name string, patches []Proppatch) ([]Propstat, []Proppatch, error) {
+	var (
+		modified, created time.Time
+		props, invalid    []Property
+		rest              []Proppatch
+	)
+	for _, patch := range patches {
+		left := Proppatch{Remove: patch.Remove}
+		for _, p := range patch.Props {
+			tp, ok := timeProps[p.XMLName]
+			if !ok || patch.Remove {
+				left.Props = append(left.Props, p)
+				continue
+			}
+			t, err := tp.parse(strings.TrimSpace(string(p.InnerXML)))
+			if err != nil {
+				invalid = append(invalid, Property{XMLName: p.XMLName})
+				continue
+			}
+			if tp.created {
+				created = t
+			} else {
+				modified = t
+			}
+			props = append(props, Property{XMLName: p.XMLName})
+		}
+		if len(left.Props) > 0 {
+			rest = append(rest, left)
+		}
+	}
+	var pstats []Propstat
+	if len(invalid) > 0 {
+		pstats = append(pstats, Propstat{Status: http.StatusConflict, Props: invalid})
+	}
+	if len(props) == 0 {
+		return pstats, rest, nil
+	}
+	pstat := Propstat{Status: http.StatusOK, Props: props}
+	user := ctx.Value(conf.UserKey).(*model.User)
+	if !user.CanWrite() {
+		pstat.Status = http.StatusForbidden
+		return append(pstats, pstat), rest, nil
+	}
+	if modified.IsZero() {
+		// the creation time only, the modified one is kept
+		obj, err := fs.Get(ctx, name, &fs.GetArgs{})
+		if err != nil {
+			return nil, nil, err
+		}
+		modified = obj.ModTime()
+	}
+	if err := fs.SetModTime(ctx, name, modified, created); err != nil {
+		if !errors.Is(err, errs.NotImplement) && !errors.Is(err, errs.NotSupport) {
+			return nil, nil, err
+		}
+		pstat.Status = http.StatusForbidden
+	}
+	return append(pstats, pstat), rest, nil
+}
+
 // Patch patches the properties of resource name. The return values are
 // constrained in the same manner as DeadPropsHolder.Patch.
 func patch(ctx context.Context, ls LockSystem, name string, patches []Proppatch) ([]Propstat, error) {
+	timePstats, patches, err := patchTimes(ctx, name, patches)
+	if err != nil || len(patches) == 0 {
+		return timePstats, err
+	}
+	pstats, err := patchProps(ctx, ls, name, patches)
+	return append(timePstats, pstats...), err
+}
+
+func patchProps(ctx context.Context, ls LockSystem, name string, patches []Proppatch) ([]Propstat, error) {
 	conflict := false
 loop:
 	for _, patch := range patches {
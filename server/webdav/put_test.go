@@ -0,0 +1,72 @@
+package webdav
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func TestPutModTime(t *testing.T) {
+	root := t.TempDir()
+	ctx := context.Background()
+	id, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/dav",
+		Addition:  `{"root_folder_path":"` + root + `"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer op.DeleteStorageById(ctx, id)
+	h := &Handler{LockSystem: NewMemLS()}
+	user := &model.User{Username: "admin", BasePath: "/", Role: model.ADMIN}
+
+	for _, c := range []struct {
+		mtime    string
+		accepted bool
+	}{
+		{mtime: "1577934245", accepted: true},
+		{mtime: "yesterday"},
+	} {
+		r := httptest.NewRequest(http.MethodPut, "/dav/a.txt", strings.NewReader("abc"))
+		r = r.WithContext(context.WithValue(r.Context(), conf.UserKey, user))
+		r.Header.Set("X-OC-Mtime", c.mtime)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("%s: unexpected status %d: %s", c.mtime, w.Code, w.Body)
+		}
+		if accepted := w.Header().Get("X-OC-Mtime") == "accepted"; accepted != c.accepted {
+			t.Errorf("%s: expect accepted %v, got %v", c.mtime, c.accepted, accepted)
+		}
+		fi, err := os.Stat(filepath.Join(root, "a.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.accepted && !fi.ModTime().Equal(time.Unix(1577934245, 0)) {
+			t.Errorf("%s: the modified time isn't kept: %v", c.mtime, fi.ModTime())
+		}
+	}
+}
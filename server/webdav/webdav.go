@@ -26,8 +26,10 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
+	log "github.com/sirupsen/logrus"
 )
 
 type Handler struct {
@@ -91,6 +93,9 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if status != 0 {
+		for k, vs := range brw.Header() {
+			w.Header()[k] = vs
+		}
 		w.WriteHeader(status)
 		if status != http.StatusNoContent {
 			w.Write([]byte(StatusText(status)))
@@ -343,6 +348,9 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request) (status int,
 	if err != nil {
 		return http.StatusForbidden, err
 	}
+	if contentRange := r.Header.Get("Content-Range"); contentRange != "" {
+		return h.handlePutRange(w, r, reqPath, contentRange)
+	}
 	size := r.ContentLength
 	if size < 0 {
 		sizeStr := r.Header.Get("X-File-Size")
@@ -389,6 +397,87 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request) (status int,
 		return http.StatusInternalServerError, err
 	}
 	w.Header().Set("Etag", etag)
+	if mtime := r.Header.Get("X-OC-Mtime"); mtime != "" {
+		// tells the clients the time is kept, as ownCloud does
+		if unix, err := strconv.ParseInt(mtime, 10, 64); err == nil {
+			if err = fs.SetModTime(ctx, reqPath, time.Unix(unix, 0), time.Time{}); err == nil {
+				w.Header().Set("X-OC-Mtime", "accepted")
+			} else {
+				log.Warnf("failed set the modified time of [%s]: %+v", reqPath, err)
+			}
+		}
+	}
+	return http.StatusCreated, nil
+}
+
+// handlePutRange receives one chunk of a resumable upload. The chunks are sent
+// in order as "Content-Range: bytes start-end/size" and staged until the file
+// is complete, "bytes */size" with an empty body asks what has been received.
+// The response to an incomplete upload is 202 with a Range header of the
+// received bytes, a chunk that does not continue them gets 416.
+func (h *Handler) handlePutRange(w http.ResponseWriter, r *http.Request, reqPath, contentRange string) (int, error) {
+	ctx := r.Context()
+	start, end, size, err := http_range.ParseUploadContentRange(contentRange)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if setting.GetBool(conf.IgnoreSystemFiles) && utils.IsSystemFile(path.Base(reqPath)) {
+		return http.StatusForbidden, errs.IgnoredSystemFile
+	}
+	user := ctx.Value(conf.UserKey).(*model.User)
+	if start < 0 {
+		if st, ok := fs.FindUploadStage(user.ID, reqPath, size); ok {
+			if rng := st.Range(); rng != "" {
+				w.Header().Set("Range", rng)
+			}
+		}
+		return http.StatusAccepted, nil
+	}
+	mimetype := r.Header.Get("Content-Type")
+	if mimetype == "" || mimetype == "application/octet-stream" {
+		mimetype = utils.GetMimeType(reqPath)
+	}
+	st, err := fs.PutRange(ctx, reqPath, r.Body, start, end, size, mimetype, h.getModTime(r))
+	if st != nil {
+		if rng := st.Range(); rng != "" {
+			w.Header().Set("Range", rng)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, fs.ErrUploadOffsetMismatch) {
+			return http.StatusRequestedRangeNotSatisfiable, err
+		}
+		if errors.Is(err, fs.ErrUploadStageBusy) {
+			return http.StatusConflict, err
+		}
+		if errors.Is(err, fs.ErrUploadStageFull) {
+			return http.StatusInsufficientStorage, err
+		}
+		if errors.Is(err, fs.ErrUploadStageOverflow) {
+			st.Remove()
+			return http.StatusBadRequest, err
+		}
+		return http.StatusInternalServerError, err
+	}
+	if !st.Complete() {
+		return http.StatusAccepted, nil
+	}
+	w.Header().Del("Range")
+	if _, err = st.Commit(ctx, false); err != nil {
+		if errs.IsNotFoundError(err) {
+			return http.StatusNotFound, err
+		}
+		return http.StatusMethodNotAllowed, err
+	}
+	fi, err := fs.Get(ctx, reqPath, &fs.GetArgs{})
+	if err != nil {
+		fi = &model.Object{Name: path.Base(reqPath), Size: size, Modified: st.Modified}
+	}
+	etag, err := findETag(ctx, h.LockSystem, reqPath, fi)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	w.Header().Set("Etag", etag)
 	return http.StatusCreated, nil
 }
 